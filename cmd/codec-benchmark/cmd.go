@@ -0,0 +1,140 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+const (
+	maxDepth int = 8
+)
+
+/*
+	fill gives every field of an event a value so the encodings can be compared. Slices get
+	items elements, which is roughly how many messages or insights Symbl sends in one event.
+*/
+func fill(v reflect.Value, name string, items, depth int) {
+	if depth > maxDepth {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem(), name, items, depth+1)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Date(2023, 3, 1, 17, 4, 5, 123000000, time.UTC)))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			fill(v.Field(i), v.Type().Field(i).Name, items, depth+1)
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), items, items))
+		for j := 0; j < items; j++ {
+			fill(v.Index(j), fmt.Sprintf("%s %d", name, j), items, depth+1)
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		for j := 0; j < 2; j++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			value := reflect.New(v.Type().Elem()).Elem()
+			fill(key, fmt.Sprintf("%s key %d", name, j), items, depth+1)
+			fill(value, name, items, depth+1)
+			v.SetMapIndex(key, value)
+		}
+	case reflect.String:
+		v.SetString(fmt.Sprintf("sample %s text for the benchmark", name))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(4096)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(4096)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(0.8731)
+	}
+}
+
+func main() {
+	items := flag.Int("items", 5, "number of elements in each list of the sample events")
+	flag.Parse()
+
+	fmt.Printf("%-32s %10s %10s %7s %12s %12s %12s %12s\n",
+		"event", "json B", "proto B", "size", "json enc", "proto enc", "json dec", "proto dec")
+
+	failed := false
+	for _, eventType := range shared.EventTypes() {
+		event := shared.NewEvent(eventType)
+		fill(reflect.ValueOf(event).Elem(), eventType, *items, 0)
+
+		byJson, err := codec.Marshal(codec.ContentTypeJSON, event)
+		if err != nil {
+			fmt.Printf("json Marshal %s failed. Err: %v\n", eventType, err)
+			os.Exit(1)
+		}
+		byProto, err := codec.Marshal(codec.ContentTypeProtobuf, event)
+		if err != nil {
+			fmt.Printf("protobuf Marshal %s failed. Err: %v\n", eventType, err)
+			os.Exit(1)
+		}
+
+		// both encodings must give back the same event
+		decoded := shared.NewEvent(eventType)
+		err = codec.Unmarshal(byProto, decoded)
+		if err != nil {
+			fmt.Printf("protobuf Unmarshal %s failed. Err: %v\n", eventType, err)
+			os.Exit(1)
+		}
+		roundTrip, _ := json.Marshal(decoded)
+		if !bytes.Equal(roundTrip, byJson) {
+			fmt.Printf("%s does not round trip through protobuf\n", eventType)
+			failed = true
+		}
+
+		jsonEnc := testing.Benchmark(func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				codec.Marshal(codec.ContentTypeJSON, event)
+			}
+		})
+		protoEnc := testing.Benchmark(func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				codec.Marshal(codec.ContentTypeProtobuf, event)
+			}
+		})
+		jsonDec := testing.Benchmark(func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				codec.Unmarshal(byJson, shared.NewEvent(eventType))
+			}
+		})
+		protoDec := testing.Benchmark(func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				codec.Unmarshal(byProto, shared.NewEvent(eventType))
+			}
+		})
+
+		fmt.Printf("%-32s %10d %10d %6.0f%% %9dns %9dns %9dns %9dns\n",
+			eventType, len(byJson), len(byProto), 100*float64(len(byProto))/float64(len(byJson)),
+			jsonEnc.NsPerOp(), protoEnc.NsPerOp(), jsonDec.NsPerOp(), protoDec.NsPerOp())
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -15,7 +15,7 @@ import (
 
 func NewHandler(options HandlerOptions) *Handler {
 	handler := Handler{
-		session:     options.Session,
+		sessions:    options.Sessions,
 		symblClient: options.SymblClient,
 		cache:       make(map[string]*utils.MessageCache),
 	}
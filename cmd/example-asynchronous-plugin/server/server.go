@@ -5,14 +5,21 @@ package server
 
 import (
 	"context"
-	"os"
+	"fmt"
+	"net/http"
 
 	symbl "github.com/dvonthenen/symbl-go-sdk/pkg/client"
-	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	klog "k8s.io/klog/v2"
 
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	health "github.com/dvonthenen/enterprise-conversation-application/pkg/health"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	middlewaresdk "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk"
+	database "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/database"
 	interfacessdk "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	supervisor "github.com/dvonthenen/enterprise-conversation-application/pkg/supervisor"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 
 	handlers "github.com/dvonthenen/enterprise-conversation-application/cmd/example-asynchronous-plugin/handlers"
 )
@@ -22,42 +29,67 @@ func New(options ServerOptions) (*Server, error) {
 		options.BindPort = DefaultPort
 	}
 
-	var connectionStr string
-	if v := os.Getenv("NEO4J_CONNECTION"); v != "" {
-		klog.V(4).Info("NEO4J_CONNECTION found")
-		connectionStr = v
-	} else {
-		klog.Errorf("NEO4J_CONNECTION not found\n")
-		return nil, ErrInvalidInput
+	if options.Neo4j == nil {
+		neo4jConfig, err := dbconfig.FromEnv()
+		if err != nil {
+			klog.Errorf("dbconfig.FromEnv failed. Err: %v\n", err)
+			return nil, err
+		}
+		options.Neo4j = neo4jConfig
 	}
-	var username string
-	if v := os.Getenv("NEO4J_USERNAME"); v != "" {
-		klog.V(4).Info("NEO4J_USERNAME found")
-		username = v
-	} else {
-		klog.Errorf("NEO4J_USERNAME not found\n")
-		return nil, ErrInvalidInput
+
+	// tls
+	tlsOptions := tlsconfig.Options{
+		CrtFile: options.CrtFile,
+		KeyFile: options.KeyFile,
 	}
-	var password string
-	if v := os.Getenv("NEO4J_PASSWORD"); v != "" {
-		klog.V(4).Info("NEO4J_PASSWORD found")
-		password = v
-	} else {
-		klog.Errorf("NEO4J_PASSWORD not found\n")
-		return nil, ErrInvalidInput
+	if options.TLS != nil {
+		tlsOptions = *options.TLS
 	}
-
-	creds := Credentials{
-		ConnectionStr: connectionStr,
-		Username:      username,
-		Password:      password,
+	reloader, err := tlsconfig.New(tlsOptions)
+	if err != nil {
+		klog.Errorf("TLS options are invalid. Err: %v\n", err)
+		return nil, err
 	}
 
 	// server
 	server := &Server{
 		options: options,
-		creds:   creds,
+		tls:     reloader,
+		health:  health.New(health.CheckerOptions{}),
+	}
+
+	// reconnect the database and the subscribers when they drop, the Neo4j driver reopens its own connections
+	neo4jSupervisor, err := supervisor.New(supervisor.SupervisorOptions{
+		Component:  metrics.ComponentPlugin,
+		Connection: supervisor.ConnectionNeo4j,
+		Check: func(ctx context.Context) error {
+			return health.Neo4j(ctx, server.driver)
+		},
+	})
+	if err != nil {
+		klog.Errorf("supervisor.New failed. Err: %v\n", err)
+		return nil, err
+	}
+	busSupervisor, err := supervisor.New(supervisor.SupervisorOptions{
+		Component:  metrics.ComponentPlugin,
+		Connection: supervisor.ConnectionBus,
+		Check: func(ctx context.Context) error {
+			if server.middlewareAnalyzer == nil {
+				return health.ErrNotConnected
+			}
+			return server.middlewareAnalyzer.Healthy()
+		},
+		Reconnect: server.ReconnectAnalyzer,
+	})
+	if err != nil {
+		klog.Errorf("supervisor.New failed. Err: %v\n", err)
+		return nil, err
 	}
+	server.supervisors = []*supervisor.Supervisor{neo4jSupervisor, busSupervisor}
+
+	server.health.Add(health.CheckNeo4j, neo4jSupervisor.Healthy)
+	server.health.Add(health.CheckSubscribers, busSupervisor.Healthy)
 	return server, nil
 }
 
@@ -138,7 +170,64 @@ func (s *Server) Start() error {
 		return err
 	}
 
-	// TODO: start metrics and tracing
+	// metrics endpoint
+	if s.metrics == nil && s.options.Metrics != nil {
+		metricsServer := metrics.NewServer(*s.options.Metrics)
+		err := metricsServer.Start()
+		if err != nil {
+			klog.V(1).Infof("metrics.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.metrics = metricsServer
+	}
+
+	// tracing
+	if s.tracer == nil && s.options.Tracing != nil {
+		tracer, err := tracing.NewProvider(*s.options.Tracing)
+		if err != nil {
+			klog.V(1).Infof("tracing.NewProvider failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		err = tracer.Start()
+		if err != nil {
+			klog.V(1).Infof("tracer.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.tracer = tracer
+	}
+
+	// health endpoints
+	if s.server == nil {
+		mux := http.NewServeMux()
+		s.health.Register(mux)
+
+		s.server = &http.Server{
+			Addr:    fmt.Sprintf("%s:%d", s.options.BindAddress, s.options.BindPort),
+			Handler: mux,
+		}
+
+		server := s.server
+		go func() {
+			// this is a blocking call
+			err := s.tls.ListenAndServeTLS(server)
+			if err != nil {
+				klog.V(6).Infof("ListenAndServeTLS server stopped. Err: %v\n", err)
+			}
+		}()
+	}
+	// reconnect with backoff from now on
+	for _, sup := range s.supervisors {
+		err := sup.Start()
+		if err != nil {
+			klog.V(1).Infof("supervisor.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+	}
+	s.health.Started()
 
 	klog.V(4).Infof("Server.Start Succeeded\n")
 	klog.V(6).Infof("Server.Start LEAVE\n")
@@ -171,27 +260,42 @@ func (s *Server) RebuildDatabase() error {
 	klog.V(6).Infof("Server.RebuildDatabase ENTER\n")
 
 	//teardown
+	if s.sessions != nil {
+		s.sessions.Teardown(context.Background())
+		s.sessions = nil
+	}
 	if s.driver != nil {
 		ctx := context.Background()
 		(*s.driver).Close(ctx)
 		s.driver = nil
 	}
 
-	// init neo4j
-	auth := neo4j.BasicAuth(s.creds.Username, s.creds.Password, "")
-
 	// You typically have one driver instance for the entire application. The
 	// driver maintains a pool of database connections to be used by the sessions.
 	// The driver is thread safe.
-	driver, err := neo4j.NewDriverWithContext(s.creds.ConnectionStr, auth)
+	driver, err := s.options.Neo4j.NewDriver()
 	if err != nil {
-		klog.V(1).Infof("NewDriverWithContext failed. Err: %v\n", err)
+		klog.V(1).Infof("NewDriver failed. Err: %v\n", err)
+		klog.V(6).Infof("Server.RebuildDatabase LEAVE\n")
+		return err
+	}
+
+	// sessions are not thread safe, hand them out per conversation or callback
+	sessions, err := database.NewSessionFactory(database.SessionFactoryOptions{
+		Driver:          &driver,
+		DatabaseName:    s.options.Neo4j.Database(""),
+		TenantDatabases: s.options.Neo4j.TenantDatabases,
+		Encryption:      s.options.Encryption,
+	})
+	if err != nil {
+		klog.V(1).Infof("NewSessionFactory failed. Err: %v\n", err)
 		klog.V(6).Infof("Server.RebuildDatabase LEAVE\n")
 		return err
 	}
 
 	// housekeeping
 	s.driver = &driver
+	s.sessions = sessions
 
 	klog.V(4).Infof("Server.RebuildDatabase Succeeded\n")
 	klog.V(6).Infof("Server.RebuildDatabase LEAVE\n")
@@ -211,13 +315,9 @@ func (s *Server) RebuildAsynchronousAnalyzer() error {
 		s.middlewareAnalyzer = nil
 	}
 
-	// create database session
-	ctx := context.Background()
-	session := (*s.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-
 	// create handler
 	messageHandler := handlers.NewHandler(handlers.HandlerOptions{
-		Session:     &session,
+		Sessions:    s.sessions,
 		SymblClient: s.symblClient,
 	})
 
@@ -226,8 +326,11 @@ func (s *Server) RebuildAsynchronousAnalyzer() error {
 	callback = messageHandler
 
 	middlewareAnalyzer, err := middlewaresdk.NewAsynchronousAnalyzer(middlewaresdk.AsynchronousAnalyzerOption{
-		RabbitURI: s.options.RabbitURI,
-		Callback:  &callback,
+		RabbitURI:  s.options.RabbitURI,
+		Callback:   &callback,
+		Sessions:   s.sessions,
+		PluginName: "example-asynchronous-plugin",
+		Tenants:    s.options.Tenants,
 	})
 	if err != nil {
 		klog.V(1).Infof("NewAsynchronousAnalyzer failed. Err: %v\n", err)
@@ -244,10 +347,47 @@ func (s *Server) RebuildAsynchronousAnalyzer() error {
 	return nil
 }
 
+/*
+	ReconnectAnalyzer replaces the middleware after its bus dropped. The dead letter queue and
+	the shared queue consumer have their own connections, so everything is rebuilt and all of
+	the subscribers are created again.
+*/
+func (s *Server) ReconnectAnalyzer() error {
+	klog.V(6).Infof("Server.ReconnectAnalyzer ENTER\n")
+
+	err := s.RebuildAsynchronousAnalyzer()
+	if err != nil {
+		klog.V(1).Infof("RebuildAsynchronousAnalyzer failed. Err: %v\n", err)
+		klog.V(6).Infof("Server.ReconnectAnalyzer LEAVE\n")
+		return err
+	}
+
+	err = s.middlewareAnalyzer.Init()
+	if err != nil {
+		klog.V(1).Infof("middlewareAnalyzer.Init() failed. Err: %v\n", err)
+		klog.V(6).Infof("Server.ReconnectAnalyzer LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Server.ReconnectAnalyzer Succeeded\n")
+	klog.V(6).Infof("Server.ReconnectAnalyzer LEAVE\n")
+
+	return nil
+}
+
 func (s *Server) Stop() error {
 	klog.V(6).Infof("Server.Stop ENTER\n")
 
-	// TODO: stop metrics and tracing
+	// readiness turns false while everything below shuts down
+	s.health.Drain()
+
+	// no reconnects while shutting down
+	for _, sup := range s.supervisors {
+		err := sup.Stop()
+		if err != nil {
+			klog.V(1).Infof("supervisor.Stop failed. Err: %v\n", err)
+		}
+	}
 
 	// clean up middleware
 	if s.middlewareAnalyzer != nil {
@@ -261,13 +401,44 @@ func (s *Server) Stop() error {
 	// clean up symbl client
 	s.symblClient = nil
 
-	// clean up neo4j driver
+	// clean up neo4j sessions and driver
+	if s.sessions != nil {
+		s.sessions.Teardown(context.Background())
+	}
+	s.sessions = nil
 	if s.driver != nil {
 		ctx := context.Background()
 		(*s.driver).Close(ctx)
 	}
 	s.driver = nil
 
+	// stop this endpoint
+	if s.server != nil {
+		err := s.server.Close()
+		if err != nil {
+			klog.V(1).Infof("server.Close() failed. Err: %v\n", err)
+		}
+	}
+	s.server = nil
+
+	// stop the metrics endpoint
+	if s.metrics != nil {
+		err := s.metrics.Stop()
+		if err != nil {
+			klog.V(1).Infof("metrics.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.metrics = nil
+
+	// flush the spans
+	if s.tracer != nil {
+		err := s.tracer.Stop()
+		if err != nil {
+			klog.V(1).Infof("tracer.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.tracer = nil
+
 	klog.V(4).Infof("Server.Stop Succeeded\n")
 	klog.V(6).Infof("Server.Stop LEAVE\n")
 
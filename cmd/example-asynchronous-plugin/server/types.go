@@ -4,39 +4,60 @@
 package server
 
 import (
+	"net/http"
+
 	symbl "github.com/dvonthenen/symbl-go-sdk/pkg/client"
 	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	health "github.com/dvonthenen/enterprise-conversation-application/pkg/health"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	middlewaresdk "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk"
+	database "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/database"
+	supervisor "github.com/dvonthenen/enterprise-conversation-application/pkg/supervisor"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
-// Credentials is the input needed to login to neo4j
-type Credentials struct {
-	ConnectionStr string
-	Username      string
-	Password      string
-}
-
 // ServerOptions for the main HTTP endpoint
 type ServerOptions struct {
 	CrtFile     string
 	KeyFile     string
+	TLS         *tlsconfig.Options // nil serves CrtFile and KeyFile with the defaults
 	BindAddress string
 	BindPort    int
+	Neo4j       *dbconfig.Config // defaults to dbconfig.FromEnv()
 	RabbitURI   string
+	Tenants     []string                 // empty handles the conversations of every tenant
+	Encryption  *encryption.Options      // nil reads the content as it is saved
+	Metrics     *metrics.ServerOptions   // nil disables the metrics endpoint
+	Tracing     *tracing.ProviderOptions // nil disables tracing
 }
 
 type Server struct {
 	// server versions
 	options ServerOptions
-	creds   Credentials
 
 	// middleware
 	middlewareAnalyzer *middlewaresdk.AsynchronousAnalyzer
 
 	// neo4j
-	driver *neo4j.DriverWithContext
+	driver   *neo4j.DriverWithContext
+	sessions *database.SessionFactory
 
 	// symbl client
 	symblClient *symbl.RestClient
+
+	// metrics and tracing
+	metrics *metrics.Server
+	tracer  *tracing.Provider
+
+	// health endpoints
+	server *http.Server
+	tls    *tlsconfig.Reloader
+	health *health.Checker
+
+	// reconnects
+	supervisors []*supervisor.Supervisor
 }
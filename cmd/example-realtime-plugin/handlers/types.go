@@ -5,8 +5,8 @@ package handlers
 
 import (
 	symbl "github.com/dvonthenen/symbl-go-sdk/pkg/client"
-	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 
+	database "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/database"
 	interfacessdk "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
 	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
 )
@@ -15,7 +15,7 @@ import (
 	Handler for messages
 */
 type HandlerOptions struct {
-	Session     *neo4j.SessionWithContext // retrieve insights
+	Sessions    *database.SessionFactory // retrieve insights, a session per conversation or callback
 	SymblClient *symbl.RestClient
 }
 
@@ -24,7 +24,7 @@ type Handler struct {
 	cache map[string]*utils.MessageCache
 
 	// housekeeping
-	session      *neo4j.SessionWithContext
+	sessions     *database.SessionFactory
 	symblClient  *symbl.RestClient
 	msgPublisher *interfacessdk.MessagePublisher
 }
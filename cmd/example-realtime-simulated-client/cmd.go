@@ -5,12 +5,10 @@ package main
 
 // streaming
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
 	"time"
 
 	// sse "github.com/r3labs/sse/v2"
@@ -19,11 +17,62 @@ import (
 	symbl "github.com/dvonthenen/symbl-go-sdk/pkg/client"
 	interfaces "github.com/dvonthenen/symbl-go-sdk/pkg/client/interfaces"
 
+	daemon "github.com/dvonthenen/enterprise-conversation-application/pkg/daemon"
+
 	handlers "github.com/dvonthenen/enterprise-conversation-application/cmd/example-realtime-simulated-client/handlers"
 )
 
 type HeadersContext struct{}
 
+// microphoneStream sends the microphone to the streaming client between Start and Stop
+type microphoneStream struct {
+	client *symbl.StreamClient
+	mic    *microphone.Microphone
+}
+
+func (m *microphoneStream) Start() error {
+	// init microphone library
+	microphone.Initialize()
+
+	mic, err := microphone.New(microphone.AudioConfig{
+		InputChannels: 1,
+		SamplingRate:  16000,
+	})
+	if err != nil {
+		fmt.Printf("Initialize failed. Err: %v\n", err)
+		return err
+	}
+
+	// start the mic
+	err = mic.Start()
+	if err != nil {
+		fmt.Printf("mic.Start failed. Err: %v\n", err)
+		return err
+	}
+	m.mic = mic
+
+	go func() {
+		// this is a blocking call
+		mic.Stream(m.client)
+	}()
+
+	return nil
+}
+
+func (m *microphoneStream) Stop() error {
+	// close stream
+	err := m.mic.Stop()
+	if err != nil {
+		fmt.Printf("mic.Stop failed. Err: %v\n", err)
+	}
+	microphone.Teardown()
+
+	// close client
+	m.client.Stop()
+
+	return err
+}
+
 func main() {
 	// init
 	symbl.Init(symbl.SybmlInit{
@@ -149,48 +198,8 @@ func main() {
 	// delay...
 	time.Sleep(time.Second)
 
-	// mic stuf
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, os.Kill)
-
-	// init microphone library
-	microphone.Initialize()
-
-	mic, err := microphone.New(microphone.AudioConfig{
-		InputChannels: 1,
-		SamplingRate:  16000,
+	// stream the microphone, then stop on SIGINT or SIGTERM
+	daemon.Main(&microphoneStream{client: client}, daemon.RunOptions{
+		Name: "example-realtime-simulated-client",
 	})
-	if err != nil {
-		fmt.Printf("Initialize failed. Err: %v\n", err)
-		os.Exit(1)
-	}
-
-	// start the mic
-	err = mic.Start()
-	if err != nil {
-		fmt.Printf("mic.Start failed. Err: %v\n", err)
-		os.Exit(1)
-	}
-
-	go func() {
-		// this is a blocking call
-		mic.Stream(client)
-	}()
-
-	fmt.Print("Press ENTER to exit!\n\n")
-	input := bufio.NewScanner(os.Stdin)
-	input.Scan()
-
-	// close stream
-	err = mic.Stop()
-	if err != nil {
-		fmt.Printf("mic.Stop failed. Err: %v\n", err)
-		os.Exit(1)
-	}
-	microphone.Teardown()
-
-	// close client
-	client.Stop()
-
-	fmt.Printf("Succeeded!\n\n")
 }
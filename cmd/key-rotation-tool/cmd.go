@@ -0,0 +1,96 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	dataminer "github.com/dvonthenen/enterprise-conversation-application/pkg/rest-dataminer"
+)
+
+func usage() {
+	fmt.Printf("Usage: go run cmd.go [-key-file FILE] [-tenant TENANT] [-add-key ID] [-batch N]\n\n")
+	fmt.Printf("Re-encrypts the content saved by the Dataminers with the current key of the tenant and\n")
+	fmt.Printf("encrypts the content saved before encryption was turned on. -add-key first adds a new\n")
+	fmt.Printf("key to the key file, rotating the tenant onto it. Connects using the same NEO4J_*\n")
+	fmt.Printf("environment variables as the Dataminers.\n\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	keyFile := flag.String("key-file", os.Getenv("ERI_ENCRYPTION_KEY_FILE"), "key file used by the Dataminers, also ERI_ENCRYPTION_KEY_FILE")
+	tenant := flag.String("tenant", "", "tenant to rotate, empty for the default tenant")
+	addKey := flag.String("add-key", "", "id of a new key to add for the tenant before rotating")
+	batch := flag.Int("batch", encryption.DefaultBatchSize, "values to re-encrypt per transaction")
+	flag.Usage = usage
+	flag.Parse()
+
+	// init
+	dataminer.Init(dataminer.EnterpriseInit{
+		LogLevel:  dataminer.LogLevelStandard, // LogLevelStandard / LogLevelFull / LogLevelTrace / LogLevelVerbose
+		Component: "key-rotation-tool",
+	})
+
+	neo4jConfig, err := dbconfig.FromEnv()
+	if err != nil {
+		usage()
+		os.Exit(1)
+	}
+
+	provider, err := encryption.NewLocalKeyProvider(*keyFile)
+	if err != nil {
+		fmt.Printf("encryption.NewLocalKeyProvider failed. Err: %v\n", err)
+		usage()
+		os.Exit(1)
+	}
+
+	if len(*addKey) > 0 {
+		err = provider.AddKey(*tenant, *addKey)
+		if err != nil {
+			fmt.Printf("provider.AddKey failed. Err: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Added key %s, the Dataminers wrap their next data key with it\n", *addKey)
+	}
+
+	encryptor, err := encryption.New(encryption.Options{
+		Provider: provider,
+		Tenant:   *tenant,
+	})
+	if err != nil {
+		fmt.Printf("encryption.New failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+
+	driver, err := neo4jConfig.NewDriver()
+	if err != nil {
+		fmt.Printf("neo4jConfig.NewDriver failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+	defer driver.Close(context.Background())
+
+	rotator, err := encryption.NewRotator(encryption.RotatorOptions{
+		Driver:       &driver,
+		DatabaseName: neo4jConfig.Database(*tenant),
+		Encryptor:    encryptor,
+		BatchSize:    *batch,
+	})
+	if err != nil {
+		fmt.Printf("encryption.NewRotator failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+
+	result, err := rotator.Rotate()
+	if err != nil {
+		fmt.Printf("rotator.Rotate failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Re-encrypted %d node and %d relationship values\n", result.Nodes, result.Relationships)
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	dataminer "github.com/dvonthenen/enterprise-conversation-application/pkg/rest-dataminer"
+)
+
+func usage() {
+	fmt.Printf("Usage: go run cmd.go [-policy reference|none] [-batch N]\n\n")
+	fmt.Printf("Moves the raw payloads copied onto nodes and relationships by older Dataminers into\n")
+	fmt.Printf("RawEvent nodes, or removes them with -policy none. Connects using the same NEO4J_*\n")
+	fmt.Printf("environment variables as the Dataminers.\n\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	policy := flag.String("policy", string(rawevent.PolicyReference), "reference keeps one RawEvent per payload, none drops the payloads")
+	batch := flag.Int("batch", rawevent.DefaultBatchSize, "raw properties to move per transaction")
+	flag.Usage = usage
+	flag.Parse()
+
+	// init
+	dataminer.Init(dataminer.EnterpriseInit{
+		LogLevel:  dataminer.LogLevelStandard, // LogLevelStandard / LogLevelFull / LogLevelTrace / LogLevelVerbose
+		Component: "raw-compaction-tool",
+	})
+
+	neo4jConfig, err := dbconfig.FromEnv()
+	if err != nil {
+		usage()
+		os.Exit(1)
+	}
+
+	driver, err := neo4jConfig.NewDriver()
+	if err != nil {
+		fmt.Printf("neo4jConfig.NewDriver failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+	defer driver.Close(context.Background())
+
+	compactor, err := rawevent.NewCompactor(rawevent.CompactorOptions{
+		Driver:       &driver,
+		DatabaseName: neo4jConfig.Database(""),
+		Policy:       rawevent.Policy(*policy),
+		BatchSize:    *batch,
+	})
+	if err != nil {
+		fmt.Printf("rawevent.NewCompactor failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+
+	result, err := compactor.Compact()
+	if err != nil {
+		fmt.Printf("compactor.Compact failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Compacted raw payloads on %d nodes and %d relationships\n", result.Nodes, result.Relationships)
+}
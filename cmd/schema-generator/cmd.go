@@ -0,0 +1,186 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+const (
+	schemaDraft string = "https://json-schema.org/draft/2020-12/schema"
+	schemaBase  string = "https://github.com/dvonthenen/enterprise-conversation-application/schemas"
+)
+
+// schema walks Go types and collects the named structs as definitions
+type schema struct {
+	defs map[string]interface{}
+}
+
+func defName(t reflect.Type) string {
+	path := t.PkgPath()
+	if i := strings.Index(path, "/pkg/"); i >= 0 {
+		path = path[i+len("/pkg/"):]
+	}
+	path = strings.ReplaceAll(path, "/interfaces", "")
+	path = strings.ReplaceAll(path, "/", ".")
+	return path + "." + t.Name()
+}
+
+func (s *schema) typeOf(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.typeOf(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.typeOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.typeOf(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return s.structOf(t)
+		}
+		name := defName(t)
+		if _, ok := s.defs[name]; !ok {
+			s.defs[name] = nil // recursion guard
+			s.defs[name] = s.structOf(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	}
+
+	// interface{} and anything else
+	return map[string]interface{}{}
+}
+
+func (s *schema) structOf(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		// embedded structs are flattened
+		if field.Anonymous && len(name) == 0 {
+			embedded := s.structOf(reflect.Indirect(reflect.New(field.Type)).Type())
+			for k, v := range embedded["properties"].(map[string]interface{}) {
+				properties[k] = v
+			}
+			continue
+		}
+
+		if len(name) == 0 {
+			name = field.Name
+		}
+		properties[name] = s.typeOf(field.Type)
+
+		omitempty := false
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitempty = true
+			}
+		}
+		if !omitempty && field.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+
+	result := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+	return result
+}
+
+func writeFile(file string, data []byte) {
+	err := os.WriteFile(file, data, 0644)
+	if err != nil {
+		fmt.Printf("os.WriteFile failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", file)
+}
+
+func generateSchema(name string, t reflect.Type) []byte {
+	s := &schema{
+		defs: make(map[string]interface{}),
+	}
+
+	root := s.structOf(t)
+	root["$schema"] = schemaDraft
+	root["$id"] = fmt.Sprintf("%s/v%s/%s.json", schemaBase, shared.EnvelopeVersion, name)
+	root["title"] = t.Name()
+	root["description"] = fmt.Sprintf("Payload for %s, envelope version %s", name, shared.EnvelopeVersion)
+	if len(s.defs) > 0 {
+		root["$defs"] = s.defs
+	}
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		fmt.Printf("json.MarshalIndent failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+	return append(data, '\n')
+}
+
+func main() {
+	out := flag.String("out", "schemas/v1", "directory to write the schema files to")
+	format := flag.String("format", "json", "json for JSON Schema files or proto for the protobuf definitions")
+	flag.Parse()
+
+	err := os.MkdirAll(*out, 0755)
+	if err != nil {
+		fmt.Printf("os.MkdirAll failed. Err: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "json":
+		for _, name := range shared.EventTypes() {
+			t := reflect.TypeOf(shared.NewEvent(name)).Elem()
+			writeFile(filepath.Join(*out, name+".json"), generateSchema(name, t))
+		}
+		writeFile(filepath.Join(*out, shared.EnvelopeKey+".json"), generateSchema(shared.EnvelopeKey, reflect.TypeOf(shared.Envelope{})))
+	case "proto":
+		writeFile(filepath.Join(*out, protoFile), []byte(generateProto()))
+	default:
+		fmt.Printf("Unknown format %s\n", *format)
+		os.Exit(1)
+	}
+}
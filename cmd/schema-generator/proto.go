@@ -0,0 +1,200 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+const (
+	protoPackage string = "conversation.v1"
+	protoFile    string = "events.proto"
+)
+
+var (
+	versionSegment = regexp.MustCompile(`^v[0-9]+$`)
+	invalidChars   = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// proto walks Go types the same way pkg/codec encodes them and collects the messages
+type proto struct {
+	messages map[string]string
+	comments map[string]string
+}
+
+// messageName drops the api, shared and version parts of the package, ie api.streaming.v1.Topic
+// is StreamingTopic and shared.TopicResponse is TopicResponse. Anonymous structs use the hint.
+func messageName(t reflect.Type, hint string) string {
+	if len(t.Name()) == 0 {
+		return hint
+	}
+
+	parts := strings.Split(defName(t), ".")
+
+	var name string
+	for _, part := range parts[:len(parts)-1] {
+		if len(part) == 0 || part == "api" || part == "shared" || versionSegment.MatchString(part) {
+			continue
+		}
+		name += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return name + parts[len(parts)-1]
+}
+
+func scalarOf(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int64"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint64"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.String:
+		return "string"
+	}
+	return ""
+}
+
+// valueOf is the type of a single value, the second result is true when it is carried as JSON bytes
+func (p *proto) valueOf(t reflect.Type, hint string) (string, bool) {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "google.protobuf.Timestamp", false
+	}
+	if scalar := scalarOf(t); len(scalar) > 0 {
+		return scalar, false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return p.messageOf(t, hint), false
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", false
+		}
+	}
+	return "bytes", true
+}
+
+// fieldOf follows appendField in pkg/codec
+func (p *proto) fieldOf(t reflect.Type, hint string) (string, bool) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return p.valueOf(t.Elem(), hint)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", false
+		}
+		if !codec.Repeatable(t.Elem()) {
+			return "bytes", true
+		}
+		elem := t.Elem()
+		if elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		typ, _ := p.valueOf(elem, hint)
+		return "repeated " + typ, false
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String || !codec.Repeatable(t.Elem()) {
+			return "bytes", true
+		}
+		elem := t.Elem()
+		if elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		typ, _ := p.valueOf(elem, hint)
+		return fmt.Sprintf("map<string, %s>", typ), false
+	case reflect.Array, reflect.Interface:
+		return "bytes", true
+	}
+	return p.valueOf(t, hint)
+}
+
+func (p *proto) messageOf(t reflect.Type, hint string) string {
+	name := messageName(t, hint)
+	if _, ok := p.messages[name]; ok {
+		return name
+	}
+	p.messages[name] = "" // recursion guard
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "message %s {\n", name)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if codec.Skipped(field) {
+			continue
+		}
+
+		fieldName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(fieldName) == 0 {
+			fieldName = field.Name
+		}
+		fieldName = invalidChars.ReplaceAllString(fieldName, "_")
+
+		typ, isJSON := p.fieldOf(field.Type, name+field.Name)
+		options := ""
+		if strings.HasPrefix(typ, "repeated ") && len(scalarOf(field.Type.Elem())) > 0 && scalarOf(field.Type.Elem()) != "string" {
+			options = " [packed = false]"
+		}
+		comment := ""
+		if isJSON {
+			comment = " // JSON"
+		}
+		fmt.Fprintf(&sb, "  %s %s = %d%s;%s\n", typ, fieldName, codec.FieldNumber(i), options, comment)
+	}
+	sb.WriteString("}\n")
+
+	p.messages[name] = sb.String()
+	return name
+}
+
+// generateProto returns a proto3 file describing the protobuf wire format of every event
+func generateProto() string {
+	p := &proto{
+		messages: make(map[string]string),
+		comments: make(map[string]string),
+	}
+	for _, name := range shared.EventTypes() {
+		t := reflect.TypeOf(shared.NewEvent(name)).Elem()
+		message := p.messageOf(t, "")
+		if len(p.comments[message]) > 0 {
+			p.comments[message] += ", "
+		}
+		p.comments[message] += name
+	}
+
+	names := make([]string, 0, len(p.messages))
+	for name := range p.messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by cmd/schema-generator. DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "// Protobuf wire format for the bus events, envelope version %s.\n", shared.EnvelopeVersion)
+	sb.WriteString("// Fields marked JSON carry the JSON encoding of a value protobuf can't describe.\n\n")
+	sb.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&sb, "package %s;\n\n", protoPackage)
+	sb.WriteString("import \"google/protobuf/timestamp.proto\";\n")
+	for _, name := range names {
+		sb.WriteString("\n")
+		if channels, ok := p.comments[name]; ok {
+			fmt.Fprintf(&sb, "// payload for %s\n", channels)
+		}
+		sb.WriteString(p.messages[name])
+	}
+
+	return sb.String()
+}
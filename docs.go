@@ -5,6 +5,14 @@
 Package provides an off the shell implementation for Go to handle streaming and async
 conversations generically from a variety of platforms, including CPaaS, CRM, Salesforce, etc
 
+The command line tools under cmd are run with go run:
+
+	cmd/dead-letter-tool lists, re-drives or purges the events a plugin dead-lettered
+	cmd/schema-generator writes the JSON Schemas of the bus events
+	cmd/codec-benchmark compares the JSON and protobuf wire formats
+	cmd/raw-compaction-tool applies the raw payload policy to the RawEvent nodes already saved
+	cmd/key-rotation-tool adds content encryption keys and moves the saved content onto the current key
+
 GitHub repo: https://github.com/dvonthenen/enterprise-conversation-application
 */
 package sdk
@@ -0,0 +1,321 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	klog "k8s.io/klog/v2"
+
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	The Guard sits in front of the entry points for a conversation. The token is taken from
+	the Authorization header, the X-API-KEY header Symbl clients already send, or the
+	access_token query parameter for browsers using EventSource. Each Authenticator is
+	tried in turn and the Authorizer then decides if the identity may take the Action on the
+	conversation.
+
+	With ClientCertificates, a client presenting a certificate the TLS listener verified,
+	ie a trusted CPaaS caller using mTLS, is identified by the common name of the certificate
+	and doesn't need a token.
+
+	The tenant of the client is the TenantClaim of its token. Clients whose token has no
+	tenant, including the client certificates, name it in the X-ERI-TENANT header. A client
+	is never let into a running conversation of another tenant.
+
+	ActionRead covers the analytics of the tenant of the client, ie the trends, and isn't
+	about a single conversation.
+
+	ActionAdmin is only allowed for tokens with the AdminScope in their scope or scp claim and
+	covers the tenant of the client, client certificates alone are never enough.
+
+	All methods can be called on a nil Guard and let every request through.
+*/
+func New(options Options) (*Guard, error) {
+	if len(options.Authenticators) == 0 && !options.ClientCertificates {
+		klog.V(1).Infof("Authenticators is empty\n")
+		return nil, ErrInvalidInput
+	}
+	for _, authenticator := range options.Authenticators {
+		if authenticator == nil {
+			klog.V(1).Infof("Authenticator is nil\n")
+			return nil, ErrInvalidInput
+		}
+	}
+
+	if len(options.TenantClaim) == 0 {
+		options.TenantClaim = DefaultTenantClaim
+	}
+	if len(options.AdminScope) == 0 {
+		options.AdminScope = DefaultAdminScope
+	}
+
+	g := &Guard{
+		options:        options,
+		authenticators: options.Authenticators,
+		authorizer:     options.Authorizer,
+	}
+	return g, nil
+}
+
+// Check authenticates the request and authorizes the action, owner is nil when the conversation isn't running
+func (g *Guard) Check(r *http.Request, action Action, conversationId string, owner *Identity) (*Identity, error) {
+	if g == nil {
+		return nil, nil
+	}
+
+	var identity *Identity
+	if g.options.ClientCertificates {
+		identity = certificateIdentity(r)
+	}
+
+	token := Token(r)
+	if identity == nil && len(token) == 0 {
+		klog.V(2).Infof("No token for %s of conversation %s\n", action, conversationId)
+		return nil, reject(action, ErrUnauthenticated)
+	}
+
+	lastErr := ErrUnauthenticated
+	if identity == nil {
+		for _, authenticator := range g.authenticators {
+			found, err := authenticator.Authenticate(r.Context(), token)
+			if err == nil {
+				identity = found
+				break
+			}
+			lastErr = err
+		}
+	}
+	if identity == nil {
+		klog.V(2).Infof("Token rejected for %s of conversation %s. Err: %v\n", action, conversationId, lastErr)
+		if errors.Is(lastErr, ErrUnavailable) {
+			return nil, reject(action, lastErr)
+		}
+		return nil, reject(action, ErrUnauthenticated)
+	}
+
+	// a copy, the authenticators hand out the same identity for every request with the token
+	resolved := *identity
+	resolved.Tenant = g.claimedTenant(identity)
+	tenant, err := Tenant(r, &resolved)
+	if err != nil {
+		klog.V(2).Infof("Tenant rejected for %s of conversation %s. Err: %v\n", action, conversationId, err)
+		return nil, reject(action, err)
+	}
+	resolved.Tenant = tenant
+	identity = &resolved
+
+	// whatever the Authorizer allows, tenants stay out of each other's conversations
+	if owner != nil && owner.Tenant != identity.Tenant {
+		klog.V(2).Infof("%s of tenant %s may not %s conversation %s of tenant %s\n", identity.Subject, identity.Tenant, action, conversationId, owner.Tenant)
+		return nil, reject(action, ErrForbidden)
+	}
+
+	if action == ActionAdmin && !g.hasScope(identity, g.options.AdminScope) {
+		klog.V(2).Infof("%s doesn't have scope %s\n", identity.Subject, g.options.AdminScope)
+		return nil, reject(action, ErrForbidden)
+	}
+
+	if g.authorizer != nil {
+		err := g.authorizer.Authorize(r.Context(), Request{
+			Identity:       identity,
+			Action:         action,
+			ConversationId: conversationId,
+			Owner:          owner,
+		})
+		if err != nil {
+			klog.V(2).Infof("%s may not %s conversation %s. Err: %v\n", identity.Subject, action, conversationId, err)
+			return nil, reject(action, err)
+		}
+	}
+
+	klog.V(4).Infof("%s may %s conversation %s\n", identity.Subject, action, conversationId)
+	return identity, nil
+}
+
+// Handler only passes the requests allowed to take the action on the conversation to next
+func (g *Guard) Handler(next http.Handler, action Action, conversationId string, owner *Identity) http.Handler {
+	if g == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := g.Check(r, action, conversationId, owner)
+		if err != nil {
+			Reject(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+/*
+	AdminHandler only passes the requests allowed ActionAdmin to next. The X-ERI-TENANT header
+	is set to the tenant of the client, so next only has to look there.
+*/
+func (g *Guard) AdminHandler(next http.Handler) http.Handler {
+	if g == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := g.Check(r, ActionAdmin, "", nil)
+		if err != nil {
+			Reject(w, err)
+			return
+		}
+		r.Header.Set(shared.HeaderTenant, identity.Tenant)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Token is the bearer token of the request, empty when there is none
+func Token(r *http.Request) string {
+	if value := r.Header.Get(HeaderAuthorization); len(value) > 0 {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if value := r.Header.Get(HeaderApiKey); len(value) > 0 {
+		return value
+	}
+	return r.URL.Query().Get(QueryAccessToken)
+}
+
+/*
+	Tenant is the tenant of the request: the tenant of the identity, or the X-ERI-TENANT header
+	when the identity has none, or shared.DefaultTenant when neither does. A header naming
+	another tenant than the identity is rejected with ErrForbidden.
+
+	Without an identity, ie no Guard is configured, nobody vouches for the header so only
+	shared.DefaultTenant is allowed.
+*/
+func Tenant(r *http.Request, identity *Identity) (string, error) {
+	header := r.Header.Get(shared.HeaderTenant)
+	if identity == nil && len(header) > 0 && header != shared.DefaultTenant {
+		return "", ErrForbidden
+	}
+
+	tenant := header
+	if identity != nil && len(identity.Tenant) > 0 {
+		if len(header) > 0 && header != identity.Tenant {
+			return "", ErrForbidden
+		}
+		tenant = identity.Tenant
+	}
+
+	if len(tenant) == 0 {
+		return shared.DefaultTenant, nil
+	}
+	if !shared.ValidTenant(tenant) {
+		return "", ErrInvalidTenant
+	}
+	return tenant, nil
+}
+
+// Status is the HTTP status for an error returned by Check or Tenant
+func Status(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidTenant):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusForbidden
+}
+
+// Reject answers a request Check didn't allow
+func Reject(w http.ResponseWriter, err error) {
+	status := Status(err)
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// certificateIdentity is the identity of the verified client certificate, nil when there is none
+func certificateIdentity(r *http.Request) *Identity {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	if len(cert.Subject.CommonName) == 0 {
+		return nil
+	}
+	return &Identity{
+		Subject: cert.Subject.CommonName,
+		Issuer:  cert.Issuer.String(),
+	}
+}
+
+// claimedTenant is the tenant in the claims of the identity, empty when there is none
+func (g *Guard) claimedTenant(identity *Identity) string {
+	tenant, _ := identity.Claims[g.options.TenantClaim].(string)
+	return tenant
+}
+
+// hasScope is true when the scope or scp claim of the identity lists the scope
+func (g *Guard) hasScope(identity *Identity, scope string) bool {
+	var scopes []string
+	for _, claim := range []string{"scope", "scp"} {
+		switch value := identity.Claims[claim].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(value)...)
+		case []any:
+			for _, item := range value {
+				if str, ok := item.(string); ok {
+					scopes = append(scopes, str)
+				}
+			}
+		}
+	}
+
+	for _, found := range scopes {
+		if found == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// reject counts the rejection and returns err
+func reject(action Action, err error) error {
+	metrics.ObserveAuthRejection(string(action), Status(err))
+	return err
+}
+
+// Same is true when both identities are the same client
+func (i *Identity) Same(other *Identity) bool {
+	if i == nil || other == nil {
+		return false
+	}
+	return len(i.Subject) > 0 && i.Subject == other.Subject && i.Issuer == other.Issuer
+}
+
+// Authorize calls f
+func (f AuthorizerFunc) Authorize(ctx context.Context, request Request) error {
+	return f(ctx, request)
+}
+
+// Authorize lets anyone open a conversation that isn't running, after that only its owner. Reading the analytics and ActionAdmin, which the Guard checks the scope of, are always allowed
+func (a OwnerAuthorizer) Authorize(ctx context.Context, request Request) error {
+	if request.Action == ActionAdmin {
+		return nil
+	}
+	if request.Owner == nil && (request.Action == ActionOpen || request.Action == ActionRead) {
+		return nil
+	}
+	if !request.Identity.Same(request.Owner) {
+		return ErrForbidden
+	}
+	return nil
+}
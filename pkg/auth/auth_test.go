@@ -0,0 +1,142 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+// tokens accepts the tokens it knows
+type tokens map[string]*Identity
+
+func (t tokens) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	identity, ok := t[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return identity, nil
+}
+
+func TestTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		identity *Identity
+		want     string
+		err      error
+	}{
+		{name: "nothing", want: shared.DefaultTenant},
+		{name: "default header without identity", header: shared.DefaultTenant, want: shared.DefaultTenant},
+		{name: "header without identity", header: "acme", err: ErrForbidden},
+		{name: "identity without tenant", identity: &Identity{}, want: shared.DefaultTenant},
+		{name: "header of identity without tenant", header: "acme", identity: &Identity{}, want: "acme"},
+		{name: "identity tenant", identity: &Identity{Tenant: "acme"}, want: "acme"},
+		{name: "matching header", header: "acme", identity: &Identity{Tenant: "acme"}, want: "acme"},
+		{name: "other header", header: "globex", identity: &Identity{Tenant: "acme"}, err: ErrForbidden},
+		{name: "invalid header", header: "acme/eu", identity: &Identity{}, err: ErrInvalidTenant},
+		{name: "invalid identity tenant", identity: &Identity{Tenant: "-acme"}, err: ErrInvalidTenant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if len(tt.header) > 0 {
+				r.Header.Set(shared.HeaderTenant, tt.header)
+			}
+
+			got, err := Tenant(r, tt.identity)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Tenant got err %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Tenant got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuardCheck(t *testing.T) {
+	alice := &Identity{Subject: "alice", Issuer: "idp", Claims: map[string]any{"tenant": "acme"}}
+	bob := &Identity{Subject: "bob", Issuer: "idp", Claims: map[string]any{"tenant": "acme"}}
+	admin := &Identity{Subject: "admin", Issuer: "idp", Claims: map[string]any{"tenant": "acme", "scope": "openid eca:admin"}}
+	untenanted := &Identity{Subject: "carol", Issuer: "idp"}
+
+	guard, err := New(Options{
+		Authenticators: []Authenticator{tokens{
+			"alice":      alice,
+			"bob":        bob,
+			"admin":      admin,
+			"untenanted": untenanted,
+		}},
+		Authorizer: OwnerAuthorizer{},
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		header  string
+		action  Action
+		owner   *Identity
+		subject string
+		tenant  string
+		err     error
+	}{
+		{name: "no token", action: ActionOpen, err: ErrUnauthenticated},
+		{name: "unknown token", token: "mallory", action: ActionOpen, err: ErrUnauthenticated},
+		{name: "open", token: "alice", action: ActionOpen, subject: "alice", tenant: "acme"},
+		{name: "observe own conversation", token: "alice", action: ActionObserve, owner: &Identity{Subject: "alice", Issuer: "idp", Tenant: "acme"}, subject: "alice", tenant: "acme"},
+		{name: "observe conversation of another client", token: "bob", action: ActionObserve, owner: &Identity{Subject: "alice", Issuer: "idp", Tenant: "acme"}, err: ErrForbidden},
+		{name: "conversation of another tenant", token: "alice", action: ActionObserve, owner: &Identity{Subject: "alice", Issuer: "idp", Tenant: "globex"}, err: ErrForbidden},
+		{name: "header of another tenant", token: "alice", header: "globex", action: ActionOpen, err: ErrForbidden},
+		{name: "header names the tenant", token: "untenanted", header: "globex", action: ActionOpen, subject: "carol", tenant: "globex"},
+		{name: "default tenant", token: "untenanted", action: ActionRead, subject: "carol", tenant: shared.DefaultTenant},
+		{name: "admin without scope", token: "alice", action: ActionAdmin, err: ErrForbidden},
+		{name: "admin", token: "admin", action: ActionAdmin, subject: "admin", tenant: "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if len(tt.token) > 0 {
+				r.Header.Set(HeaderAuthorization, "Bearer "+tt.token)
+			}
+			if len(tt.header) > 0 {
+				r.Header.Set(shared.HeaderTenant, tt.header)
+			}
+
+			identity, err := guard.Check(r, tt.action, "conversation", tt.owner)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Check got err %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if identity.Subject != tt.subject || identity.Tenant != tt.tenant {
+				t.Errorf("Check got %s of %s, want %s of %s", identity.Subject, identity.Tenant, tt.subject, tt.tenant)
+			}
+		})
+	}
+
+	// the identity handed out by the authenticator keeps its tenant
+	if len(untenanted.Tenant) > 0 {
+		t.Errorf("Check changed the identity of the authenticator to tenant %s", untenanted.Tenant)
+	}
+}
+
+func TestNilGuard(t *testing.T) {
+	var guard *Guard
+
+	identity, err := guard.Check(httptest.NewRequest("GET", "/", nil), ActionOpen, "conversation", nil)
+	if err != nil || identity != nil {
+		t.Errorf("Check on a nil Guard got %v and %v, want nothing", identity, err)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// Action is what a client asks to do with a conversation
+type Action string
+
+const (
+	// ActionOpen streams a conversation through the proxy
+	ActionOpen Action = "open"
+
+	// ActionObserve subscribes to the notifications of a conversation
+	ActionObserve Action = "observe"
+
+	// ActionRead reads the analytics of the tenant of the client, ie the trends
+	ActionRead Action = "read"
+
+	// ActionAdmin manages the operational endpoints, ie the outbox, and needs the AdminScope
+	ActionAdmin Action = "admin"
+)
+
+const (
+	// DefaultSymblURL is called with the token, Symbl answers 401 when it isn't valid
+	DefaultSymblURL string = "https://api.symbl.ai/v1/conversations?limit=1"
+
+	// DefaultSymblCacheTTL is how long a token Symbl accepted is trusted before asking again
+	DefaultSymblCacheTTL time.Duration = 5 * time.Minute
+
+	// DefaultLeeway allows for clock skew when checking exp and nbf
+	DefaultLeeway time.Duration = time.Minute
+
+	// DefaultTenantClaim is the token claim holding the tenant of the client
+	DefaultTenantClaim string = "tenant"
+
+	// DefaultAdminScope is the token scope needed for ActionAdmin
+	DefaultAdminScope string = "eca:admin"
+
+	// where the token is looked for, in order
+	HeaderAuthorization string = "Authorization"
+	HeaderApiKey        string = "X-API-KEY"
+	QueryAccessToken    string = "access_token"
+
+	// maxCachedTokens bounds the tokens remembered by the SymblAuthenticator
+	maxCachedTokens int = 4096
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnauthenticated the request has no token or none of the authenticators accepted it
+	ErrUnauthenticated = errors.New("request is not authenticated")
+
+	// ErrForbidden the authorizer rejected the request
+	ErrForbidden = errors.New("request is not allowed")
+
+	// ErrInvalidToken the token is malformed, expired or its signature doesn't match
+	ErrInvalidToken = errors.New("token is invalid")
+
+	// ErrUnsupportedAlgorithm the token is signed with an algorithm that isn't configured
+	ErrUnsupportedAlgorithm = errors.New("token algorithm is not supported")
+
+	// ErrUnavailable the token couldn't be checked, ie Symbl didn't answer
+	ErrUnavailable = errors.New("token could not be checked")
+
+	// ErrInvalidTenant the tenant isn't a valid tenant id
+	ErrInvalidTenant = errors.New("tenant is invalid")
+)
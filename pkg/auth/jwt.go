@@ -0,0 +1,224 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	NewJWTAuthenticator checks tokens signed by your own identity provider. HS256 tokens are
+	checked with the Secret, RS256 and ES256 tokens with the public key in PublicKeyFile.
+	The exp and nbf claims are always checked, the iss and aud claims when Issuer and
+	Audience are set. The sub claim becomes the Subject of the Identity.
+*/
+func NewJWTAuthenticator(options JWTOptions) (*JWTAuthenticator, error) {
+	if len(options.Secret) == 0 && len(options.PublicKeyFile) == 0 {
+		klog.V(1).Infof("Secret and PublicKeyFile are empty\n")
+		return nil, ErrInvalidInput
+	}
+	if options.Leeway == 0 {
+		options.Leeway = DefaultLeeway
+	}
+
+	a := &JWTAuthenticator{
+		options: options,
+	}
+
+	if len(options.PublicKeyFile) > 0 {
+		publicKey, err := readPublicKey(options.PublicKeyFile)
+		if err != nil {
+			klog.V(1).Infof("readPublicKey(%s) failed. Err: %v\n", options.PublicKeyFile, err)
+			return nil, err
+		}
+		a.publicKey = publicKey
+	}
+
+	return a, nil
+}
+
+// Authenticate verifies the signature and claims of the token
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = a.verify(header.Alg, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]any)
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = a.validate(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return newIdentity(claims), nil
+}
+
+// verify checks the signature with the key configured for the algorithm
+func (a *JWTAuthenticator) verify(alg string, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "HS256":
+		if len(a.options.Secret) == 0 {
+			return ErrUnsupportedAlgorithm
+		}
+		mac := hmac.New(sha256.New, a.options.Secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidToken
+		}
+	case "RS256":
+		publicKey, ok := a.publicKey.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature)
+		if err != nil {
+			return ErrInvalidToken
+		}
+	case "ES256":
+		publicKey, ok := a.publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		if len(signature) != 64 {
+			return ErrInvalidToken
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrInvalidToken
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+
+	return nil
+}
+
+// validate checks the time, issuer and audience claims
+func (a *JWTAuthenticator) validate(claims map[string]any) error {
+	now := time.Now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(a.options.Leeway)) {
+		klog.V(3).Infof("Token has expired or has no exp claim\n")
+		return ErrInvalidToken
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.options.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		klog.V(3).Infof("Token is not valid yet\n")
+		return ErrInvalidToken
+	}
+
+	if len(a.options.Issuer) > 0 {
+		if iss, _ := claims["iss"].(string); iss != a.options.Issuer {
+			klog.V(3).Infof("Token issuer %s doesn't match\n", iss)
+			return ErrInvalidToken
+		}
+	}
+
+	if len(a.options.Audience) > 0 {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == a.options.Audience
+		case []any:
+			for _, item := range aud {
+				if item == a.options.Audience {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			klog.V(3).Infof("Token audience doesn't contain %s\n", a.options.Audience)
+			return ErrInvalidToken
+		}
+	}
+
+	return nil
+}
+
+// readPublicKey reads a PEM public key or the public key of a PEM certificate
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidInput
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// decodeSegment decodes a base64 JSON part of a token
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// newIdentity takes the subject and issuer out of the claims
+func newIdentity(claims map[string]any) *Identity {
+	subject, _ := claims["sub"].(string)
+	issuer, _ := claims["iss"].(string)
+
+	return &Identity{
+		Subject: subject,
+		Issuer:  issuer,
+		Claims:  claims,
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	NewSymblAuthenticator accepts the Symbl access tokens the clients already use to stream
+	through the proxy. The token is sent to Symbl and accepted when Symbl accepts it. An
+	accepted token is trusted for CacheTTL, or until it expires if that is sooner, so a
+	conversation doesn't cost a call to Symbl for every request.
+
+	The Identity is taken from the claims of the token. The signature isn't checked here,
+	Symbl already did.
+*/
+func NewSymblAuthenticator(options SymblOptions) (*SymblAuthenticator, error) {
+	if len(options.URL) == 0 {
+		options.URL = DefaultSymblURL
+	}
+	if options.CacheTTL == 0 {
+		options.CacheTTL = DefaultSymblCacheTTL
+	}
+	if options.Client == nil {
+		options.Client = http.DefaultClient
+	}
+
+	a := &SymblAuthenticator{
+		options: options,
+		client:  options.Client,
+		cache:   make(map[string]*cachedIdentity),
+	}
+	return a, nil
+}
+
+// Authenticate asks Symbl if the token is valid, unless it accepted it recently
+func (a *SymblAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	a.mu.Lock()
+	cached, ok := a.cache[key]
+	a.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.identity, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.options.URL, nil)
+	if err != nil {
+		klog.V(1).Infof("http.NewRequest failed. Err: %v\n", err)
+		return nil, err
+	}
+	req.Header.Set(HeaderAuthorization, "Bearer "+token)
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		klog.V(1).Infof("Symbl token check failed. Err: %v\n", err)
+		return nil, ErrUnavailable
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		klog.V(3).Infof("Symbl rejected the token\n")
+		return nil, ErrInvalidToken
+	case res.StatusCode >= 300:
+		klog.V(1).Infof("Symbl token check returned %d\n", res.StatusCode)
+		return nil, ErrUnavailable
+	}
+
+	// tokens that aren't JWTs keep a subject derived from the token
+	claims := make(map[string]any)
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		_ = decodeSegment(parts[1], &claims)
+	}
+	identity := newIdentity(claims)
+	if len(identity.Subject) == 0 {
+		identity.Subject = "symbl:" + key[:16]
+	}
+
+	expires := time.Now().Add(a.options.CacheTTL)
+	if exp, ok := claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(expires) {
+		expires = time.Unix(int64(exp), 0)
+	}
+
+	a.mu.Lock()
+	if len(a.cache) >= maxCachedTokens {
+		a.cache = make(map[string]*cachedIdentity)
+	}
+	a.cache[key] = &cachedIdentity{
+		identity: identity,
+		expires:  expires,
+	}
+	a.mu.Unlock()
+
+	klog.V(4).Infof("Symbl accepted the token of %s\n", identity.Subject)
+	return identity, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Identity of an authenticated client
+type Identity struct {
+	Subject string
+	Issuer  string
+	Tenant  string // set by the Guard, see Tenant
+	Claims  map[string]any
+}
+
+// Authenticator finds out who sent the token
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// Request is what the Authorizer decides on
+type Request struct {
+	Identity       *Identity
+	Action         Action
+	ConversationId string
+	Owner          *Identity // identity that opened the running conversation, nil when it isn't running
+}
+
+// Authorizer returns ErrForbidden, or any other error, to reject the request
+type Authorizer interface {
+	Authorize(ctx context.Context, request Request) error
+}
+
+// AuthorizerFunc turns a function into an Authorizer
+type AuthorizerFunc func(ctx context.Context, request Request) error
+
+// Options for New
+type Options struct {
+	Authenticators     []Authenticator // tried in order, the first to accept the token wins
+	Authorizer         Authorizer      // nil allows every authenticated client
+	ClientCertificates bool            // a client certificate verified by the TLS listener authenticates without a token
+	TenantClaim        string          // DefaultTenantClaim when empty
+	AdminScope         string          // DefaultAdminScope when empty
+}
+
+// Guard authenticates and authorizes the requests for a conversation
+type Guard struct {
+	options        Options
+	authenticators []Authenticator
+	authorizer     Authorizer
+}
+
+// OwnerAuthorizer only lets the client that opened a conversation open it again or observe it
+type OwnerAuthorizer struct{}
+
+// JWTOptions for NewJWTAuthenticator, give either a Secret or a PublicKeyFile
+type JWTOptions struct {
+	Secret        []byte        // HS256
+	PublicKeyFile string        // PEM public key or certificate, RS256 or ES256
+	Issuer        string        // the iss claim has to match when set
+	Audience      string        // the aud claim has to contain it when set
+	Leeway        time.Duration // DefaultLeeway when zero
+}
+
+// JWTAuthenticator checks tokens signed by your identity provider
+type JWTAuthenticator struct {
+	options   JWTOptions
+	publicKey crypto.PublicKey
+}
+
+// SymblOptions for NewSymblAuthenticator
+type SymblOptions struct {
+	URL      string        // DefaultSymblURL when empty
+	CacheTTL time.Duration // DefaultSymblCacheTTL when zero
+	Client   *http.Client  // http.DefaultClient when nil
+}
+
+// SymblAuthenticator accepts the tokens Symbl accepts
+type SymblAuthenticator struct {
+	options SymblOptions
+	client  *http.Client
+
+	// accepted tokens by their hash
+	cache map[string]*cachedIdentity
+	mu    sync.Mutex
+}
+
+// cachedIdentity is an accepted token and when to ask Symbl again
+type cachedIdentity struct {
+	identity *Identity
+	expires  time.Time
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package bus
+
+import (
+	"net/url"
+
+	klog "k8s.io/klog/v2"
+
+	businproc "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/inproc"
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	busnats "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/nats"
+	busrabbit "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/rabbit"
+)
+
+/*
+	New creates the bus for the URI scheme
+
+	amqp://, amqps://         RabbitMQ
+	nats://, tls://           NATS
+	inproc://                 in-process, every inproc bus in the binary shares one broker
+*/
+func New(options BusOptions) (*interfaces.Bus, error) {
+	if len(options.URI) == 0 {
+		klog.V(1).Infof("URI is empty\n")
+		return nil, ErrInvalidInput
+	}
+
+	switch scheme(options.URI) {
+	case "amqp", "amqps":
+		return busrabbit.New(busrabbit.BusOptions{
+			RabbitURI: options.URI,
+		})
+	case "nats", "tls":
+		return busnats.New(busnats.BusOptions{
+			NatsURI: options.URI,
+		})
+	case "inproc":
+		return businproc.New(businproc.BusOptions{})
+	}
+
+	klog.V(1).Infof("Unsupported scheme for %s\n", options.URI)
+	return nil, ErrUnsupportedScheme
+}
+
+// IsRabbit is true when the URI points to RabbitMQ, which the dead letter and shared queues need
+func IsRabbit(uri string) bool {
+	s := scheme(uri)
+	return s == "amqp" || s == "amqps"
+}
+
+func scheme(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme
+}
@@ -0,0 +1,16 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package bus
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnsupportedScheme the URI doesn't match a known bus
+	ErrUnsupportedScheme = errors.New("unsupported bus URI scheme")
+)
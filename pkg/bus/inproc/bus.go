@@ -0,0 +1,278 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package inproc
+
+import (
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+)
+
+var (
+	defaultBroker = NewBroker()
+)
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string][]*subscriber),
+	}
+}
+
+/*
+	New creates an in-process bus. Every bus created on the same broker can talk to each
+	other, so a Dataminer and Middleware Plugins can run in a single binary.
+*/
+func New(options BusOptions) (*interfaces.Bus, error) {
+	if options.Broker == nil {
+		options.Broker = defaultBroker
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultQueueSize
+	}
+
+	var bus interfaces.Bus
+	bus = &Bus{
+		options:     options,
+		broker:      options.Broker,
+		publishers:  make(map[string]bool),
+		subscribers: make(map[string]*subscriber),
+	}
+	return &bus, nil
+}
+
+func (b *Bus) Init() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, sub := range b.subscribers {
+		sub.start()
+	}
+	b.initialized = true
+
+	return nil
+}
+
+func (b *Bus) CreatePublisher(options interfaces.PublisherOptions) error {
+	if len(options.Name) == 0 {
+		klog.V(1).Infof("Name is empty\n")
+		return ErrInvalidInput
+	}
+
+	b.mu.Lock()
+	b.publishers[options.Name] = true
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *Bus) CreateSubscriber(options interfaces.SubscriberOptions) error {
+	if len(options.Name) == 0 || options.Handler == nil {
+		klog.V(1).Infof("Name or Handler is empty\n")
+		return ErrInvalidInput
+	}
+
+	sub := &subscriber{
+		name:    options.Name,
+		handler: options.Handler,
+		keys:    keys(options.Keys),
+		queue:   make(chan []byte, b.options.QueueSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	existing := b.subscribers[options.Name]
+	b.subscribers[options.Name] = sub
+	if b.initialized {
+		sub.start()
+	}
+	b.mu.Unlock()
+
+	// replaces a subscriber with the same name
+	if existing != nil {
+		b.broker.remove(existing)
+		existing.close()
+	}
+
+	b.broker.add(sub)
+
+	return nil
+}
+
+// keys returns nil when the subscriber receives every key
+func keys(list []string) map[string]bool {
+	if len(list) == 0 {
+		return nil
+	}
+
+	keys := make(map[string]bool)
+	for _, key := range list {
+		keys[key] = true
+	}
+	return keys
+}
+
+func (b *Bus) Publish(name string, data []byte) error {
+	return b.PublishWithKey(name, "", data)
+}
+
+func (b *Bus) PublishWithKey(name string, key string, data []byte) error {
+	b.mu.Lock()
+	found := b.publishers[name]
+	b.mu.Unlock()
+
+	if !found {
+		klog.V(1).Infof("Publisher %s not found\n", name)
+		metrics.BusPublishFailures.WithLabelValues(metrics.BusInproc).Inc()
+		return ErrPublisherNotFound
+	}
+
+	b.broker.publish(name, key, data)
+
+	return nil
+}
+
+func (b *Bus) DeletePublisher(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.publishers[name] {
+		return ErrPublisherNotFound
+	}
+	delete(b.publishers, name)
+
+	return nil
+}
+
+func (b *Bus) DeleteSubscriber(name string) error {
+	b.mu.Lock()
+	sub := b.subscribers[name]
+	delete(b.subscribers, name)
+	b.mu.Unlock()
+
+	if sub == nil {
+		return ErrSubscriberNotFound
+	}
+
+	b.broker.remove(sub)
+	sub.close()
+
+	return nil
+}
+
+// Healthy is true once the bus is started, there is no broker to lose
+func (b *Bus) Healthy() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.initialized {
+		return ErrNotInitialized
+	}
+	return nil
+}
+
+// Reconnect has nothing to do, the broker is in the process
+func (b *Bus) Reconnect() error {
+	return nil
+}
+
+func (b *Bus) Teardown() error {
+	b.mu.Lock()
+	subscribers := b.subscribers
+	b.subscribers = make(map[string]*subscriber)
+	b.publishers = make(map[string]bool)
+	b.initialized = false
+	b.mu.Unlock()
+
+	for _, sub := range subscribers {
+		b.broker.remove(sub)
+		sub.close()
+	}
+
+	return nil
+}
+
+/*
+	Broker
+*/
+func (br *Broker) add(sub *subscriber) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	br.subscribers[sub.name] = append(br.subscribers[sub.name], sub)
+}
+
+func (br *Broker) remove(sub *subscriber) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	subscribers := br.subscribers[sub.name]
+	for i, s := range subscribers {
+		if s == sub {
+			br.subscribers[sub.name] = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+	if len(br.subscribers[sub.name]) == 0 {
+		delete(br.subscribers, sub.name)
+	}
+}
+
+// publish hands a copy of the message to every subscriber of the channel that takes the key
+func (br *Broker) publish(name string, key string, data []byte) {
+	br.mu.Lock()
+	subscribers := make([]*subscriber, len(br.subscribers[name]))
+	copy(subscribers, br.subscribers[name])
+	br.mu.Unlock()
+
+	klog.V(5).Infof("Publishing to %s with %d subscribers\n", name, len(subscribers))
+
+	for _, sub := range subscribers {
+		if sub.keys != nil && !sub.keys[key] {
+			continue
+		}
+
+		message := make([]byte, len(data))
+		copy(message, data)
+
+		select {
+		case sub.queue <- message:
+		case <-sub.stop:
+		}
+	}
+}
+
+/*
+	Subscriber
+*/
+func (s *subscriber) start() {
+	if s.running {
+		return
+	}
+	s.running = true
+
+	go func() {
+		defer close(s.done)
+
+		for {
+			select {
+			case data := <-s.queue:
+				err := (*s.handler).ProcessMessage(data)
+				if err != nil {
+					klog.V(1).Infof("ProcessMessage() failed. Err: %v\n", err)
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *subscriber) close() {
+	close(s.stop)
+	if s.running {
+		<-s.done
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package inproc
+
+import (
+	"errors"
+)
+
+const (
+	// defaults
+	DefaultQueueSize int = 1000
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrNotInitialized the bus hasn't been started
+	ErrNotInitialized = errors.New("bus is not initialized")
+
+	// ErrPublisherNotFound publisher was not created on this bus
+	ErrPublisherNotFound = errors.New("publisher not found")
+
+	// ErrSubscriberNotFound subscriber was not created on this bus
+	ErrSubscriberNotFound = errors.New("subscriber not found")
+)
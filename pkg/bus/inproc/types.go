@@ -0,0 +1,44 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package inproc
+
+import (
+	"sync"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+// Broker delivers messages between all of the buses created on it
+type Broker struct {
+	subscribers map[string][]*subscriber
+	mu          sync.Mutex
+}
+
+// BusOptions to create a bus, the default broker is used when Broker is nil
+type BusOptions struct {
+	Broker    *Broker
+	QueueSize int
+}
+
+// Bus is a single component's view of the broker
+type Bus struct {
+	options BusOptions
+	broker  *Broker
+
+	// housekeeping
+	publishers  map[string]bool
+	subscribers map[string]*subscriber
+	initialized bool
+	mu          sync.Mutex
+}
+
+type subscriber struct {
+	name    string
+	handler *interfaces.MessageHandler
+	keys    map[string]bool
+	queue   chan []byte
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
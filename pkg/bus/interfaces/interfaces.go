@@ -0,0 +1,36 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package interfaces
+
+/*
+	Each Subscriber implements this message handler which serves as a callback.
+	This function is called when a message is received on the named channel
+*/
+type MessageHandler interface {
+	ProcessMessage(byData []byte) error
+}
+
+/*
+	Bus moves messages between the Dataminer components and the Middleware Plugins.
+
+	Messages are published to named channels. The insight channels (see pkg/shared) are
+	broadcast to every subscriber and each conversation has its own channel, named by the
+	conversationId, for application messages sent back to the client. A message on an insight
+	channel is published with a routing key, the tenant of the event, and a subscriber listing
+	Keys only receives the messages published with one of them. Healthy returns nil
+	while the bus is initialized and connected to its broker. Reconnect replaces a broken
+	connection and recreates every publisher and subscriber that hasn't been deleted.
+*/
+type Bus interface {
+	Init() error
+	CreatePublisher(options PublisherOptions) error
+	CreateSubscriber(options SubscriberOptions) error
+	Publish(name string, data []byte) error
+	PublishWithKey(name string, key string, data []byte) error
+	DeletePublisher(name string) error
+	DeleteSubscriber(name string) error
+	Healthy() error
+	Reconnect() error
+	Teardown() error
+}
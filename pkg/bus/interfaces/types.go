@@ -0,0 +1,32 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package interfaces
+
+/*
+	Channel Types
+*/
+type ChannelType int64
+
+const (
+	// every subscriber gets every message with one of its keys, used for the insight channels
+	ChannelTypeBroadcast ChannelType = iota
+
+	// application messages for a single conversation
+	ChannelTypeConversation
+)
+
+/*
+	Configuration Options for Bus Publisher/Subscriber
+*/
+type PublisherOptions struct {
+	Name string
+	Type ChannelType
+}
+
+type SubscriberOptions struct {
+	Name    string
+	Type    ChannelType
+	Handler *MessageHandler
+	Keys    []string // routing keys to receive on a broadcast channel, empty receives them all
+}
@@ -0,0 +1,298 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package nats
+
+import (
+	nats "github.com/nats-io/nats.go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+)
+
+/*
+	New creates a bus on a NATS server.
+
+	Every channel is a subject named after the channel, a message published with a routing key
+	goes to the subject name.key. A subscriber without keys also takes name.> on a broadcast
+	channel. NATS hands the messages for a subject from a single connection to each subscriber
+	in the order they were published, and each subscriber processes its messages one at a time,
+	so events for a conversation, which all belong to one tenant, stay in order.
+*/
+func New(options BusOptions) (*interfaces.Bus, error) {
+	if len(options.NatsURI) == 0 {
+		klog.V(1).Infof("NatsURI is empty\n")
+		return nil, ErrInvalidInput
+	}
+	if len(options.ClientName) == 0 {
+		options.ClientName = DefaultClientName
+	}
+
+	var bus interfaces.Bus
+	bus = &Bus{
+		options:     options,
+		publishers:  make(map[string]bool),
+		subscribers: make(map[string]*subscriber),
+	}
+	return &bus, nil
+}
+
+func (b *Bus) Init() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.connect()
+	if err != nil {
+		return err
+	}
+	b.initialized = true
+
+	return nil
+}
+
+// connect dials the server and subscribes everything created so far
+func (b *Bus) connect() error {
+	connection, err := nats.Connect(b.options.NatsURI,
+		nats.Name(b.options.ClientName),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(DefaultReconnectWait),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			klog.V(1).Infof("NATS disconnected. Err: %v\n", err)
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			klog.V(3).Infof("NATS reconnected to %s\n", conn.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		klog.V(1).Infof("nats.Connect failed. Err: %v\n", err)
+		return err
+	}
+	b.connection = connection
+
+	for _, sub := range b.subscribers {
+		err := b.subscribe(sub)
+		if err != nil {
+			klog.V(1).Infof("subscribe %s failed. Err: %v\n", sub.options.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Bus) CreatePublisher(options interfaces.PublisherOptions) error {
+	if len(options.Name) == 0 {
+		klog.V(1).Infof("Name is empty\n")
+		return ErrInvalidInput
+	}
+
+	b.mu.Lock()
+	b.publishers[options.Name] = true
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *Bus) CreateSubscriber(options interfaces.SubscriberOptions) error {
+	if len(options.Name) == 0 || options.Handler == nil {
+		klog.V(1).Infof("Name or Handler is empty\n")
+		return ErrInvalidInput
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscriber{
+		options: options,
+	}
+	b.subscribers[options.Name] = sub
+
+	// subscribers created after Init start right away
+	if b.initialized {
+		err := b.subscribe(sub)
+		if err != nil {
+			klog.V(1).Infof("subscribe %s failed. Err: %v\n", options.Name, err)
+			delete(b.subscribers, options.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subject of a message on the channel name published with key
+func subject(name string, key string) string {
+	if len(key) == 0 {
+		return name
+	}
+	return name + "." + key
+}
+
+func subjects(options interfaces.SubscriberOptions) []string {
+	if len(options.Keys) == 0 {
+		if options.Type == interfaces.ChannelTypeBroadcast {
+			return []string{options.Name, options.Name + ".>"}
+		}
+		return []string{options.Name}
+	}
+
+	var subjects []string
+	for _, key := range options.Keys {
+		subjects = append(subjects, subject(options.Name, key))
+	}
+	return subjects
+}
+
+func (b *Bus) subscribe(sub *subscriber) error {
+	handler := sub.options.Handler
+	name := sub.options.Name
+
+	sub.subscriptions = nil
+	for _, subject := range subjects(sub.options) {
+		// each subscription has its own callback goroutine, the lock keeps the handler sequential
+		subscription, err := b.connection.Subscribe(subject, func(msg *nats.Msg) {
+			sub.mu.Lock()
+			defer sub.mu.Unlock()
+
+			err := (*handler).ProcessMessage(msg.Data)
+			if err != nil {
+				klog.V(1).Infof("ProcessMessage %s failed. Err: %v\n", name, err)
+			}
+		})
+		if err != nil {
+			b.unsubscribe(sub)
+			return err
+		}
+		sub.subscriptions = append(sub.subscriptions, subscription)
+	}
+
+	return nil
+}
+
+// unsubscribe lets the messages already received finish
+func (b *Bus) unsubscribe(sub *subscriber) error {
+	var retErr error
+	for _, subscription := range sub.subscriptions {
+		err := subscription.Drain()
+		if err != nil {
+			klog.V(1).Infof("Drain %s failed. Err: %v\n", subscription.Subject, err)
+			retErr = err
+		}
+	}
+	sub.subscriptions = nil
+
+	return retErr
+}
+
+func (b *Bus) Publish(name string, data []byte) error {
+	return b.PublishWithKey(name, "", data)
+}
+
+func (b *Bus) PublishWithKey(name string, key string, data []byte) error {
+	b.mu.Lock()
+	connection := b.connection
+	found := b.publishers[name]
+	b.mu.Unlock()
+
+	if connection == nil {
+		klog.V(1).Infof("Bus is not initialized\n")
+		metrics.BusPublishFailures.WithLabelValues(metrics.BusNats).Inc()
+		return ErrNotInitialized
+	}
+	if !found {
+		klog.V(1).Infof("Publisher %s not found\n", name)
+		metrics.BusPublishFailures.WithLabelValues(metrics.BusNats).Inc()
+		return ErrPublisherNotFound
+	}
+
+	err := connection.Publish(subject(name, key), data)
+	if err != nil {
+		klog.V(1).Infof("Publish %s failed. Err: %v\n", name, err)
+		metrics.BusPublishFailures.WithLabelValues(metrics.BusNats).Inc()
+		return err
+	}
+
+	// core NATS is fire and forget, make sure the server has it before reporting success
+	err = connection.FlushTimeout(DefaultFlushTimeout)
+	if err != nil {
+		klog.V(1).Infof("FlushTimeout %s failed. Err: %v\n", name, err)
+		metrics.BusPublishFailures.WithLabelValues(metrics.BusNats).Inc()
+		return err
+	}
+
+	return nil
+}
+
+func (b *Bus) DeletePublisher(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.publishers[name] {
+		return ErrPublisherNotFound
+	}
+	delete(b.publishers, name)
+
+	return nil
+}
+
+func (b *Bus) DeleteSubscriber(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := b.subscribers[name]
+	if sub == nil {
+		return ErrSubscriberNotFound
+	}
+	delete(b.subscribers, name)
+
+	return b.unsubscribe(sub)
+}
+
+func (b *Bus) Healthy() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.initialized || b.connection == nil {
+		return ErrNotInitialized
+	}
+	if !b.connection.IsConnected() {
+		return ErrNotConnected
+	}
+
+	return nil
+}
+
+// Reconnect only dials again once the client has given up, until then it reconnects by itself
+func (b *Bus) Reconnect() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.initialized {
+		return ErrNotInitialized
+	}
+	if b.connection != nil && !b.connection.IsClosed() {
+		return nil
+	}
+
+	return b.connect()
+}
+
+func (b *Bus) Teardown() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.connection != nil {
+		err := b.connection.Drain()
+		if err != nil {
+			klog.V(1).Infof("connection.Drain failed. Err: %v\n", err)
+		}
+	}
+
+	b.connection = nil
+	b.publishers = make(map[string]bool)
+	b.subscribers = make(map[string]*subscriber)
+	b.initialized = false
+
+	return nil
+}
@@ -0,0 +1,371 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package nats
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	server "github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+// recorder hands every message it receives to the test
+type recorder struct {
+	received chan string
+}
+
+func (r recorder) ProcessMessage(byData []byte) error {
+	r.received <- string(byData)
+	return nil
+}
+
+func runServer(port int) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = port
+	return natstest.RunServer(&opts)
+}
+
+func newBus(t *testing.T, s *server.Server) *interfaces.Bus {
+	bus, err := New(BusOptions{
+		NatsURI: s.ClientURL(),
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+	return bus
+}
+
+func newRecorder() (recorder, *interfaces.MessageHandler) {
+	rec := recorder{
+		received: make(chan string, 10),
+	}
+	var handler interfaces.MessageHandler
+	handler = rec
+	return rec, &handler
+}
+
+func waitFor(t *testing.T, rec recorder, want string) {
+	t.Helper()
+
+	select {
+	case got := <-rec.received:
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", want)
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	s := runServer(-1)
+	defer s.Shutdown()
+
+	publisher := newBus(t, s)
+	subscriber := newBus(t, s)
+	defer (*publisher).Teardown()
+	defer (*subscriber).Teardown()
+
+	rec, handler := newRecorder()
+
+	// created before Init
+	err := (*subscriber).CreateSubscriber(interfaces.SubscriberOptions{
+		Name:    "realtime-message-created",
+		Type:    interfaces.ChannelTypeBroadcast,
+		Handler: handler,
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscriber failed. Err: %v", err)
+	}
+	err = (*subscriber).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	// created after Init
+	err = (*subscriber).CreateSubscriber(interfaces.SubscriberOptions{
+		Name:    "c1",
+		Type:    interfaces.ChannelTypeConversation,
+		Handler: handler,
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscriber failed. Err: %v", err)
+	}
+
+	err = (*publisher).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "realtime-message-created", data: "message"},
+		{name: "c1", data: "application message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (*publisher).CreatePublisher(interfaces.PublisherOptions{
+				Name: tt.name,
+			})
+			if err != nil {
+				t.Fatalf("CreatePublisher failed. Err: %v", err)
+			}
+
+			err = (*publisher).Publish(tt.name, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Publish failed. Err: %v", err)
+			}
+			waitFor(t, rec, tt.data)
+		})
+	}
+
+	// events arrive in the order they were published
+	for i := 0; i < 5; i++ {
+		err = (*publisher).Publish("c1", []byte(fmt.Sprintf("%d", i)))
+		if err != nil {
+			t.Fatalf("Publish failed. Err: %v", err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		waitFor(t, rec, fmt.Sprintf("%d", i))
+	}
+
+	// nothing arrives once the subscriber is deleted
+	err = (*subscriber).DeleteSubscriber("c1")
+	if err != nil {
+		t.Fatalf("DeleteSubscriber failed. Err: %v", err)
+	}
+	err = (*publisher).Publish("c1", []byte("dropped"))
+	if err != nil {
+		t.Fatalf("Publish failed. Err: %v", err)
+	}
+	select {
+	case got := <-rec.received:
+		t.Errorf("got %s after DeleteSubscriber", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPublishWithKey(t *testing.T) {
+	s := runServer(-1)
+	defer s.Shutdown()
+
+	bus := newBus(t, s)
+	defer (*bus).Teardown()
+
+	tenants, tenantsHandler := newRecorder()
+	all, allHandler := newRecorder()
+
+	err := (*bus).CreateSubscriber(interfaces.SubscriberOptions{
+		Name:    "realtime-topic-created",
+		Type:    interfaces.ChannelTypeBroadcast,
+		Handler: tenantsHandler,
+		Keys:    []string{"acme", "acme.eu"},
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscriber failed. Err: %v", err)
+	}
+	err = (*bus).CreateSubscriber(interfaces.SubscriberOptions{
+		Name:    "realtime-topic-created-all",
+		Type:    interfaces.ChannelTypeBroadcast,
+		Handler: allHandler,
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscriber failed. Err: %v", err)
+	}
+	err = (*bus).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		key     string
+		tenants bool
+		all     bool
+	}{
+		{name: "listed key", channel: "realtime-topic-created", key: "acme", tenants: true},
+		{name: "listed key with a dot", channel: "realtime-topic-created", key: "acme.eu", tenants: true},
+		{name: "other key", channel: "realtime-topic-created", key: "globex"},
+		{name: "no key", channel: "realtime-topic-created"},
+		{name: "any key", channel: "realtime-topic-created-all", key: "globex", all: true},
+		{name: "any key without key", channel: "realtime-topic-created-all", all: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (*bus).CreatePublisher(interfaces.PublisherOptions{
+				Name: tt.channel,
+				Type: interfaces.ChannelTypeBroadcast,
+			})
+			if err != nil {
+				t.Fatalf("CreatePublisher failed. Err: %v", err)
+			}
+
+			err = (*bus).PublishWithKey(tt.channel, tt.key, []byte(tt.name))
+			if err != nil {
+				t.Fatalf("PublishWithKey failed. Err: %v", err)
+			}
+
+			if tt.tenants {
+				waitFor(t, tenants, tt.name)
+			}
+			if tt.all {
+				waitFor(t, all, tt.name)
+			}
+			select {
+			case got := <-tenants.received:
+				t.Errorf("got %s on the keyed subscriber", got)
+			case got := <-all.received:
+				t.Errorf("got %s on the subscriber of every key", got)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestPublishAck(t *testing.T) {
+	s := runServer(-1)
+
+	bus := newBus(t, s)
+	defer (*bus).Teardown()
+
+	err := (*bus).CreatePublisher(interfaces.PublisherOptions{
+		Name: "realtime-message-created",
+	})
+	if err != nil {
+		t.Fatalf("CreatePublisher failed. Err: %v", err)
+	}
+
+	err = (*bus).Publish("realtime-message-created", []byte("message"))
+	if err != ErrNotInitialized {
+		t.Errorf("Publish before Init got %v, want %v", err, ErrNotInitialized)
+	}
+
+	err = (*bus).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	// Publish only succeeds once the server has the message
+	err = (*bus).Publish("realtime-message-created", []byte("message"))
+	if err != nil {
+		t.Errorf("Publish failed. Err: %v", err)
+	}
+
+	err = (*bus).Publish("realtime-topic-created", []byte("topic"))
+	if err != ErrPublisherNotFound {
+		t.Errorf("Publish without publisher got %v, want %v", err, ErrPublisherNotFound)
+	}
+
+	// the server never confirms the message while it's down
+	s.Shutdown()
+	err = (*bus).Publish("realtime-message-created", []byte("message"))
+	if err == nil {
+		t.Errorf("Publish succeeded without a server")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	s := runServer(-1)
+	port := s.Addr().(*net.TCPAddr).Port
+
+	publisher := newBus(t, s)
+	subscriber := newBus(t, s)
+	defer (*publisher).Teardown()
+	defer (*subscriber).Teardown()
+
+	rec, handler := newRecorder()
+
+	err := (*subscriber).CreateSubscriber(interfaces.SubscriberOptions{
+		Name:    "realtime-message-created",
+		Handler: handler,
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscriber failed. Err: %v", err)
+	}
+	err = (*subscriber).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	err = (*publisher).CreatePublisher(interfaces.PublisherOptions{
+		Name: "realtime-message-created",
+	})
+	if err != nil {
+		t.Fatalf("CreatePublisher failed. Err: %v", err)
+	}
+	err = (*publisher).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	err = (*publisher).Publish("realtime-message-created", []byte("before"))
+	if err != nil {
+		t.Fatalf("Publish failed. Err: %v", err)
+	}
+	waitFor(t, rec, "before")
+
+	// the buses report the outage and come back by themselves
+	s.Shutdown()
+	waitHealthy(t, publisher, ErrNotConnected)
+	waitHealthy(t, subscriber, ErrNotConnected)
+
+	s = runServer(port)
+	defer s.Shutdown()
+	waitHealthy(t, publisher, nil)
+	waitHealthy(t, subscriber, nil)
+
+	// the subscription was restored on the new server, it can get there after the first publish
+	deadline := time.Now().Add(5 * time.Second)
+	for received := false; !received; {
+		err = (*publisher).Publish("realtime-message-created", []byte("after"))
+		if err != nil {
+			t.Fatalf("Publish failed. Err: %v", err)
+		}
+
+		select {
+		case got := <-rec.received:
+			if got != "after" {
+				t.Errorf("got %s, want after", got)
+			}
+			received = true
+		case <-time.After(100 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for after")
+			}
+		}
+	}
+
+	// nothing to do while the client is still connected
+	err = (*publisher).Reconnect()
+	if err != nil {
+		t.Errorf("Reconnect failed. Err: %v", err)
+	}
+}
+
+func waitHealthy(t *testing.T, bus *interfaces.Bus, want error) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		err := (*bus).Healthy()
+		if err == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Healthy got %v, want %v", err, want)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package nats
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// defaults
+	DefaultClientName    string        = "enterprise-conversation-application"
+	DefaultReconnectWait time.Duration = 2 * time.Second
+	DefaultFlushTimeout  time.Duration = 5 * time.Second
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrNotInitialized the bus is not connected
+	ErrNotInitialized = errors.New("bus is not initialized")
+
+	// ErrNotConnected the connection is down and reconnecting
+	ErrNotConnected = errors.New("bus is reconnecting")
+
+	// ErrPublisherNotFound publisher was never created
+	ErrPublisherNotFound = errors.New("publisher not found")
+
+	// ErrSubscriberNotFound subscriber was never created
+	ErrSubscriberNotFound = errors.New("subscriber not found")
+)
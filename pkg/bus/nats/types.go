@@ -0,0 +1,36 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package nats
+
+import (
+	"sync"
+
+	nats "github.com/nats-io/nats.go"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+// BusOptions to connect to NATS
+type BusOptions struct {
+	NatsURI    string
+	ClientName string
+}
+
+// Bus implements the bus on top of core NATS subjects
+type Bus struct {
+	options BusOptions
+
+	// nats
+	connection  *nats.Conn
+	publishers  map[string]bool
+	subscribers map[string]*subscriber
+	initialized bool
+	mu          sync.Mutex
+}
+
+type subscriber struct {
+	options       interfaces.SubscriberOptions
+	subscriptions []*nats.Subscription
+	mu            sync.Mutex
+}
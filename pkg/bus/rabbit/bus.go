@@ -0,0 +1,307 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rabbit
+
+import (
+	rabbit "github.com/dvonthenen/rabbitmq-manager/pkg"
+	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+)
+
+func New(options BusOptions) (*interfaces.Bus, error) {
+	if len(options.RabbitURI) == 0 {
+		klog.V(1).Infof("RabbitURI is empty\n")
+		return nil, ErrInvalidInput
+	}
+
+	manager, err := rabbit.New(rabbitinterfaces.ManagerOptions{
+		RabbitURI: options.RabbitURI,
+	})
+	if err != nil {
+		klog.V(1).Infof("rabbit.New failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	var bus interfaces.Bus
+	bus = &Bus{
+		options:     options,
+		manager:     manager,
+		publishers:  make(map[string]interfaces.PublisherOptions),
+		subscribers: make(map[string]interfaces.SubscriberOptions),
+	}
+	return &bus, nil
+}
+
+func (b *Bus) Init() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := (*b.manager).Init()
+	if err != nil {
+		klog.V(1).Infof("manager.Init failed. Err: %v\n", err)
+		return err
+	}
+
+	err = b.dial()
+	if err != nil {
+		klog.V(1).Infof("dial failed. Err: %v\n", err)
+		return err
+	}
+
+	// the broadcast channels created so far
+	for name, options := range b.publishers {
+		if options.Type != interfaces.ChannelTypeBroadcast {
+			continue
+		}
+		err := b.declareTopic(name)
+		if err != nil {
+			klog.V(1).Infof("declareTopic %s failed. Err: %v\n", name, err)
+			return err
+		}
+	}
+	for name, options := range b.subscribers {
+		if options.Type != interfaces.ChannelTypeBroadcast {
+			continue
+		}
+		err := b.subscribeTopic(options)
+		if err != nil {
+			klog.V(1).Infof("subscribeTopic %s failed. Err: %v\n", name, err)
+			return err
+		}
+	}
+	b.initialized = true
+
+	return nil
+}
+
+func (b *Bus) CreatePublisher(options interfaces.PublisherOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.createPublisher(options)
+	if err != nil {
+		return err
+	}
+
+	// recreated by Reconnect
+	b.publishers[options.Name] = options
+
+	return nil
+}
+
+func (b *Bus) createPublisher(options interfaces.PublisherOptions) error {
+	// declared by Init
+	if options.Type == interfaces.ChannelTypeBroadcast {
+		if b.connection == nil {
+			return nil
+		}
+		return b.declareTopic(options.Name)
+	}
+
+	_, err := (*b.manager).CreatePublisher(rabbitinterfaces.PublisherOptions{
+		Name:        options.Name,
+		Type:        rabbitinterfaces.ExchangeTypeDirect,
+		AutoDeleted: true,
+		IfUnused:    true,
+	})
+	if err != nil {
+		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", options.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (b *Bus) CreateSubscriber(options interfaces.SubscriberOptions) error {
+	if options.Handler == nil {
+		klog.V(1).Infof("Handler is nil\n")
+		return ErrInvalidInput
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// subscribers created after Init start right away
+	err := b.createSubscriber(options, b.initialized)
+	if err != nil {
+		return err
+	}
+
+	// recreated by Reconnect
+	b.subscribers[options.Name] = options
+
+	return nil
+}
+
+func (b *Bus) createSubscriber(options interfaces.SubscriberOptions, start bool) error {
+	if options.Type == interfaces.ChannelTypeBroadcast {
+		if !start {
+			return nil
+		}
+		return b.subscribeTopic(options)
+	}
+
+	var handler rabbitinterfaces.RabbitMessageHandler
+	handler = *options.Handler
+
+	subscriber, err := (*b.manager).CreateSubscriber(rabbitinterfaces.SubscriberOptions{
+		Name:        options.Name,
+		Type:        rabbitinterfaces.ExchangeTypeDirect,
+		AutoDeleted: true,
+		IfUnused:    true,
+		Handler:     &handler,
+	})
+	if err != nil {
+		klog.V(1).Infof("CreateSubscriber %s failed. Err: %v\n", options.Name, err)
+		return err
+	}
+
+	if start {
+		err = (*subscriber).Init()
+		if err != nil {
+			klog.V(1).Infof("subscriber.Init %s failed. Err: %v\n", options.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Bus) Publish(name string, data []byte) error {
+	return b.PublishWithKey(name, "", data)
+}
+
+// PublishWithKey routes by key on the broadcast channels, the conversation channels ignore it
+func (b *Bus) PublishWithKey(name string, key string, data []byte) error {
+	b.mu.Lock()
+	manager := b.manager
+	channel := b.channel
+	options, found := b.publishers[name]
+	b.mu.Unlock()
+
+	var err error
+	if found && options.Type == interfaces.ChannelTypeBroadcast {
+		err = publishTopic(channel, name, key, data)
+	} else {
+		err = (*manager).PublishMessageByName(name, data)
+	}
+	if err != nil {
+		metrics.BusPublishFailures.WithLabelValues(metrics.BusRabbit).Inc()
+	}
+	return err
+}
+
+func (b *Bus) DeletePublisher(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	options := b.publishers[name]
+	delete(b.publishers, name)
+
+	// the exchange goes away with its last queue
+	if options.Type == interfaces.ChannelTypeBroadcast {
+		return nil
+	}
+	return (*b.manager).DeletePublisher(name)
+}
+
+func (b *Bus) DeleteSubscriber(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	options := b.subscribers[name]
+	delete(b.subscribers, name)
+
+	if options.Type == interfaces.ChannelTypeBroadcast {
+		return b.unsubscribeTopic(name)
+	}
+	return (*b.manager).DeleteSubscriber(name)
+}
+
+/*
+	Healthy watches the connection of the broadcast channels because the manager doesn't expose
+	its own. Once it closes, ie when RabbitMQ restarts, the channels of the manager are gone too
+	and the bus stays unhealthy until Reconnect replaces them.
+*/
+func (b *Bus) Healthy() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.initialized {
+		return ErrNotInitialized
+	}
+	if b.connection == nil {
+		return ErrNotConnected
+	}
+	if b.connection.IsClosed() {
+		return ErrConnectionLost
+	}
+
+	return nil
+}
+
+// Reconnect replaces the manager, the rabbitmq-manager can't recover channels on a closed connection
+func (b *Bus) Reconnect() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.initialized {
+		return ErrNotInitialized
+	}
+
+	manager, err := rabbit.New(rabbitinterfaces.ManagerOptions{
+		RabbitURI: b.options.RabbitURI,
+	})
+	if err != nil {
+		klog.V(1).Infof("rabbit.New failed. Err: %v\n", err)
+		return err
+	}
+
+	// the old channels are usually gone already, stop whatever is left so nothing is consumed twice
+	err = (*b.manager).Teardown()
+	if err != nil {
+		klog.V(3).Infof("Teardown of the old manager failed. Err: %v\n", err)
+	}
+	b.manager = manager
+
+	b.close()
+	err = b.dial()
+	if err != nil {
+		klog.V(1).Infof("dial failed. Err: %v\n", err)
+		return err
+	}
+
+	for name, options := range b.publishers {
+		err := b.createPublisher(options)
+		if err != nil {
+			klog.V(1).Infof("Recreating publisher %s failed. Err: %v\n", name, err)
+			return err
+		}
+	}
+	for name, options := range b.subscribers {
+		err := b.createSubscriber(options, true)
+		if err != nil {
+			klog.V(1).Infof("Recreating subscriber %s failed. Err: %v\n", name, err)
+			return err
+		}
+	}
+
+	klog.V(3).Infof("Reconnected %d publishers and %d subscribers\n", len(b.publishers), len(b.subscribers))
+
+	return nil
+}
+
+func (b *Bus) Teardown() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.close()
+
+	b.publishers = make(map[string]interfaces.PublisherOptions)
+	b.subscribers = make(map[string]interfaces.SubscriberOptions)
+	b.initialized = false
+	return (*b.manager).Teardown()
+}
@@ -0,0 +1,27 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rabbit
+
+import (
+	"errors"
+)
+
+const (
+	// binding key of a subscriber without keys
+	allKeys string = "#"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrNotInitialized the bus is not connected
+	ErrNotInitialized = errors.New("bus is not initialized")
+
+	// ErrNotConnected RabbitMQ can't be reached
+	ErrNotConnected = errors.New("not connected to RabbitMQ")
+
+	// ErrConnectionLost the connection to RabbitMQ closed, Reconnect restores it
+	ErrConnectionLost = errors.New("connection to RabbitMQ lost")
+)
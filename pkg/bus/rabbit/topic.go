@@ -0,0 +1,162 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rabbit
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+/*
+	The broadcast channels are topic exchanges on a connection of the bus, the rabbitmq-manager
+	only publishes and binds with an empty routing key. A subscriber binds its queue to each of
+	its keys, or to # for every key, so RabbitMQ never delivers the messages of other keys.
+*/
+func (b *Bus) dial() error {
+	connection, err := amqp.Dial(b.options.RabbitURI)
+	if err != nil {
+		klog.V(1).Infof("amqp.Dial failed. Err: %v\n", err)
+		return err
+	}
+
+	channel, err := connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+		connection.Close()
+		return err
+	}
+
+	b.connection = connection
+	b.channel = channel
+	b.consumers = make(map[string]*amqp.Channel)
+
+	return nil
+}
+
+// close takes the channels of the publishers and subscribers down with the connection
+func (b *Bus) close() {
+	if b.connection != nil {
+		b.connection.Close()
+		b.connection = nil
+	}
+	b.channel = nil
+	b.consumers = make(map[string]*amqp.Channel)
+}
+
+func (b *Bus) declareTopic(name string) error {
+	// an error on the channel closes it, the publisher is recreated after a failed publish
+	if b.channel == nil || b.channel.IsClosed() {
+		channel, err := b.connection.Channel()
+		if err != nil {
+			klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+			return err
+		}
+		b.channel = channel
+	}
+
+	err := b.channel.ExchangeDeclare(name, amqp.ExchangeTopic, false, true, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("ExchangeDeclare %s failed. Err: %v\n", name, err)
+		return err
+	}
+	return nil
+}
+
+func (b *Bus) subscribeTopic(options interfaces.SubscriberOptions) error {
+	// replaces a subscriber with the same name
+	err := b.unsubscribeTopic(options.Name)
+	if err != nil {
+		klog.V(3).Infof("unsubscribeTopic %s failed. Err: %v\n", options.Name, err)
+	}
+
+	channel, err := b.connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+		return err
+	}
+
+	err = channel.ExchangeDeclare(options.Name, amqp.ExchangeTopic, false, true, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("ExchangeDeclare %s failed. Err: %v\n", options.Name, err)
+		channel.Close()
+		return err
+	}
+
+	q, err := channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		klog.V(1).Infof("QueueDeclare %s failed. Err: %v\n", options.Name, err)
+		channel.Close()
+		return err
+	}
+
+	keys := options.Keys
+	if len(keys) == 0 {
+		keys = []string{allKeys}
+	}
+	for _, key := range keys {
+		err = channel.QueueBind(q.Name, key, options.Name, false, nil)
+		if err != nil {
+			klog.V(1).Infof("QueueBind %s to %s failed. Err: %v\n", options.Name, key, err)
+			channel.Close()
+			return err
+		}
+	}
+
+	msgs, err := channel.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("Consume %s failed. Err: %v\n", options.Name, err)
+		channel.Close()
+		return err
+	}
+	b.consumers[options.Name] = channel
+
+	// ends when the channel closes
+	handler := options.Handler
+	go func() {
+		for d := range msgs {
+			err := (*handler).ProcessMessage(d.Body)
+			if err != nil {
+				klog.V(1).Infof("ProcessMessage %s failed. Err: %v\n", options.Name, err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *Bus) unsubscribeTopic(name string) error {
+	channel := b.consumers[name]
+	if channel == nil {
+		return nil
+	}
+	delete(b.consumers, name)
+
+	return channel.Close()
+}
+
+func publishTopic(channel *amqp.Channel, name string, key string, data []byte) error {
+	if channel == nil {
+		klog.V(1).Infof("Bus is not initialized\n")
+		return ErrNotInitialized
+	}
+
+	err := channel.PublishWithContext(context.Background(),
+		name,  // exchange
+		key,   // routing key
+		false, // mandatory
+		false, // immediate
+		amqp.Publishing{
+			ContentType: "text/plain",
+			Body:        data,
+		})
+	if err != nil {
+		klog.V(1).Infof("PublishWithContext %s failed. Err: %v\n", name, err)
+		return err
+	}
+	return nil
+}
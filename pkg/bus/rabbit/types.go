@@ -0,0 +1,38 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rabbit
+
+import (
+	"sync"
+
+	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+// BusOptions to connect to RabbitMQ
+type BusOptions struct {
+	RabbitURI string
+}
+
+// Bus implements the bus on top of the rabbitmq-manager and topic exchanges
+type Bus struct {
+	options BusOptions
+
+	// rabbit
+	manager     *rabbitinterfaces.Manager
+	initialized bool
+	mu          sync.Mutex
+
+	// everything created that Reconnect has to recreate
+	publishers  map[string]interfaces.PublisherOptions
+	subscribers map[string]interfaces.SubscriberOptions
+
+	// the broadcast channels route by key, which the manager can't do, so they have a
+	// connection of their own. it also tells if the broker is reachable.
+	connection *amqp.Connection
+	channel    *amqp.Channel            // publishes to the broadcast channels
+	consumers  map[string]*amqp.Channel // one per broadcast subscriber
+}
@@ -0,0 +1,9 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package bus
+
+// BusOptions picks the bus from the scheme of the URI
+type BusOptions struct {
+	URI string
+}
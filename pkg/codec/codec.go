@@ -0,0 +1,132 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package codec
+
+import (
+	"encoding/json"
+
+	klog "k8s.io/klog/v2"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	The bus only moves bytes and RabbitMQ through the rabbitmq-manager doesn't hand message
+	properties to subscribers, so the content type travels in a small header at the front of
+	the message:
+
+	0x00 | length of content type (1 byte) | content type | body
+
+	JSON messages are sent without the header so plugins that don't know about it keep working.
+*/
+
+// Supported is true for the content types Marshal can encode, empty is JSON
+func Supported(contentType string) bool {
+	switch contentType {
+	case "", ContentTypeJSON, ContentTypeProtobuf:
+		return true
+	}
+	return false
+}
+
+// Frame puts the content type header in front of the body
+func Frame(contentType string, body []byte) ([]byte, error) {
+	if len(contentType) == 0 || len(contentType) > maxHeader {
+		klog.V(1).Infof("invalid content type %s\n", contentType)
+		return nil, ErrInvalidInput
+	}
+
+	framed := make([]byte, 0, 2+len(contentType)+len(body))
+	framed = append(framed, frameMarker, byte(len(contentType)))
+	framed = append(framed, contentType...)
+	framed = append(framed, body...)
+	return framed, nil
+}
+
+// Unframe returns the content type and body of a message, messages without a header are JSON
+func Unframe(data []byte) (string, []byte, error) {
+	if len(data) == 0 || data[0] != frameMarker {
+		return ContentTypeJSON, data, nil
+	}
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return "", nil, ErrInvalidFrame
+	}
+
+	size := int(data[1])
+	return string(data[2 : 2+size]), data[2+size:], nil
+}
+
+// Marshal encodes the value for the content type
+func Marshal(contentType string, v interface{}) ([]byte, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return json.Marshal(v)
+	case ContentTypeProtobuf:
+		body, err := marshalProto(v)
+		if err != nil {
+			return nil, err
+		}
+		return Frame(ContentTypeProtobuf, body)
+	}
+
+	klog.V(1).Infof("Marshal unsupported content type %s\n", contentType)
+	return nil, ErrUnsupportedContentType
+}
+
+// Unmarshal decodes a message in any of the supported encodings
+func Unmarshal(data []byte, v interface{}) error {
+	contentType, body, err := Unframe(data)
+	if err != nil {
+		return err
+	}
+
+	switch contentType {
+	case ContentTypeJSON:
+		return json.Unmarshal(body, v)
+	case ContentTypeProtobuf:
+		return unmarshalProto(body, v)
+	}
+
+	klog.V(1).Infof("Unmarshal unsupported content type %s\n", contentType)
+	return ErrUnsupportedContentType
+}
+
+/*
+	ReadEnvelope returns the envelope of an event in any of the supported encodings, nil when it
+	was sent without one. The envelope is the first field of every event, so it is the first
+	field number in protobuf as well.
+*/
+func ReadEnvelope(data []byte) (*shared.Envelope, error) {
+	var message struct {
+		Envelope *shared.Envelope `json:"envelope,omitempty"`
+	}
+	err := Unmarshal(data, &message)
+	if err != nil {
+		return nil, err
+	}
+	return message.Envelope, nil
+}
+
+/*
+	Transcode re-encodes a JSON event for the channel in the content type. Channels without a
+	known payload, like the application messages, are left as JSON.
+*/
+func Transcode(eventType string, data []byte, contentType string) ([]byte, error) {
+	if len(contentType) == 0 || contentType == ContentTypeJSON {
+		return data, nil
+	}
+
+	event := shared.NewEvent(eventType)
+	if event == nil {
+		return data, nil
+	}
+
+	err := json.Unmarshal(data, event)
+	if err != nil {
+		klog.V(1).Infof("json.Unmarshal %s failed. Err: %v\n", eventType, err)
+		return nil, err
+	}
+
+	return Marshal(contentType, event)
+}
@@ -0,0 +1,35 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package codec
+
+import (
+	"errors"
+)
+
+const (
+	// content types
+	ContentTypeJSON     string = "application/json"
+	ContentTypeProtobuf string = "application/x-protobuf"
+
+	// JSON never starts with a zero byte, so framed messages can't be mistaken for it
+	frameMarker byte = 0x00
+	maxHeader   int  = 255
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnsupportedContentType the content type isn't one of the known encodings
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+
+	// ErrInvalidFrame the message frame is truncated or malformed
+	ErrInvalidFrame = errors.New("invalid message frame")
+
+	// ErrUnknownEvent no payload type is registered for the channel
+	ErrUnknownEvent = errors.New("unknown event type")
+
+	// ErrWireType the protobuf wire type doesn't match the Go field
+	ErrWireType = errors.New("unexpected protobuf wire type")
+)
@@ -0,0 +1,526 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package codec
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	protowire "google.golang.org/protobuf/encoding/protowire"
+)
+
+/*
+	Protobuf encoding of the Go structs without generated code.
+
+	Each struct is a message and the field number is the position of the field in the Go
+	struct starting at 1, so the schema generator can write the matching .proto file. Zero
+	values are left out like proto3 does, time.Time is a google.protobuf.Timestamp and
+	anything protobuf can't describe (interfaces, nested slices, ...) is sent as JSON bytes.
+*/
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	plans    sync.Map
+)
+
+// FieldNumber is the protobuf field number for the Go struct field at index
+func FieldNumber(index int) protowire.Number {
+	return protowire.Number(index + 1)
+}
+
+// Skipped is true for struct fields that are never encoded
+func Skipped(field reflect.StructField) bool {
+	return !field.IsExported() || field.Tag.Get("json") == "-"
+}
+
+// Repeatable is true when a slice or map of the type maps onto protobuf without JSON
+func Repeatable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	case reflect.Array, reflect.Map, reflect.Interface, reflect.Pointer,
+		reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+func planFor(t reflect.Type) *plan {
+	if cached, ok := plans.Load(t); ok {
+		return cached.(*plan)
+	}
+
+	p := &plan{
+		fields: make([]planField, 0, t.NumField()),
+		byNum:  make(map[protowire.Number]int),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if Skipped(t.Field(i)) {
+			continue
+		}
+		p.byNum[FieldNumber(i)] = len(p.fields)
+		p.fields = append(p.fields, planField{
+			index: i,
+			num:   FieldNumber(i),
+		})
+	}
+
+	plans.Store(t, p)
+	return p
+}
+
+func packable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func marshalProto(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, ErrInvalidInput
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, ErrInvalidInput
+	}
+
+	return appendStruct(nil, rv)
+}
+
+func appendStruct(b []byte, sv reflect.Value) ([]byte, error) {
+	if sv.Type() == timeType {
+		t := sv.Interface().(time.Time)
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(t.Unix()))
+		if t.Nanosecond() != 0 {
+			b = protowire.AppendTag(b, 2, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(t.Nanosecond()))
+		}
+		return b, nil
+	}
+
+	for _, field := range planFor(sv.Type()).fields {
+		var err error
+		b, err = appendField(b, field.num, sv.Field(field.index))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// appendField leaves out zero values
+func appendField(b []byte, num protowire.Number, fv reflect.Value) ([]byte, error) {
+	switch fv.Kind() {
+	case reflect.Pointer:
+		if fv.IsNil() {
+			return b, nil
+		}
+		return appendValue(b, num, fv.Elem())
+	case reflect.Struct:
+		if fv.Type() == timeType {
+			if fv.Interface().(time.Time).IsZero() {
+				return b, nil
+			}
+			return appendValue(b, num, fv)
+		}
+
+		// empty messages are left out
+		inner, err := appendStruct(nil, fv)
+		if err != nil || len(inner) == 0 {
+			return b, err
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, inner), nil
+	case reflect.Slice:
+		if fv.Len() == 0 {
+			return b, nil
+		}
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return appendValue(b, num, fv)
+		}
+		if !Repeatable(fv.Type().Elem()) {
+			return appendJSON(b, num, fv)
+		}
+		for j := 0; j < fv.Len(); j++ {
+			var err error
+			b, err = appendElement(b, num, fv.Index(j))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if fv.Len() == 0 {
+			return b, nil
+		}
+		if fv.Type().Key().Kind() != reflect.String || !Repeatable(fv.Type().Elem()) {
+			return appendJSON(b, num, fv)
+		}
+		return appendMap(b, num, fv)
+	case reflect.Array, reflect.Interface:
+		if fv.IsZero() {
+			return b, nil
+		}
+		return appendJSON(b, num, fv)
+	}
+
+	if fv.IsZero() {
+		return b, nil
+	}
+	return appendValue(b, num, fv)
+}
+
+// appendElement always writes the value so repeated fields keep their positions
+func appendElement(b []byte, num protowire.Number, ev reflect.Value) ([]byte, error) {
+	if ev.Kind() == reflect.Pointer {
+		if ev.IsNil() {
+			ev = reflect.New(ev.Type().Elem()).Elem()
+		} else {
+			ev = ev.Elem()
+		}
+	}
+	return appendValue(b, num, ev)
+}
+
+func appendValue(b []byte, num protowire.Number, v reflect.Value) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		inner, err := appendStruct(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, inner), nil
+	case reflect.Bool:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v.Bool())), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v.Uint()), nil
+	case reflect.Float32:
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float())), nil
+	case reflect.String:
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, v.String()), nil
+	case reflect.Slice:
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v.Bytes()), nil
+	}
+
+	return appendJSON(b, num, v)
+}
+
+func appendMap(b []byte, num protowire.Number, mv reflect.Value) ([]byte, error) {
+	keys := mv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	for _, key := range keys {
+		entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, key.String())
+
+		var err error
+		entry, err = appendElement(entry, 2, mv.MapIndex(key))
+		if err != nil {
+			return nil, err
+		}
+
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+
+	return b, nil
+}
+
+func appendJSON(b []byte, num protowire.Number, v reflect.Value) ([]byte, error) {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, data), nil
+}
+
+func unmarshalProto(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrInvalidInput
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return ErrInvalidInput
+	}
+
+	return consumeStruct(data, rv)
+}
+
+func consumeStruct(b []byte, sv reflect.Value) error {
+	if sv.Type() == timeType {
+		return consumeTimestamp(b, sv)
+	}
+
+	p := planFor(sv.Type())
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		// unknown fields are skipped so newer producers don't break older consumers
+		i, ok := p.byNum[num]
+		if !ok {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		n, err := consumeField(b, typ, sv.Field(p.fields[i].index))
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func consumeTimestamp(b []byte, sv reflect.Value) error {
+	var seconds, nanos uint64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if typ != protowire.VarintType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		} else if num == 1 {
+			seconds, n = protowire.ConsumeVarint(b)
+		} else if num == 2 {
+			nanos, n = protowire.ConsumeVarint(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+
+	sv.Set(reflect.ValueOf(time.Unix(int64(seconds), int64(nanos)).UTC()))
+	return nil
+}
+
+func consumeField(b []byte, typ protowire.Type, fv reflect.Value) (int, error) {
+	switch fv.Kind() {
+	case reflect.Pointer:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return consumeField(b, typ, fv.Elem())
+	case reflect.Slice:
+		elemType := fv.Type().Elem()
+		if elemType.Kind() == reflect.Uint8 {
+			return consumeValue(b, typ, fv)
+		}
+		if !Repeatable(elemType) {
+			return consumeJSON(b, typ, fv)
+		}
+
+		// accept packed scalars as well
+		if typ == protowire.BytesType && packable(elemType) {
+			packed, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			for len(packed) > 0 {
+				elem := reflect.New(elemType).Elem()
+				m, err := consumeValue(packed, wireType(elemType), elem)
+				if err != nil {
+					return 0, err
+				}
+				packed = packed[m:]
+				fv.Set(reflect.Append(fv, elem))
+			}
+			return n, nil
+		}
+
+		// decode in place so large elements aren't copied
+		fv.Set(reflect.Append(fv, reflect.Zero(elemType)))
+		return consumeField(b, typ, fv.Index(fv.Len()-1))
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String || !Repeatable(fv.Type().Elem()) {
+			return consumeJSON(b, typ, fv)
+		}
+		return consumeMapEntry(b, typ, fv)
+	case reflect.Array, reflect.Interface:
+		return consumeJSON(b, typ, fv)
+	}
+
+	return consumeValue(b, typ, fv)
+}
+
+func wireType(t reflect.Type) protowire.Type {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return protowire.VarintType
+	case reflect.Float32:
+		return protowire.Fixed32Type
+	case reflect.Float64:
+		return protowire.Fixed64Type
+	}
+	return protowire.BytesType
+}
+
+func consumeValue(b []byte, typ protowire.Type, v reflect.Value) (int, error) {
+	if typ != wireType(v.Type()) {
+		return 0, ErrWireType
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		inner, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		return n, consumeStruct(inner, v)
+	case reflect.Bool:
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetBool(protowire.DecodeBool(x))
+		return n, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetInt(int64(x))
+		return n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetUint(x)
+		return n, nil
+	case reflect.Float32:
+		x, n := protowire.ConsumeFixed32(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetFloat(float64(math.Float32frombits(x)))
+		return n, nil
+	case reflect.Float64:
+		x, n := protowire.ConsumeFixed64(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetFloat(math.Float64frombits(x))
+		return n, nil
+	case reflect.String:
+		x, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetString(string(x))
+		return n, nil
+	case reflect.Slice:
+		x, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetBytes(append([]byte(nil), x...))
+		return n, nil
+	}
+
+	return consumeJSON(b, typ, v)
+}
+
+func consumeMapEntry(b []byte, typ protowire.Type, mv reflect.Value) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, ErrWireType
+	}
+	entry, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	key := reflect.New(mv.Type().Key()).Elem()
+	value := reflect.New(mv.Type().Elem()).Elem()
+	for len(entry) > 0 {
+		num, entryType, m := protowire.ConsumeTag(entry)
+		if m < 0 {
+			return 0, protowire.ParseError(m)
+		}
+		entry = entry[m:]
+
+		var err error
+		switch num {
+		case 1:
+			m, err = consumeValue(entry, entryType, key)
+		case 2:
+			m, err = consumeField(entry, entryType, value)
+		default:
+			m = protowire.ConsumeFieldValue(num, entryType, entry)
+			if m < 0 {
+				err = protowire.ParseError(m)
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		entry = entry[m:]
+	}
+
+	if mv.IsNil() {
+		mv.Set(reflect.MakeMap(mv.Type()))
+	}
+	mv.SetMapIndex(key, value)
+
+	return n, nil
+}
+
+func consumeJSON(b []byte, typ protowire.Type, v reflect.Value) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, ErrWireType
+	}
+	data, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	return n, json.Unmarshal(data, v.Addr().Interface())
+}
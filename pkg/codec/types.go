@@ -0,0 +1,19 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package codec
+
+import (
+	protowire "google.golang.org/protobuf/encoding/protowire"
+)
+
+// plan is the list of encoded fields for a struct type, worked out once per type
+type plan struct {
+	fields []planField
+	byNum  map[protowire.Number]int
+}
+
+type planField struct {
+	index int
+	num   protowire.Number
+}
@@ -0,0 +1,699 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+	klog "k8s.io/klog/v2"
+
+	auth "github.com/dvonthenen/enterprise-conversation-application/pkg/auth"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
+)
+
+/*
+	Load builds the configuration for a command. Each source overrides the previous one:
+
+		1. the defaults
+		2. the YAML or JSON file given by -config or ERI_CONFIG
+		3. the environment variables
+		4. the command line flags in args
+
+	The result is validated for the component before it is returned. flag.ErrHelp is
+	returned when args asks for the usage.
+*/
+func Load(component Component, args []string) (*Config, error) {
+	klog.V(6).Infof("config.Load ENTER\n")
+
+	c := &Config{
+		TLS: TLSConfig{
+			CrtFile: DefaultCrtFile,
+			KeyFile: DefaultKeyFile,
+		},
+		Bus: BusConfig{
+			RabbitURI: DefaultRabbitURI,
+		},
+		Metrics: MetricsConfig{
+			Address: defaultMetricsAddress(component),
+			Path:    metrics.DefaultPath,
+		},
+		component: component,
+	}
+
+	// the flags are parsed once to find the file and again on top of the file and environment
+	var file string
+	scratch := *c
+	fs := scratch.flagSet(component, &file, os.Stderr)
+	err := fs.Parse(args)
+	if err != nil {
+		klog.V(6).Infof("config.Load LEAVE\n")
+		return nil, err
+	}
+
+	if len(file) == 0 {
+		file = os.Getenv("ERI_CONFIG")
+	}
+	if len(file) > 0 {
+		err = c.loadFile(file)
+		if err != nil {
+			klog.V(1).Infof("loadFile(%s) failed. Err: %v\n", file, err)
+			klog.V(6).Infof("config.Load LEAVE\n")
+			return nil, err
+		}
+	}
+
+	err = c.loadEnv()
+	if err != nil {
+		klog.V(1).Infof("loadEnv failed. Err: %v\n", err)
+		klog.V(6).Infof("config.Load LEAVE\n")
+		return nil, err
+	}
+
+	err = c.flagSet(component, &file, io.Discard).Parse(args)
+	if err != nil {
+		klog.V(6).Infof("config.Load LEAVE\n")
+		return nil, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		klog.V(1).Infof("Validate failed. Err: %v\n", err)
+		klog.V(6).Infof("config.Load LEAVE\n")
+		return nil, err
+	}
+
+	klog.V(4).Infof("config.Load Succeeded\n")
+	klog.V(6).Infof("config.Load LEAVE\n")
+
+	return c, nil
+}
+
+func (c *Config) flagSet(component Component, file *string, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(string(component), flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	// the klog flags, ie -v, are given on the same command line
+	klog.InitFlags(fs)
+
+	fs.StringVar(file, "config", *file, "YAML or JSON config file, also ERI_CONFIG")
+	fs.StringVar(&c.TLS.CrtFile, "crt", c.TLS.CrtFile, "TLS certificate file")
+	fs.StringVar(&c.TLS.KeyFile, "key", c.TLS.KeyFile, "TLS key file")
+	fs.StringVar(&c.TLS.ClientCAFile, "client-ca", c.TLS.ClientCAFile, "PEM CA certificates to verify client certificates against")
+	fs.StringVar(&c.TLS.ClientAuth, "client-auth", c.TLS.ClientAuth, "client certificates: none, optional or require")
+	fs.StringVar(&c.TLS.MinVersion, "tls-min-version", c.TLS.MinVersion, "oldest TLS version accepted: 1.2 or 1.3")
+	fs.StringVar(&c.Bus.RabbitURI, "rabbit", c.Bus.RabbitURI, "message bus URI, amqp:// or nats://")
+	fs.StringVar(&c.Neo4j.Connection, "neo4j-connection", c.Neo4j.Connection, "Neo4j connection URI")
+	fs.StringVar(&c.Neo4j.Database, "neo4j-database", c.Neo4j.Database, "Neo4j database name")
+	fs.StringVar(&c.Neo4j.AuthMode, "neo4j-auth-mode", c.Neo4j.AuthMode, "Neo4j auth mode: basic, bearer, kerberos or none")
+	fs.StringVar(&c.Neo4j.Username, "neo4j-username", c.Neo4j.Username, "Neo4j username")
+	fs.StringVar(&c.Neo4j.CACert, "neo4j-ca-cert", c.Neo4j.CACert, "PEM file with the CA certificates to trust")
+	fs.IntVar(&c.Neo4j.MaxConnections, "neo4j-max-connections", c.Neo4j.MaxConnections, "max connections in the Neo4j driver pool")
+	fs.StringVar(&c.Neo4j.AcquisitionTimeout, "neo4j-acquisition-timeout", c.Neo4j.AcquisitionTimeout, "how long to wait for a pooled Neo4j connection")
+	fs.StringVar(&c.Metrics.Address, "metrics-address", c.Metrics.Address, "address for the metrics endpoint, empty disables it")
+	fs.StringVar(&c.Metrics.Path, "metrics-path", c.Metrics.Path, "path for the metrics endpoint")
+	fs.StringVar(&c.Tracing.Exporter, "tracing-exporter", c.Tracing.Exporter, "span exporter: otlp, stdout or none")
+	fs.StringVar(&c.Tracing.Endpoint, "tracing-endpoint", c.Tracing.Endpoint, "OTLP gRPC collector address")
+	fs.BoolVar(&c.Tracing.Insecure, "tracing-insecure", c.Tracing.Insecure, "connect to the OTLP collector without TLS")
+	fs.StringVar(&c.Encryption.KeyFile, "encryption-key-file", c.Encryption.KeyFile, "key file to encrypt the content saved in Neo4j, empty disables it")
+
+	switch component {
+	case ComponentProxyDataminer:
+		fs.IntVar(&c.Dataminer.StartPort, "start-port", c.Dataminer.StartPort, "first port for proxy instances")
+		fs.IntVar(&c.Dataminer.EndPort, "end-port", c.Dataminer.EndPort, "last port for proxy instances")
+		fs.BoolVar(&c.Dataminer.Transcription, "transcription", c.Dataminer.Transcription, "forward transcription to the client")
+		fs.BoolVar(&c.Dataminer.Messaging, "messaging", c.Dataminer.Messaging, "forward messages to the client")
+		fs.StringVar(&c.Bus.ContentType, "content-type", c.Bus.ContentType, "wire format for bus events")
+		fs.StringVar(&c.Dataminer.RawPolicy, "raw-policy", c.Dataminer.RawPolicy, "raw payload policy: reference, inline or none")
+	case ComponentRestDataminer:
+		fs.BoolVar(&c.Dataminer.DisableDuplicate, "disable-duplicate", c.Dataminer.DisableDuplicate, "don't reprocess conversations already in the database")
+		fs.StringVar(&c.Bus.ContentType, "content-type", c.Bus.ContentType, "wire format for bus events")
+		fs.StringVar(&c.Dataminer.RawPolicy, "raw-policy", c.Dataminer.RawPolicy, "raw payload policy: reference, inline or none")
+	case ComponentPlugin:
+		fs.StringVar(&c.Plugin.BindAddress, "bind-address", c.Plugin.BindAddress, "address the plugin listens on")
+		fs.IntVar(&c.Plugin.BindPort, "bind-port", c.Plugin.BindPort, "port the plugin listens on")
+	}
+
+	switch component {
+	case ComponentProxyDataminer, ComponentRestDataminer:
+		fs.BoolVar(&c.Redaction.Enabled, "redaction", c.Redaction.Enabled, "mask PII before it is saved or published")
+		fs.StringVar(&c.Redaction.Content, "redaction-content", c.Redaction.Content, "PII in transcripts and insights: mask, hash, drop or keep")
+		fs.StringVar(&c.Redaction.User, "redaction-user", c.Redaction.User, "userId and email: hash, mask, drop or keep")
+		fs.StringVar(&c.Redaction.Name, "redaction-name", c.Redaction.Name, "speaker names: keep, mask, hash or drop")
+		fs.StringVar(&c.Redaction.Entity, "redaction-entity", c.Redaction.Entity, "values of PII entities: hash, mask, drop or keep")
+		fs.BoolVar(&c.Auth.Symbl, "auth-symbl", c.Auth.Symbl, "accept the Symbl access tokens of the clients")
+		fs.StringVar(&c.Auth.JWTPublicKeyFile, "auth-jwt-public-key-file", c.Auth.JWTPublicKeyFile, "PEM public key or certificate for RS256 or ES256 tokens")
+		fs.StringVar(&c.Auth.JWTIssuer, "auth-jwt-issuer", c.Auth.JWTIssuer, "iss claim the tokens must have")
+		fs.StringVar(&c.Auth.JWTAudience, "auth-jwt-audience", c.Auth.JWTAudience, "aud claim the tokens must have")
+		fs.BoolVar(&c.Auth.ClientCertificates, "auth-client-certificates", c.Auth.ClientCertificates, "accept verified client certificates instead of a token")
+		fs.StringVar(&c.Auth.Authorization, "auth-authorization", c.Auth.Authorization, "who may open or observe a conversation: owner or any")
+		fs.StringVar(&c.Auth.TenantClaim, "auth-tenant-claim", c.Auth.TenantClaim, "token claim holding the tenant of the client")
+	}
+
+	return fs
+}
+
+func (c *Config) loadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(f)
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+		if err == io.EOF {
+			err = nil
+		}
+	case ".json":
+		decoder := json.NewDecoder(f)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	default:
+		err = ErrUnsupportedFile
+	}
+	return err
+}
+
+func (c *Config) loadEnv() error {
+	envString("ERI_CRT_FILE", &c.TLS.CrtFile)
+	envString("ERI_KEY_FILE", &c.TLS.KeyFile)
+	envString("ERI_CLIENT_CA_FILE", &c.TLS.ClientCAFile)
+	envString("ERI_CLIENT_AUTH", &c.TLS.ClientAuth)
+	envString("ERI_TLS_MIN_VERSION", &c.TLS.MinVersion)
+	envString("ERI_TLS_RELOAD_INTERVAL", &c.TLS.ReloadInterval)
+	if v := os.Getenv("ERI_TLS_CIPHER_SUITES"); v != "" {
+		klog.V(4).Info("ERI_TLS_CIPHER_SUITES found")
+		c.TLS.CipherSuites = strings.Split(v, ",")
+	}
+	envString("ERI_RABBIT_URI", &c.Bus.RabbitURI)
+	envString("ERI_CONTENT_TYPE", &c.Bus.ContentType)
+
+	envString("NEO4J_CONNECTION", &c.Neo4j.Connection)
+	envString("NEO4J_DATABASE", &c.Neo4j.Database)
+	envString("NEO4J_AUTH_MODE", &c.Neo4j.AuthMode)
+	envString("NEO4J_USERNAME", &c.Neo4j.Username)
+	envString("NEO4J_PASSWORD", &c.Neo4j.Password)
+	envString("NEO4J_REALM", &c.Neo4j.Realm)
+	envString("NEO4J_TOKEN", &c.Neo4j.Token)
+	envString("NEO4J_KERBEROS_TICKET", &c.Neo4j.KerberosTicket)
+	envString("NEO4J_CA_CERT", &c.Neo4j.CACert)
+	envString("NEO4J_ACQUISITION_TIMEOUT", &c.Neo4j.AcquisitionTimeout)
+	envString("NEO4J_MAX_CONNECTION_LIFETIME", &c.Neo4j.MaxConnectionLifetime)
+	if v := os.Getenv("NEO4J_TENANT_DATABASES"); v != "" {
+		klog.V(4).Info("NEO4J_TENANT_DATABASES found")
+		tenantDatabases, err := dbconfig.ParseTenantDatabases(v)
+		if err != nil {
+			return err
+		}
+		c.Neo4j.TenantDatabases = tenantDatabases
+	}
+	err := envInt("NEO4J_MAX_CONNECTIONS", &c.Neo4j.MaxConnections)
+	if err != nil {
+		return err
+	}
+
+	envString("ERI_RAW_POLICY", &c.Dataminer.RawPolicy)
+	for name, value := range map[string]*bool{
+		"ERI_TRANSCRIPTION":     &c.Dataminer.Transcription,
+		"ERI_MESSAGING":         &c.Dataminer.Messaging,
+		"ERI_DISABLE_DUPLICATE": &c.Dataminer.DisableDuplicate,
+	} {
+		err = envBool(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	for name, value := range map[string]*int{
+		"ERI_START_PORT": &c.Dataminer.StartPort,
+		"ERI_END_PORT":   &c.Dataminer.EndPort,
+		"ERI_BIND_PORT":  &c.Plugin.BindPort,
+	} {
+		err = envInt(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	envString("ERI_BIND_ADDRESS", &c.Plugin.BindAddress)
+	if v := os.Getenv("ERI_TENANTS"); v != "" {
+		klog.V(4).Info("ERI_TENANTS found")
+		c.Plugin.Tenants = strings.Split(v, ",")
+	}
+
+	envString("ERI_METRICS_ADDRESS", &c.Metrics.Address)
+	envString("ERI_METRICS_PATH", &c.Metrics.Path)
+
+	err = envBool("ERI_REDACTION", &c.Redaction.Enabled)
+	if err != nil {
+		return err
+	}
+	envString("ERI_REDACTION_CONTENT", &c.Redaction.Content)
+	envString("ERI_REDACTION_USER", &c.Redaction.User)
+	envString("ERI_REDACTION_NAME", &c.Redaction.Name)
+	envString("ERI_REDACTION_ENTITY", &c.Redaction.Entity)
+	envString("ERI_REDACTION_HASH_KEY", &c.Redaction.HashKey)
+	if v := os.Getenv("ERI_REDACTION_ENTITY_TYPES"); v != "" {
+		klog.V(4).Info("ERI_REDACTION_ENTITY_TYPES found")
+		c.Redaction.EntityTypes = strings.Split(v, ",")
+	}
+
+	envString("ERI_ENCRYPTION_KEY_FILE", &c.Encryption.KeyFile)
+	envString("ERI_ENCRYPTION_DATA_KEY_TTL", &c.Encryption.DataKeyTTL)
+
+	for name, value := range map[string]*bool{
+		"ERI_AUTH_SYMBL":               &c.Auth.Symbl,
+		"ERI_AUTH_CLIENT_CERTIFICATES": &c.Auth.ClientCertificates,
+	} {
+		err = envBool(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	envString("ERI_AUTH_SYMBL_CACHE_TTL", &c.Auth.SymblCacheTTL)
+	envString("ERI_AUTH_JWT_SECRET", &c.Auth.JWTSecret)
+	envString("ERI_AUTH_JWT_PUBLIC_KEY_FILE", &c.Auth.JWTPublicKeyFile)
+	envString("ERI_AUTH_JWT_ISSUER", &c.Auth.JWTIssuer)
+	envString("ERI_AUTH_JWT_AUDIENCE", &c.Auth.JWTAudience)
+	envString("ERI_AUTH_AUTHORIZATION", &c.Auth.Authorization)
+	envString("ERI_AUTH_TENANT_CLAIM", &c.Auth.TenantClaim)
+	envString("ERI_AUTH_ADMIN_SCOPE", &c.Auth.AdminScope)
+
+	envString("ERI_TRACING_EXPORTER", &c.Tracing.Exporter)
+	envString("ERI_TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	err = envBool("ERI_TRACING_INSECURE", &c.Tracing.Insecure)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks the sections used by the component
+func (c *Config) Validate() error {
+	if len(c.TLS.CrtFile) == 0 || len(c.TLS.KeyFile) == 0 {
+		klog.V(1).Infof("CrtFile or KeyFile is empty\n")
+		return ErrInvalidInput
+	}
+	if len(c.Bus.RabbitURI) == 0 {
+		klog.V(1).Infof("RabbitURI is empty\n")
+		return ErrInvalidInput
+	}
+
+	_, err := c.TLSOptions()
+	if err != nil {
+		klog.V(1).Infof("TLS is invalid. Err: %v\n", err)
+		return err
+	}
+
+	_, err = c.Neo4jConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Metrics.Address) > 0 && !strings.HasPrefix(c.Metrics.Path, "/") {
+		klog.V(1).Infof("Metrics Path %s must start with /\n", c.Metrics.Path)
+		return ErrInvalidInput
+	}
+	if !tracing.Supported(tracing.Exporter(c.Tracing.Exporter)) {
+		klog.V(1).Infof("Tracing Exporter %s is not supported\n", c.Tracing.Exporter)
+		return tracing.ErrUnsupportedExporter
+	}
+	_, err = c.EncryptionOptions()
+	if err != nil {
+		klog.V(1).Infof("Encryption is invalid. Err: %v\n", err)
+		return err
+	}
+
+	switch c.component {
+	case ComponentProxyDataminer, ComponentRestDataminer:
+		if !codec.Supported(c.Bus.ContentType) {
+			klog.V(1).Infof("ContentType %s is not supported\n", c.Bus.ContentType)
+			return codec.ErrUnsupportedContentType
+		}
+		if !rawevent.Supported(rawevent.Policy(c.Dataminer.RawPolicy)) {
+			klog.V(1).Infof("RawPolicy %s is not supported\n", c.Dataminer.RawPolicy)
+			return rawevent.ErrUnsupportedPolicy
+		}
+		if options := c.RedactionOptions(); options != nil {
+			_, err := redaction.New(*options)
+			if err != nil {
+				klog.V(1).Infof("Redaction is invalid. Err: %v\n", err)
+				return err
+			}
+		}
+		if !validPort(c.Dataminer.StartPort) || !validPort(c.Dataminer.EndPort) {
+			klog.V(1).Infof("StartPort %d or EndPort %d is out of range\n", c.Dataminer.StartPort, c.Dataminer.EndPort)
+			return ErrInvalidPort
+		}
+		if c.Dataminer.StartPort > 0 && c.Dataminer.EndPort > 0 && c.Dataminer.StartPort >= c.Dataminer.EndPort {
+			klog.V(1).Infof("StartPort %d must be less than EndPort %d\n", c.Dataminer.StartPort, c.Dataminer.EndPort)
+			return ErrInvalidPort
+		}
+	}
+
+	switch c.component {
+	case ComponentProxyDataminer:
+		_, err = c.AuthOptions()
+		if err != nil {
+			klog.V(1).Infof("Auth is invalid. Err: %v\n", err)
+			return err
+		}
+	case ComponentPlugin:
+		if !validPort(c.Plugin.BindPort) {
+			klog.V(1).Infof("BindPort %d is out of range\n", c.Plugin.BindPort)
+			return ErrInvalidPort
+		}
+		for _, tenant := range c.Plugin.Tenants {
+			if !shared.ValidTenant(tenant) {
+				klog.V(1).Infof("Tenant %s is invalid\n", tenant)
+				return ErrInvalidInput
+			}
+		}
+	}
+
+	return nil
+}
+
+// Neo4jConfig converts the neo4j section for dbconfig and validates it
+func (c *Config) Neo4jConfig() (*dbconfig.Config, error) {
+	neo4jConfig := &dbconfig.Config{
+		ConnectionStr:         c.Neo4j.Connection,
+		DatabaseName:          c.Neo4j.Database,
+		TenantDatabases:       c.Neo4j.TenantDatabases,
+		AuthMode:              dbconfig.AuthMode(c.Neo4j.AuthMode),
+		Username:              c.Neo4j.Username,
+		Password:              c.Neo4j.Password,
+		Realm:                 c.Neo4j.Realm,
+		Token:                 c.Neo4j.Token,
+		KerberosTicket:        c.Neo4j.KerberosTicket,
+		CACertFile:            c.Neo4j.CACert,
+		MaxConnectionPoolSize: c.Neo4j.MaxConnections,
+	}
+
+	var err error
+	if len(c.Neo4j.AcquisitionTimeout) > 0 {
+		neo4jConfig.ConnectionAcquisitionTimeout, err = time.ParseDuration(c.Neo4j.AcquisitionTimeout)
+		if err != nil {
+			klog.V(1).Infof("AcquisitionTimeout is invalid. Err: %v\n", err)
+			return nil, err
+		}
+	}
+	if len(c.Neo4j.MaxConnectionLifetime) > 0 {
+		neo4jConfig.MaxConnectionLifetime, err = time.ParseDuration(c.Neo4j.MaxConnectionLifetime)
+		if err != nil {
+			klog.V(1).Infof("MaxConnectionLifetime is invalid. Err: %v\n", err)
+			return nil, err
+		}
+	}
+
+	err = neo4jConfig.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return neo4jConfig, nil
+}
+
+// TLSOptions is the TLS for every listener of the server, the files are read by tlsconfig.New
+func (c *Config) TLSOptions() (*tlsconfig.Options, error) {
+	options := &tlsconfig.Options{
+		CrtFile:      c.TLS.CrtFile,
+		KeyFile:      c.TLS.KeyFile,
+		ClientCAFile: c.TLS.ClientCAFile,
+		ClientAuth:   tlsconfig.ClientAuth(c.TLS.ClientAuth),
+	}
+	if len(options.ClientAuth) > 0 && !tlsconfig.SupportedClientAuth(options.ClientAuth) {
+		klog.V(1).Infof("ClientAuth %s is not supported\n", c.TLS.ClientAuth)
+		return nil, tlsconfig.ErrUnsupportedClientAuth
+	}
+	if len(options.ClientAuth) > 0 && options.ClientAuth != tlsconfig.ClientAuthNone && len(options.ClientCAFile) == 0 {
+		klog.V(1).Infof("ClientAuth %s needs a ClientCAFile\n", c.TLS.ClientAuth)
+		return nil, ErrInvalidInput
+	}
+
+	var err error
+	options.MinVersion, err = tlsconfig.ParseVersion(c.TLS.MinVersion)
+	if err != nil {
+		klog.V(1).Infof("MinVersion %s is not supported\n", c.TLS.MinVersion)
+		return nil, err
+	}
+	options.CipherSuites, err = tlsconfig.ParseCipherSuites(c.TLS.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.TLS.ReloadInterval) > 0 {
+		options.ReloadInterval, err = time.ParseDuration(c.TLS.ReloadInterval)
+		if err != nil {
+			klog.V(1).Infof("ReloadInterval is invalid. Err: %v\n", err)
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+// MetricsOptions is the metrics endpoint for the server options, nil when it is disabled
+func (c *Config) MetricsOptions() *metrics.ServerOptions {
+	if len(c.Metrics.Address) == 0 {
+		return nil
+	}
+	return &metrics.ServerOptions{
+		Address: c.Metrics.Address,
+		Path:    c.Metrics.Path,
+	}
+}
+
+// TracingOptions is the span exporter for the server options, nil when tracing is disabled
+func (c *Config) TracingOptions(serviceName string) *tracing.ProviderOptions {
+	exporter := tracing.Exporter(c.Tracing.Exporter)
+	if len(exporter) == 0 || exporter == tracing.ExporterNone {
+		return nil
+	}
+	return &tracing.ProviderOptions{
+		ServiceName: serviceName,
+		Exporter:    exporter,
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+	}
+}
+
+// RedactionOptions is the PII redaction for the server options, nil when it is disabled
+func (c *Config) RedactionOptions() *redaction.Options {
+	if !c.Redaction.Enabled {
+		return nil
+	}
+
+	policies := make(map[redaction.Field]redaction.Action)
+	for field, action := range map[redaction.Field]string{
+		redaction.FieldContent: c.Redaction.Content,
+		redaction.FieldUser:    c.Redaction.User,
+		redaction.FieldName:    c.Redaction.Name,
+		redaction.FieldEntity:  c.Redaction.Entity,
+	} {
+		if len(action) > 0 {
+			policies[field] = redaction.Action(action)
+		}
+	}
+
+	return &redaction.Options{
+		Policies:    policies,
+		Rules:       c.Redaction.Rules,
+		EntityTypes: c.Redaction.EntityTypes,
+		HashKey:     c.Redaction.HashKey,
+	}
+}
+
+// EncryptionOptions is the content encryption for the server options, nil when it is disabled
+func (c *Config) EncryptionOptions() (*encryption.Options, error) {
+	if len(c.Encryption.KeyFile) == 0 {
+		return nil, nil
+	}
+
+	provider, err := encryption.NewLocalKeyProvider(c.Encryption.KeyFile)
+	if err != nil {
+		klog.V(1).Infof("NewLocalKeyProvider(%s) failed. Err: %v\n", c.Encryption.KeyFile, err)
+		return nil, err
+	}
+
+	options := &encryption.Options{
+		Provider: provider,
+	}
+	if len(c.Encryption.DataKeyTTL) > 0 {
+		options.DataKeyTTL, err = time.ParseDuration(c.Encryption.DataKeyTTL)
+		if err != nil {
+			klog.V(1).Infof("DataKeyTTL is invalid. Err: %v\n", err)
+			return nil, err
+		}
+	}
+
+	// conversations without a tenant are saved with the keys of the default tenant
+	_, err = provider.CurrentKey(context.Background(), options.Tenant)
+	if err != nil {
+		klog.V(1).Infof("CurrentKey failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// AuthOptions is the authn/authz of the Proxy/Dataminer entry points and the outbox endpoints, nil when it is disabled
+func (c *Config) AuthOptions() (*auth.Options, error) {
+	options := &auth.Options{
+		ClientCertificates: c.Auth.ClientCertificates,
+		TenantClaim:        c.Auth.TenantClaim,
+		AdminScope:         c.Auth.AdminScope,
+	}
+
+	// local keys first, they don't need a call to Symbl
+	if len(c.Auth.JWTSecret) > 0 || len(c.Auth.JWTPublicKeyFile) > 0 {
+		authenticator, err := auth.NewJWTAuthenticator(auth.JWTOptions{
+			Secret:        []byte(c.Auth.JWTSecret),
+			PublicKeyFile: c.Auth.JWTPublicKeyFile,
+			Issuer:        c.Auth.JWTIssuer,
+			Audience:      c.Auth.JWTAudience,
+		})
+		if err != nil {
+			klog.V(1).Infof("NewJWTAuthenticator failed. Err: %v\n", err)
+			return nil, err
+		}
+		options.Authenticators = append(options.Authenticators, authenticator)
+	}
+	if c.Auth.Symbl {
+		symblOptions := auth.SymblOptions{}
+		if len(c.Auth.SymblCacheTTL) > 0 {
+			var err error
+			symblOptions.CacheTTL, err = time.ParseDuration(c.Auth.SymblCacheTTL)
+			if err != nil {
+				klog.V(1).Infof("SymblCacheTTL is invalid. Err: %v\n", err)
+				return nil, err
+			}
+		}
+		authenticator, err := auth.NewSymblAuthenticator(symblOptions)
+		if err != nil {
+			klog.V(1).Infof("NewSymblAuthenticator failed. Err: %v\n", err)
+			return nil, err
+		}
+		options.Authenticators = append(options.Authenticators, authenticator)
+	}
+	if len(options.Authenticators) == 0 && !options.ClientCertificates {
+		return nil, nil
+	}
+
+	switch c.Auth.Authorization {
+	case "", AuthorizationOwner:
+		options.Authorizer = auth.OwnerAuthorizer{}
+	case AuthorizationAny:
+	default:
+		klog.V(1).Infof("Authorization %s is not supported\n", c.Auth.Authorization)
+		return nil, ErrInvalidInput
+	}
+
+	return options, nil
+}
+
+// Redacted is the effective configuration as YAML with the secrets masked
+func (c *Config) Redacted() string {
+	redacted := *c
+
+	for _, secret := range []*string{&redacted.Neo4j.Password, &redacted.Neo4j.Token, &redacted.Neo4j.KerberosTicket, &redacted.Redaction.HashKey, &redacted.Auth.JWTSecret} {
+		if len(*secret) > 0 {
+			*secret = redactedValue
+		}
+	}
+	if u, err := url.Parse(redacted.Bus.RabbitURI); err == nil {
+		redacted.Bus.RabbitURI = u.Redacted()
+	}
+
+	// only show the sections the component uses
+	switch c.component {
+	case ComponentProxyDataminer:
+		redacted.Dataminer.DisableDuplicate = false
+		redacted.Plugin = PluginConfig{}
+	case ComponentRestDataminer:
+		redacted.Dataminer = DataminerConfig{
+			RawPolicy:        redacted.Dataminer.RawPolicy,
+			DisableDuplicate: redacted.Dataminer.DisableDuplicate,
+		}
+		redacted.Plugin = PluginConfig{}
+		redacted.Auth = AuthConfig{}
+	case ComponentPlugin:
+		redacted.Bus.ContentType = ""
+		redacted.Dataminer = DataminerConfig{}
+		redacted.Redaction = RedactionConfig{}
+		redacted.Auth = AuthConfig{}
+	}
+
+	data, err := yaml.Marshal(&redacted)
+	if err != nil {
+		return fmt.Sprintf("yaml.Marshal failed. Err: %v\n", err)
+	}
+	return string(data)
+}
+
+func defaultMetricsAddress(component Component) string {
+	switch component {
+	case ComponentProxyDataminer:
+		return DefaultProxyMetricsAddress
+	case ComponentRestDataminer:
+		return DefaultRestMetricsAddress
+	}
+	return DefaultPluginMetricsAddress
+}
+
+func validPort(port int) bool {
+	return port >= 0 && port <= 65535
+}
+
+func envString(name string, value *string) {
+	if v := os.Getenv(name); v != "" {
+		klog.V(4).Infof("%s found\n", name)
+		*value = v
+	}
+}
+
+func envInt(name string, value *int) error {
+	if v := os.Getenv(name); v != "" {
+		klog.V(4).Infof("%s found\n", name)
+		number, err := strconv.Atoi(v)
+		if err != nil {
+			klog.V(1).Infof("%s is not a number. Err: %v\n", name, err)
+			return err
+		}
+		*value = number
+	}
+	return nil
+}
+
+func envBool(name string, value *bool) error {
+	if v := os.Getenv(name); v != "" {
+		klog.V(4).Infof("%s found\n", name)
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			klog.V(1).Infof("%s is not true or false. Err: %v\n", name, err)
+			return err
+		}
+		*value = b
+	}
+	return nil
+}
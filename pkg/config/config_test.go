@@ -0,0 +1,134 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	klog "k8s.io/klog/v2"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(file, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile failed. Err: %v", err)
+	}
+	return file
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "bus:\n  rabbitUri: amqp://file\ndataminer:\n  startPort: 7000\n")
+	jsonFile := writeFile(t, "config.json", `{"bus":{"rabbitUri":"amqp://json"}}`)
+
+	tests := []struct {
+		name      string
+		component Component
+		env       map[string]string
+		args      []string
+		wantURI   string
+		wantPort  int
+		wantErr   error
+	}{
+		{
+			name:      "defaults",
+			component: ComponentProxyDataminer,
+			wantURI:   DefaultRabbitURI,
+		},
+		{
+			name:      "file over defaults",
+			component: ComponentProxyDataminer,
+			args:      []string{"-config", yamlFile},
+			wantURI:   "amqp://file",
+			wantPort:  7000,
+		},
+		{
+			name:      "file from ERI_CONFIG",
+			component: ComponentProxyDataminer,
+			env:       map[string]string{"ERI_CONFIG": jsonFile},
+			wantURI:   "amqp://json",
+		},
+		{
+			name:      "environment over file",
+			component: ComponentProxyDataminer,
+			env:       map[string]string{"ERI_RABBIT_URI": "amqp://env", "ERI_START_PORT": "8000"},
+			args:      []string{"-config", yamlFile},
+			wantURI:   "amqp://env",
+			wantPort:  8000,
+		},
+		{
+			name:      "flags over environment",
+			component: ComponentProxyDataminer,
+			env:       map[string]string{"ERI_RABBIT_URI": "amqp://env", "ERI_START_PORT": "8000"},
+			args:      []string{"-config", yamlFile, "-rabbit", "amqp://flag", "-start-port", "9000"},
+			wantURI:   "amqp://flag",
+			wantPort:  9000,
+		},
+		{
+			name:      "klog flags next to the config flags",
+			component: ComponentProxyDataminer,
+			args:      []string{"-v", "3", "-config", yamlFile, "-start-port", "9000"},
+			wantURI:   "amqp://file",
+			wantPort:  9000,
+		},
+		{
+			name:      "flag of another component",
+			component: ComponentPlugin,
+			args:      []string{"-start-port", "9000"},
+			wantErr:   errors.New("flag provided but not defined: -start-port"),
+		},
+		{
+			name:      "usage",
+			component: ComponentPlugin,
+			args:      []string{"-h"},
+			wantErr:   flag.ErrHelp,
+		},
+		{
+			name:      "missing file",
+			component: ComponentPlugin,
+			args:      []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")},
+			wantErr:   os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NEO4J_CONNECTION", "neo4j://localhost:7687")
+			t.Setenv("NEO4J_USERNAME", "neo4j")
+			t.Setenv("NEO4J_PASSWORD", "password")
+			for _, name := range []string{"ERI_CONFIG", "ERI_RABBIT_URI", "ERI_START_PORT"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			cfg, err := Load(tt.component, tt.args)
+			if tt.wantErr != nil {
+				if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load failed. Err: %v", err)
+			}
+
+			if cfg.Bus.RabbitURI != tt.wantURI {
+				t.Errorf("RabbitURI got %s, want %s", cfg.Bus.RabbitURI, tt.wantURI)
+			}
+			if cfg.Dataminer.StartPort != tt.wantPort {
+				t.Errorf("StartPort got %d, want %d", cfg.Dataminer.StartPort, tt.wantPort)
+			}
+		})
+	}
+
+	// -v reached klog
+	if !klog.V(3).Enabled() {
+		t.Errorf("-v 3 was not applied to klog")
+	}
+}
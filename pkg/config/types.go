@@ -0,0 +1,123 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package config
+
+import (
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
+)
+
+// Component selects the sections and flags a command uses
+type Component string
+
+// TLSConfig is the certificate and client certificate policy for the HTTPS listeners
+type TLSConfig struct {
+	CrtFile        string   `json:"crtFile,omitempty" yaml:"crtFile,omitempty"`
+	KeyFile        string   `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	ClientCAFile   string   `json:"clientCaFile,omitempty" yaml:"clientCaFile,omitempty"`
+	ClientAuth     string   `json:"clientAuth,omitempty" yaml:"clientAuth,omitempty"`
+	MinVersion     string   `json:"minVersion,omitempty" yaml:"minVersion,omitempty"`
+	CipherSuites   []string `json:"cipherSuites,omitempty" yaml:"cipherSuites,omitempty"`
+	ReloadInterval string   `json:"reloadInterval,omitempty" yaml:"reloadInterval,omitempty"`
+}
+
+// BusConfig is the message bus, RabbitURI picks RabbitMQ or NATS by its scheme
+type BusConfig struct {
+	RabbitURI   string `json:"rabbitUri,omitempty" yaml:"rabbitUri,omitempty"`
+	ContentType string `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+}
+
+// Neo4jConfig mirrors dbconfig.Config, durations are strings like 30s
+type Neo4jConfig struct {
+	Connection            string            `json:"connection,omitempty" yaml:"connection,omitempty"`
+	Database              string            `json:"database,omitempty" yaml:"database,omitempty"`
+	TenantDatabases       map[string]string `json:"tenantDatabases,omitempty" yaml:"tenantDatabases,omitempty"`
+	AuthMode              string            `json:"authMode,omitempty" yaml:"authMode,omitempty"`
+	Username              string            `json:"username,omitempty" yaml:"username,omitempty"`
+	Password              string            `json:"password,omitempty" yaml:"password,omitempty"`
+	Realm                 string            `json:"realm,omitempty" yaml:"realm,omitempty"`
+	Token                 string            `json:"token,omitempty" yaml:"token,omitempty"`
+	KerberosTicket        string            `json:"kerberosTicket,omitempty" yaml:"kerberosTicket,omitempty"`
+	CACert                string            `json:"caCert,omitempty" yaml:"caCert,omitempty"`
+	MaxConnections        int               `json:"maxConnections,omitempty" yaml:"maxConnections,omitempty"`
+	AcquisitionTimeout    string            `json:"acquisitionTimeout,omitempty" yaml:"acquisitionTimeout,omitempty"`
+	MaxConnectionLifetime string            `json:"maxConnectionLifetime,omitempty" yaml:"maxConnectionLifetime,omitempty"`
+}
+
+// DataminerConfig is used by the Dataminer Services, zero ports use the server defaults
+type DataminerConfig struct {
+	StartPort        int    `json:"startPort,omitempty" yaml:"startPort,omitempty"`
+	EndPort          int    `json:"endPort,omitempty" yaml:"endPort,omitempty"`
+	Transcription    bool   `json:"transcription,omitempty" yaml:"transcription,omitempty"`
+	Messaging        bool   `json:"messaging,omitempty" yaml:"messaging,omitempty"`
+	RawPolicy        string `json:"rawPolicy,omitempty" yaml:"rawPolicy,omitempty"`
+	DisableDuplicate bool   `json:"disableDuplicate,omitempty" yaml:"disableDuplicate,omitempty"`
+}
+
+// PluginConfig is used by the plugin servers, a zero port uses the server default and no tenants every tenant
+type PluginConfig struct {
+	BindAddress string   `json:"bindAddress,omitempty" yaml:"bindAddress,omitempty"`
+	BindPort    int      `json:"bindPort,omitempty" yaml:"bindPort,omitempty"`
+	Tenants     []string `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+}
+
+// MetricsConfig is the Prometheus endpoint, an empty address disables it
+type MetricsConfig struct {
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// TracingConfig is the OpenTelemetry exporter, an empty exporter disables tracing
+type TracingConfig struct {
+	Exporter string `json:"exporter,omitempty" yaml:"exporter,omitempty"`
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Insecure bool   `json:"insecure,omitempty" yaml:"insecure,omitempty"`
+}
+
+// RedactionConfig masks PII in the Dataminer Services, the policies are redaction.Action values
+type RedactionConfig struct {
+	Enabled     bool             `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Content     string           `json:"content,omitempty" yaml:"content,omitempty"`
+	User        string           `json:"user,omitempty" yaml:"user,omitempty"`
+	Name        string           `json:"name,omitempty" yaml:"name,omitempty"`
+	Entity      string           `json:"entity,omitempty" yaml:"entity,omitempty"`
+	EntityTypes []string         `json:"entityTypes,omitempty" yaml:"entityTypes,omitempty"`
+	Rules       []redaction.Rule `json:"rules,omitempty" yaml:"rules,omitempty"`
+	HashKey     string           `json:"hashKey,omitempty" yaml:"hashKey,omitempty"`
+}
+
+// EncryptionConfig encrypts the content saved in Neo4j, an empty key file disables it
+type EncryptionConfig struct {
+	KeyFile    string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	DataKeyTTL string `json:"dataKeyTtl,omitempty" yaml:"dataKeyTtl,omitempty"`
+}
+
+// AuthConfig guards the Proxy/Dataminer entry points and the outbox endpoint of both Dataminers, it is disabled until Symbl, a JWT key or client certificates are set
+type AuthConfig struct {
+	Symbl              bool   `json:"symbl,omitempty" yaml:"symbl,omitempty"`
+	SymblCacheTTL      string `json:"symblCacheTtl,omitempty" yaml:"symblCacheTtl,omitempty"`
+	JWTSecret          string `json:"jwtSecret,omitempty" yaml:"jwtSecret,omitempty"`
+	JWTPublicKeyFile   string `json:"jwtPublicKeyFile,omitempty" yaml:"jwtPublicKeyFile,omitempty"`
+	JWTIssuer          string `json:"jwtIssuer,omitempty" yaml:"jwtIssuer,omitempty"`
+	JWTAudience        string `json:"jwtAudience,omitempty" yaml:"jwtAudience,omitempty"`
+	ClientCertificates bool   `json:"clientCertificates,omitempty" yaml:"clientCertificates,omitempty"`
+	Authorization      string `json:"authorization,omitempty" yaml:"authorization,omitempty"`
+	TenantClaim        string `json:"tenantClaim,omitempty" yaml:"tenantClaim,omitempty"`
+	AdminScope         string `json:"adminScope,omitempty" yaml:"adminScope,omitempty"`
+}
+
+// Config is the configuration shared by all commands
+type Config struct {
+	TLS        TLSConfig        `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Bus        BusConfig        `json:"bus,omitempty" yaml:"bus,omitempty"`
+	Neo4j      Neo4jConfig      `json:"neo4j,omitempty" yaml:"neo4j,omitempty"`
+	Dataminer  DataminerConfig  `json:"dataminer,omitempty" yaml:"dataminer,omitempty"`
+	Plugin     PluginConfig     `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Metrics    MetricsConfig    `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+	Tracing    TracingConfig    `json:"tracing,omitempty" yaml:"tracing,omitempty"`
+	Redaction  RedactionConfig  `json:"redaction,omitempty" yaml:"redaction,omitempty"`
+	Encryption EncryptionConfig `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	Auth       AuthConfig       `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	component Component
+}
@@ -0,0 +1,31 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package daemon
+
+import (
+	"time"
+)
+
+// exit codes returned by Run
+const (
+	// ExitOK the service started and stopped cleanly
+	ExitOK int = 0
+
+	// ExitStartFailed the service failed to start
+	ExitStartFailed int = 1
+
+	// ExitStopFailed the service returned an error while stopping
+	ExitStopFailed int = 2
+
+	// ExitStopTimeout the service didn't stop within the StopTimeout
+	ExitStopTimeout int = 3
+
+	// ExitAborted a second signal arrived while the service was stopping
+	ExitAborted int = 4
+)
+
+const (
+	// DefaultStopTimeout is how long Stop gets before Run gives up on it
+	DefaultStopTimeout time.Duration = 30 * time.Second
+)
@@ -0,0 +1,87 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	Run starts the service, waits for SIGINT or SIGTERM and then stops the service. Stop gets
+	StopTimeout to finish, and a second signal gives up on it right away. The result is one
+	of the Exit codes, which commands pass to os.Exit so systemd or Kubernetes can tell a
+	clean shutdown from a failed one.
+*/
+func Run(service Service, options RunOptions) int {
+	klog.V(6).Infof("daemon.Run ENTER\n")
+
+	if len(options.Name) == 0 {
+		options.Name = filepath.Base(os.Args[0])
+	}
+	if options.StopTimeout == 0 {
+		options.StopTimeout = DefaultStopTimeout
+	}
+	if len(options.Signals) == 0 {
+		options.Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+
+	// registered before Start so a signal during startup isn't lost
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, options.Signals...)
+	defer signal.Stop(sig)
+
+	fmt.Printf("Starting %s...\n", options.Name)
+	err := service.Start()
+	if err != nil {
+		fmt.Printf("%s failed to start. Err: %v\n", options.Name, err)
+		klog.V(6).Infof("daemon.Run LEAVE\n")
+		return ExitStartFailed
+	}
+	fmt.Printf("%s started, send SIGINT or SIGTERM to stop\n\n", options.Name)
+
+	received := <-sig
+	fmt.Printf("Received %v, stopping %s...\n", received, options.Name)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- service.Stop()
+	}()
+
+	timer := time.NewTimer(options.StopTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			fmt.Printf("%s failed to stop. Err: %v\n", options.Name, err)
+			klog.V(6).Infof("daemon.Run LEAVE\n")
+			return ExitStopFailed
+		}
+	case <-timer.C:
+		fmt.Printf("%s didn't stop within %v\n", options.Name, options.StopTimeout)
+		klog.V(6).Infof("daemon.Run LEAVE\n")
+		return ExitStopTimeout
+	case received = <-sig:
+		fmt.Printf("Received %v again, giving up on stopping %s\n", received, options.Name)
+		klog.V(6).Infof("daemon.Run LEAVE\n")
+		return ExitAborted
+	}
+
+	fmt.Printf("%s stopped\n\n", options.Name)
+	klog.V(6).Infof("daemon.Run LEAVE\n")
+
+	return ExitOK
+}
+
+// Main runs the service and exits the process with the result of Run
+func Main(service Service, options RunOptions) {
+	os.Exit(Run(service, options))
+}
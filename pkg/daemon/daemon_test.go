@@ -0,0 +1,68 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// service signals the test process once it has started
+type service struct {
+	startErr   error
+	stopErr    error
+	stopSignal bool // a second signal while stopping
+	stopBlock  bool // Stop doesn't return until the test ends
+	release    chan struct{}
+}
+
+func (s *service) Start() error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	return syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+}
+
+func (s *service) Stop() error {
+	if s.stopSignal {
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	}
+	if s.stopBlock {
+		<-s.release
+	}
+	return s.stopErr
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *service
+		want    int
+	}{
+		{name: "clean shutdown", service: &service{}, want: ExitOK},
+		{name: "start failed", service: &service{startErr: errors.New("port in use")}, want: ExitStartFailed},
+		{name: "stop failed", service: &service{stopErr: errors.New("flush failed")}, want: ExitStopFailed},
+		{name: "stop timeout", service: &service{stopBlock: true}, want: ExitStopTimeout},
+		{name: "second signal", service: &service{stopSignal: true, stopBlock: true}, want: ExitAborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.service.release = make(chan struct{})
+			defer close(tt.service.release)
+
+			got := Run(tt.service, RunOptions{
+				Name:        tt.name,
+				StopTimeout: 500 * time.Millisecond,
+				Signals:     []os.Signal{syscall.SIGUSR1},
+			})
+			if got != tt.want {
+				t.Errorf("Run got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
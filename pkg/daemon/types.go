@@ -0,0 +1,22 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package daemon
+
+import (
+	"os"
+	"time"
+)
+
+// Service is started and stopped by Run, Start must not block
+type Service interface {
+	Start() error
+	Stop() error
+}
+
+// RunOptions for Run
+type RunOptions struct {
+	Name        string        // used in the console output, defaults to the executable name
+	StopTimeout time.Duration // DefaultStopTimeout when 0
+	Signals     []os.Signal   // SIGINT and SIGTERM when empty
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package dbconfig
+
+import (
+	"crypto/x509"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	FromEnv reads the neo4j configuration from the environment. NEO4J_CONNECTION is always
+	required, the remaining variables depend on NEO4J_AUTH_MODE. NEO4J_TENANT_DATABASES
+	is a comma separated list of tenant=database pairs.
+*/
+func FromEnv() (*Config, error) {
+	config := &Config{
+		ConnectionStr:  os.Getenv("NEO4J_CONNECTION"),
+		DatabaseName:   os.Getenv("NEO4J_DATABASE"),
+		AuthMode:       AuthMode(os.Getenv("NEO4J_AUTH_MODE")),
+		Username:       os.Getenv("NEO4J_USERNAME"),
+		Password:       os.Getenv("NEO4J_PASSWORD"),
+		Realm:          os.Getenv("NEO4J_REALM"),
+		Token:          os.Getenv("NEO4J_TOKEN"),
+		KerberosTicket: os.Getenv("NEO4J_KERBEROS_TICKET"),
+		CACertFile:     os.Getenv("NEO4J_CA_CERT"),
+	}
+
+	if v := os.Getenv("NEO4J_TENANT_DATABASES"); v != "" {
+		klog.V(4).Info("NEO4J_TENANT_DATABASES found")
+		tenantDatabases, err := ParseTenantDatabases(v)
+		if err != nil {
+			return nil, err
+		}
+		config.TenantDatabases = tenantDatabases
+	}
+	if v := os.Getenv("NEO4J_MAX_CONNECTIONS"); v != "" {
+		klog.V(4).Info("NEO4J_MAX_CONNECTIONS found")
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			klog.Errorf("NEO4J_MAX_CONNECTIONS is invalid. Err: %v\n", err)
+			return nil, err
+		}
+		config.MaxConnectionPoolSize = size
+	}
+	if v := os.Getenv("NEO4J_ACQUISITION_TIMEOUT"); v != "" {
+		klog.V(4).Info("NEO4J_ACQUISITION_TIMEOUT found")
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			klog.Errorf("NEO4J_ACQUISITION_TIMEOUT is invalid. Err: %v\n", err)
+			return nil, err
+		}
+		config.ConnectionAcquisitionTimeout = timeout
+	}
+	if v := os.Getenv("NEO4J_MAX_CONNECTION_LIFETIME"); v != "" {
+		klog.V(4).Info("NEO4J_MAX_CONNECTION_LIFETIME found")
+		lifetime, err := time.ParseDuration(v)
+		if err != nil {
+			klog.Errorf("NEO4J_MAX_CONNECTION_LIFETIME is invalid. Err: %v\n", err)
+			return nil, err
+		}
+		config.MaxConnectionLifetime = lifetime
+	}
+
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ParseTenantDatabases parses a comma separated list of tenant=database pairs
+func ParseTenantDatabases(value string) (map[string]string, error) {
+	tenantDatabases := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		tenant, database, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || len(tenant) == 0 || len(database) == 0 {
+			klog.Errorf("Tenant database %s is not tenant=database\n", pair)
+			return nil, ErrInvalidInput
+		}
+		tenantDatabases[tenant] = database
+	}
+	return tenantDatabases, nil
+}
+
+// Validate checks that the inputs required by the auth mode are present
+func (c *Config) Validate() error {
+	if len(c.ConnectionStr) == 0 {
+		klog.Errorf("NEO4J_CONNECTION not found\n")
+		return ErrInvalidInput
+	}
+
+	switch c.AuthMode {
+	case "", AuthModeBasic:
+		if len(c.Username) == 0 || len(c.Password) == 0 {
+			klog.Errorf("NEO4J_USERNAME or NEO4J_PASSWORD not found\n")
+			return ErrInvalidInput
+		}
+	case AuthModeBearer:
+		if len(c.Token) == 0 {
+			klog.Errorf("NEO4J_TOKEN not found\n")
+			return ErrInvalidInput
+		}
+	case AuthModeKerberos:
+		if len(c.KerberosTicket) == 0 {
+			klog.Errorf("NEO4J_KERBEROS_TICKET not found\n")
+			return ErrInvalidInput
+		}
+	case AuthModeNone:
+	default:
+		klog.Errorf("AuthMode %s is not supported\n", c.AuthMode)
+		return ErrUnsupportedAuthMode
+	}
+
+	if c.MaxConnectionPoolSize < 0 || c.ConnectionAcquisitionTimeout < 0 || c.MaxConnectionLifetime < 0 {
+		klog.Errorf("neo4j pool settings can't be negative\n")
+		return ErrInvalidInput
+	}
+
+	return nil
+}
+
+// Database is the database for the tenant, or DatabaseName when the tenant doesn't have one
+func (c *Config) Database(tenant string) string {
+	if c == nil {
+		return DefaultDatabaseName
+	}
+	if database, ok := c.TenantDatabases[tenant]; ok && len(tenant) > 0 {
+		return database
+	}
+	if len(c.DatabaseName) > 0 {
+		return c.DatabaseName
+	}
+	return DefaultDatabaseName
+}
+
+// Databases lists every database in use, the default database first and each tenant database once
+func (c *Config) Databases() []string {
+	databases := []string{c.Database("")}
+	if c == nil {
+		return databases
+	}
+
+	tenantDatabases := make([]string, 0, len(c.TenantDatabases))
+	for _, database := range c.TenantDatabases {
+		tenantDatabases = append(tenantDatabases, database)
+	}
+	sort.Strings(tenantDatabases)
+
+	for _, database := range tenantDatabases {
+		if database != databases[len(databases)-1] && database != databases[0] {
+			databases = append(databases, database)
+		}
+	}
+	return databases
+}
+
+/*
+	Tenant is the tenant the database belongs to: shared.DefaultTenant for the default database
+	and the tenant of a tenant database. It is empty for a database several tenants share, its
+	data can't be told apart by the database alone.
+*/
+func (c *Config) Tenant(database string) string {
+	if database == c.Database("") {
+		return shared.DefaultTenant
+	}
+	if c == nil {
+		return ""
+	}
+
+	var found string
+	for tenant, tenantDatabase := range c.TenantDatabases {
+		if tenantDatabase != database {
+			continue
+		}
+		if len(found) > 0 {
+			return ""
+		}
+		found = tenant
+	}
+	return found
+}
+
+// SessionConfig for a session against the tenant's database
+func (c *Config) SessionConfig(tenant string) neo4j.SessionConfig {
+	return neo4j.SessionConfig{DatabaseName: c.Database(tenant)}
+}
+
+func (c *Config) authToken() neo4j.AuthToken {
+	switch c.AuthMode {
+	case AuthModeBearer:
+		return neo4j.BearerAuth(c.Token)
+	case AuthModeKerberos:
+		return neo4j.KerberosAuth(c.KerberosTicket)
+	case AuthModeNone:
+		return neo4j.NoAuth()
+	}
+	return neo4j.BasicAuth(c.Username, c.Password, c.Realm)
+}
+
+// NewDriver creates a driver with the auth mode, CA certificates and pool settings
+func (c *Config) NewDriver() (neo4j.DriverWithContext, error) {
+	klog.V(6).Infof("Config.NewDriver ENTER\n")
+
+	err := c.Validate()
+	if err != nil {
+		klog.V(1).Infof("Validate failed. Err: %v\n", err)
+		klog.V(6).Infof("Config.NewDriver LEAVE\n")
+		return nil, err
+	}
+
+	var rootCAs *x509.CertPool
+	if len(c.CACertFile) > 0 {
+		pem, err := os.ReadFile(c.CACertFile)
+		if err != nil {
+			klog.V(1).Infof("ReadFile(%s) failed. Err: %v\n", c.CACertFile, err)
+			klog.V(6).Infof("Config.NewDriver LEAVE\n")
+			return nil, err
+		}
+
+		rootCAs = x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			klog.V(1).Infof("AppendCertsFromPEM(%s) failed\n", c.CACertFile)
+			klog.V(6).Infof("Config.NewDriver LEAVE\n")
+			return nil, ErrInvalidCACert
+		}
+	}
+
+	driver, err := neo4j.NewDriverWithContext(c.ConnectionStr, c.authToken(), func(config *neo4j.Config) {
+		if rootCAs != nil {
+			config.RootCAs = rootCAs
+		}
+		if c.MaxConnectionPoolSize > 0 {
+			config.MaxConnectionPoolSize = c.MaxConnectionPoolSize
+		}
+		if c.ConnectionAcquisitionTimeout > 0 {
+			config.ConnectionAcquisitionTimeout = c.ConnectionAcquisitionTimeout
+		}
+		if c.MaxConnectionLifetime > 0 {
+			config.MaxConnectionLifetime = c.MaxConnectionLifetime
+		}
+	})
+	if err != nil {
+		klog.V(1).Infof("NewDriverWithContext failed. Err: %v\n", err)
+		klog.V(6).Infof("Config.NewDriver LEAVE\n")
+		return nil, err
+	}
+
+	klog.V(4).Infof("Config.NewDriver Succeeded\n")
+	klog.V(6).Infof("Config.NewDriver LEAVE\n")
+
+	return driver, nil
+}
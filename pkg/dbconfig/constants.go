@@ -0,0 +1,39 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package dbconfig
+
+import (
+	"errors"
+)
+
+const (
+	// AuthModeBasic logs in with NEO4J_USERNAME, NEO4J_PASSWORD and optionally NEO4J_REALM
+	AuthModeBasic AuthMode = "basic"
+
+	// AuthModeBearer logs in with the SSO token in NEO4J_TOKEN
+	AuthModeBearer AuthMode = "bearer"
+
+	// AuthModeKerberos logs in with the base64 encoded ticket in NEO4J_KERBEROS_TICKET
+	AuthModeKerberos AuthMode = "kerberos"
+
+	// AuthModeNone for servers with authentication disabled
+	AuthModeNone AuthMode = "none"
+
+	// DefaultAuthMode is used when no mode is given
+	DefaultAuthMode AuthMode = AuthModeBasic
+
+	// DefaultDatabaseName is the database sessions use when none is configured
+	DefaultDatabaseName string = "neo4j"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnsupportedAuthMode the auth mode is not known
+	ErrUnsupportedAuthMode = errors.New("neo4j auth mode is not supported")
+
+	// ErrInvalidCACert the CA certificate file has no PEM certificates in it
+	ErrInvalidCACert = errors.New("no certificates found in CA certificate file")
+)
@@ -0,0 +1,37 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package dbconfig
+
+import (
+	"time"
+)
+
+// AuthMode selects how to login to neo4j
+type AuthMode string
+
+// Config is everything needed to connect to neo4j, shared by all commands
+type Config struct {
+	// connection
+	ConnectionStr string
+
+	// databases, TenantDatabases maps a tenant to its own database
+	DatabaseName    string
+	TenantDatabases map[string]string
+
+	// auth
+	AuthMode       AuthMode
+	Username       string
+	Password       string
+	Realm          string
+	Token          string
+	KerberosTicket string
+
+	// PEM file with the CA certificates trusted for neo4j+s:// and bolt+s://
+	CACertFile string
+
+	// pool, zero uses the driver defaults
+	MaxConnectionPoolSize        int
+	ConnectionAcquisitionTimeout time.Duration
+	MaxConnectionLifetime        time.Duration
+}
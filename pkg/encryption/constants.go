@@ -0,0 +1,43 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// Prefix starts every encrypted value, values without it are read as plaintext
+	Prefix string = "enc:v1:"
+
+	// DefaultDataKeyTTL is how long a data key encrypts new values before a new one is made
+	DefaultDataKeyTTL time.Duration = time.Hour
+
+	// DefaultBatchSize number of values the rotator re-encrypts per transaction
+	DefaultBatchSize int = 500
+
+	// KeySize is the size in bytes of the data keys and the keys in a key file, AES-256
+	KeySize int = 32
+
+	// unwrapped data keys kept by an Encryptor before the cache starts over
+	maxOpenedKeys int = 1024
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrKeyNotFound the tenant has no key, or no key with the id
+	ErrKeyNotFound = errors.New("encryption key not found for the tenant")
+
+	// ErrInvalidKey the key isn't KeySize bytes encoded with base64 or its id is already used
+	ErrInvalidKey = errors.New("encryption key must be 32 bytes of base64 with a unique id")
+
+	// ErrMalformed the value starts with Prefix but can't be parsed
+	ErrMalformed = errors.New("encrypted value is malformed")
+
+	// ErrNotImplemented the KMS key provider was created without a KMSClient
+	ErrNotImplemented = errors.New("kms key provider needs a KMSClient")
+)
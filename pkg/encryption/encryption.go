@@ -0,0 +1,402 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	Values are encrypted with AES-256-GCM using a data key that is wrapped by the current key
+	of the tenant in the KeyProvider. Each value carries the id of the key and its wrapped data
+	key, so it can be read after the tenant moves on to a new key:
+
+		enc:v1:<key id>:<wrapped data key>:<nonce and ciphertext>
+
+	A data key is used for DataKeyTTL before a new one is made, which keeps the calls to the
+	provider down to one per TTL. The tenant is authenticated along with every value, a value
+	copied to another tenant can't be decrypted there.
+
+	All methods can be called on a nil Encryptor and leave the values as they are.
+*/
+func New(options Options) (*Encryptor, error) {
+	if options.Provider == nil {
+		klog.V(1).Infof("Provider is nil\n")
+		return nil, ErrInvalidInput
+	}
+	if options.DataKeyTTL <= 0 {
+		options.DataKeyTTL = DefaultDataKeyTTL
+	}
+
+	e := &Encryptor{
+		options:   options,
+		provider:  options.Provider,
+		opened:    make(map[string]*dataKey),
+		rewrapped: make(map[string]string),
+	}
+	return e, nil
+}
+
+// Encrypted is true when the value was returned by Encrypt
+func Encrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt returns the encrypted value for plaintext
+func (e *Encryptor) Encrypt(ctx context.Context, plaintext string) (string, error) {
+	if e == nil {
+		return plaintext, nil
+	}
+
+	key, err := e.dataKey(ctx)
+	if err != nil {
+		klog.V(1).Infof("dataKey failed. Err: %v\n", err)
+		return "", err
+	}
+
+	aead, err := newAEAD(key.key)
+	if err != nil {
+		klog.V(1).Infof("newAEAD failed. Err: %v\n", err)
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		klog.V(1).Infof("rand.Read failed. Err: %v\n", err)
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(e.options.Tenant))
+
+	return key.header + encode(sealed), nil
+}
+
+// Decrypt returns the plaintext of a value returned by Encrypt, other values are returned as they are
+func (e *Encryptor) Decrypt(ctx context.Context, value string) (string, error) {
+	if e == nil || !Encrypted(value) {
+		return value, nil
+	}
+
+	header, keyId, wrapped, body, err := parse(value)
+	if err != nil {
+		klog.V(1).Infof("parse failed. Err: %v\n", err)
+		return "", err
+	}
+
+	key, err := e.open(ctx, header, keyId, wrapped)
+	if err != nil {
+		klog.V(1).Infof("open failed. Err: %v\n", err)
+		return "", err
+	}
+
+	aead, err := newAEAD(key.key)
+	if err != nil {
+		klog.V(1).Infof("newAEAD failed. Err: %v\n", err)
+		return "", err
+	}
+	if len(body) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+
+	plaintext, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], []byte(e.options.Tenant))
+	if err != nil {
+		klog.V(1).Infof("aead.Open failed. Err: %v\n", err)
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+/*
+	DecryptAll decrypts the values in the results of a read transaction. It looks into strings,
+	slices, maps, nodes, relationships, paths and records, and returns a copy of v with every
+	encrypted string replaced by its plaintext.
+*/
+func (e *Encryptor) DecryptAll(ctx context.Context, v any) (any, error) {
+	if e == nil {
+		return v, nil
+	}
+
+	switch value := v.(type) {
+	case string:
+		return e.Decrypt(ctx, value)
+	case []string:
+		values := make([]string, len(value))
+		for i, item := range value {
+			plaintext, err := e.Decrypt(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = plaintext
+		}
+		return values, nil
+	case []any:
+		values := make([]any, len(value))
+		for i, item := range value {
+			plaintext, err := e.DecryptAll(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = plaintext
+		}
+		return values, nil
+	case map[string]any:
+		values := make(map[string]any, len(value))
+		for key, item := range value {
+			plaintext, err := e.DecryptAll(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = plaintext
+		}
+		return values, nil
+	case neo4j.Node:
+		props, err := e.DecryptAll(ctx, value.Props)
+		if err != nil {
+			return nil, err
+		}
+		value.Props = props.(map[string]any)
+		return value, nil
+	case neo4j.Relationship:
+		props, err := e.DecryptAll(ctx, value.Props)
+		if err != nil {
+			return nil, err
+		}
+		value.Props = props.(map[string]any)
+		return value, nil
+	case neo4j.Path:
+		nodes := make([]neo4j.Node, len(value.Nodes))
+		for i, node := range value.Nodes {
+			plaintext, err := e.DecryptAll(ctx, node)
+			if err != nil {
+				return nil, err
+			}
+			nodes[i] = plaintext.(neo4j.Node)
+		}
+		relationships := make([]neo4j.Relationship, len(value.Relationships))
+		for i, relationship := range value.Relationships {
+			plaintext, err := e.DecryptAll(ctx, relationship)
+			if err != nil {
+				return nil, err
+			}
+			relationships[i] = plaintext.(neo4j.Relationship)
+		}
+		return neo4j.Path{Nodes: nodes, Relationships: relationships}, nil
+	case *neo4j.Record:
+		if value == nil {
+			return value, nil
+		}
+		values, err := e.DecryptAll(ctx, value.Values)
+		if err != nil {
+			return nil, err
+		}
+		return &neo4j.Record{Keys: value.Keys, Values: values.([]any)}, nil
+	case []*neo4j.Record:
+		records := make([]*neo4j.Record, len(value))
+		for i, record := range value {
+			plaintext, err := e.DecryptAll(ctx, record)
+			if err != nil {
+				return nil, err
+			}
+			records[i] = plaintext.(*neo4j.Record)
+		}
+		return records, nil
+	}
+
+	return v, nil
+}
+
+// Rewrap moves a value onto the current key of the tenant, plaintext values are encrypted
+func (e *Encryptor) Rewrap(ctx context.Context, value string) (string, error) {
+	if e == nil {
+		return value, nil
+	}
+	// the data key used by Encrypt can still be wrapped by an older key
+	if !Encrypted(value) {
+		encrypted, err := e.Encrypt(ctx, value)
+		if err != nil {
+			return "", err
+		}
+		value = encrypted
+	}
+
+	header, keyId, wrapped, _, err := parse(value)
+	if err != nil {
+		klog.V(1).Infof("parse failed. Err: %v\n", err)
+		return "", err
+	}
+
+	currentKeyId, err := e.provider.CurrentKey(ctx, e.options.Tenant)
+	if err != nil {
+		klog.V(1).Infof("CurrentKey failed. Err: %v\n", err)
+		return "", err
+	}
+	if keyId == currentKeyId {
+		return value, nil
+	}
+
+	// the data key stays the same, only the way it is wrapped changes
+	e.mu.Lock()
+	newHeader, ok := e.rewrapped[header]
+	e.mu.Unlock()
+
+	if !ok {
+		key, err := e.open(ctx, header, keyId, wrapped)
+		if err != nil {
+			klog.V(1).Infof("open failed. Err: %v\n", err)
+			return "", err
+		}
+
+		rewrapped, err := e.provider.WrapKey(ctx, e.options.Tenant, currentKeyId, key.key)
+		if err != nil {
+			klog.V(1).Infof("WrapKey failed. Err: %v\n", err)
+			return "", err
+		}
+		newHeader = formatHeader(currentKeyId, rewrapped)
+
+		e.mu.Lock()
+		e.rewrapped[header] = newHeader
+		e.mu.Unlock()
+	}
+
+	return newHeader + value[len(header):], nil
+}
+
+// currentPrefix starts the values encrypted with the current key of the tenant
+func (e *Encryptor) currentPrefix(ctx context.Context) (string, error) {
+	keyId, err := e.provider.CurrentKey(ctx, e.options.Tenant)
+	if err != nil {
+		return "", err
+	}
+	return Prefix + encode([]byte(keyId)) + ":", nil
+}
+
+// dataKey returns the data key for new values, a new one is made once it is older than the TTL
+func (e *Encryptor) dataKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.current != nil && time.Since(e.current.created) < e.options.DataKeyTTL {
+		return e.current, nil
+	}
+
+	keyId, err := e.provider.CurrentKey(ctx, e.options.Tenant)
+	if err != nil {
+		klog.V(1).Infof("CurrentKey failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	key := make([]byte, KeySize)
+	_, err = rand.Read(key)
+	if err != nil {
+		klog.V(1).Infof("rand.Read failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	wrapped, err := e.provider.WrapKey(ctx, e.options.Tenant, keyId, key)
+	if err != nil {
+		klog.V(1).Infof("WrapKey failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	e.current = &dataKey{
+		key:     key,
+		keyId:   keyId,
+		header:  formatHeader(keyId, wrapped),
+		created: time.Now(),
+	}
+	e.remember(e.current)
+	klog.V(4).Infof("New data key wrapped by key %s\n", keyId)
+
+	return e.current, nil
+}
+
+// open returns the unwrapped data key for the header
+func (e *Encryptor) open(ctx context.Context, header, keyId string, wrapped []byte) (*dataKey, error) {
+	e.mu.Lock()
+	key, ok := e.opened[header]
+	e.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	unwrapped, err := e.provider.UnwrapKey(ctx, e.options.Tenant, keyId, wrapped)
+	if err != nil {
+		klog.V(1).Infof("UnwrapKey failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	key = &dataKey{
+		key:     unwrapped,
+		keyId:   keyId,
+		header:  header,
+		created: time.Now(),
+	}
+
+	e.mu.Lock()
+	e.remember(key)
+	e.mu.Unlock()
+
+	return key, nil
+}
+
+// remember keeps the unwrapped data key, the caller holds the lock
+func (e *Encryptor) remember(key *dataKey) {
+	if len(e.opened) >= maxOpenedKeys {
+		e.opened = make(map[string]*dataKey)
+	}
+	e.opened[key.header] = key
+}
+
+func formatHeader(keyId string, wrapped []byte) string {
+	return Prefix + encode([]byte(keyId)) + ":" + encode(wrapped) + ":"
+}
+
+// parse splits an encrypted value into its header, key id, wrapped data key and body
+func parse(value string) (string, string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 {
+		return "", "", nil, nil, ErrMalformed
+	}
+
+	keyId, err := decode(parts[0])
+	if err != nil {
+		return "", "", nil, nil, ErrMalformed
+	}
+	wrapped, err := decode(parts[1])
+	if err != nil {
+		return "", "", nil, nil, ErrMalformed
+	}
+	body, err := decode(parts[2])
+	if err != nil {
+		return "", "", nil, nil, ErrMalformed
+	}
+
+	return value[:len(value)-len(parts[2])], string(keyId), wrapped, body, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,270 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// writeKeyFile writes a key file with a new key for each id, keyed by tenant/id
+func writeKeyFile(t *testing.T, path string, ids ...string) {
+	t.Helper()
+
+	var file KeyFile
+	for _, id := range ids {
+		tenant, keyId, found := strings.Cut(id, "/")
+		if !found {
+			tenant, keyId = "", id
+		}
+
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatalf("GenerateKey failed. Err: %v", err)
+		}
+		file.Keys = append(file.Keys, LocalKey{
+			Id:     keyId,
+			Tenant: tenant,
+			Key:    key,
+		})
+	}
+
+	saveKeyFile(t, path, &file)
+}
+
+func saveKeyFile(t *testing.T, path string, file *KeyFile) {
+	t.Helper()
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		t.Fatalf("yaml.Marshal failed. Err: %v", err)
+	}
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatalf("os.WriteFile failed. Err: %v", err)
+	}
+}
+
+// withoutOldKey copies the key file without its first key and returns the path of the copy
+func withoutOldKey(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := readKeyFile(path)
+	if err != nil {
+		t.Fatalf("readKeyFile failed. Err: %v", err)
+	}
+
+	rotated := filepath.Join(t.TempDir(), "keys.yaml")
+	saveKeyFile(t, rotated, &KeyFile{Keys: file.Keys[1:]})
+	return rotated
+}
+
+func newEncryptors(t *testing.T, path string) *Encryptors {
+	t.Helper()
+
+	provider, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider failed. Err: %v", err)
+	}
+	encryptors, err := NewEncryptors(Options{
+		Provider: provider,
+	})
+	if err != nil {
+		t.Fatalf("NewEncryptors failed. Err: %v", err)
+	}
+	return encryptors
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeyFile(t, path, "default-1", "acme/acme-1")
+	encryptors := newEncryptors(t, path)
+
+	tests := []struct {
+		name      string
+		tenant    string
+		plaintext string
+	}{
+		{name: "empty", tenant: "default", plaintext: ""},
+		{name: "text", tenant: "default", plaintext: "the quarterly numbers look good"},
+		{name: "unicode", tenant: "default", plaintext: "Grüße aus München 👋"},
+		{name: "separator in the text", tenant: "default", plaintext: "enc:v1:a:b:c"},
+		{name: "tenant", tenant: "acme", plaintext: "the quarterly numbers look good"},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := encryptors.Tenant(tt.tenant)
+
+			value, err := e.Encrypt(ctx, tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt failed. Err: %v", err)
+			}
+			if !Encrypted(value) || strings.Contains(value, tt.plaintext) && len(tt.plaintext) > 0 {
+				t.Fatalf("Encrypt returned %s", value)
+			}
+
+			got, err := e.Decrypt(ctx, value)
+			if err != nil {
+				t.Fatalf("Decrypt failed. Err: %v", err)
+			}
+			if got != tt.plaintext {
+				t.Errorf("Decrypt got %s, want %s", got, tt.plaintext)
+			}
+		})
+	}
+
+	// values saved before encryption are read as they are
+	got, err := encryptors.Tenant("default").Decrypt(ctx, "plaintext")
+	if err != nil || got != "plaintext" {
+		t.Errorf("Decrypt of plaintext got %s and %v", got, err)
+	}
+
+	// without encryption the values are left alone
+	var none *Encryptors
+	value, err := none.Tenant("acme").Encrypt(ctx, "plaintext")
+	if err != nil || value != "plaintext" {
+		t.Errorf("Encrypt without Encryptors got %s and %v", value, err)
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeyFile(t, path, "default-1", "acme/acme-1", "globex/globex-1")
+	encryptors := newEncryptors(t, path)
+
+	ctx := context.Background()
+	value, err := encryptors.Tenant("acme").Encrypt(ctx, "secret")
+	if err != nil {
+		t.Fatalf("Encrypt failed. Err: %v", err)
+	}
+	header, _, _, _, err := parse(value)
+	if err != nil {
+		t.Fatalf("parse failed. Err: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tenant string
+		value  string
+		err    error
+	}{
+		{name: "malformed", tenant: "acme", value: Prefix + "garbage", err: ErrMalformed},
+		{name: "body isn't base64", tenant: "acme", value: header + "!", err: ErrMalformed},
+		{name: "body too short", tenant: "acme", value: header + encode([]byte("short")), err: ErrMalformed},
+		{name: "another tenant", tenant: "globex", value: value, err: ErrKeyNotFound},
+		{name: "the default tenant", tenant: "default", value: value, err: ErrKeyNotFound},
+		{name: "tampered", tenant: "acme", value: value[:len(value)-2] + "AA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encryptors.Tenant(tt.tenant).Decrypt(ctx, tt.value)
+			if err == nil {
+				t.Fatalf("Decrypt got %s, want an error", got)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Decrypt got err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeyFile(t, path, "acme/acme-1")
+
+	provider, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider failed. Err: %v", err)
+	}
+	e, err := New(Options{
+		Provider: provider,
+		Tenant:   "acme",
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	ctx := context.Background()
+	old, err := e.Encrypt(ctx, "before the rotation")
+	if err != nil {
+		t.Fatalf("Encrypt failed. Err: %v", err)
+	}
+
+	err = provider.AddKey("acme", "acme-2")
+	if err != nil {
+		t.Fatalf("AddKey failed. Err: %v", err)
+	}
+	err = provider.AddKey("acme", "acme-2")
+	if err == nil {
+		t.Errorf("AddKey of an existing id succeeded")
+	}
+
+	keyId := func(value string) string {
+		t.Helper()
+		_, keyId, _, _, err := parse(value)
+		if err != nil {
+			t.Fatalf("parse failed. Err: %v", err)
+		}
+		return keyId
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "value of the old key", value: old, want: "before the rotation"},
+		{name: "plaintext", value: "saved before encryption", want: "saved before encryption"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewrapped, err := e.Rewrap(ctx, tt.value)
+			if err != nil {
+				t.Fatalf("Rewrap failed. Err: %v", err)
+			}
+			if got := keyId(rewrapped); got != "acme-2" {
+				t.Errorf("Rewrap got key %s, want acme-2", got)
+			}
+
+			// once on the current key there is nothing left to do
+			again, err := e.Rewrap(ctx, rewrapped)
+			if err != nil || again != rewrapped {
+				t.Errorf("Rewrap of a current value got %s and %v", again, err)
+			}
+
+			got, err := e.Decrypt(ctx, rewrapped)
+			if err != nil {
+				t.Fatalf("Decrypt failed. Err: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt got %s, want %s", got, tt.want)
+			}
+
+			// the old key can go once every value is rewrapped
+			got, err = newEncryptors(t, withoutOldKey(t, path)).Tenant("acme").Decrypt(ctx, rewrapped)
+			if err != nil {
+				t.Fatalf("Decrypt without the old key failed. Err: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt without the old key got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// values still on the old key can't be read without it
+	_, err = newEncryptors(t, withoutOldKey(t, path)).Tenant("acme").Decrypt(ctx, old)
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Decrypt without the old key got %v, want %v", err, ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	The key file lists the keys of each tenant, the default tenant has no tenant field. The
+	last key listed for a tenant is its current key, so rotating a key is adding a new one
+	at the end with AddKey. Older keys have to stay in the file until the Rotator has moved
+	every value onto the new key. The file is read again when it changes.
+
+		keys:
+		  - id: default-1
+		    key: <32 bytes encoded with base64>
+		  - id: acme-1
+		    tenant: acme
+		    key: <32 bytes encoded with base64>
+*/
+func NewLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	if len(path) == 0 {
+		klog.V(1).Infof("key file is empty\n")
+		return nil, ErrInvalidInput
+	}
+
+	p := &LocalKeyProvider{
+		path: path,
+	}
+
+	err := p.load()
+	if err != nil {
+		klog.V(1).Infof("load(%s) failed. Err: %v\n", path, err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GenerateKey returns a new random key encoded for a key file
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// CurrentKey is the id of the last key listed for the tenant
+func (p *LocalKeyProvider) CurrentKey(ctx context.Context, tenant string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.load()
+	if err != nil {
+		klog.V(1).Infof("load(%s) failed. Err: %v\n", p.path, err)
+		return "", err
+	}
+
+	keyId, ok := p.current[tenant]
+	if !ok {
+		klog.V(1).Infof("Tenant %s has no key in %s\n", tenant, p.path)
+		return "", ErrKeyNotFound
+	}
+	return keyId, nil
+}
+
+// WrapKey encrypts the data key with the key of the tenant
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, tenant, keyId string, dataKey []byte) ([]byte, error) {
+	key, err := p.key(tenant, keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		klog.V(1).Infof("newAEAD failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		klog.V(1).Infof("rand.Read failed. Err: %v\n", err)
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(tenant+"/"+keyId)), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, tenant, keyId string, wrapped []byte) ([]byte, error) {
+	key, err := p.key(tenant, keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		klog.V(1).Infof("newAEAD failed. Err: %v\n", err)
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(tenant+"/"+keyId))
+}
+
+// AddKey appends a new random key for the tenant to the key file, making it the current key
+func (p *LocalKeyProvider) AddKey(tenant, keyId string) error {
+	klog.V(6).Infof("LocalKeyProvider.AddKey ENTER\n")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(keyId) == 0 {
+		klog.V(1).Infof("keyId is empty\n")
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return ErrInvalidInput
+	}
+
+	file, err := readKeyFile(p.path)
+	if err != nil {
+		klog.V(1).Infof("readKeyFile(%s) failed. Err: %v\n", p.path, err)
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return err
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		klog.V(1).Infof("GenerateKey failed. Err: %v\n", err)
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return err
+	}
+	file.Keys = append(file.Keys, LocalKey{
+		Id:     keyId,
+		Tenant: tenant,
+		Key:    key,
+	})
+
+	_, _, err = parseKeyFile(file)
+	if err != nil {
+		klog.V(1).Infof("Key %s for tenant %s can't be added. Err: %v\n", keyId, tenant, err)
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return err
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		klog.V(1).Infof("yaml.Marshal failed. Err: %v\n", err)
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return err
+	}
+
+	// replace the file in one step so a running command never reads half of it
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".*")
+	if err != nil {
+		klog.V(1).Infof("os.CreateTemp failed. Err: %v\n", err)
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p.path)
+	}
+	if err != nil {
+		klog.V(1).Infof("Write %s failed. Err: %v\n", p.path, err)
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return err
+	}
+
+	// read it again even if the modification time looks the same
+	p.keys = nil
+	err = p.load()
+	if err != nil {
+		klog.V(1).Infof("load(%s) failed. Err: %v\n", p.path, err)
+		klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+		return err
+	}
+
+	klog.V(3).Infof("Key %s added for tenant %s\n", keyId, tenant)
+	klog.V(6).Infof("LocalKeyProvider.AddKey LEAVE\n")
+
+	return nil
+}
+
+// key looks up the key of the tenant, reading the file again when it changed
+func (p *LocalKeyProvider) key(tenant, keyId string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.load()
+	if err != nil {
+		klog.V(1).Infof("load(%s) failed. Err: %v\n", p.path, err)
+		return nil, err
+	}
+
+	key, ok := p.keys[tenant][keyId]
+	if !ok {
+		klog.V(1).Infof("Key %s for tenant %s not found in %s\n", keyId, tenant, p.path)
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// load reads the key file unless it is unchanged since the last time, the caller holds the lock
+func (p *LocalKeyProvider) load() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	if p.keys != nil && info.ModTime().Equal(p.modTime) {
+		return nil
+	}
+
+	file, err := readKeyFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	keys, current, err := parseKeyFile(file)
+	if err != nil {
+		return err
+	}
+
+	p.keys = keys
+	p.current = current
+	p.modTime = info.ModTime()
+	klog.V(4).Infof("Key file %s loaded\n", p.path)
+
+	return nil
+}
+
+func readKeyFile(path string) (*KeyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML
+	file := &KeyFile{}
+	err = yaml.Unmarshal(data, file)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// parseKeyFile decodes the keys by tenant and id and finds the current key of each tenant
+func parseKeyFile(file *KeyFile) (map[string]map[string][]byte, map[string]string, error) {
+	keys := make(map[string]map[string][]byte)
+	current := make(map[string]string)
+
+	for _, localKey := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(localKey.Key)
+		if err != nil || len(key) != KeySize || len(localKey.Id) == 0 {
+			klog.V(1).Infof("Key %s for tenant %s is invalid\n", localKey.Id, localKey.Tenant)
+			return nil, nil, ErrInvalidKey
+		}
+
+		if keys[localKey.Tenant] == nil {
+			keys[localKey.Tenant] = make(map[string][]byte)
+		}
+		if _, ok := keys[localKey.Tenant][localKey.Id]; ok {
+			klog.V(1).Infof("Key %s for tenant %s is listed twice\n", localKey.Id, localKey.Tenant)
+			return nil, nil, ErrInvalidKey
+		}
+
+		keys[localKey.Tenant][localKey.Id] = key
+		current[localKey.Tenant] = localKey.Id
+	}
+
+	return keys, current, nil
+}
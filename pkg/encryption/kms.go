@@ -0,0 +1,73 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	"context"
+
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	The KMSKeyProvider leaves the keys of the tenants in a key management service and only
+	sends it the data keys to wrap and unwrap. No service is bundled: implement KMSClient on
+	top of the SDK of your service, ie AWS KMS, Google Cloud KMS or Vault Transit, and pass
+	it in the options.
+
+	The tenant and key id are sent as the encryption context. Rotating the key of a tenant is
+	pointing it at a new KMS key id, keys rotated inside the service need nothing here.
+*/
+func NewKMSKeyProvider(options KMSKeyProviderOptions) (*KMSKeyProvider, error) {
+	if options.Client == nil {
+		klog.V(1).Infof("KMSClient is nil\n")
+		return nil, ErrNotImplemented
+	}
+	if len(options.Keys) == 0 {
+		klog.V(1).Infof("No KMS key ids given\n")
+		return nil, ErrInvalidInput
+	}
+
+	p := &KMSKeyProvider{
+		options: options,
+		client:  options.Client,
+	}
+	return p, nil
+}
+
+// CurrentKey is the KMS key id configured for the tenant
+func (p *KMSKeyProvider) CurrentKey(ctx context.Context, tenant string) (string, error) {
+	keyId, ok := p.options.Keys[tenant]
+	if !ok || len(keyId) == 0 {
+		klog.V(1).Infof("Tenant %s has no KMS key\n", tenant)
+		return "", ErrKeyNotFound
+	}
+	return keyId, nil
+}
+
+// WrapKey has the KMS encrypt the data key
+func (p *KMSKeyProvider) WrapKey(ctx context.Context, tenant, keyId string, dataKey []byte) ([]byte, error) {
+	wrapped, err := p.client.Encrypt(ctx, keyId, dataKey, encryptionContext(tenant, keyId))
+	if err != nil {
+		klog.V(1).Infof("KMSClient.Encrypt failed. Err: %v\n", err)
+		return nil, err
+	}
+	return wrapped, nil
+}
+
+// UnwrapKey has the KMS decrypt the data key
+func (p *KMSKeyProvider) UnwrapKey(ctx context.Context, tenant, keyId string, wrapped []byte) ([]byte, error) {
+	dataKey, err := p.client.Decrypt(ctx, keyId, wrapped, encryptionContext(tenant, keyId))
+	if err != nil {
+		klog.V(1).Infof("KMSClient.Decrypt failed. Err: %v\n", err)
+		return nil, err
+	}
+	return dataKey, nil
+}
+
+func encryptionContext(tenant, keyId string) map[string]string {
+	return map[string]string{
+		"tenant": tenant,
+		"keyId":  keyId,
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+)
+
+// targets are the properties the Dataminers encrypt
+var targets = []target{
+	{match: "(x:Message)", property: "content"},
+	{match: "(x:Insight)", property: "content"},
+	{match: "(x:OutboxEvent)", property: "payload"},
+	{match: "(x)", property: "raw"},
+	{match: "()-[x]->()", property: "raw", relationship: true},
+}
+
+func NewRotator(options RotatorOptions) (*Rotator, error) {
+	if options.Driver == nil || options.Encryptor == nil {
+		klog.V(1).Infof("Driver or Encryptor is nil\n")
+		return nil, ErrInvalidInput
+	}
+	if len(options.DatabaseName) == 0 {
+		options.DatabaseName = dbconfig.DefaultDatabaseName
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+
+	r := &Rotator{
+		options:   options,
+		driver:    options.Driver,
+		encryptor: options.Encryptor,
+	}
+	return r, nil
+}
+
+/*
+	Rotate moves the values encrypted with older keys of the tenant onto its current key and
+	encrypts the values that were saved before encryption was turned on. Only the wrapped data
+	keys change for values that are already encrypted. Values already on the current key are
+	skipped, so running it again or after an interruption is safe.
+*/
+func (r *Rotator) Rotate() (*RotateResult, error) {
+	klog.V(6).Infof("Rotator.Rotate ENTER\n")
+
+	ctx := context.Background()
+	session := (*r.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: r.options.DatabaseName})
+	defer session.Close(ctx)
+
+	result := &RotateResult{}
+	for _, t := range targets {
+		count, err := r.rotate(ctx, session, t)
+		if err != nil {
+			klog.V(1).Infof("rotate %s.%s failed. Err: %v\n", t.match, t.property, err)
+			klog.V(6).Infof("Rotator.Rotate LEAVE\n")
+			return nil, err
+		}
+
+		if t.relationship {
+			result.Relationships += count
+		} else {
+			result.Nodes += count
+		}
+	}
+
+	klog.V(4).Infof("Rotator.Rotate Succeeded\n")
+	klog.V(6).Infof("Rotator.Rotate LEAVE\n")
+
+	return result, nil
+}
+
+// rotate works through the values of the target not on the current key, one batch per transaction
+func (r *Rotator) rotate(ctx context.Context, session neo4j.SessionWithContext, t target) (int, error) {
+	findQuery := fmt.Sprintf(`
+		MATCH %s
+		WHERE x.%s IS NOT NULL AND NOT x.%s STARTS WITH $prefix
+		RETURN elementId(x) AS id, x.%s AS value
+		LIMIT $batch_size
+		`, t.match, t.property, t.property, t.property)
+	updateQuery := fmt.Sprintf(`
+		UNWIND $items AS item
+		MATCH %s
+		WHERE elementId(x) = item.id
+		SET x.%s = item.value
+		`, t.match, t.property)
+
+	total := 0
+	for {
+		// the current key can change while this runs
+		prefix, err := r.encryptor.currentPrefix(ctx)
+		if err != nil {
+			klog.V(1).Infof("currentPrefix failed. Err: %v\n", err)
+			return total, err
+		}
+
+		batchCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		count, err := session.ExecuteWrite(batchCtx,
+			func(tx neo4j.ManagedTransaction) (any, error) {
+				result, err := tx.Run(batchCtx, findQuery, map[string]any{
+					"prefix":     prefix,
+					"batch_size": r.options.BatchSize,
+				})
+				if err != nil {
+					klog.V(1).Infof("neo4j.Run failed find values. Err: %v\n", err)
+					return 0, err
+				}
+				records, err := result.Collect(batchCtx)
+				if err != nil {
+					klog.V(1).Infof("neo4j.Collect failed. Err: %v\n", err)
+					return 0, err
+				}
+				if len(records) == 0 {
+					return 0, nil
+				}
+
+				items := make([]map[string]any, 0, len(records))
+				for _, record := range records {
+					id, _ := record.Get("id")
+					value, _ := record.Get("value")
+
+					str, ok := value.(string)
+					if !ok {
+						klog.V(1).Infof("%s.%s of %v is not a string\n", t.match, t.property, id)
+						return 0, ErrMalformed
+					}
+					rotated, err := r.encryptor.Rewrap(batchCtx, str)
+					if err != nil {
+						klog.V(1).Infof("Rewrap %s.%s of %v failed. Err: %v\n", t.match, t.property, id, err)
+						return 0, err
+					}
+
+					items = append(items, map[string]any{
+						"id":    id,
+						"value": rotated,
+					})
+				}
+
+				_, err = tx.Run(batchCtx, updateQuery, map[string]any{
+					"items": items,
+				})
+				if err != nil {
+					klog.V(1).Infof("neo4j.Run failed update values. Err: %v\n", err)
+					return 0, err
+				}
+
+				return len(items), nil
+			})
+		cancel()
+		if err != nil {
+			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+			return total, err
+		}
+
+		if count.(int) == 0 {
+			break
+		}
+		total += count.(int)
+		klog.V(3).Infof("Rotated %d values of %s.%s\n", total, t.match, t.property)
+	}
+
+	return total, nil
+}
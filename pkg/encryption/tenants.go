@@ -0,0 +1,57 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	klog "k8s.io/klog/v2"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	NewEncryptors hands out the Encryptor of each tenant, made the first time the tenant is
+	seen and kept for the life of the process so its data keys are reused. The Tenant in the
+	options is ignored.
+
+	The keys of shared.DefaultTenant are the ones without a tenant, which is where the values
+	saved before tenants were introduced were encrypted.
+*/
+func NewEncryptors(options Options) (*Encryptors, error) {
+	if options.Provider == nil {
+		klog.V(1).Infof("Provider is nil\n")
+		return nil, ErrInvalidInput
+	}
+
+	es := &Encryptors{
+		options:    options,
+		encryptors: make(map[string]*Encryptor),
+	}
+	return es, nil
+}
+
+// Tenant returns the Encryptor of the tenant, nil when the Encryptors is nil which leaves the values as they are
+func (es *Encryptors) Tenant(tenant string) *Encryptor {
+	if es == nil {
+		return nil
+	}
+	if tenant == shared.DefaultTenant {
+		tenant = ""
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	e, ok := es.encryptors[tenant]
+	if !ok {
+		options := es.options
+		options.Tenant = tenant
+
+		// only fails without a provider, which NewEncryptors already checked
+		e, _ = New(options)
+		es.encryptors[tenant] = e
+		klog.V(4).Infof("Encryptor created for tenant %s\n", tenant)
+	}
+
+	return e
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package encryption
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+/*
+	KeyProvider holds the keys of each tenant. The Encryptor encrypts the values with data
+	keys of its own and only asks the provider to wrap and unwrap those data keys, so the
+	keys of the tenants never leave the provider.
+*/
+type KeyProvider interface {
+	// CurrentKey is the id of the key wrapping new data keys for the tenant
+	CurrentKey(ctx context.Context, tenant string) (string, error)
+
+	// WrapKey encrypts the data key using the key of the tenant with keyId
+	WrapKey(ctx context.Context, tenant, keyId string, dataKey []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a data key returned by WrapKey
+	UnwrapKey(ctx context.Context, tenant, keyId string, wrapped []byte) ([]byte, error)
+}
+
+// Options to init the Encryptor
+type Options struct {
+	Provider   KeyProvider
+	Tenant     string        // tenant whose keys are used, "" is the default tenant
+	DataKeyTTL time.Duration // DefaultDataKeyTTL when zero
+}
+
+// dataKey is an unwrapped data key and the header of the values it encrypts
+type dataKey struct {
+	key     []byte
+	keyId   string
+	header  string
+	created time.Time
+}
+
+// Encryptor encrypts and decrypts the values of one tenant using envelope encryption
+type Encryptor struct {
+	options  Options
+	provider KeyProvider
+
+	// data key for new values, unwrapped and re-wrapped data keys by header
+	current   *dataKey
+	opened    map[string]*dataKey
+	rewrapped map[string]string
+	mu        sync.Mutex
+}
+
+// Encryptors keeps an Encryptor per tenant
+type Encryptors struct {
+	options Options
+
+	// Encryptor by tenant, "" is the default tenant
+	encryptors map[string]*Encryptor
+	mu         sync.Mutex
+}
+
+// KeyFile is the file read by the LocalKeyProvider, in YAML or JSON
+type KeyFile struct {
+	Keys []LocalKey `json:"keys" yaml:"keys"`
+}
+
+// LocalKey is a key of a tenant, the last key listed for a tenant is its current key
+type LocalKey struct {
+	Id     string `json:"id" yaml:"id"`
+	Tenant string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+	Key    string `json:"key" yaml:"key"` // KeySize bytes encoded with base64
+}
+
+// LocalKeyProvider wraps the data keys using the keys in a key file
+type LocalKeyProvider struct {
+	path string
+
+	// keys by tenant and id, read again when the file changes
+	modTime time.Time
+	keys    map[string]map[string][]byte
+	current map[string]string
+	mu      sync.Mutex
+}
+
+// KMSClient is the part of a key management service used by the KMSKeyProvider
+type KMSClient interface {
+	Encrypt(ctx context.Context, keyId string, plaintext []byte, encryptionContext map[string]string) ([]byte, error)
+	Decrypt(ctx context.Context, keyId string, ciphertext []byte, encryptionContext map[string]string) ([]byte, error)
+}
+
+// KMSKeyProviderOptions to init the KMSKeyProvider
+type KMSKeyProviderOptions struct {
+	Client KMSClient
+	Keys   map[string]string // KMS key id for each tenant, "" is the default tenant
+}
+
+// KMSKeyProvider wraps the data keys using a key management service
+type KMSKeyProvider struct {
+	options KMSKeyProviderOptions
+	client  KMSClient
+}
+
+// RotatorOptions to init the rotator
+type RotatorOptions struct {
+	Driver       *neo4j.DriverWithContext
+	DatabaseName string // dbconfig.DefaultDatabaseName when empty
+	Encryptor    *Encryptor
+	BatchSize    int
+}
+
+// RotateResult counts the values that were re-encrypted
+type RotateResult struct {
+	Nodes         int
+	Relationships int
+}
+
+// Rotator re-encrypts the stored values with the current key of the tenant
+type Rotator struct {
+	options   RotatorOptions
+	driver    *neo4j.DriverWithContext
+	encryptor *Encryptor
+}
+
+// target is a property holding values the Rotator re-encrypts
+type target struct {
+	match        string
+	property     string
+	relationship bool
+}
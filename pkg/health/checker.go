@@ -0,0 +1,193 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+/*
+	Each server adds a check for every dependency it needs, ie its Neo4j driver and its
+	message bus, and serves them on its HTTPS listener:
+
+		GET /healthz    the checks, 503 when any of them fails
+		GET /readyz     the checks, 503 when any of them fails or the server is starting or draining
+
+	Both return a Report. Readiness turns false as soon as Stop begins so load balancers and
+	Kubernetes stop sending new conversations while the server drains.
+*/
+func New(options CheckerOptions) *Checker {
+	if options.Timeout == 0 {
+		options.Timeout = DefaultCheckTimeout
+	}
+
+	c := &Checker{
+		options: options,
+		checks:  make(map[string]CheckFunc),
+	}
+	return c
+}
+
+// Add registers a check, adding a name twice replaces the check
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Started marks the server as ready once its checks pass
+func (c *Checker) Started() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.started = true
+	c.draining = false
+}
+
+// Drain marks the server as stopping, readiness stays false until Started is called again
+func (c *Checker) Drain() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.draining = true
+}
+
+// Check runs every check at the same time and waits for all of them
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.Lock()
+	report := &Report{
+		Status:   StatusOK,
+		Started:  c.started,
+		Draining: c.draining,
+		Checks:   make(map[string]Result),
+	}
+	checks := make(map[string]CheckFunc)
+	for _, name := range c.names {
+		checks[name] = c.checks[name]
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+
+			result := Result{
+				Status: StatusOK,
+			}
+			err := run(ctx, check)
+			if err != nil {
+				klog.V(3).Infof("Health check %s failed. Err: %v\n", name, err)
+				result = Result{
+					Status: StatusUnavailable,
+					Error:  err.Error(),
+				}
+			}
+
+			mu.Lock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run gives up on a check that ignores the context once the timeout passes
+func run(ctx context.Context, check CheckFunc) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Register serves /healthz and /readyz on the mux
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc(DefaultLivenessPath, c.serveLiveness)
+	mux.HandleFunc(DefaultReadinessPath, c.serveReadiness)
+}
+
+func (c *Checker) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	writeReport(w, report, report.Status == StatusOK)
+}
+
+func (c *Checker) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+
+	ready := report.Status == StatusOK && report.Started && !report.Draining
+	if !ready {
+		report.Status = StatusUnavailable
+	}
+	writeReport(w, report, ready)
+}
+
+func writeReport(w http.ResponseWriter, report *Report, ok bool) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		str := fmt.Sprintf("json.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof(str)
+		http.Error(w, str, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(data)
+}
+
+// Neo4j checks that the driver can reach the database
+func Neo4j(ctx context.Context, driver *neo4j.DriverWithContext) error {
+	if driver == nil {
+		return ErrNotConnected
+	}
+	return (*driver).VerifyConnectivity(ctx)
+}
+
+// Bus checks that the bus is connected to its broker
+func Bus(bus *businterfaces.Bus) error {
+	if bus == nil {
+		return ErrNotConnected
+	}
+	return (*bus).Healthy()
+}
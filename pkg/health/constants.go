@@ -0,0 +1,32 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package health
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// endpoints
+	DefaultLivenessPath  string = "/healthz"
+	DefaultReadinessPath string = "/readyz"
+
+	// DefaultCheckTimeout is how long each check gets before it counts as failed
+	DefaultCheckTimeout time.Duration = 2 * time.Second
+
+	// status of a check and of the whole report
+	StatusOK          string = "ok"
+	StatusUnavailable string = "unavailable"
+
+	// names of the common checks
+	CheckNeo4j       string = "neo4j"
+	CheckBus         string = "bus"
+	CheckSubscribers string = "subscribers"
+)
+
+var (
+	// ErrNotConnected the dependency hasn't been created or was torn down
+	ErrNotConnected = errors.New("not connected")
+)
@@ -0,0 +1,46 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CheckFunc returns nil while the dependency it checks is usable
+type CheckFunc func(ctx context.Context) error
+
+// CheckerOptions for New
+type CheckerOptions struct {
+	Timeout time.Duration // DefaultCheckTimeout when 0
+}
+
+// Checker runs the checks registered by a server and serves the results
+type Checker struct {
+	options CheckerOptions
+
+	// checks in the order they were added
+	names  []string
+	checks map[string]CheckFunc
+
+	// lifecycle
+	started  bool
+	draining bool
+	mu       sync.Mutex
+}
+
+// Result of a single check
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the JSON body of /healthz and /readyz
+type Report struct {
+	Status   string            `json:"status"`
+	Started  bool              `json:"started"`
+	Draining bool              `json:"draining"`
+	Checks   map[string]Result `json:"checks"`
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package logging
+
+import (
+	"errors"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+// Format picks how the log lines are written
+type Format string
+
+const (
+	// FormatText is the klog text output
+	FormatText Format = "text"
+
+	// FormatJSON writes one JSON object per line with the values as fields
+	FormatJSON Format = "json"
+)
+
+const (
+	// EnvFormat sets the Format when EnterpriseInit doesn't
+	EnvFormat string = "ERI_LOG_FORMAT"
+
+	// fields added to the log lines
+	KeyComponent      string = "component"
+	KeyConversationId string = "conversationId"
+	KeyEventType      string = "eventType"
+	KeyEventId        string = "eventId"
+
+	// Redacted replaces the sensitive values
+	Redacted string = "[REDACTED]"
+)
+
+var (
+	// DefaultRedactedKeys are the fields holding transcripts, PII or credentials
+	DefaultRedactedKeys = redactedKeys(
+		shared.ContentKeys,
+		shared.UserKeys,
+		shared.NameKeys,
+		shared.EntityKeys,
+		[]string{"accessToken", "token", "apiKey", "appSecret", "password", "authorization"},
+	)
+
+	// ErrUnsupportedFormat the format isn't one of the Format values
+	ErrUnsupportedFormat = errors.New("log format must be text or json")
+)
+
+func redactedKeys(lists ...[]string) []string {
+	keys := make([]string, 0)
+	for _, list := range lists {
+		keys = append(keys, list...)
+	}
+	return keys
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package logging
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	logr "github.com/go-logr/logr"
+	funcr "github.com/go-logr/logr/funcr"
+	klog "k8s.io/klog/v2"
+	textlogger "k8s.io/klog/v2/textlogger"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	Init picks the output of klog. FormatText keeps the klog text lines. FormatJSON sends
+	every klog line to a JSON logger instead, tagged with the component:
+
+		{"ts":"...","caller":{...},"level":0,"msg":"Event ... queued","component":"symbl-proxy-dataminer",
+			"conversationId":"...","eventType":"conversation-message"}
+
+	klog still decides which lines are written using its -v level. Unless RedactionDisabled
+	is set, the values of DefaultRedactedKeys and RedactKeys are replaced by Redacted, both
+	in the key/value pairs and inside JSON printed in the message, so transcripts and tokens
+	don't end up in the log stream. The text lines then go through the klog text logger
+	writing to Output so they are redacted too.
+*/
+func Init(options Options) error {
+	switch options.Format {
+	case "", FormatText:
+		if options.RedactionDisabled {
+			return nil
+		}
+	case FormatJSON:
+	default:
+		klog.V(1).Infof("Log format %s is not supported\n", options.Format)
+		return ErrUnsupportedFormat
+	}
+
+	output := options.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	var r *redactor
+	if !options.RedactionDisabled {
+		keys := make([]string, 0)
+		keys = append(keys, DefaultRedactedKeys...)
+		r = newRedactor(append(keys, options.RedactKeys...))
+	}
+
+	var logger logr.Logger
+	if options.Format == FormatJSON {
+		logger = funcr.NewJSON(func(obj string) {
+			fmt.Fprintln(output, obj)
+		}, funcr.Options{
+			LogCaller:    funcr.All,
+			LogTimestamp: true,
+			Verbosity:    math.MaxInt32, // klog has already checked -v
+		})
+	} else {
+		logger = textlogger.NewLogger(textlogger.NewConfig(
+			textlogger.Verbosity(math.MaxInt32), // klog has already checked -v
+			textlogger.Output(output),
+		))
+	}
+
+	// logr.New initializes the sink again, the JSON sink adds to its depth which covers the
+	// frame added by sink, the text sink resets it
+	logger = logr.New(sink{
+		LogSink:  logger.GetSink(),
+		redactor: r,
+	})
+	if options.Format != FormatJSON {
+		logger = logger.WithCallDepth(1)
+	}
+
+	if len(options.Component) > 0 {
+		logger = logger.WithValues(KeyComponent, options.Component)
+	}
+	klog.SetLogger(logger)
+
+	return nil
+}
+
+// Conversation is the logger for lines about one conversation, eventType is left out when empty
+func Conversation(conversationId, eventType string) klog.Logger {
+	kv := []interface{}{KeyConversationId, conversationId}
+	if len(eventType) > 0 {
+		kv = append(kv, KeyEventType, eventType)
+	}
+	return klog.LoggerWithValues(klog.Background(), kv...)
+}
+
+// FromEnvelope is the logger for lines about the event, older events don't have an envelope
+func FromEnvelope(envelope *shared.Envelope, eventType string) klog.Logger {
+	if envelope == nil {
+		return klog.LoggerWithValues(klog.Background(), KeyEventType, eventType)
+	}
+	return klog.LoggerWithValues(Conversation(envelope.ConversationID, eventType), KeyEventId, envelope.ID)
+}
+
+func newRedactor(keys []string) *redactor {
+	r := &redactor{
+		keys: make(map[string]bool),
+	}
+
+	quoted := make([]string, 0)
+	for _, key := range keys {
+		r.keys[strings.ToLower(key)] = true
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+
+	// "key": "value" and Authorization: Bearer value
+	r.fields = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"|(bearer\s+)[\w\-.~+/]+=*`)
+
+	return r
+}
+
+// colors are written by prettyjson, they are noise in a JSON line
+var colors = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// message strips the colors and surrounding new lines and redacts JSON fields
+func (r *redactor) message(msg string) string {
+	msg = strings.TrimSpace(colors.ReplaceAllString(msg, ""))
+	if r == nil {
+		return msg
+	}
+	return r.fields.ReplaceAllStringFunc(msg, func(match string) string {
+		sub := r.fields.FindStringSubmatch(match)
+		if len(sub[1]) > 0 {
+			return sub[1] + `"` + Redacted + `"`
+		}
+		return sub[2] + Redacted
+	})
+}
+
+// values redacts the values of the sensitive keys and the JSON in string values
+func (r *redactor) values(kv []interface{}) []interface{} {
+	if len(kv) == 0 {
+		return kv
+	}
+
+	redacted := make([]interface{}, len(kv))
+	copy(redacted, kv)
+	for i := 1; i < len(redacted); i += 2 {
+		key, ok := redacted[i-1].(string)
+		if ok && r != nil && r.keys[strings.ToLower(key)] {
+			redacted[i] = Redacted
+			continue
+		}
+		if str, ok := redacted[i].(string); ok {
+			redacted[i] = r.message(str)
+		}
+	}
+	return redacted
+}
+
+func (s sink) Info(level int, msg string, kv ...interface{}) {
+	// the text output separates the pretty printed messages using lines of dashes
+	msg = s.redactor.message(msg)
+	if len(strings.Trim(msg, "-")) == 0 {
+		return
+	}
+	s.LogSink.Info(level, msg, s.redactor.values(kv)...)
+}
+
+func (s sink) Error(err error, msg string, kv ...interface{}) {
+	s.LogSink.Error(err, s.redactor.message(msg), s.redactor.values(kv)...)
+}
+
+func (s sink) WithValues(kv ...interface{}) logr.LogSink {
+	return sink{
+		LogSink:  s.LogSink.WithValues(s.redactor.values(kv)...),
+		redactor: s.redactor,
+	}
+}
+
+func (s sink) WithName(name string) logr.LogSink {
+	return sink{
+		LogSink:  s.LogSink.WithName(name),
+		redactor: s.redactor,
+	}
+}
+
+func (s sink) WithCallDepth(depth int) logr.LogSink {
+	withDepth, ok := s.LogSink.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	return sink{
+		LogSink:  withDepth.WithCallDepth(depth),
+		redactor: s.redactor,
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package logging
+
+import (
+	"io"
+	"regexp"
+
+	logr "github.com/go-logr/logr"
+)
+
+// Options for Init
+type Options struct {
+	Format    Format    // FormatText when empty
+	Component string    // added to every line
+	Output    io.Writer // os.Stderr when nil
+
+	// redaction
+	RedactKeys        []string // added to DefaultRedactedKeys
+	RedactionDisabled bool
+}
+
+// redactor hides the values of the sensitive keys, in key/value pairs and in JSON messages
+type redactor struct {
+	keys   map[string]bool
+	fields *regexp.Regexp
+}
+
+// sink redacts each line before handing it to the JSON or text sink
+type sink struct {
+	logr.LogSink
+	redactor *redactor
+}
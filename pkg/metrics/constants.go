@@ -0,0 +1,36 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package metrics
+
+const (
+	// Namespace prefixes every metric name
+	Namespace string = "eca"
+
+	// DefaultPath is where the metrics are served
+	DefaultPath string = "/metrics"
+
+	// ComponentPlugin labels metrics recorded by the plugin SDK
+	ComponentPlugin string = "plugin"
+
+	// TypeUnknown labels Symbl messages of a type we don't know about
+	TypeUnknown string = "unknown"
+
+	// bus labels for publish failures
+	BusRabbit string = "rabbitmq"
+	BusNats   string = "nats"
+	BusInproc string = "inproc"
+
+	// transports for client notifications
+	TransportBus             string = "bus"
+	TransportWebSocket       string = "websocket"
+	TransportServerSentEvent string = "sse"
+
+	// reasons a Symbl response was skipped
+	ReasonStale     string = "stale"
+	ReasonDuplicate string = "duplicate"
+
+	// results for client notifications
+	ResultSucceeded string = "succeeded"
+	ResultFailed    string = "failed"
+)
@@ -0,0 +1,213 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	collectors "github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+/*
+	The collectors are shared by everything running in the process and registered with
+	Registry. Labels are kept to bounded values like the component, exchange or Symbl message
+	type, never a conversationId.
+*/
+var (
+	// Registry holds every collector below plus the Go runtime and process collectors
+	Registry = prometheus.NewRegistry()
+
+	// ProxyInstances is the number of live proxy instances in the Proxy/Dataminer
+	ProxyInstances = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "proxy_instances",
+		Help:      "Number of live proxy instances.",
+	})
+
+	// ConversationsStarted counts conversations by the component that saw them start
+	ConversationsStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "conversations_started_total",
+		Help:      "Conversations started.",
+	}, []string{"component"})
+
+	// ConversationsTornDown counts conversations by the component that saw them end
+	ConversationsTornDown = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "conversations_torn_down_total",
+		Help:      "Conversations torn down.",
+	}, []string{"component"})
+
+	// SymblMessagesReceived counts the messages from Symbl by type
+	SymblMessagesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "symbl_messages_received_total",
+		Help:      "Messages received from Symbl by type.",
+	}, []string{"component", "type"})
+
+	// Neo4jWriteDuration is the latency of the write transactions, query is the exchange of the event
+	Neo4jWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "neo4j_write_duration_seconds",
+		Help:      "Latency of Neo4j write transactions by query.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"query"})
+
+	// Neo4jWriteErrors counts the failed write transactions
+	Neo4jWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "neo4j_write_errors_total",
+		Help:      "Failed Neo4j write transactions by query.",
+	}, []string{"query"})
+
+	// BusPublishFailures counts messages the bus failed to publish
+	BusPublishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "bus_publish_failures_total",
+		Help:      "Messages the bus failed to publish.",
+	}, []string{"bus"})
+
+	// CallbackDuration is the latency of the plugin callbacks by event type
+	CallbackDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "plugin_callback_duration_seconds",
+		Help:      "Latency of plugin callbacks by event type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"event_type"})
+
+	// CallbackErrors counts the plugin callbacks that returned an error
+	CallbackErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "plugin_callback_errors_total",
+		Help:      "Plugin callbacks that returned an error by event type.",
+	}, []string{"event_type"})
+
+	// Notifications counts the application messages fanned out towards the clients
+	Notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "notifications_total",
+		Help:      "Client notifications sent by component, transport and result.",
+	}, []string{"component", "transport", "result"})
+
+	// ConnectionUp is 1 while a supervised connection works and 0 while it is reconnecting
+	ConnectionUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "connection_up",
+		Help:      "Whether a supervised connection is up by component and connection.",
+	}, []string{"component", "connection"})
+
+	// Reconnects counts the attempts to reconnect a supervised connection
+	Reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "reconnects_total",
+		Help:      "Reconnect attempts by component, connection and result.",
+	}, []string{"component", "connection", "result"})
+
+	// Redactions counts the values masked before they were saved or published
+	Redactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "redactions_total",
+		Help:      "Values redacted by field and rule.",
+	}, []string{"field", "rule"})
+
+	// SkippedMessages counts the Symbl responses at or below the highest applied sequence number
+	SkippedMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "skipped_messages_total",
+		Help:      "Stale or duplicate Symbl responses that were not applied by reason.",
+	}, []string{"reason"})
+
+	// OutboxFailedEvents is the number of outbox events that ran out of attempts, each holds back its conversation
+	OutboxFailedEvents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "outbox_failed_events",
+		Help:      "Outbox events that ran out of attempts and wait to be requeued by database.",
+	}, []string{"database"})
+
+	// AuthRejections counts the requests for a conversation that were turned away
+	AuthRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "auth_rejections_total",
+		Help:      "Rejected conversation requests by action and HTTP status.",
+	}, []string{"action", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ProxyInstances,
+		ConversationsStarted,
+		ConversationsTornDown,
+		SymblMessagesReceived,
+		Neo4jWriteDuration,
+		Neo4jWriteErrors,
+		BusPublishFailures,
+		CallbackDuration,
+		CallbackErrors,
+		Notifications,
+		ConnectionUp,
+		Reconnects,
+		Redactions,
+		SkippedMessages,
+		OutboxFailedEvents,
+		AuthRejections,
+	)
+}
+
+// ObserveNeo4jWrite records a write transaction that began at start
+func ObserveNeo4jWrite(query string, start time.Time, err error) {
+	Neo4jWriteDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+	if err != nil {
+		Neo4jWriteErrors.WithLabelValues(query).Inc()
+	}
+}
+
+// ObserveCallback records a plugin callback that began at start
+func ObserveCallback(eventType string, start time.Time, err error) {
+	CallbackDuration.WithLabelValues(eventType).Observe(time.Since(start).Seconds())
+	if err != nil {
+		CallbackErrors.WithLabelValues(eventType).Inc()
+	}
+}
+
+// ObserveReconnect records an attempt to reconnect, the connection is up when it succeeded
+func ObserveReconnect(component, connection string, err error) {
+	result := ResultSucceeded
+	if err != nil {
+		result = ResultFailed
+	}
+	Reconnects.WithLabelValues(component, connection, result).Inc()
+	SetConnectionUp(component, connection, err == nil)
+}
+
+// SetConnectionUp records the state of a supervised connection
+func SetConnectionUp(component, connection string, up bool) {
+	value := 0.0
+	if up {
+		value = 1.0
+	}
+	ConnectionUp.WithLabelValues(component, connection).Set(value)
+}
+
+// ObserveNotification records a client notification sent by the component over the transport
+func ObserveNotification(component, transport string, err error) {
+	result := ResultSucceeded
+	if err != nil {
+		result = ResultFailed
+	}
+	Notifications.WithLabelValues(component, transport, result).Inc()
+}
+
+// ObserveRedactions records count values of the field redacted by the rule
+func ObserveRedactions(field, rule string, count int) {
+	Redactions.WithLabelValues(field, rule).Add(float64(count))
+}
+
+// ObserveAuthRejection records a request for the action rejected with the HTTP status
+func ObserveAuthRejection(action string, status int) {
+	AuthRejections.WithLabelValues(action, strconv.Itoa(status)).Inc()
+}
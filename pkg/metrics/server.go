@@ -0,0 +1,87 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	promhttp "github.com/prometheus/client_golang/prometheus/promhttp"
+	klog "k8s.io/klog/v2"
+)
+
+func NewServer(options ServerOptions) *Server {
+	if len(options.Path) == 0 {
+		options.Path = DefaultPath
+	}
+
+	s := &Server{
+		options: options,
+	}
+	return s
+}
+
+// Handler serves the collectors in Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+func (s *Server) Start() error {
+	klog.V(6).Infof("metrics.Server.Start ENTER\n")
+
+	mux := http.NewServeMux()
+	mux.Handle(s.options.Path, Handler())
+
+	s.server = &http.Server{
+		Addr:              s.options.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// listen first so a port in use fails Start, then serve plain HTTP for the scraper
+	listener, err := net.Listen("tcp", s.options.Address)
+	if err != nil {
+		klog.V(1).Infof("net.Listen(%s) failed. Err: %v\n", s.options.Address, err)
+		klog.V(6).Infof("metrics.Server.Start LEAVE\n")
+		return err
+	}
+
+	server := s.server
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			klog.V(1).Infof("metrics Serve failed. Err: %v\n", err)
+		}
+	}()
+
+	klog.V(4).Infof("Serving metrics on %s%s\n", s.options.Address, s.options.Path)
+	klog.V(6).Infof("metrics.Server.Start LEAVE\n")
+
+	return nil
+}
+
+func (s *Server) Stop() error {
+	klog.V(6).Infof("metrics.Server.Stop ENTER\n")
+
+	if s.server == nil {
+		klog.V(6).Infof("metrics.Server.Stop LEAVE\n")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		klog.V(1).Infof("metrics Shutdown failed. Err: %v\n", err)
+	}
+	s.server = nil
+
+	klog.V(4).Infof("metrics.Server.Stop Succeeded\n")
+	klog.V(6).Infof("metrics.Server.Stop LEAVE\n")
+
+	return err
+}
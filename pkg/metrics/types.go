@@ -0,0 +1,20 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package metrics
+
+import (
+	"net/http"
+)
+
+// ServerOptions for the metrics endpoint
+type ServerOptions struct {
+	Address string
+	Path    string // DefaultPath when empty
+}
+
+// Server serves the metrics over HTTP
+type Server struct {
+	options ServerOptions
+	server  *http.Server
+}
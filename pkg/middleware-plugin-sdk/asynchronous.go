@@ -4,29 +4,77 @@
 package middleware
 
 import (
-	rabbit "github.com/dvonthenen/rabbitmq-manager/pkg"
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	"fmt"
+	"sort"
+	"strings"
+
 	klog "k8s.io/klog/v2"
 
+	messagebus "github.com/dvonthenen/enterprise-conversation-application/pkg/bus"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	deadletter "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/deadletter"
 	middlewareinterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	partition "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/partition"
 	router "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/router/asynchronous"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 func NewAsynchronousAnalyzer(options AsynchronousAnalyzerOption) (*AsynchronousAnalyzer, error) {
-	// setup rabbit manager
-	rabbitMgr, err := rabbit.New(rabbitinterfaces.ManagerOptions{
-		RabbitURI: options.RabbitURI,
-	})
-	if err != nil {
-		klog.V(1).Infof("rabbit.New failed. Err: %v\n", err)
-		return nil, err
+	// message bus, picked by the RabbitURI scheme
+	if options.Bus == nil {
+		bus, err := messagebus.New(messagebus.BusOptions{
+			URI: options.RabbitURI,
+		})
+		if err != nil {
+			klog.V(1).Infof("messagebus.New failed. Err: %v\n", err)
+			return nil, err
+		}
+		options.Bus = bus
+	}
+
+	// partitions are durable queues on the broker
+	if options.SharedQueue && !messagebus.IsRabbit(options.RabbitURI) {
+		klog.V(1).Infof("SharedQueue requires a RabbitMQ RabbitURI\n")
+		return nil, ErrSharedQueueRequiresRabbit
+	}
+
+	// events that run out of retries are dead-lettered into a durable queue on the broker
+	if (options.RetryPolicy.MaxAttempts != 0 || len(options.RetryPolicies) > 0) && !messagebus.IsRabbit(options.RabbitURI) {
+		klog.V(1).Infof("RetryPolicy requires a RabbitMQ RabbitURI\n")
+		return nil, ErrRetryRequiresRabbit
+	}
+
+	// retry defaults
+	if len(options.PluginName) == 0 {
+		options.PluginName = deadletter.DefaultPluginName
+	}
+	if options.RetryPolicy.MaxAttempts == 0 {
+		options.RetryPolicy = deadletter.DefaultRetryPolicy()
+	}
+
+	// setup dead letter queue, without RabbitMQ failed events are logged and dropped without retries
+	var deadLetters *deadletter.Queue
+	if messagebus.IsRabbit(options.RabbitURI) {
+		queue, err := deadletter.NewQueue(deadletter.QueueOptions{
+			RabbitURI: options.RabbitURI,
+			Plugin:    options.PluginName,
+		})
+		if err != nil {
+			klog.V(1).Infof("deadletter.NewQueue failed. Err: %v\n", err)
+			return nil, err
+		}
+		deadLetters = queue
 	}
 
 	// create middleware
 	mgr := &AsynchronousAnalyzer{
-		rabbitManager: rabbitMgr,
-		callback:      options.Callback,
+		options:     options,
+		bus:         options.Bus,
+		deadLetters: deadLetters,
+		callback:    options.Callback,
+		traces:      tracing.NewConversations(),
 	}
 
 	// set publisher
@@ -40,13 +88,13 @@ func NewAsynchronousAnalyzer(options AsynchronousAnalyzerOption) (*AsynchronousA
 /*
 	This initializes all of the subscribers to the Symbl Proxy/Dataminer component
 
-	Each rabbit subscriber listens for a specific Symbl derived/discovered conversation insight and
+	Each bus subscriber listens for a specific Symbl derived/discovered conversation insight and
 	is then notified through a callback handler with the original Symbl RealTime API message struct
 */
 func (aa *AsynchronousAnalyzer) Init() error {
 	klog.V(6).Infof("AsynchronousAnalyzer.Init ENTER\n")
 
-	type InitFunc func(router.HandlerOptions) *rabbitinterfaces.RabbitMessageHandler
+	type InitFunc func(router.HandlerOptions) *businterfaces.MessageHandler
 	type MyHandler struct {
 		Name string
 		Func InitFunc
@@ -91,33 +139,110 @@ func (aa *AsynchronousAnalyzer) Init() error {
 		Func: router.NewConversationTeardownHandler,
 	})
 
+	// failed events end up here
+	if aa.deadLetters != nil {
+		err := aa.deadLetters.Init()
+		if err != nil {
+			klog.V(1).Infof("deadLetters.Init failed. Err: %v\n", err)
+			klog.V(6).Infof("AsynchronousAnalyzer.Init LEAVE\n")
+			return err
+		}
+	}
+
+	handlers := make(map[string]*businterfaces.MessageHandler)
+
 	for _, myHandler := range myHandlers {
 		// create subscriber
 		handler := myHandler.Func(router.HandlerOptions{
-			Manager:  aa.rabbitManager,
+			Bus:      aa.bus,
 			Callback: aa.callback,
+			Sessions: aa.options.Sessions,
+			Traces:   aa.traces,
 		})
 
-		_, err := (*aa.rabbitManager).CreateSubscriber(rabbitinterfaces.SubscriberOptions{
-			Name:        myHandler.Name,
-			Type:        rabbitinterfaces.ExchangeTypeFanout,
-			AutoDeleted: true,
-			IfUnused:    true,
-			Handler:     handler,
+		// retry then dead letter failed events
+		if aa.deadLetters != nil {
+			policy, ok := aa.options.RetryPolicies[myHandler.Name]
+			if !ok {
+				policy = aa.options.RetryPolicy
+			}
+			handler = deadletter.NewRetryHandler(deadletter.RetryHandlerOptions{
+				Name:    myHandler.Name,
+				Policy:  policy,
+				Handler: handler,
+				Queue:   aa.deadLetters,
+			})
+		}
+
+		handlers[myHandler.Name] = handler
+	}
+
+	// dead letters sent back by the dead letter tool
+	if aa.deadLetters != nil {
+		err := aa.deadLetters.Consume(deadletter.NewRedriveHandler(deadletter.RedriveHandlerOptions{
+			Handlers: handlers,
+		}))
+		if err != nil {
+			klog.V(1).Infof("deadLetters.Consume failed. Err: %v\n", err)
+			klog.V(6).Infof("AsynchronousAnalyzer.Init LEAVE\n")
+			return err
+		}
+	}
+
+	var failed []string
+	if aa.options.SharedQueue {
+		// replicas of this plugin share the events partitioned by conversation
+		consumer, err := partition.New(partition.ConsumerOptions{
+			RabbitURI:  aa.options.RabbitURI,
+			Plugin:     aa.options.PluginName,
+			Partitions: aa.options.Partitions,
+			Handlers:   handlers,
+			Tenants:    aa.options.Tenants,
 		})
 		if err != nil {
-			klog.V(1).Infof("CreateSubscription failed. Err: %v\n", err)
+			klog.V(1).Infof("partition.New failed. Err: %v\n", err)
+			klog.V(6).Infof("AsynchronousAnalyzer.Init LEAVE\n")
+			return err
+		}
+
+		err = consumer.Init()
+		if err != nil {
+			klog.V(1).Infof("consumer.Init failed. Err: %v\n", err)
+			klog.V(6).Infof("AsynchronousAnalyzer.Init LEAVE\n")
+			return err
+		}
+		aa.consumer = consumer
+	} else {
+		// every replica gets every event
+		for name, handler := range handlers {
+			err := (*aa.bus).CreateSubscriber(businterfaces.SubscriberOptions{
+				Name:    name,
+				Type:    businterfaces.ChannelTypeBroadcast,
+				Handler: handler,
+				Keys:    aa.options.Tenants,
+			})
+			if err != nil {
+				klog.V(1).Infof("CreateSubscription failed. Err: %v\n", err)
+				failed = append(failed, name)
+			}
 		}
 	}
 
 	// init the system
-	err := (*aa.rabbitManager).Init()
+	err := (*aa.bus).Init()
 	if err != nil {
-		klog.V(1).Infof("rabbitManager.Init failed. Err: %v\n", err)
+		klog.V(1).Infof("bus.Init failed. Err: %v\n", err)
 		klog.V(6).Infof("AsynchronousAnalyzer.Init LEAVE\n")
 		return err
 	}
 
+	// subscribers that couldn't be created leave the plugin unhealthy
+	sort.Strings(failed)
+	aa.mu.Lock()
+	aa.subscribed = true
+	aa.failed = failed
+	aa.mu.Unlock()
+
 	klog.V(4).Infof("Init Succeeded\n")
 	klog.V(6).Infof("AsynchronousAnalyzer.Init LEAVE\n")
 
@@ -127,16 +252,15 @@ func (aa *AsynchronousAnalyzer) Init() error {
 func (aa *AsynchronousAnalyzer) PublishMessage(name string, data []byte) error {
 	klog.V(6).Infof("AsynchronousAnalyzer.PublishMessage ENTER\n")
 
-	publisher, err := (*aa.rabbitManager).GetPublisherByName(name)
-	if err != nil {
-		klog.V(1).Infof("GetPublisherByName failed. Err: %v\n", err)
-		klog.V(6).Infof("AsynchronousAnalyzer.PublishMessage LEAVE\n")
-		return err
-	}
+	// the channel is named by the conversationId, continue the trace of its callback
+	data, span := aa.traces.StartNotification(name, aa.options.PluginName, data)
+	defer span.End()
 
-	err = (*publisher).SendMessage(data)
+	err := (*aa.bus).Publish(name, data)
+	metrics.ObserveNotification(metrics.ComponentPlugin, metrics.TransportBus, err)
 	if err != nil {
-		klog.V(1).Infof("SendMessage failed. Err: %v\n", err)
+		tracing.Fail(span, err)
+		klog.V(1).Infof("bus.Publish failed. Err: %v\n", err)
 		klog.V(6).Infof("AsynchronousAnalyzer.PublishMessage LEAVE\n")
 		return err
 	}
@@ -145,12 +269,54 @@ func (aa *AsynchronousAnalyzer) PublishMessage(name string, data []byte) error {
 	return nil
 }
 
+// Healthy returns nil while every subscriber is running and the bus is connected
+func (aa *AsynchronousAnalyzer) Healthy() error {
+	aa.mu.Lock()
+	subscribed := aa.subscribed
+	failed := aa.failed
+	aa.mu.Unlock()
+
+	if !subscribed {
+		return ErrNotSubscribed
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", ErrSubscriberFailed, strings.Join(failed, ", "))
+	}
+	if aa.consumer != nil {
+		err := aa.consumer.Healthy()
+		if err != nil {
+			return err
+		}
+	}
+	return (*aa.bus).Healthy()
+}
+
 func (aa *AsynchronousAnalyzer) Teardown() error {
 	klog.V(6).Infof("AsynchronousAnalyzer.Teardown ENTER\n")
 
-	err := (*aa.rabbitManager).Teardown()
+	aa.mu.Lock()
+	aa.subscribed = false
+	aa.failed = nil
+	aa.mu.Unlock()
+
+	if aa.consumer != nil {
+		err := aa.consumer.Stop()
+		if err != nil {
+			klog.V(1).Infof("consumer.Stop failed. Err: %v\n", err)
+		}
+		aa.consumer = nil
+	}
+
+	if aa.deadLetters != nil {
+		err := aa.deadLetters.Teardown()
+		if err != nil {
+			klog.V(1).Infof("deadLetters.Teardown failed. Err: %v\n", err)
+		}
+	}
+
+	err := (*aa.bus).Teardown()
 	if err != nil {
-		klog.V(1).Infof("rabbitManager.Teardown failed. Err: %v\n", err)
+		klog.V(1).Infof("bus.Teardown failed. Err: %v\n", err)
 		klog.V(6).Infof("AsynchronousAnalyzer.Stop LEAVE\n")
 		return err
 	}
@@ -0,0 +1,22 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package middleware
+
+import (
+	"errors"
+)
+
+var (
+	// ErrSharedQueueRequiresRabbit shared queue mode partitions events on a RabbitMQ broker
+	ErrSharedQueueRequiresRabbit = errors.New("shared queue requires a RabbitMQ RabbitURI")
+
+	// ErrRetryRequiresRabbit events that run out of retries are dead-lettered on a RabbitMQ broker
+	ErrRetryRequiresRabbit = errors.New("retry policy requires a RabbitMQ RabbitURI")
+
+	// ErrNotSubscribed Init hasn't subscribed to the events or Teardown was called
+	ErrNotSubscribed = errors.New("not subscribed to events")
+
+	// ErrSubscriberFailed some of the subscribers couldn't be created
+	ErrSubscriberFailed = errors.New("subscribers failed")
+)
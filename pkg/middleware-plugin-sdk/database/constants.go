@@ -0,0 +1,21 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package database
+
+import (
+	"errors"
+)
+
+const (
+	// DefaultDatabaseName used when no database is given
+	DefaultDatabaseName string = "neo4j"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrSessionReleased the conversation session was already released
+	ErrSessionReleased = errors.New("conversation session was released")
+)
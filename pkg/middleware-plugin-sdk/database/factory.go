@@ -0,0 +1,194 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package database
+
+import (
+	"context"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+)
+
+/*
+	Neo4j sessions are not thread safe, but callbacks for different conversations, and for
+	different insights of the same conversation, run concurrently. Plugins share one driver
+	through the SessionFactory and either run each callback in its own session using
+	ExecuteRead/ExecuteWrite or keep a session per conversation using Conversation().
+
+	Every call takes the tenant of the conversation, ie Envelope.GetTenant(), which picks the
+	database from TenantDatabases and the keys to decrypt with. When the Dataminers encrypt the
+	content, give the factory the same Encryption options. The values returned by the work of
+	ExecuteRead/ExecuteWrite are then decrypted, so return the records, ie using
+	result.Collect, or call Decrypt on the values read inside the work.
+*/
+func NewSessionFactory(options SessionFactoryOptions) (*SessionFactory, error) {
+	if options.Driver == nil {
+		klog.V(1).Infof("Driver is nil\n")
+		return nil, ErrInvalidInput
+	}
+	if len(options.DatabaseName) == 0 {
+		options.DatabaseName = DefaultDatabaseName
+	}
+
+	var encryptors *encryption.Encryptors
+	if options.Encryption != nil {
+		var err error
+		encryptors, err = encryption.NewEncryptors(*options.Encryption)
+		if err != nil {
+			klog.V(1).Infof("encryption.NewEncryptors failed. Err: %v\n", err)
+			return nil, err
+		}
+	}
+
+	sf := &SessionFactory{
+		options:       options,
+		driver:        options.Driver,
+		encryptors:    encryptors,
+		conversations: make(map[string]*ConversationSession),
+	}
+	return sf, nil
+}
+
+// Database is the database of the tenant, or DatabaseName when the tenant doesn't have one
+func (sf *SessionFactory) Database(tenant string) string {
+	if database, ok := sf.options.TenantDatabases[tenant]; ok && len(tenant) > 0 {
+		return database
+	}
+	return sf.options.DatabaseName
+}
+
+// NewSession opens a session on the tenant's database for the caller, who must close it when done
+func (sf *SessionFactory) NewSession(ctx context.Context, tenant string) neo4j.SessionWithContext {
+	return (*sf.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: sf.Database(tenant)})
+}
+
+// ExecuteRead runs the work in a read transaction on a session of its own
+func (sf *SessionFactory) ExecuteRead(ctx context.Context, tenant string, work neo4j.ManagedTransactionWork) (any, error) {
+	session := sf.NewSession(ctx, tenant)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, work)
+	if err != nil {
+		return nil, err
+	}
+	return sf.encryptors.Tenant(tenant).DecryptAll(ctx, result)
+}
+
+// ExecuteWrite runs the work in a write transaction on a session of its own
+func (sf *SessionFactory) ExecuteWrite(ctx context.Context, tenant string, work neo4j.ManagedTransactionWork) (any, error) {
+	session := sf.NewSession(ctx, tenant)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, work)
+	if err != nil {
+		return nil, err
+	}
+	return sf.encryptors.Tenant(tenant).DecryptAll(ctx, result)
+}
+
+// Decrypt returns v with the values the Dataminers encrypted for the tenant decrypted, ie records or node properties
+func (sf *SessionFactory) Decrypt(ctx context.Context, tenant string, v any) (any, error) {
+	return sf.encryptors.Tenant(tenant).DecryptAll(ctx, v)
+}
+
+// Conversation returns the session for the conversation of the tenant, creating it on first use
+func (sf *SessionFactory) Conversation(ctx context.Context, tenant, conversationId string) *ConversationSession {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	cs, ok := sf.conversations[conversationId]
+	if !ok {
+		cs = &ConversationSession{
+			conversationId: conversationId,
+			session:        sf.NewSession(ctx, tenant),
+			encryptor:      sf.encryptors.Tenant(tenant),
+		}
+		sf.conversations[conversationId] = cs
+		klog.V(4).Infof("Session opened for conversation %s\n", conversationId)
+	}
+
+	return cs
+}
+
+// Release closes the session for the conversation, the SDK calls it when the conversation is torn down
+func (sf *SessionFactory) Release(ctx context.Context, conversationId string) error {
+	sf.mu.Lock()
+	cs, ok := sf.conversations[conversationId]
+	delete(sf.conversations, conversationId)
+	sf.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	err := cs.close(ctx)
+	if err != nil {
+		klog.V(1).Infof("Session close for conversation %s failed. Err: %v\n", conversationId, err)
+		return err
+	}
+	klog.V(4).Infof("Session released for conversation %s\n", conversationId)
+
+	return nil
+}
+
+// Teardown closes the sessions of all conversations, the driver is left open
+func (sf *SessionFactory) Teardown(ctx context.Context) error {
+	sf.mu.Lock()
+	conversations := sf.conversations
+	sf.conversations = make(map[string]*ConversationSession)
+	sf.mu.Unlock()
+
+	var lastErr error
+	for conversationId, cs := range conversations {
+		err := cs.close(ctx)
+		if err != nil {
+			klog.V(1).Infof("Session close for conversation %s failed. Err: %v\n", conversationId, err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// ExecuteRead runs the work in a read transaction, waiting for any other transaction on the conversation
+func (cs *ConversationSession) ExecuteRead(ctx context.Context, work neo4j.ManagedTransactionWork) (any, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.released {
+		return nil, ErrSessionReleased
+	}
+
+	result, err := cs.session.ExecuteRead(ctx, work)
+	if err != nil {
+		return nil, err
+	}
+	return cs.encryptor.DecryptAll(ctx, result)
+}
+
+// ExecuteWrite runs the work in a write transaction, waiting for any other transaction on the conversation
+func (cs *ConversationSession) ExecuteWrite(ctx context.Context, work neo4j.ManagedTransactionWork) (any, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.released {
+		return nil, ErrSessionReleased
+	}
+
+	result, err := cs.session.ExecuteWrite(ctx, work)
+	if err != nil {
+		return nil, err
+	}
+	return cs.encryptor.DecryptAll(ctx, result)
+}
+
+func (cs *ConversationSession) close(ctx context.Context) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.released = true
+	return cs.session.Close(ctx)
+}
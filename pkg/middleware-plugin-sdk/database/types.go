@@ -0,0 +1,42 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package database
+
+import (
+	"sync"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+)
+
+// SessionFactoryOptions to init the factory
+type SessionFactoryOptions struct {
+	Driver          *neo4j.DriverWithContext
+	DatabaseName    string
+	TenantDatabases map[string]string   // tenant to its own database, the other tenants use DatabaseName
+	Encryption      *encryption.Options // nil returns the values as they are saved
+}
+
+// SessionFactory hands out Neo4j sessions backed by a single thread safe driver
+type SessionFactory struct {
+	options SessionFactoryOptions
+	driver  *neo4j.DriverWithContext
+
+	// decrypts the content saved by the Dataminers with the keys of its tenant
+	encryptors *encryption.Encryptors
+
+	// one session per active conversation
+	conversations map[string]*ConversationSession
+	mu            sync.Mutex
+}
+
+// ConversationSession is a session used only for one conversation, one transaction at a time
+type ConversationSession struct {
+	conversationId string
+	session        neo4j.SessionWithContext
+	encryptor      *encryption.Encryptor
+	released       bool
+	mu             sync.Mutex
+}
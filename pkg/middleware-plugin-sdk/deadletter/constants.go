@@ -0,0 +1,29 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package deadletter
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// defaults
+	DefaultPluginName     string        = "middleware-plugin"
+	DefaultMaxAttempts    int           = 3
+	DefaultInitialBackoff time.Duration = 500 * time.Millisecond
+	DefaultMaxBackoff     time.Duration = 10 * time.Second
+
+	// queue name suffixes
+	queueSuffix   string = ".dead-letter"
+	redriveSuffix string = ".redrive"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrHandlerNotFound no handler for the dead letter exchange
+	ErrHandlerNotFound = errors.New("handler for exchange not found")
+)
@@ -0,0 +1,121 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package deadletter
+
+import (
+	"encoding/json"
+	"time"
+
+	klog "k8s.io/klog/v2"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+/*
+	NewRetryHandler wraps a subscriber handler so failed events are retried with backoff
+	and dead-lettered once the policy runs out of attempts. The attempts are made inline,
+	so the later events of the subscriber wait behind the failing one and stay in order.
+	ProcessMessage only returns an error when the event couldn't be dead-lettered.
+*/
+func NewRetryHandler(options RetryHandlerOptions) *businterfaces.MessageHandler {
+	if options.Policy.MaxAttempts <= 0 {
+		options.Policy.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.Policy.InitialBackoff <= 0 {
+		options.Policy.InitialBackoff = DefaultInitialBackoff
+	}
+	if options.Policy.MaxBackoff <= 0 {
+		options.Policy.MaxBackoff = DefaultMaxBackoff
+	}
+
+	var handler businterfaces.MessageHandler
+	handler = RetryHandler{
+		options: options,
+		handler: options.Handler,
+		queue:   options.Queue,
+	}
+	return &handler
+}
+
+func (rh RetryHandler) ProcessMessage(byData []byte) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = (*rh.handler).ProcessMessage(byData)
+		if err == nil {
+			return nil
+		}
+		klog.V(3).Infof("[RetryHandler] %s attempt %d of %d failed. Err: %v\n", rh.options.Name, attempt, rh.options.Policy.MaxAttempts, err)
+
+		if attempt >= rh.options.Policy.MaxAttempts {
+			break
+		}
+		time.Sleep(rh.backoff(attempt))
+	}
+
+	if rh.queue == nil {
+		klog.V(1).Infof("[RetryHandler] %s dropped after %d attempts. Err: %v\n", rh.options.Name, rh.options.Policy.MaxAttempts, err)
+		return err
+	}
+
+	dlErr := rh.queue.Publish(&DeadLetter{
+		Exchange: rh.options.Name,
+		Payload:  byData,
+		Error:    err.Error(),
+		Attempts: rh.options.Policy.MaxAttempts,
+		FailedAt: time.Now(),
+	})
+	if dlErr != nil {
+		klog.V(1).Infof("[RetryHandler] %s dead letter failed. Err: %v\n", rh.options.Name, dlErr)
+		return dlErr
+	}
+
+	return nil
+}
+
+// backoff is how long to wait after the attempt failed
+func (rh RetryHandler) backoff(attempt int) time.Duration {
+	backoff := rh.options.Policy.InitialBackoff
+	for i := 1; i < attempt && backoff < rh.options.Policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > rh.options.Policy.MaxBackoff {
+		backoff = rh.options.Policy.MaxBackoff
+	}
+	return backoff
+}
+
+// NewRedriveHandler hands re-driven dead letters back to the handler for their original exchange
+func NewRedriveHandler(options RedriveHandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
+	handler = RedriveHandler{
+		handlers: options.Handlers,
+	}
+	return &handler
+}
+
+func (rh RedriveHandler) ProcessMessage(byData []byte) error {
+	var dl DeadLetter
+	err := json.Unmarshal(byData, &dl)
+	if err != nil {
+		klog.V(1).Infof("[RedriveHandler] json.Unmarshal failed. Err: %v\n", err)
+		return err
+	}
+
+	handler := rh.handlers[dl.Exchange]
+	if handler == nil {
+		klog.V(1).Infof("[RedriveHandler] no handler for %s\n", dl.Exchange)
+		return ErrHandlerNotFound
+	}
+
+	klog.V(3).Infof("[RedriveHandler] Re-driving %s event\n", dl.Exchange)
+	return (*handler).ProcessMessage(dl.Payload)
+}
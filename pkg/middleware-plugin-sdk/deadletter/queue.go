@@ -0,0 +1,387 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+// QueueName is the durable queue holding the dead letters for a plugin
+func QueueName(plugin string) string {
+	return plugin + queueSuffix
+}
+
+// RedriveName is the durable queue the replicas of a plugin share for re-driven dead letters
+func RedriveName(plugin string) string {
+	return plugin + redriveSuffix
+}
+
+/*
+	NewQueue creates the durable dead letter and redrive queues for a plugin.
+
+	The rabbit manager only creates temporary queues which drop messages when nobody is
+	listening, so the dead letter exchange and queues are declared directly on the broker.
+	Re-driven events go straight into the redrive queue, so only one replica of the plugin
+	handles each of them.
+*/
+func NewQueue(options QueueOptions) (*Queue, error) {
+	if len(options.RabbitURI) == 0 {
+		klog.V(1).Infof("RabbitURI is empty\n")
+		return nil, ErrInvalidInput
+	}
+	if len(options.Plugin) == 0 {
+		options.Plugin = DefaultPluginName
+	}
+
+	q := &Queue{
+		options: options,
+	}
+	return q, nil
+}
+
+func (q *Queue) Init() error {
+	klog.V(6).Infof("Queue.Init ENTER\n")
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	connection, err := amqp.Dial(q.options.RabbitURI)
+	if err != nil {
+		klog.V(1).Infof("amqp.Dial failed. Err: %v\n", err)
+		klog.V(6).Infof("Queue.Init LEAVE\n")
+		return err
+	}
+
+	channel, err := connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+		klog.V(6).Infof("Queue.Init LEAVE\n")
+		connection.Close()
+		return err
+	}
+
+	err = channel.ExchangeDeclare(
+		shared.RabbitDeadLetter, // name
+		amqp.ExchangeDirect,     // type
+		true,                    // durable
+		false,                   // auto-deleted
+		false,                   // internal
+		false,                   // no-wait
+		nil,                     // arguments
+	)
+	if err != nil {
+		klog.V(1).Infof("ExchangeDeclare %s failed. Err: %v\n", shared.RabbitDeadLetter, err)
+		klog.V(6).Infof("Queue.Init LEAVE\n")
+		connection.Close()
+		return err
+	}
+
+	_, err = channel.QueueDeclare(
+		QueueName(q.options.Plugin), // name
+		true,                        // durable
+		false,                       // auto-deleted
+		false,                       // exclusive
+		false,                       // no-wait
+		nil,                         // arguments
+	)
+	if err != nil {
+		klog.V(1).Infof("QueueDeclare %s failed. Err: %v\n", QueueName(q.options.Plugin), err)
+		klog.V(6).Infof("Queue.Init LEAVE\n")
+		connection.Close()
+		return err
+	}
+
+	err = channel.QueueBind(
+		QueueName(q.options.Plugin), // queue name
+		q.options.Plugin,            // routing key
+		shared.RabbitDeadLetter,     // exchange
+		false,
+		nil)
+	if err != nil {
+		klog.V(1).Infof("QueueBind %s failed. Err: %v\n", QueueName(q.options.Plugin), err)
+		klog.V(6).Infof("Queue.Init LEAVE\n")
+		connection.Close()
+		return err
+	}
+
+	_, err = channel.QueueDeclare(
+		RedriveName(q.options.Plugin), // name
+		true,                          // durable
+		false,                         // auto-deleted
+		false,                         // exclusive
+		false,                         // no-wait
+		nil,                           // arguments
+	)
+	if err != nil {
+		klog.V(1).Infof("QueueDeclare %s failed. Err: %v\n", RedriveName(q.options.Plugin), err)
+		klog.V(6).Infof("Queue.Init LEAVE\n")
+		connection.Close()
+		return err
+	}
+
+	q.connection = connection
+	q.channel = channel
+
+	klog.V(4).Infof("Queue.Init Succeeded\n")
+	klog.V(6).Infof("Queue.Init LEAVE\n")
+
+	return nil
+}
+
+// Publish saves a dead letter to the queue
+func (q *Queue) Publish(dl *DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.channel == nil {
+		klog.V(1).Infof("Queue is not initialized\n")
+		return ErrInvalidInput
+	}
+
+	dl.Plugin = q.options.Plugin
+
+	data, err := json.Marshal(dl)
+	if err != nil {
+		klog.V(1).Infof("json.Marshal failed. Err: %v\n", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = q.channel.PublishWithContext(ctx,
+		shared.RabbitDeadLetter, // exchange
+		q.options.Plugin,        // routing key
+		false,                   // mandatory
+		false,                   // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    dl.FailedAt,
+			Body:         data,
+		})
+	if err != nil {
+		klog.V(1).Infof("PublishWithContext failed. Err: %v\n", err)
+		return err
+	}
+
+	klog.V(3).Infof("Dead lettered %s event for %s\n", dl.Exchange, q.options.Plugin)
+
+	return nil
+}
+
+/*
+	Consume hands the events in the redrive queue to the handler, one at a time. The replicas
+	of the plugin all consume the queue, each event is handled by one of them.
+*/
+func (q *Queue) Consume(handler *businterfaces.MessageHandler) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.connection == nil || handler == nil {
+		klog.V(1).Infof("Queue is not initialized or handler is nil\n")
+		return ErrInvalidInput
+	}
+
+	channel, err := q.connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+		return err
+	}
+
+	err = channel.Qos(1, 0, false)
+	if err != nil {
+		klog.V(1).Infof("channel.Qos failed. Err: %v\n", err)
+		channel.Close()
+		return err
+	}
+
+	msgs, err := channel.Consume(RedriveName(q.options.Plugin), "", false, false, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("Consume %s failed. Err: %v\n", RedriveName(q.options.Plugin), err)
+		channel.Close()
+		return err
+	}
+
+	go func() {
+		for d := range msgs {
+			err := (*handler).ProcessMessage(d.Body)
+			if err != nil {
+				klog.V(1).Infof("ProcessMessage %s failed. Err: %v\n", RedriveName(q.options.Plugin), err)
+			}
+
+			// failures were already retried and dead-lettered again by the handler
+			err = d.Ack(false)
+			if err != nil {
+				klog.V(1).Infof("Ack failed. Err: %v\n", err)
+			}
+		}
+	}()
+
+	klog.V(4).Infof("Consuming %s\n", RedriveName(q.options.Plugin))
+
+	return nil
+}
+
+// List returns up to limit dead letters without removing them from the queue
+func (q *Queue) List(limit int) ([]*DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.channel == nil {
+		klog.V(1).Infof("Queue is not initialized\n")
+		return nil, ErrInvalidInput
+	}
+
+	deadLetters := make([]*DeadLetter, 0)
+
+	var last *amqp.Delivery
+	for limit <= 0 || len(deadLetters) < limit {
+		delivery, ok, err := q.channel.Get(QueueName(q.options.Plugin), false)
+		if err != nil {
+			klog.V(1).Infof("channel.Get failed. Err: %v\n", err)
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		last = &delivery
+
+		var dl DeadLetter
+		err = json.Unmarshal(delivery.Body, &dl)
+		if err != nil {
+			klog.V(1).Infof("json.Unmarshal failed. Err: %v\n", err)
+			continue
+		}
+		deadLetters = append(deadLetters, &dl)
+	}
+
+	// put everything back
+	if last != nil {
+		err := last.Nack(true, true)
+		if err != nil {
+			klog.V(1).Infof("Nack failed. Err: %v\n", err)
+			return nil, err
+		}
+	}
+
+	return deadLetters, nil
+}
+
+/*
+	Redrive moves up to limit dead letters into the redrive queue of the plugin. A limit of 0
+	re-drives everything currently in the queue. The events wait there when the plugin isn't
+	running. Events that fail again are dead-lettered again and won't be picked up by the same
+	call.
+*/
+func (q *Queue) Redrive(limit int) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.channel == nil {
+		klog.V(1).Infof("Queue is not initialized\n")
+		return 0, ErrInvalidInput
+	}
+
+	queue, err := q.channel.QueueInspect(QueueName(q.options.Plugin))
+	if err != nil {
+		klog.V(1).Infof("QueueInspect failed. Err: %v\n", err)
+		return 0, err
+	}
+	if limit <= 0 || limit > queue.Messages {
+		limit = queue.Messages
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	count := 0
+	for count < limit {
+		delivery, ok, err := q.channel.Get(QueueName(q.options.Plugin), false)
+		if err != nil {
+			klog.V(1).Infof("channel.Get failed. Err: %v\n", err)
+			return count, err
+		}
+		if !ok {
+			break
+		}
+
+		err = q.channel.PublishWithContext(ctx,
+			"",                            // default exchange
+			RedriveName(q.options.Plugin), // routing key
+			false,                         // mandatory
+			false,                         // immediate
+			amqp.Publishing{
+				ContentType:  "application/json",
+				DeliveryMode: amqp.Persistent,
+				Body:         delivery.Body,
+			})
+		if err != nil {
+			klog.V(1).Infof("PublishWithContext failed. Err: %v\n", err)
+			delivery.Nack(false, true)
+			return count, err
+		}
+
+		err = delivery.Ack(false)
+		if err != nil {
+			klog.V(1).Infof("Ack failed. Err: %v\n", err)
+			return count, err
+		}
+		count++
+	}
+
+	klog.V(3).Infof("Re-drove %d dead letters to %s\n", count, q.options.Plugin)
+
+	return count, nil
+}
+
+// Purge drops all dead letters for the plugin
+func (q *Queue) Purge() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.channel == nil {
+		klog.V(1).Infof("Queue is not initialized\n")
+		return 0, ErrInvalidInput
+	}
+
+	count, err := q.channel.QueuePurge(QueueName(q.options.Plugin), false)
+	if err != nil {
+		klog.V(1).Infof("QueuePurge failed. Err: %v\n", err)
+		return 0, err
+	}
+
+	klog.V(3).Infof("Purged %d dead letters for %s\n", count, q.options.Plugin)
+
+	return count, nil
+}
+
+func (q *Queue) Teardown() error {
+	klog.V(6).Infof("Queue.Teardown ENTER\n")
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.connection != nil {
+		err := q.connection.Close()
+		if err != nil {
+			klog.V(1).Infof("connection.Close failed. Err: %v\n", err)
+		}
+	}
+	q.connection = nil
+	q.channel = nil
+
+	klog.V(4).Infof("Queue.Teardown Succeeded\n")
+	klog.V(6).Infof("Queue.Teardown LEAVE\n")
+
+	return nil
+}
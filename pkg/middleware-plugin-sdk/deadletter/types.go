@@ -0,0 +1,71 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package deadletter
+
+import (
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+)
+
+// RetryPolicy controls how many times an event is handed to the plugin before it is dead-lettered
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DeadLetter is an event the plugin failed to process
+type DeadLetter struct {
+	Plugin   string    `json:"plugin"`
+	Exchange string    `json:"exchange"`
+	Payload  []byte    `json:"payload"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+/*
+	Durable queue of dead letters for a plugin
+*/
+type QueueOptions struct {
+	RabbitURI string
+	Plugin    string
+}
+
+type Queue struct {
+	options QueueOptions
+
+	// rabbit
+	connection *amqp.Connection
+	channel    *amqp.Channel
+	mu         sync.Mutex
+}
+
+/*
+	Subscriber handlers
+*/
+type RetryHandlerOptions struct {
+	Name    string
+	Policy  RetryPolicy
+	Handler *businterfaces.MessageHandler
+	Queue   *Queue
+}
+
+type RetryHandler struct {
+	options RetryHandlerOptions
+	handler *businterfaces.MessageHandler
+	queue   *Queue
+}
+
+type RedriveHandlerOptions struct {
+	Handlers map[string]*businterfaces.MessageHandler // re-driven dead letters start over here
+}
+
+type RedriveHandler struct {
+	handlers map[string]*businterfaces.MessageHandler
+}
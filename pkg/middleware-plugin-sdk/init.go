@@ -5,9 +5,12 @@ package middleware
 
 import (
 	"flag"
+	"os"
 	"strconv"
 
 	klog "k8s.io/klog/v2"
+
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
 )
 
 type LogLevel int64
@@ -26,6 +29,12 @@ const (
 type EnterpriseInit struct {
 	LogLevel      LogLevel
 	DebugFilePath string
+
+	// structured logging, see pkg/logging
+	LogFormat         logging.Format // ERI_LOG_FORMAT or text when empty
+	Component         string         // added to every JSON line
+	RedactKeys        []string       // redacted along with logging.DefaultRedactedKeys
+	RedactionDisabled bool
 }
 
 func Init(init EnterpriseInit) {
@@ -39,5 +48,28 @@ func Init(init EnterpriseInit) {
 		flag.Set("logtostderr", "false")
 		flag.Set("log_file", init.DebugFilePath)
 	}
-	flag.Parse()
+
+	if init.LogFormat == "" {
+		init.LogFormat = logging.Format(os.Getenv(logging.EnvFormat))
+	}
+
+	options := logging.Options{
+		Format:            init.LogFormat,
+		Component:         init.Component,
+		RedactKeys:        init.RedactKeys,
+		RedactionDisabled: init.RedactionDisabled,
+	}
+	if init.DebugFilePath != "" && (init.LogFormat == logging.FormatJSON || !init.RedactionDisabled) {
+		file, err := os.OpenFile(init.DebugFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			options.Output = file
+		} else {
+			klog.Errorf("os.OpenFile failed. Err: %v\n", err)
+		}
+	}
+
+	err := logging.Init(options)
+	if err != nil {
+		klog.Errorf("logging.Init failed, keeping the text format. Err: %v\n", err)
+	}
 }
@@ -0,0 +1,44 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package partition
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// defaults
+	DefaultPartitions     int           = 16
+	DefaultHeartbeat      time.Duration = 2 * time.Second
+	DefaultRouterPrefetch int           = 32
+
+	// a replica is considered gone after missing this many heartbeats
+	missedHeartbeats int = 3
+
+	// how long Stop waits for in-flight events
+	drainTimeout time.Duration = 10 * time.Second
+
+	// backoff while the router can't publish into a partition
+	routeInitialBackoff time.Duration = 100 * time.Millisecond
+	routeMaxBackoff     time.Duration = 10 * time.Second
+
+	// backoff while a partition can't hand an event to the plugin or dead-letter it
+	processInitialBackoff time.Duration = time.Second
+	processMaxBackoff     time.Duration = 30 * time.Second
+
+	// header carrying the original exchange on partitioned events
+	headerExchange string = "x-eca-exchange"
+
+	// binding key taking the events of every tenant
+	allTenants string = "#"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrNotConnected the connection to RabbitMQ is closed
+	ErrNotConnected = errors.New("not connected to RabbitMQ")
+)
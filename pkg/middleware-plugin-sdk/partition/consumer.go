@@ -0,0 +1,429 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package partition
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	uuid "github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+)
+
+func New(options ConsumerOptions) (*Consumer, error) {
+	if len(options.RabbitURI) == 0 || len(options.Plugin) == 0 || len(options.Handlers) == 0 {
+		klog.V(1).Infof("RabbitURI, Plugin or Handlers is empty\n")
+		return nil, ErrInvalidInput
+	}
+	if options.Partitions <= 0 {
+		options.Partitions = DefaultPartitions
+	}
+	if options.Heartbeat <= 0 {
+		options.Heartbeat = DefaultHeartbeat
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &Consumer{
+		options:  options,
+		id:       uuid.New().String(),
+		members:  make(map[string]time.Time),
+		owned:    make(map[int]*partitionConsumer),
+		ctx:      ctx,
+		cancel:   cancel,
+		stopPoll: make(chan struct{}),
+	}
+	return c, nil
+}
+
+func (c *Consumer) partitionExchange() string {
+	return c.options.Plugin + ".partitions"
+}
+
+func (c *Consumer) partitionQueue(partition int) string {
+	return fmt.Sprintf("%s.partition.%d", c.options.Plugin, partition)
+}
+
+func (c *Consumer) ingressQueue() string {
+	return c.options.Plugin + ".ingress"
+}
+
+func (c *Consumer) membersExchange() string {
+	return c.options.Plugin + ".members"
+}
+
+func (c *Consumer) Init() error {
+	klog.V(6).Infof("Consumer.Init ENTER\n")
+
+	connection, err := amqp.Dial(c.options.RabbitURI)
+	if err != nil {
+		klog.V(1).Infof("amqp.Dial failed. Err: %v\n", err)
+		klog.V(6).Infof("Consumer.Init LEAVE\n")
+		return err
+	}
+	c.connection = connection
+
+	channel, err := connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+		klog.V(6).Infof("Consumer.Init LEAVE\n")
+		c.teardown()
+		return err
+	}
+	c.channel = channel
+
+	// durable partitions, only one replica consumes a partition at a time
+	err = c.channel.ExchangeDeclare(c.partitionExchange(), amqp.ExchangeDirect, true, false, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("ExchangeDeclare %s failed. Err: %v\n", c.partitionExchange(), err)
+		klog.V(6).Infof("Consumer.Init LEAVE\n")
+		c.teardown()
+		return err
+	}
+
+	for partition := 0; partition < c.options.Partitions; partition++ {
+		err = c.declareQueue(c.partitionQueue(partition), c.partitionExchange(), strconv.Itoa(partition))
+		if err != nil {
+			klog.V(1).Infof("declareQueue %s failed. Err: %v\n", c.partitionQueue(partition), err)
+			klog.V(6).Infof("Consumer.Init LEAVE\n")
+			c.teardown()
+			return err
+		}
+	}
+
+	// every event type shares one ingress queue, so the order between them is kept
+	_, err = c.channel.QueueDeclare(
+		c.ingressQueue(), // name
+		true,             // durable
+		false,            // auto-deleted
+		false,            // exclusive
+		false,            // no-wait
+		amqp.Table{
+			"x-single-active-consumer": true,
+		},
+	)
+	if err != nil {
+		klog.V(1).Infof("QueueDeclare %s failed. Err: %v\n", c.ingressQueue(), err)
+		klog.V(6).Infof("Consumer.Init LEAVE\n")
+		c.teardown()
+		return err
+	}
+
+	// the insight exchanges are routed by tenant
+	keys := c.options.Tenants
+	if len(keys) == 0 {
+		keys = []string{allTenants}
+	}
+
+	for exchange := range c.options.Handlers {
+		err = c.channel.ExchangeDeclare(exchange, amqp.ExchangeTopic, false, true, false, false, nil)
+		if err != nil {
+			klog.V(1).Infof("ExchangeDeclare %s failed. Err: %v\n", exchange, err)
+			klog.V(6).Infof("Consumer.Init LEAVE\n")
+			c.teardown()
+			return err
+		}
+
+		// the ingress queue is durable, drop the binding of a run that took every tenant
+		if len(c.options.Tenants) > 0 {
+			err = c.channel.QueueUnbind(c.ingressQueue(), allTenants, exchange, nil)
+			if err != nil {
+				klog.V(1).Infof("QueueUnbind %s failed. Err: %v\n", exchange, err)
+				klog.V(6).Infof("Consumer.Init LEAVE\n")
+				c.teardown()
+				return err
+			}
+		}
+
+		for _, key := range keys {
+			err = c.channel.QueueBind(c.ingressQueue(), key, exchange, false, nil)
+			if err != nil {
+				klog.V(1).Infof("QueueBind %s to %s failed. Err: %v\n", exchange, key, err)
+				klog.V(6).Infof("Consumer.Init LEAVE\n")
+				c.teardown()
+				return err
+			}
+		}
+	}
+
+	// one replica at a time moves events from the ingress queue into the partitions
+	err = c.startRouter()
+	if err != nil {
+		klog.V(1).Infof("startRouter failed. Err: %v\n", err)
+		klog.V(6).Infof("Consumer.Init LEAVE\n")
+		c.teardown()
+		return err
+	}
+
+	// find the other replicas
+	err = c.startMembership()
+	if err != nil {
+		klog.V(1).Infof("startMembership failed. Err: %v\n", err)
+		klog.V(6).Infof("Consumer.Init LEAVE\n")
+		c.teardown()
+		return err
+	}
+
+	klog.V(4).Infof("Consumer.Init Succeeded\n")
+	klog.V(6).Infof("Consumer.Init LEAVE\n")
+
+	return nil
+}
+
+func (c *Consumer) declareQueue(name, exchange, key string) error {
+	_, err := c.channel.QueueDeclare(
+		name,  // name
+		true,  // durable
+		false, // auto-deleted
+		false, // exclusive
+		false, // no-wait
+		amqp.Table{
+			"x-single-active-consumer": true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return c.channel.QueueBind(name, key, exchange, false, nil)
+}
+
+func (c *Consumer) startMembership() error {
+	err := c.channel.ExchangeDeclare(c.membersExchange(), amqp.ExchangeFanout, false, true, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("ExchangeDeclare %s failed. Err: %v\n", c.membersExchange(), err)
+		return err
+	}
+
+	q, err := c.channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		klog.V(1).Infof("QueueDeclare failed. Err: %v\n", err)
+		return err
+	}
+
+	err = c.channel.QueueBind(q.Name, "", c.membersExchange(), false, nil)
+	if err != nil {
+		klog.V(1).Infof("QueueBind %s failed. Err: %v\n", c.membersExchange(), err)
+		return err
+	}
+
+	msgs, err := c.channel.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("Consume %s failed. Err: %v\n", c.membersExchange(), err)
+		return err
+	}
+
+	c.mu.Lock()
+	c.members[c.id] = time.Now()
+	c.mu.Unlock()
+
+	c.rebalance()
+	c.sendHeartbeat(false)
+
+	go func() {
+		for d := range msgs {
+			var hb heartbeat
+			err := json.Unmarshal(d.Body, &hb)
+			if err != nil {
+				klog.V(1).Infof("json.Unmarshal failed. Err: %v\n", err)
+				continue
+			}
+			if hb.Id == c.id {
+				continue
+			}
+
+			c.mu.Lock()
+			_, known := c.members[hb.Id]
+			if hb.Leaving {
+				delete(c.members, hb.Id)
+			} else {
+				c.members[hb.Id] = time.Now()
+			}
+			c.mu.Unlock()
+
+			if hb.Leaving || !known {
+				klog.V(3).Infof("Replica %s joined or left %s\n", hb.Id, c.options.Plugin)
+				c.rebalance()
+			}
+		}
+	}()
+
+	c.ticker = time.NewTicker(c.options.Heartbeat)
+	go func(stopChan chan struct{}) {
+		for {
+			select {
+			case <-c.ticker.C:
+				c.sendHeartbeat(false)
+				if c.expireMembers() {
+					c.rebalance()
+				}
+			case <-stopChan:
+				return
+			}
+		}
+	}(c.stopPoll)
+
+	return nil
+}
+
+func (c *Consumer) sendHeartbeat(leaving bool) {
+	data, err := json.Marshal(heartbeat{
+		Id:      c.id,
+		Leaving: leaving,
+	})
+	if err != nil {
+		klog.V(1).Infof("json.Marshal failed. Err: %v\n", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.options.Heartbeat)
+	defer cancel()
+
+	err = c.channel.PublishWithContext(ctx, c.membersExchange(), "", false, false, amqp.Publishing{
+		ContentType: "application/json",
+		Body:        data,
+	})
+	if err != nil {
+		klog.V(1).Infof("heartbeat failed. Err: %v\n", err)
+	}
+}
+
+func (c *Consumer) expireMembers() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expired := false
+	deadline := time.Now().Add(-time.Duration(missedHeartbeats) * c.options.Heartbeat)
+	for id, lastSeen := range c.members {
+		if id != c.id && lastSeen.Before(deadline) {
+			klog.V(3).Infof("Replica %s of %s stopped sending heartbeats\n", id, c.options.Plugin)
+			delete(c.members, id)
+			expired = true
+		}
+	}
+	c.members[c.id] = time.Now()
+
+	return expired
+}
+
+// rebalance spreads the partitions evenly over the replicas in ID order
+func (c *Consumer) rebalance() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := make([]string, 0, len(c.members))
+	for id := range c.members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for partition := 0; partition < c.options.Partitions; partition++ {
+		mine := ids[partition%len(ids)] == c.id
+		current := c.owned[partition]
+
+		switch {
+		case mine && current == nil:
+			pc, err := c.startPartition(partition)
+			if err != nil {
+				klog.V(1).Infof("startPartition %d failed. Err: %v\n", partition, err)
+				continue
+			}
+			c.owned[partition] = pc
+		case !mine && current != nil:
+			// finishes the in-flight event before the next replica takes over
+			err := current.channel.Cancel(current.tag, false)
+			if err != nil {
+				klog.V(1).Infof("Cancel %d failed. Err: %v\n", partition, err)
+			}
+			delete(c.owned, partition)
+		}
+	}
+
+	klog.V(4).Infof("Replica %s owns %d of %d partitions across %d replicas\n", c.id, len(c.owned), c.options.Partitions, len(ids))
+}
+
+// GetOwnedPartitions returns the partitions this replica is consuming
+func (c *Consumer) GetOwnedPartitions() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	partitions := make([]int, 0, len(c.owned))
+	for partition := range c.owned {
+		partitions = append(partitions, partition)
+	}
+	sort.Ints(partitions)
+
+	return partitions
+}
+
+// Healthy returns nil while the connection to RabbitMQ is open
+func (c *Consumer) Healthy() error {
+	connection := c.connection
+	if connection == nil || connection.IsClosed() {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+func (c *Consumer) teardown() {
+	if c.connection != nil {
+		err := c.connection.Close()
+		if err != nil {
+			klog.V(1).Infof("connection.Close failed. Err: %v\n", err)
+		}
+	}
+	c.connection = nil
+	c.channel = nil
+}
+
+func (c *Consumer) Stop() error {
+	klog.V(6).Infof("Consumer.Stop ENTER\n")
+
+	// stop routing, an event waiting to be routed goes back to the ingress queue
+	c.cancel()
+
+	// stop heartbeats
+	if c.ticker != nil {
+		c.ticker.Stop()
+		close(c.stopPoll)
+	}
+
+	// hand partitions over right away
+	if c.channel != nil {
+		c.sendHeartbeat(true)
+	}
+
+	c.mu.Lock()
+	stopping := make([]*partitionConsumer, 0, len(c.owned))
+	for partition, pc := range c.owned {
+		err := pc.channel.Cancel(pc.tag, false)
+		if err != nil {
+			klog.V(1).Infof("Cancel %d failed. Err: %v\n", partition, err)
+		}
+		stopping = append(stopping, pc)
+	}
+	c.owned = make(map[int]*partitionConsumer)
+	c.mu.Unlock()
+
+	// let in-flight events finish
+	timeout := time.After(drainTimeout)
+	for _, pc := range stopping {
+		select {
+		case <-pc.done:
+		case <-timeout:
+			klog.V(1).Infof("Timed out waiting for partitions to drain\n")
+		}
+	}
+
+	c.teardown()
+
+	klog.V(4).Infof("Consumer.Stop Succeeded\n")
+	klog.V(6).Infof("Consumer.Stop LEAVE\n")
+
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package partition
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+)
+
+// startRouter moves events from the ingress queue into the partition for their conversation
+func (c *Consumer) startRouter() error {
+	channel, err := c.connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+		return err
+	}
+
+	err = channel.Confirm(false)
+	if err != nil {
+		klog.V(1).Infof("channel.Confirm failed. Err: %v\n", err)
+		return err
+	}
+
+	err = channel.Qos(DefaultRouterPrefetch, 0, false)
+	if err != nil {
+		klog.V(1).Infof("channel.Qos failed. Err: %v\n", err)
+		return err
+	}
+
+	msgs, err := channel.Consume(c.ingressQueue(), "", false, false, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("Consume %s failed. Err: %v\n", c.ingressQueue(), err)
+		return err
+	}
+
+	go func() {
+		for d := range msgs {
+			conversationId := conversationIdFor(d.Body)
+			partition := c.partitionFor(conversationId)
+			klog.V(5).Infof("Routing %s event for %s to partition %d\n", d.Exchange, conversationId, partition)
+
+			// later events wait behind this one so the conversation stays in order
+			backoff := routeInitialBackoff
+			for {
+				err := c.route(channel, d.Exchange, partition, d.Body)
+				if err == nil {
+					break
+				}
+				klog.V(1).Infof("route %s failed. Err: %v\n", d.Exchange, err)
+
+				select {
+				case <-c.ctx.Done():
+					d.Nack(false, true)
+					return
+				case <-time.After(backoff):
+				}
+
+				backoff *= 2
+				if backoff > routeMaxBackoff {
+					backoff = routeMaxBackoff
+				}
+			}
+
+			err := d.Ack(false)
+			if err != nil {
+				klog.V(1).Infof("Ack failed. Err: %v\n", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *Consumer) route(channel *amqp.Channel, exchange string, partition int, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	confirm, err := channel.PublishWithDeferredConfirmWithContext(ctx,
+		c.partitionExchange(),   // exchange
+		strconv.Itoa(partition), // routing key
+		false,                   // mandatory
+		false,                   // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Headers: amqp.Table{
+				headerExchange: exchange,
+			},
+			Body: body,
+		})
+	if err != nil {
+		return err
+	}
+	if !confirm.Wait() {
+		return fmt.Errorf("publish to partition %d was not confirmed", partition)
+	}
+
+	return nil
+}
+
+// startPartition hands the events in a partition to the plugin handlers
+func (c *Consumer) startPartition(partition int) (*partitionConsumer, error) {
+	channel, err := c.connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("connection.Channel failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	// one at a time keeps the hand over to another replica in order
+	err = channel.Qos(1, 0, false)
+	if err != nil {
+		klog.V(1).Infof("channel.Qos failed. Err: %v\n", err)
+		channel.Close()
+		return nil, err
+	}
+
+	tag := fmt.Sprintf("%s-%d", c.id, partition)
+	msgs, err := channel.Consume(c.partitionQueue(partition), tag, false, false, false, false, nil)
+	if err != nil {
+		klog.V(1).Infof("Consume %s failed. Err: %v\n", c.partitionQueue(partition), err)
+		channel.Close()
+		return nil, err
+	}
+
+	pc := &partitionConsumer{
+		channel: channel,
+		tag:     tag,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(pc.done)
+		defer channel.Close()
+
+		backoff := processInitialBackoff
+		for d := range msgs {
+			exchange, _ := d.Headers[headerExchange].(string)
+
+			handler := c.options.Handlers[exchange]
+			if handler == nil {
+				klog.V(1).Infof("No handler for %s on partition %d\n", exchange, partition)
+			} else {
+				// the handler retries inline and dead-letters, an error means the event wasn't recorded
+				err := (*handler).ProcessMessage(d.Body)
+				if err != nil {
+					klog.V(1).Infof("ProcessMessage %s failed. Err: %v\n", exchange, err)
+
+					// back to the head of the partition, later events wait behind it
+					select {
+					case <-c.ctx.Done():
+					case <-time.After(backoff):
+					}
+					backoff *= 2
+					if backoff > processMaxBackoff {
+						backoff = processMaxBackoff
+					}
+
+					err = d.Nack(false, true)
+					if err != nil {
+						klog.V(1).Infof("Nack failed. Err: %v\n", err)
+					}
+					continue
+				}
+			}
+			backoff = processInitialBackoff
+
+			err := d.Ack(false)
+			if err != nil {
+				klog.V(1).Infof("Ack failed. Err: %v\n", err)
+			}
+		}
+		klog.V(4).Infof("Released partition %d\n", partition)
+	}()
+
+	klog.V(4).Infof("Consuming partition %d\n", partition)
+
+	return pc, nil
+}
+
+func (c *Consumer) partitionFor(conversationId string) int {
+	h := fnv.New32a()
+	h.Write([]byte(conversationId))
+	return int(h.Sum32() % uint32(c.options.Partitions))
+}
+
+func conversationIdFor(byData []byte) string {
+	contentType, body, err := codec.Unframe(byData)
+	if err != nil {
+		klog.V(1).Infof("codec.Unframe failed. Err: %v\n", err)
+		return ""
+	}
+	if contentType == codec.ContentTypeProtobuf {
+		var key envelopeKey
+		err = codec.Unmarshal(byData, &key)
+		if err != nil || key.Envelope == nil {
+			klog.V(1).Infof("codec.Unmarshal failed. Err: %v\n", err)
+			return ""
+		}
+		return key.Envelope.ConversationID
+	}
+
+	var key conversationKey
+	err = json.Unmarshal(body, &key)
+	if err != nil {
+		klog.V(1).Infof("json.Unmarshal failed. Err: %v\n", err)
+		return ""
+	}
+
+	switch {
+	case key.Envelope != nil && len(key.Envelope.ConversationID) > 0:
+		return key.Envelope.ConversationID
+	case len(key.ConversationID) > 0:
+		return key.ConversationID
+	case key.InitializationMessage != nil && len(key.InitializationMessage.ConversationID) > 0:
+		return key.InitializationMessage.ConversationID
+	case key.InitializationMessage != nil:
+		return key.InitializationMessage.Message.Data.ConversationID
+	case key.TeardownMessage != nil:
+		return key.TeardownMessage.Message.Data.ConversationID
+	case key.TeardownResult != nil:
+		return key.TeardownResult.ConversationID
+	case len(key.Payload) > 0:
+		return conversationIdFor(key.Payload)
+	}
+
+	return ""
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package partition
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+// ConsumerOptions to init the partitioned consumer, handlers are keyed by exchange name
+type ConsumerOptions struct {
+	RabbitURI  string
+	Plugin     string
+	Partitions int
+	Heartbeat  time.Duration
+	Handlers   map[string]*businterfaces.MessageHandler
+	Tenants    []string // bound on every exchange, empty for every tenant
+}
+
+/*
+	Consumer shares the events for a plugin across all of its replicas.
+
+	Events of every type land in one ingress queue and are routed by conversation into durable
+	partition queues. Only the tenants of the plugin are bound to the ingress queue. Each
+	partition is consumed by a single replica at a time, so all events for a conversation are
+	handled by the same replica in the order they were published. Replicas find each other
+	through heartbeats and split the partitions between them, taking over the partitions of a
+	replica that leaves. An event the handler returns an error for is requeued at the head of
+	its partition.
+*/
+type Consumer struct {
+	options ConsumerOptions
+	id      string
+
+	// rabbit
+	connection *amqp.Connection
+	channel    *amqp.Channel
+
+	// membership
+	members map[string]time.Time
+	owned   map[int]*partitionConsumer
+
+	// housekeeping
+	ctx      context.Context
+	cancel   context.CancelFunc
+	ticker   *time.Ticker
+	stopPoll chan struct{}
+	mu       sync.Mutex
+}
+
+type partitionConsumer struct {
+	channel *amqp.Channel
+	tag     string
+	done    chan struct{}
+}
+
+// heartbeat is sent by every replica on the membership exchange
+type heartbeat struct {
+	Id      string `json:"id"`
+	Leaving bool   `json:"leaving,omitempty"`
+}
+
+// envelopeKey reads the envelope from a protobuf event, which is always field 1
+type envelopeKey struct {
+	Envelope *shared.Envelope
+}
+
+// conversationKey finds the conversation ID in any of the event payloads
+type conversationKey struct {
+	Envelope              *shared.Envelope `json:"envelope"`
+	ConversationID        string           `json:"conversationId"`
+	InitializationMessage *struct {
+		ConversationID string `json:"conversationId"`
+		Message        struct {
+			Data struct {
+				ConversationID string `json:"conversationId"`
+			} `json:"data"`
+		} `json:"message"`
+	} `json:"initializationMessage"`
+	TeardownMessage *struct {
+		Message struct {
+			Data struct {
+				ConversationID string `json:"conversationId"`
+			} `json:"data"`
+		} `json:"message"`
+	} `json:"teardownMessage"`
+	TeardownResult *struct {
+		ConversationID string `json:"conversationId"`
+	} `json:"teardownResult"`
+
+	// events wrapped by the dead letter queue
+	Payload []byte `json:"payload"`
+}
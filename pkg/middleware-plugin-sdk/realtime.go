@@ -4,29 +4,77 @@
 package middleware
 
 import (
-	rabbit "github.com/dvonthenen/rabbitmq-manager/pkg"
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	"fmt"
+	"sort"
+	"strings"
+
 	klog "k8s.io/klog/v2"
 
+	messagebus "github.com/dvonthenen/enterprise-conversation-application/pkg/bus"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	deadletter "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/deadletter"
 	middlewareinterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	partition "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/partition"
 	router "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/router/realtime"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 func NewRealtimeAnalyzer(options RealtimeAnalyzerOption) (*RealtimeAnalyzer, error) {
-	// setup rabbit manager
-	rabbitMgr, err := rabbit.New(rabbitinterfaces.ManagerOptions{
-		RabbitURI: options.RabbitURI,
-	})
-	if err != nil {
-		klog.V(1).Infof("rabbit.New failed. Err: %v\n", err)
-		return nil, err
+	// message bus, picked by the RabbitURI scheme
+	if options.Bus == nil {
+		bus, err := messagebus.New(messagebus.BusOptions{
+			URI: options.RabbitURI,
+		})
+		if err != nil {
+			klog.V(1).Infof("messagebus.New failed. Err: %v\n", err)
+			return nil, err
+		}
+		options.Bus = bus
+	}
+
+	// partitions are durable queues on the broker
+	if options.SharedQueue && !messagebus.IsRabbit(options.RabbitURI) {
+		klog.V(1).Infof("SharedQueue requires a RabbitMQ RabbitURI\n")
+		return nil, ErrSharedQueueRequiresRabbit
+	}
+
+	// events that run out of retries are dead-lettered into a durable queue on the broker
+	if (options.RetryPolicy.MaxAttempts != 0 || len(options.RetryPolicies) > 0) && !messagebus.IsRabbit(options.RabbitURI) {
+		klog.V(1).Infof("RetryPolicy requires a RabbitMQ RabbitURI\n")
+		return nil, ErrRetryRequiresRabbit
+	}
+
+	// retry defaults
+	if len(options.PluginName) == 0 {
+		options.PluginName = deadletter.DefaultPluginName
+	}
+	if options.RetryPolicy.MaxAttempts == 0 {
+		options.RetryPolicy = deadletter.DefaultRetryPolicy()
+	}
+
+	// setup dead letter queue, without RabbitMQ failed events are logged and dropped without retries
+	var deadLetters *deadletter.Queue
+	if messagebus.IsRabbit(options.RabbitURI) {
+		queue, err := deadletter.NewQueue(deadletter.QueueOptions{
+			RabbitURI: options.RabbitURI,
+			Plugin:    options.PluginName,
+		})
+		if err != nil {
+			klog.V(1).Infof("deadletter.NewQueue failed. Err: %v\n", err)
+			return nil, err
+		}
+		deadLetters = queue
 	}
 
 	// create middleware
 	mgr := &RealtimeAnalyzer{
-		rabbitManager: rabbitMgr,
-		callback:      options.Callback,
+		options:     options,
+		bus:         options.Bus,
+		deadLetters: deadLetters,
+		callback:    options.Callback,
+		traces:      tracing.NewConversations(),
 	}
 
 	// set publisher
@@ -40,13 +88,13 @@ func NewRealtimeAnalyzer(options RealtimeAnalyzerOption) (*RealtimeAnalyzer, err
 /*
 	This initializes all of the subscribers to the Symbl Proxy/Dataminer component
 
-	Each rabbit subscriber listens for a specific Symbl derived/discovered conversation insight and
+	Each bus subscriber listens for a specific Symbl derived/discovered conversation insight and
 	is then notified through a callback handler with the original Symbl RealTime API message struct
 */
 func (ma *RealtimeAnalyzer) Init() error {
 	klog.V(6).Infof("NotificationManager.Init ENTER\n")
 
-	type InitFunc func(router.HandlerOptions) *rabbitinterfaces.RabbitMessageHandler
+	type InitFunc func(router.HandlerOptions) *businterfaces.MessageHandler
 	type MyHandler struct {
 		Name string
 		Func InitFunc
@@ -83,33 +131,110 @@ func (ma *RealtimeAnalyzer) Init() error {
 		Func: router.NewConversationTeardownHandler,
 	})
 
+	// failed events end up here
+	if ma.deadLetters != nil {
+		err := ma.deadLetters.Init()
+		if err != nil {
+			klog.V(1).Infof("deadLetters.Init failed. Err: %v\n", err)
+			klog.V(6).Infof("NotificationManager.Init LEAVE\n")
+			return err
+		}
+	}
+
+	handlers := make(map[string]*businterfaces.MessageHandler)
+
 	for _, myHandler := range myHandlers {
 		// create subscriber
 		handler := myHandler.Func(router.HandlerOptions{
-			Manager:  ma.rabbitManager,
+			Bus:      ma.bus,
 			Callback: ma.callback,
+			Sessions: ma.options.Sessions,
+			Traces:   ma.traces,
 		})
 
-		_, err := (*ma.rabbitManager).CreateSubscriber(rabbitinterfaces.SubscriberOptions{
-			Name:        myHandler.Name,
-			Type:        rabbitinterfaces.ExchangeTypeFanout,
-			AutoDeleted: true,
-			IfUnused:    true,
-			Handler:     handler,
+		// retry then dead letter failed events
+		if ma.deadLetters != nil {
+			policy, ok := ma.options.RetryPolicies[myHandler.Name]
+			if !ok {
+				policy = ma.options.RetryPolicy
+			}
+			handler = deadletter.NewRetryHandler(deadletter.RetryHandlerOptions{
+				Name:    myHandler.Name,
+				Policy:  policy,
+				Handler: handler,
+				Queue:   ma.deadLetters,
+			})
+		}
+
+		handlers[myHandler.Name] = handler
+	}
+
+	// dead letters sent back by the dead letter tool
+	if ma.deadLetters != nil {
+		err := ma.deadLetters.Consume(deadletter.NewRedriveHandler(deadletter.RedriveHandlerOptions{
+			Handlers: handlers,
+		}))
+		if err != nil {
+			klog.V(1).Infof("deadLetters.Consume failed. Err: %v\n", err)
+			klog.V(6).Infof("NotificationManager.Init LEAVE\n")
+			return err
+		}
+	}
+
+	var failed []string
+	if ma.options.SharedQueue {
+		// replicas of this plugin share the events partitioned by conversation
+		consumer, err := partition.New(partition.ConsumerOptions{
+			RabbitURI:  ma.options.RabbitURI,
+			Plugin:     ma.options.PluginName,
+			Partitions: ma.options.Partitions,
+			Handlers:   handlers,
+			Tenants:    ma.options.Tenants,
 		})
 		if err != nil {
-			klog.V(1).Infof("CreateSubscription failed. Err: %v\n", err)
+			klog.V(1).Infof("partition.New failed. Err: %v\n", err)
+			klog.V(6).Infof("NotificationManager.Init LEAVE\n")
+			return err
+		}
+
+		err = consumer.Init()
+		if err != nil {
+			klog.V(1).Infof("consumer.Init failed. Err: %v\n", err)
+			klog.V(6).Infof("NotificationManager.Init LEAVE\n")
+			return err
+		}
+		ma.consumer = consumer
+	} else {
+		// every replica gets every event
+		for name, handler := range handlers {
+			err := (*ma.bus).CreateSubscriber(businterfaces.SubscriberOptions{
+				Name:    name,
+				Type:    businterfaces.ChannelTypeBroadcast,
+				Handler: handler,
+				Keys:    ma.options.Tenants,
+			})
+			if err != nil {
+				klog.V(1).Infof("CreateSubscription failed. Err: %v\n", err)
+				failed = append(failed, name)
+			}
 		}
 	}
 
 	// init the system
-	err := (*ma.rabbitManager).Init()
+	err := (*ma.bus).Init()
 	if err != nil {
-		klog.V(1).Infof("rabbitManager.Init failed. Err: %v\n", err)
+		klog.V(1).Infof("bus.Init failed. Err: %v\n", err)
 		klog.V(6).Infof("NotificationManager.Init LEAVE\n")
 		return err
 	}
 
+	// subscribers that couldn't be created leave the plugin unhealthy
+	sort.Strings(failed)
+	ma.mu.Lock()
+	ma.subscribed = true
+	ma.failed = failed
+	ma.mu.Unlock()
+
 	klog.V(4).Infof("Init Succeeded\n")
 	klog.V(6).Infof("NotificationManager.Init LEAVE\n")
 
@@ -119,16 +244,15 @@ func (ma *RealtimeAnalyzer) Init() error {
 func (ma *RealtimeAnalyzer) PublishMessage(name string, data []byte) error {
 	klog.V(6).Infof("NotificationManager.PublishMessage ENTER\n")
 
-	publisher, err := (*ma.rabbitManager).GetPublisherByName(name)
-	if err != nil {
-		klog.V(1).Infof("GetPublisherByName failed. Err: %v\n", err)
-		klog.V(6).Infof("NotificationManager.PublishMessage LEAVE\n")
-		return err
-	}
+	// the channel is named by the conversationId, continue the trace of its callback
+	data, span := ma.traces.StartNotification(name, ma.options.PluginName, data)
+	defer span.End()
 
-	err = (*publisher).SendMessage(data)
+	err := (*ma.bus).Publish(name, data)
+	metrics.ObserveNotification(metrics.ComponentPlugin, metrics.TransportBus, err)
 	if err != nil {
-		klog.V(1).Infof("SendMessage failed. Err: %v\n", err)
+		tracing.Fail(span, err)
+		klog.V(1).Infof("bus.Publish failed. Err: %v\n", err)
 		klog.V(6).Infof("NotificationManager.PublishMessage LEAVE\n")
 		return err
 	}
@@ -137,12 +261,54 @@ func (ma *RealtimeAnalyzer) PublishMessage(name string, data []byte) error {
 	return nil
 }
 
+// Healthy returns nil while every subscriber is running and the bus is connected
+func (ma *RealtimeAnalyzer) Healthy() error {
+	ma.mu.Lock()
+	subscribed := ma.subscribed
+	failed := ma.failed
+	ma.mu.Unlock()
+
+	if !subscribed {
+		return ErrNotSubscribed
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", ErrSubscriberFailed, strings.Join(failed, ", "))
+	}
+	if ma.consumer != nil {
+		err := ma.consumer.Healthy()
+		if err != nil {
+			return err
+		}
+	}
+	return (*ma.bus).Healthy()
+}
+
 func (ma *RealtimeAnalyzer) Teardown() error {
 	klog.V(6).Infof("NotificationManager.Teardown ENTER\n")
 
-	err := (*ma.rabbitManager).Teardown()
+	ma.mu.Lock()
+	ma.subscribed = false
+	ma.failed = nil
+	ma.mu.Unlock()
+
+	if ma.consumer != nil {
+		err := ma.consumer.Stop()
+		if err != nil {
+			klog.V(1).Infof("consumer.Stop failed. Err: %v\n", err)
+		}
+		ma.consumer = nil
+	}
+
+	if ma.deadLetters != nil {
+		err := ma.deadLetters.Teardown()
+		if err != nil {
+			klog.V(1).Infof("deadLetters.Teardown failed. Err: %v\n", err)
+		}
+	}
+
+	err := (*ma.bus).Teardown()
 	if err != nil {
-		klog.V(1).Infof("rabbitManager.Teardown failed. Err: %v\n", err)
+		klog.V(1).Infof("bus.Teardown failed. Err: %v\n", err)
 		klog.V(6).Infof("NotificationManager.Stop LEAVE\n")
 		return err
 	}
@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewActionItemHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewActionItemHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = ActionItemHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (aih ActionItemHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var air shared.ActionItemResult
+	err := codec.Unmarshal(byData, &air)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[ActionItemHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("ActionItemHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var air shared.ActionItemResult
-	err = json.Unmarshal(byData, &air)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(air.Envelope, shared.RabbitAsyncActionItem)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(air)
 	if err != nil {
-		klog.V(1).Infof("[ActionItemHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("ActionItemHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := aih.traces.StartCallback(air.Envelope, shared.RabbitAsyncActionItem)
+	start := time.Now()
 	err = (*aih.callback).ActionItemResult(&air)
+	metrics.ObserveCallback(shared.RabbitAsyncActionItem, start, err)
+	aih.traces.EndCallback(air.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[ActionItemHandler] Callback succeeded\n")
+		logger.V(5).Info("[ActionItemHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[ActionItemHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[ActionItemHandler] Callback failed", "err", err)
 		return err
 	}
 
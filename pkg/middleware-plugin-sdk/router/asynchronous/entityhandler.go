@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewEntityHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewEntityHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = EntityHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (eh EntityHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var er shared.EntityResult
+	err := codec.Unmarshal(byData, &er)
 	if err != nil {
-		klog.V(1).Infof("[EntityHandler] prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[EntityHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("EntityHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var er shared.EntityResult
-	err = json.Unmarshal(byData, &er)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(er.Envelope, shared.RabbitAsyncEntity)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(er)
 	if err != nil {
-		klog.V(1).Infof("[EntityHandler] json.Unmarshal failed. Err: %v\n", err)
+		logger.V(1).Info("[EntityHandler] prettyjson.Marshal failed", "err", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("EntityHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := eh.traces.StartCallback(er.Envelope, shared.RabbitAsyncEntity)
+	start := time.Now()
 	err = (*eh.callback).EntityResult(&er)
+	metrics.ObserveCallback(shared.RabbitAsyncEntity, start, err)
+	eh.traces.EndCallback(er.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[EntityHandler] Callback succeeded\n")
+		logger.V(5).Info("[EntityHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[EntityHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[EntityHandler] Callback failed", "err", err)
 		return err
 	}
 
@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewFollowUpHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewFollowUpHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = FollowUpHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (fuh FollowUpHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var fur shared.FollowUpResult
+	err := codec.Unmarshal(byData, &fur)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[FollowUpHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("FollowUpHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var fur shared.FollowUpResult
-	err = json.Unmarshal(byData, &fur)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(fur.Envelope, shared.RabbitAsyncFollowUp)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(fur)
 	if err != nil {
-		klog.V(1).Infof("[FollowUpHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("FollowUpHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := fuh.traces.StartCallback(fur.Envelope, shared.RabbitAsyncFollowUp)
+	start := time.Now()
 	err = (*fuh.callback).FollowUpResult(&fur)
+	metrics.ObserveCallback(shared.RabbitAsyncFollowUp, start, err)
+	fuh.traces.EndCallback(fur.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[FollowUpHandler] Callback succeeded\n")
+		logger.V(5).Info("[FollowUpHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[FollowUpHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[FollowUpHandler] Callback failed", "err", err)
 		return err
 	}
 
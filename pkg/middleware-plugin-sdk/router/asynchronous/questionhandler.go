@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewQuestionHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewQuestionHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = QuestionHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (qh QuestionHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var qr shared.QuestionResult
+	err := codec.Unmarshal(byData, &qr)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[QuestionHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("QuestionHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var qr shared.QuestionResult
-	err = json.Unmarshal(byData, &qr)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(qr.Envelope, shared.RabbitAsyncQuestion)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(qr)
 	if err != nil {
-		klog.V(1).Infof("[QuestionHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("QuestionHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := qh.traces.StartCallback(qr.Envelope, shared.RabbitAsyncQuestion)
+	start := time.Now()
 	err = (*qh.callback).QuestionResult(&qr)
+	metrics.ObserveCallback(shared.RabbitAsyncQuestion, start, err)
+	qh.traces.EndCallback(qr.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[QuestionHandler] Callback succeeded\n")
+		logger.V(5).Info("[QuestionHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[QuestionHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[QuestionHandler] Callback failed", "err", err)
 		return err
 	}
 
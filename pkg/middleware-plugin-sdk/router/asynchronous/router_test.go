@@ -0,0 +1,206 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package router
+
+import (
+	"testing"
+	"time"
+
+	messagebus "github.com/dvonthenen/enterprise-conversation-application/pkg/bus"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+// recorder records which callback each event reached
+type recorder struct {
+	called chan string
+}
+
+func (r *recorder) record(name, conversationId string) error {
+	r.called <- name + ":" + conversationId
+	return nil
+}
+
+func (r *recorder) InitializedConversation(ci *shared.InitializationResult) error {
+	return r.record("InitializedConversation", ci.InitializationMessage.ConversationID)
+}
+
+func (r *recorder) MessageResult(mr *shared.MessageResult) error {
+	return r.record("MessageResult", mr.ConversationID)
+}
+
+func (r *recorder) QuestionResult(qr *shared.QuestionResult) error {
+	return r.record("QuestionResult", qr.ConversationID)
+}
+
+func (r *recorder) FollowUpResult(fr *shared.FollowUpResult) error {
+	return r.record("FollowUpResult", fr.ConversationID)
+}
+
+func (r *recorder) ActionItemResult(air *shared.ActionItemResult) error {
+	return r.record("ActionItemResult", air.ConversationID)
+}
+
+func (r *recorder) TopicResult(tr *shared.TopicResult) error {
+	return r.record("TopicResult", tr.ConversationID)
+}
+
+func (r *recorder) TrackerResult(tr *shared.TrackerResult) error {
+	return r.record("TrackerResult", tr.ConversationID)
+}
+
+func (r *recorder) EntityResult(er *shared.EntityResult) error {
+	return r.record("EntityResult", er.ConversationID)
+}
+
+func (r *recorder) TeardownConversation(ct *shared.TeardownResult) error {
+	return r.record("TeardownConversation", ct.TeardownMessage.ConversationID)
+}
+
+func (r *recorder) SetClientPublisher(mp *interfaces.MessagePublisher) {}
+
+func newBus(t *testing.T) *businterfaces.Bus {
+	bus, err := messagebus.New(messagebus.BusOptions{
+		URI: "inproc://",
+	})
+	if err != nil {
+		t.Fatalf("bus.New failed. Err: %v", err)
+	}
+	return bus
+}
+
+func TestHandlers(t *testing.T) {
+	var callback interfaces.AsynchronousCallback
+	rec := &recorder{
+		called: make(chan string, 1),
+	}
+	callback = rec
+
+	// the dataminer and the plugin each get their own bus on the shared broker
+	dataminer := newBus(t)
+	plugin := newBus(t)
+	defer (*dataminer).Teardown()
+	defer (*plugin).Teardown()
+
+	options := HandlerOptions{
+		Bus:      plugin,
+		Callback: &callback,
+	}
+
+	// in conversation order, teardown removes the publisher created by init
+	tests := []struct {
+		exchange string
+		handler  *businterfaces.MessageHandler
+		payload  string
+		want     string
+	}{
+		{
+			exchange: shared.RabbitAsyncConversationInit,
+			handler:  NewConversationInitHandler(options),
+			payload:  `{"initializationMessage":{"conversationId":"c1"}}`,
+			want:     "InitializedConversation:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncMessage,
+			handler:  NewMessageHandler(options),
+			payload:  `{"conversationId":"c1","messageResult":{}}`,
+			want:     "MessageResult:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncQuestion,
+			handler:  NewQuestionHandler(options),
+			payload:  `{"conversationId":"c1","questionResult":{}}`,
+			want:     "QuestionResult:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncFollowUp,
+			handler:  NewFollowUpHandler(options),
+			payload:  `{"conversationId":"c1","followUpResult":{}}`,
+			want:     "FollowUpResult:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncActionItem,
+			handler:  NewActionItemHandler(options),
+			payload:  `{"conversationId":"c1","acitonItemResult":{}}`,
+			want:     "ActionItemResult:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncTopic,
+			handler:  NewTopicHandler(options),
+			payload:  `{"conversationId":"c1","topicResult":{}}`,
+			want:     "TopicResult:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncTracker,
+			handler:  NewTrackerHandler(options),
+			payload:  `{"conversationId":"c1","trackerResult":{}}`,
+			want:     "TrackerResult:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncEntity,
+			handler:  NewEntityHandler(options),
+			payload:  `{"conversationId":"c1","entityResult":{}}`,
+			want:     "EntityResult:c1",
+		},
+		{
+			exchange: shared.RabbitAsyncConversationTeardown,
+			handler:  NewConversationTeardownHandler(options),
+			payload:  `{"teardownResult":{"conversationId":"c1"}}`,
+			want:     "TeardownConversation:c1",
+		},
+	}
+
+	for _, tt := range tests {
+		err := (*plugin).CreateSubscriber(businterfaces.SubscriberOptions{
+			Name:    tt.exchange,
+			Type:    businterfaces.ChannelTypeBroadcast,
+			Handler: tt.handler,
+		})
+		if err != nil {
+			t.Fatalf("CreateSubscriber %s failed. Err: %v", tt.exchange, err)
+		}
+
+		err = (*dataminer).CreatePublisher(businterfaces.PublisherOptions{
+			Name: tt.exchange,
+			Type: businterfaces.ChannelTypeBroadcast,
+		})
+		if err != nil {
+			t.Fatalf("CreatePublisher %s failed. Err: %v", tt.exchange, err)
+		}
+	}
+
+	err := (*plugin).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+	err = (*dataminer).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exchange, func(t *testing.T) {
+			err := (*dataminer).Publish(tt.exchange, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("Publish failed. Err: %v", err)
+			}
+
+			select {
+			case got := <-rec.called:
+				if got != tt.want {
+					t.Errorf("got %s, want %s", got, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %s", tt.want)
+			}
+		})
+	}
+
+	// the conversation publisher is gone after teardown
+	err = (*plugin).DeletePublisher("c1")
+	if err == nil {
+		t.Errorf("publisher for c1 was not deleted by the teardown")
+	}
+}
@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewTopicHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewTopicHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = TopicHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (th TopicHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var tr shared.TopicResult
+	err := codec.Unmarshal(byData, &tr)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[TopicHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TopicHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var tr shared.TopicResult
-	err = json.Unmarshal(byData, &tr)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(tr.Envelope, shared.RabbitAsyncTopic)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(tr)
 	if err != nil {
-		klog.V(1).Infof("[TopicHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("TopicHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := th.traces.StartCallback(tr.Envelope, shared.RabbitAsyncTopic)
+	start := time.Now()
 	err = (*th.callback).TopicResult(&tr)
+	metrics.ObserveCallback(shared.RabbitAsyncTopic, start, err)
+	th.traces.EndCallback(tr.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[TopicHandler] Callback succeeded\n")
+		logger.V(5).Info("[TopicHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[TopicHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[TopicHandler] Callback failed", "err", err)
 		return err
 	}
 
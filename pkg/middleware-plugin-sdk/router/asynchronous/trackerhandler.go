@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewTrackerHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewTrackerHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = TrackerHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (th TrackerHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var tr shared.TrackerResult
+	err := codec.Unmarshal(byData, &tr)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[TrackerHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TrackerHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var tr shared.TrackerResult
-	err = json.Unmarshal(byData, &tr)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(tr.Envelope, shared.RabbitAsyncTracker)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(tr)
 	if err != nil {
-		klog.V(1).Infof("[TrackerHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("TrackerHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := th.traces.StartCallback(tr.Envelope, shared.RabbitAsyncTracker)
+	start := time.Now()
 	err = (*th.callback).TrackerResult(&tr)
+	metrics.ObserveCallback(shared.RabbitAsyncTracker, start, err)
+	th.traces.EndCallback(tr.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[TrackerHandler] Callback succeeded\n")
+		logger.V(5).Info("[TrackerHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[TrackerHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[TrackerHandler] Callback failed", "err", err)
 		return err
 	}
 
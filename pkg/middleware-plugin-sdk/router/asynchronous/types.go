@@ -4,72 +4,85 @@
 package router
 
 import (
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
-
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	database "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/database"
 	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 /*
 	Subscriber handlers
 */
 type HandlerOptions struct {
-	Manager  *rabbitinterfaces.Manager
+	Bus      *businterfaces.Bus
 	Callback *interfaces.AsynchronousCallback
+	Sessions *database.SessionFactory
+	Traces   *tracing.Conversations
 }
 
 type ConversationInitHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
-
-	appMessage *rabbitinterfaces.Publisher
+	traces   *tracing.Conversations
 }
 
 type MessageHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type QuestionHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type FollowUpHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type ActionItemHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type TopicHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type SummaryHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type TrackerHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type EntityHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type SummaryUiHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	traces   *tracing.Conversations
 }
 
 type ConversationTeardownHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.AsynchronousCallback
+	sessions *database.SessionFactory
+	traces   *tracing.Conversations
 }
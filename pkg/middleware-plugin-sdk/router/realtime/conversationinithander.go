@@ -4,67 +4,77 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewConversationInitHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewConversationInitHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = ConversationInitHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (ch ConversationInitHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var ir shared.InitializationResponse
+	err := codec.Unmarshal(byData, &ir)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[InitializationHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("InitializationHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var ir shared.InitializationResponse
-	err = json.Unmarshal(byData, &ir)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(ir.Envelope, shared.RabbitRealTimeConversationInit)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(ir)
 	if err != nil {
-		klog.V(1).Infof("[InitializationHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("InitializationHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	/*
 		Create Application Channel Publisher
 
-		This implements the rabbit channel for sending your High-level Application messages
+		This implements the bus channel for sending your High-level Application messages
 		sent by this component based on the conversationId
 	*/
-	_, err = (*ch.manager).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        ir.InitializationMessage.Message.Data.ConversationID,
-		AutoDeleted: true,
-		IfUnused:    true,
+	err = (*ch.bus).CreatePublisher(businterfaces.PublisherOptions{
+		Name: ir.InitializationMessage.Message.Data.ConversationID,
+		Type: businterfaces.ChannelTypeConversation,
 	})
 	if err == nil {
-		klog.V(4).Infof("[InitializationHandler] CreatePublisher succeeded\n")
+		logger.V(4).Info("[InitializationHandler] CreatePublisher succeeded")
 	} else {
-		klog.V(1).Infof("[InitializationHandler] CreatePublisher failed. Err: %v\n", err)
+		logger.V(1).Info("[InitializationHandler] CreatePublisher failed", "err", err)
 		return err
 	}
 
 	// invoke callback
+	span := ch.traces.StartCallback(ir.Envelope, shared.RabbitRealTimeConversationInit)
+	start := time.Now()
 	err = (*ch.callback).InitializedConversation(&ir)
+	metrics.ObserveCallback(shared.RabbitRealTimeConversationInit, start, err)
+	ch.traces.EndCallback(ir.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[InitializationHandler] Callback succeeded\n")
+		logger.V(5).Info("[InitializationHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[InitializationHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[InitializationHandler] Callback failed", "err", err)
 		return err
 	}
 
@@ -4,65 +4,86 @@
 package router
 
 import (
-	"encoding/json"
+	"context"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewConversationTeardownHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewConversationTeardownHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = ConversationTeardownHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		sessions: options.Sessions,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (ch ConversationTeardownHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var tr shared.TeardownResponse
+	err := codec.Unmarshal(byData, &tr)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[TeardownHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TeardownHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var tr shared.TeardownResponse
-	err = json.Unmarshal(byData, &tr)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(tr.Envelope, shared.RabbitRealTimeConversationTeardown)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(tr)
 	if err != nil {
-		klog.V(1).Infof("[TeardownHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("TeardownHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	/*
 		Delete Application Channel Publisher
 
-		This cleans up the rabbit channel for sending your High-level Application messages
+		This cleans up the bus channel for sending your High-level Application messages
 		sent by this component based on the conversationId
 	*/
-	err = (*ch.manager).DeletePublisher(tr.TeardownMessage.Message.Data.ConversationID)
+	err = (*ch.bus).DeletePublisher(tr.TeardownMessage.Message.Data.ConversationID)
 	if err == nil {
-		klog.V(4).Infof("[TeardownHandler] DeletePublisher succeeded\n")
+		logger.V(4).Info("[TeardownHandler] DeletePublisher succeeded")
 	} else {
-		klog.V(1).Infof("[TeardownHandler] DeletePublisher failed. Err: %v\n", err)
+		logger.V(1).Info("[TeardownHandler] DeletePublisher failed", "err", err)
 		return err
 	}
 
 	// invoke callback
+	span := ch.traces.StartCallback(tr.Envelope, shared.RabbitRealTimeConversationTeardown)
+	start := time.Now()
 	err = (*ch.callback).TeardownConversation(&tr)
+	metrics.ObserveCallback(shared.RabbitRealTimeConversationTeardown, start, err)
+	ch.traces.EndCallback(tr.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[TeardownHandler] Callback succeeded\n")
+		logger.V(5).Info("[TeardownHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[TeardownHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[TeardownHandler] Callback failed", "err", err)
 		return err
 	}
 
+	// the plugin is done with the conversation
+	if ch.sessions != nil {
+		err = ch.sessions.Release(context.Background(), tr.TeardownMessage.Message.Data.ConversationID)
+		if err != nil {
+			logger.V(1).Info("[TeardownHandler] Release failed", "err", err)
+		}
+	}
+
 	return nil
 }
@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewInsightHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewInsightHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = InsightHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (ih InsightHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var ir shared.InsightResponse
+	err := codec.Unmarshal(byData, &ir)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[InsightHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("InsightHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var ir shared.InsightResponse
-	err = json.Unmarshal(byData, &ir)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(ir.Envelope, shared.RabbitRealTimeInsight)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(ir)
 	if err != nil {
-		klog.V(1).Infof("[InsightHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("InsightHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := ih.traces.StartCallback(ir.Envelope, shared.RabbitRealTimeInsight)
+	start := time.Now()
 	err = (*ih.callback).InsightResponseMessage(&ir)
+	metrics.ObserveCallback(shared.RabbitRealTimeInsight, start, err)
+	ih.traces.EndCallback(ir.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[InsightHandler] Callback succeeded\n")
+		logger.V(5).Info("[InsightHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[InsightHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[InsightHandler] Callback failed", "err", err)
 		return err
 	}
 
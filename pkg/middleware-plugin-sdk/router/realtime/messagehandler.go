@@ -4,49 +4,60 @@
 package router
 
 import (
-	"encoding/json"
+	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	klog "k8s.io/klog/v2"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
 )
 
-func NewMessageHandler(options HandlerOptions) *rabbitinterfaces.RabbitMessageHandler {
-	var handler rabbitinterfaces.RabbitMessageHandler
+func NewMessageHandler(options HandlerOptions) *businterfaces.MessageHandler {
+	var handler businterfaces.MessageHandler
 	handler = MessageHandler{
-		manager:  options.Manager,
+		bus:      options.Bus,
 		callback: options.Callback,
+		traces:   options.Traces,
 	}
 	return &handler
 }
 
 func (mh MessageHandler) ProcessMessage(byData []byte) error {
-	// pretty print
-	prettyJson, err := prettyjson.Format(byData)
+	// reform struct
+	var mr shared.MessageResponse
+	err := codec.Unmarshal(byData, &mr)
 	if err != nil {
-		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof("[MessageHandler] codec.Unmarshal failed. Err: %v\n", err)
 		return err
 	}
-	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("MessageHandler:\n%v\n", string(prettyJson))
-	klog.V(6).Infof("-------------------------------\n\n")
 
-	// reform struct
-	var mr shared.MessageResponse
-	err = json.Unmarshal(byData, &mr)
+	// tag the lines with the conversation
+	logger := logging.FromEnvelope(mr.Envelope, shared.RabbitRealTimeMessage)
+
+	// pretty print
+	prettyJson, err := prettyjson.Marshal(mr)
 	if err != nil {
-		klog.V(1).Infof("[MessageHandler] json.Unmarshal failed. Err: %v\n", err)
+		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		return err
 	}
+	klog.V(6).Infof("\n\n-------------------------------\n")
+	logger.V(2).Info("MessageHandler", "message", string(prettyJson))
+	klog.V(6).Infof("-------------------------------\n\n")
 
 	// invoke callback
+	span := mh.traces.StartCallback(mr.Envelope, shared.RabbitRealTimeMessage)
+	start := time.Now()
 	err = (*mh.callback).MessageResponseMessage(&mr)
+	metrics.ObserveCallback(shared.RabbitRealTimeMessage, start, err)
+	mh.traces.EndCallback(mr.Envelope, span, err)
 	if err == nil {
-		klog.V(5).Infof("[MessageHandler] Callback succeeded\n")
+		logger.V(5).Info("[MessageHandler] Callback succeeded")
 	} else {
-		klog.V(1).Infof("[MessageHandler] Callback failed. Err: %v\n", err)
+		logger.V(1).Info("[MessageHandler] Callback failed", "err", err)
 		return err
 	}
 
@@ -0,0 +1,198 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package router
+
+import (
+	"testing"
+	"time"
+
+	messagebus "github.com/dvonthenen/enterprise-conversation-application/pkg/bus"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+// recorder records which callback each event reached
+type recorder struct {
+	called chan string
+}
+
+func (r *recorder) record(name, conversationId string) error {
+	r.called <- name + ":" + conversationId
+	return nil
+}
+
+func (r *recorder) InitializedConversation(im *shared.InitializationResponse) error {
+	return r.record("InitializedConversation", im.InitializationMessage.Message.Data.ConversationID)
+}
+
+func (r *recorder) RecognitionResultMessage(rr *shared.RecognitionResponse) error {
+	return r.record("RecognitionResultMessage", rr.ConversationID)
+}
+
+func (r *recorder) MessageResponseMessage(mr *shared.MessageResponse) error {
+	return r.record("MessageResponseMessage", mr.ConversationID)
+}
+
+func (r *recorder) InsightResponseMessage(ir *shared.InsightResponse) error {
+	return r.record("InsightResponseMessage", ir.ConversationID)
+}
+
+func (r *recorder) TopicResponseMessage(tr *shared.TopicResponse) error {
+	return r.record("TopicResponseMessage", tr.ConversationID)
+}
+
+func (r *recorder) TrackerResponseMessage(tr *shared.TrackerResponse) error {
+	return r.record("TrackerResponseMessage", tr.ConversationID)
+}
+
+func (r *recorder) EntityResponseMessage(er *shared.EntityResponse) error {
+	return r.record("EntityResponseMessage", er.ConversationID)
+}
+
+func (r *recorder) TeardownConversation(tm *shared.TeardownResponse) error {
+	return r.record("TeardownConversation", tm.TeardownMessage.Message.Data.ConversationID)
+}
+
+func (r *recorder) UserDefinedMessage(data []byte) error {
+	return r.record("UserDefinedMessage", "")
+}
+
+func (r *recorder) UnhandledMessage(byMsg []byte) error {
+	return r.record("UnhandledMessage", "")
+}
+
+func (r *recorder) SetClientPublisher(mp *interfaces.MessagePublisher) {}
+
+func newBus(t *testing.T) *businterfaces.Bus {
+	bus, err := messagebus.New(messagebus.BusOptions{
+		URI: "inproc://",
+	})
+	if err != nil {
+		t.Fatalf("bus.New failed. Err: %v", err)
+	}
+	return bus
+}
+
+func TestHandlers(t *testing.T) {
+	var callback interfaces.InsightCallback
+	rec := &recorder{
+		called: make(chan string, 1),
+	}
+	callback = rec
+
+	// the dataminer and the plugin each get their own bus on the shared broker
+	dataminer := newBus(t)
+	plugin := newBus(t)
+	defer (*dataminer).Teardown()
+	defer (*plugin).Teardown()
+
+	options := HandlerOptions{
+		Bus:      plugin,
+		Callback: &callback,
+	}
+
+	// in conversation order, teardown removes the publisher created by init
+	tests := []struct {
+		exchange string
+		handler  *businterfaces.MessageHandler
+		payload  string
+		want     string
+	}{
+		{
+			exchange: shared.RabbitRealTimeConversationInit,
+			handler:  NewConversationInitHandler(options),
+			payload:  `{"initializationMessage":{"message":{"data":{"conversationId":"c1"}}}}`,
+			want:     "InitializedConversation:c1",
+		},
+		{
+			exchange: shared.RabbitRealTimeMessage,
+			handler:  NewMessageHandler(options),
+			payload:  `{"conversationId":"c1","messageResponse":{"type":"message_response"}}`,
+			want:     "MessageResponseMessage:c1",
+		},
+		{
+			exchange: shared.RabbitRealTimeInsight,
+			handler:  NewInsightHandler(options),
+			payload:  `{"conversationId":"c1","insightResponse":{"type":"insight_response"}}`,
+			want:     "InsightResponseMessage:c1",
+		},
+		{
+			exchange: shared.RabbitRealTimeTopic,
+			handler:  NewTopicHandler(options),
+			payload:  `{"conversationId":"c1","topicResponse":{"type":"topic_response"}}`,
+			want:     "TopicResponseMessage:c1",
+		},
+		{
+			exchange: shared.RabbitRealTimeTracker,
+			handler:  NewTrackerHandler(options),
+			payload:  `{"conversationId":"c1","trackerResponse":{"type":"tracker_response"}}`,
+			want:     "TrackerResponseMessage:c1",
+		},
+		{
+			exchange: shared.RabbitRealTimeEntity,
+			handler:  NewEntityHandler(options),
+			payload:  `{"conversationId":"c1","entityResponse":{"type":"entity_response"}}`,
+			want:     "EntityResponseMessage:c1",
+		},
+		{
+			exchange: shared.RabbitRealTimeConversationTeardown,
+			handler:  NewConversationTeardownHandler(options),
+			payload:  `{"teardownMessage":{"message":{"data":{"conversationId":"c1"}}}}`,
+			want:     "TeardownConversation:c1",
+		},
+	}
+
+	for _, tt := range tests {
+		err := (*plugin).CreateSubscriber(businterfaces.SubscriberOptions{
+			Name:    tt.exchange,
+			Type:    businterfaces.ChannelTypeBroadcast,
+			Handler: tt.handler,
+		})
+		if err != nil {
+			t.Fatalf("CreateSubscriber %s failed. Err: %v", tt.exchange, err)
+		}
+
+		err = (*dataminer).CreatePublisher(businterfaces.PublisherOptions{
+			Name: tt.exchange,
+			Type: businterfaces.ChannelTypeBroadcast,
+		})
+		if err != nil {
+			t.Fatalf("CreatePublisher %s failed. Err: %v", tt.exchange, err)
+		}
+	}
+
+	err := (*plugin).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+	err = (*dataminer).Init()
+	if err != nil {
+		t.Fatalf("Init failed. Err: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exchange, func(t *testing.T) {
+			err := (*dataminer).Publish(tt.exchange, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("Publish failed. Err: %v", err)
+			}
+
+			select {
+			case got := <-rec.called:
+				if got != tt.want {
+					t.Errorf("got %s, want %s", got, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %s", tt.want)
+			}
+		})
+	}
+
+	// the conversation publisher is gone after teardown
+	err = (*plugin).DeletePublisher("c1")
+	if err == nil {
+		t.Errorf("publisher for c1 was not deleted by the teardown")
+	}
+}
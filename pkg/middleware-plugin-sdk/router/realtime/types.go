@@ -4,52 +4,61 @@
 package router
 
 import (
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
-
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	database "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/database"
 	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 /*
 	Subscriber handlers
 */
 type HandlerOptions struct {
-	Manager  *rabbitinterfaces.Manager
+	Bus      *businterfaces.Bus
 	Callback *interfaces.InsightCallback
+	Sessions *database.SessionFactory
+	Traces   *tracing.Conversations
 }
 
 type ConversationInitHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.InsightCallback
-
-	appMessage *rabbitinterfaces.Publisher
+	traces   *tracing.Conversations
 }
 
 type EntityHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.InsightCallback
+	traces   *tracing.Conversations
 }
 
 type InsightHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.InsightCallback
+	traces   *tracing.Conversations
 }
 
 type MessageHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.InsightCallback
+	traces   *tracing.Conversations
 }
 
 type TopicHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.InsightCallback
+	traces   *tracing.Conversations
 }
 
 type TrackerHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.InsightCallback
+	traces   *tracing.Conversations
 }
 
 type ConversationTeardownHandler struct {
-	manager  *rabbitinterfaces.Manager
+	bus      *businterfaces.Bus
 	callback *interfaces.InsightCallback
+	sessions *database.SessionFactory
+	traces   *tracing.Conversations
 }
@@ -4,9 +4,14 @@
 package middleware
 
 import (
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	"sync"
 
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	database "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/database"
+	deadletter "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/deadletter"
 	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/interfaces"
+	partition "github.com/dvonthenen/enterprise-conversation-application/pkg/middleware-plugin-sdk/partition"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 /*
@@ -14,15 +19,43 @@ import (
 */
 type RealtimeAnalyzerOption struct {
 	RabbitURI string
+	Bus       *businterfaces.Bus // defaults to the bus for the RabbitURI scheme
 	Callback  *interfaces.InsightCallback
+
+	// conversation sessions handed out by the factory are released on teardown
+	Sessions *database.SessionFactory
+
+	// retries and dead letters, policies are keyed by exchange name and need a RabbitMQ RabbitURI
+	PluginName    string
+	RetryPolicy   deadletter.RetryPolicy
+	RetryPolicies map[string]deadletter.RetryPolicy
+
+	// replicas share events partitioned by conversation instead of each getting a copy
+	SharedQueue bool
+	Partitions  int
+
+	// only the events of these tenants are routed to the plugin, empty for every tenant
+	Tenants []string
 }
 
 type RealtimeAnalyzer struct {
-	// rabbit
-	rabbitManager *rabbitinterfaces.Manager
+	options RealtimeAnalyzerOption
+
+	// message bus
+	bus         *businterfaces.Bus
+	deadLetters *deadletter.Queue
+	consumer    *partition.Consumer
 
 	// callback
 	callback *interfaces.InsightCallback
+
+	// tracing
+	traces *tracing.Conversations
+
+	// subscribers, see Healthy
+	subscribed bool
+	failed     []string
+	mu         sync.Mutex
 }
 
 /*
@@ -30,13 +63,41 @@ type RealtimeAnalyzer struct {
 */
 type AsynchronousAnalyzerOption struct {
 	RabbitURI string
+	Bus       *businterfaces.Bus // defaults to the bus for the RabbitURI scheme
 	Callback  *interfaces.AsynchronousCallback
+
+	// conversation sessions handed out by the factory are released on teardown
+	Sessions *database.SessionFactory
+
+	// retries and dead letters, policies are keyed by exchange name and need a RabbitMQ RabbitURI
+	PluginName    string
+	RetryPolicy   deadletter.RetryPolicy
+	RetryPolicies map[string]deadletter.RetryPolicy
+
+	// replicas share events partitioned by conversation instead of each getting a copy
+	SharedQueue bool
+	Partitions  int
+
+	// only the events of these tenants are routed to the plugin, empty for every tenant
+	Tenants []string
 }
 
 type AsynchronousAnalyzer struct {
-	// rabbit
-	rabbitManager *rabbitinterfaces.Manager
+	options AsynchronousAnalyzerOption
+
+	// message bus
+	bus         *businterfaces.Bus
+	deadLetters *deadletter.Queue
+	consumer    *partition.Consumer
 
 	// callback
 	callback *interfaces.AsynchronousCallback
+
+	// tracing
+	traces *tracing.Conversations
+
+	// subscribers, see Healthy
+	subscribed bool
+	failed     []string
+	mu         sync.Mutex
 }
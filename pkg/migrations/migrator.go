@@ -0,0 +1,280 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	sdkinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/streaming/v1/interfaces"
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+func New(options MigratorOptions) (*Migrator, error) {
+	if options.Driver == nil {
+		klog.V(1).Infof("Driver is nil\n")
+		return nil, ErrInvalidInput
+	}
+
+	if len(options.DatabaseName) == 0 {
+		options.DatabaseName = dbconfig.DefaultDatabaseName
+	}
+
+	m := &Migrator{
+		driver:       options.Driver,
+		databaseName: options.DatabaseName,
+		tenant:       options.Tenant,
+	}
+	return m, nil
+}
+
+// Run applies all migrations in order. Each migration is safe to run more than once.
+func (m *Migrator) Run() error {
+	klog.V(6).Infof("Migrator.Run ENTER\n")
+
+	myMigrations := []migration{
+		{
+			Name: "topic-root-words",
+			Func: m.topicRootWords,
+		},
+		{
+			Name: "conversation-tenants",
+			Func: m.conversationTenants,
+		},
+	}
+
+	for _, migration := range myMigrations {
+		klog.V(4).Infof("Running migration %s...\n", migration.Name)
+		err := migration.Func()
+		if err != nil {
+			klog.V(1).Infof("Migration %s failed. Err: %v\n", migration.Name, err)
+			klog.V(6).Infof("Migrator.Run LEAVE\n")
+			return err
+		}
+	}
+
+	klog.V(4).Infof("Migrator.Run Succeeded\n")
+	klog.V(6).Infof("Migrator.Run LEAVE\n")
+
+	return nil
+}
+
+/*
+	topicRootWords moves the rootWords string property on Topic nodes to RootWord nodes.
+
+	The string was built by a buggy join and can't be split back apart, so root words are
+	re-derived from the raw TopicResponse saved on the Topic, or in its RawEvent once the raw
+	payloads have been compacted. The string is only used when it holds a single word.
+*/
+func (m *Migrator) topicRootWords() error {
+	klog.V(6).Infof("Migrator.topicRootWords ENTER\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	session := (*m.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.databaseName})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			findTopicsQuery := utils.ReplaceIndexes(`
+				MATCH (t:Topic)
+				WHERE t.rootWords IS NOT NULL
+				OPTIONAL MATCH (c:Conversation)-[:TOPICS]-(t)
+				WITH t, head(collect(c.#conversation_index#)) AS conversationId
+				OPTIONAL MATCH (r:RawEvent { #raw_event_index#: t.#raw_event_index# })
+				RETURN t.#topic_index# AS topicId, t.rootWords AS rootWords, coalesce(t.raw, r.raw) AS raw, conversationId
+				`)
+			result, err := tx.Run(ctx, findTopicsQuery, nil)
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed find topic objects. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Collect(ctx)
+		})
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteRead failed. Err: %v\n", err)
+		klog.V(6).Infof("Migrator.topicRootWords LEAVE\n")
+		return err
+	}
+
+	records := result.([]*neo4j.Record)
+	klog.V(3).Infof("Found %d topics with a rootWords property\n", len(records))
+
+	for _, record := range records {
+		topicId := recordString(record, "topicId")
+		conversationId := recordString(record, "conversationId")
+		words := rootWords(topicId, recordString(record, "raw"), recordString(record, "rootWords"))
+
+		_, err := session.ExecuteWrite(ctx,
+			func(tx neo4j.ManagedTransaction) (any, error) {
+				for _, word := range words {
+					createRootWordQuery := utils.ReplaceIndexes(`
+						MATCH (t:Topic { #topic_index#: $topic_id })
+						MERGE (r:RootWord { #root_word_index#: $root_word_id })
+							ON CREATE SET
+								r.createdAt = datetime(),
+								r.lastAccessed = datetime()
+							ON MATCH SET
+								r.lastAccessed = datetime()
+						SET r += { #root_word_index#: $root_word_id, text: $text }
+						MERGE (t)-[x:TOPIC_ROOT_WORD { #conversation_index#: $conversation_id }]-(r)
+							ON CREATE SET
+								x.createdAt = datetime(),
+								x.lastAccessed = datetime()
+							ON MATCH SET
+								x.lastAccessed = datetime()
+						SET x += { #conversation_index#: $conversation_id }
+						`)
+					_, err := tx.Run(ctx, createRootWordQuery, map[string]any{
+						"conversation_id": conversationId,
+						"topic_id":        topicId,
+						"root_word_id":    utils.NormalizeId(word),
+						"text":            strings.ToLower(word),
+					})
+					if err != nil {
+						klog.V(1).Infof("neo4j.Run failed create root word object. Err: %v\n", err)
+						return nil, err
+					}
+				}
+
+				removePropertyQuery := utils.ReplaceIndexes(`
+					MATCH (t:Topic { #topic_index#: $topic_id })
+					REMOVE t.rootWords
+					`)
+				result, err := tx.Run(ctx, removePropertyQuery, map[string]any{
+					"topic_id": topicId,
+				})
+				if err != nil {
+					klog.V(1).Infof("neo4j.Run failed remove rootWords property. Err: %v\n", err)
+					return nil, err
+				}
+				return result.Collect(ctx)
+			})
+		if err != nil {
+			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+			klog.V(6).Infof("Migrator.topicRootWords LEAVE\n")
+			return err
+		}
+	}
+
+	klog.V(4).Infof("Migrator.topicRootWords Succeeded\n")
+	klog.V(6).Infof("Migrator.topicRootWords LEAVE\n")
+
+	return nil
+}
+
+/*
+	conversationTenants puts the conversations saved before there were tenants in the tenant of
+	the database, the default tenant for the default database. The trend rollups of that time
+	counted the conversations of every tenant together, they are removed and the next rollup
+	counts them again per tenant.
+*/
+func (m *Migrator) conversationTenants() error {
+	klog.V(6).Infof("Migrator.conversationTenants ENTER\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	session := (*m.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.databaseName})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			if len(m.tenant) > 0 {
+				setTenantQuery := `
+					MATCH (c:Conversation)
+					WHERE c.tenantId IS NULL
+					SET c.tenantId = $tenant_id
+					`
+				_, err := tx.Run(ctx, setTenantQuery, map[string]any{
+					"tenant_id": m.tenant,
+				})
+				if err != nil {
+					klog.V(1).Infof("neo4j.Run failed set conversation tenants. Err: %v\n", err)
+					return nil, err
+				}
+			} else {
+				klog.V(1).Infof("Database %s is shared by several tenants, its conversations without a tenant are left alone\n", m.databaseName)
+			}
+
+			deleteRollupQuery := `
+				MATCH (r:TrendRollup)
+				WHERE r.tenantId IS NULL
+				DETACH DELETE r
+				`
+			result, err := tx.Run(ctx, deleteRollupQuery, nil)
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed delete rollup objects. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+		klog.V(6).Infof("Migrator.conversationTenants LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Migrator.conversationTenants Succeeded\n")
+	klog.V(6).Infof("Migrator.conversationTenants LEAVE\n")
+
+	return nil
+}
+
+// rootWords are the root words of the topic from its raw TopicResponse, or else from the rootWords string
+func rootWords(topicId, raw, rootWords string) []string {
+	words := rootWordsFromRaw(topicId, raw)
+	if len(words) == 0 {
+		words = rootWordsFromString(rootWords)
+	}
+	return words
+}
+
+func rootWordsFromRaw(topicId, raw string) []string {
+	words := make([]string, 0)
+
+	var tr sdkinterfaces.TopicResponse
+	err := json.Unmarshal([]byte(raw), &tr)
+	if err != nil {
+		klog.V(4).Infof("raw for topic %s is not a TopicResponse\n", topicId)
+		return words
+	}
+
+	for _, topic := range tr.Topics {
+		if topic.ID != topicId {
+			continue
+		}
+		for _, word := range topic.RootWords {
+			if len(word.Text) > 0 {
+				words = append(words, word.Text)
+			}
+		}
+	}
+
+	return words
+}
+
+func rootWordsFromString(rootWords string) []string {
+	// async topics stored a placeholder and multiple words were garbled together
+	if len(rootWords) == 0 || rootWords == "TODO" || strings.Contains(rootWords, ",") {
+		return []string{}
+	}
+	return []string{rootWords}
+}
+
+func recordString(record *neo4j.Record, key string) string {
+	value, ok := record.Get(key)
+	if !ok {
+		return ""
+	}
+	str, _ := value.(string)
+	return str
+}
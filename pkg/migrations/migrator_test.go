@@ -0,0 +1,165 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+const topicRaw = `{"type":"topic_response","topics":[
+	{"id":"topic-1","phrases":"pricing model","rootWords":[{"text":"Pricing"},{"text":"Model"}]},
+	{"id":"topic-2","phrases":"renewal","rootWords":[{"text":"Renewal"}]}]}`
+
+func TestRootWords(t *testing.T) {
+	tests := []struct {
+		name      string
+		topicId   string
+		raw       string
+		rootWords string
+		want      []string
+	}{
+		{name: "raw", topicId: "topic-1", raw: topicRaw, rootWords: "TODO", want: []string{"Pricing", "Model"}},
+		{name: "raw of several topics", topicId: "topic-2", raw: topicRaw, rootWords: "renewal", want: []string{"Renewal"}},
+		{name: "topic not in raw", topicId: "topic-3", raw: topicRaw, rootWords: "discount", want: []string{"discount"}},
+		{name: "raw isn't a TopicResponse", topicId: "topic-1", raw: "not json", rootWords: "pricing", want: []string{"pricing"}},
+		{name: "no raw", topicId: "topic-1", rootWords: "pricing", want: []string{"pricing"}},
+		{name: "placeholder", topicId: "topic-1", rootWords: "TODO", want: []string{}},
+		{name: "garbled words", topicId: "topic-1", rootWords: "pricing,model", want: []string{}},
+		{name: "nothing", topicId: "topic-1", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rootWords(tt.topicId, tt.raw, tt.rootWords)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rootWords got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTopicRootWordsTwice needs a Neo4j database, it is skipped without NEO4J_CONNECTION
+func TestTopicRootWordsTwice(t *testing.T) {
+	if len(os.Getenv("NEO4J_CONNECTION")) == 0 {
+		t.Skip("NEO4J_CONNECTION isn't set")
+	}
+
+	config, err := dbconfig.FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed. Err: %v", err)
+	}
+	driver, err := config.NewDriver()
+	if err != nil {
+		t.Fatalf("NewDriver failed. Err: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	defer driver.Close(ctx)
+
+	m, err := New(MigratorOptions{
+		Driver:       &driver,
+		DatabaseName: config.Database(""),
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	// ids of this run so the test leaves the rest of the database alone
+	prefix := fmt.Sprintf("migrations-test-%d-", time.Now().UnixNano())
+	conversationId := prefix + "conversation"
+	topicId := prefix + "topic"
+	word := prefix + "pricing"
+	raw := fmt.Sprintf(`{"topics":[{"id":%q,"rootWords":[{"text":%q},{"text":"Model"}]}]}`, topicId, word)
+
+	session := driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.databaseName})
+	defer session.Close(ctx)
+
+	write := func(query string, params map[string]any) {
+		t.Helper()
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			result, err := tx.Run(ctx, utils.ReplaceIndexes(query), params)
+			if err != nil {
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
+		if err != nil {
+			t.Fatalf("ExecuteWrite failed. Err: %v", err)
+		}
+	}
+
+	params := map[string]any{
+		"conversation_id": conversationId,
+		"topic_id":        topicId,
+		"raw":             raw,
+		"text":            word,
+	}
+	defer write(`
+		MATCH (n)
+		WHERE n.#conversation_index# = $conversation_id OR n.#topic_index# = $topic_id OR (n:RootWord AND n.text = $text)
+		DETACH DELETE n
+		`, params)
+	write(`
+		CREATE (c:Conversation { #conversation_index#: $conversation_id })
+		CREATE (t:Topic { #topic_index#: $topic_id, rootWords: "TODO", raw: $raw })
+		CREATE (c)-[:TOPICS]->(t)
+		`, params)
+
+	// the second run finds the property again, as after a run that stopped halfway
+	for i := 0; i < 2; i++ {
+		write(`
+			MATCH (t:Topic { #topic_index#: $topic_id })
+			SET t.rootWords = "TODO"
+			`, params)
+
+		err = m.topicRootWords()
+		if err != nil {
+			t.Fatalf("topicRootWords failed. Err: %v", err)
+		}
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, utils.ReplaceIndexes(`
+			MATCH (t:Topic { #topic_index#: $topic_id })-[x:TOPIC_ROOT_WORD]-(r:RootWord)
+			RETURN t.rootWords AS rootWords, x.#conversation_index# AS conversationId, r.text AS text
+			`), params)
+		if err != nil {
+			return nil, err
+		}
+		return result.Collect(ctx)
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRead failed. Err: %v", err)
+	}
+
+	var texts []string
+	for _, record := range result.([]*neo4j.Record) {
+		if value, _ := record.Get("rootWords"); value != nil {
+			t.Errorf("topicRootWords left rootWords %v", value)
+		}
+		if got := recordString(record, "conversationId"); got != conversationId {
+			t.Errorf("TOPIC_ROOT_WORD got conversation %s, want %s", got, conversationId)
+		}
+		texts = append(texts, recordString(record, "text"))
+	}
+	sort.Strings(texts)
+
+	want := []string{word, "model"}
+	sort.Strings(want)
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("topicRootWords linked %v, want %v", texts, want)
+	}
+}
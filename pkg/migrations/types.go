@@ -0,0 +1,27 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package migrations
+
+import (
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// MigratorOptions to init the migrator
+type MigratorOptions struct {
+	Driver       *neo4j.DriverWithContext
+	DatabaseName string // dbconfig.DefaultDatabaseName when empty
+	Tenant       string // tenant of the conversations saved without one, see dbconfig.Config.Tenant. Empty leaves them alone
+}
+
+// Migrator upgrades data written by older versions of the dataminers
+type Migrator struct {
+	driver       *neo4j.DriverWithContext
+	databaseName string
+	tenant       string
+}
+
+type migration struct {
+	Name string
+	Func func() error
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package outbox
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// event status
+	StatusPending   string = "pending"
+	StatusPublished string = "published"
+	StatusFailed    string = "failed"
+
+	// relay defaults
+	DefaultPollInterval   time.Duration = 5 * time.Second
+	DefaultBatchSize      int           = 100
+	DefaultMaxAttempts    int           = 10
+	DefaultInitialBackoff time.Duration = time.Second
+	DefaultMaxBackoff     time.Duration = 5 * time.Minute
+	DefaultRetention      time.Duration = 24 * time.Hour
+	DefaultClaimTTL       time.Duration = time.Minute
+
+	// endpoint
+	DefaultOutboxPath string = "/v1/outbox/"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrEventNotFound outbox event was not found
+	ErrEventNotFound = errors.New("outbox event was not found")
+)
@@ -0,0 +1,94 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	klog "k8s.io/klog/v2"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	ServeHTTP exposes the events of the tenant in the X-ERI-TENANT header under DefaultOutboxPath.
+	It trusts the header, so only serve it behind auth.Guard.AdminHandler which sets it to the
+	tenant of the client. The payloads of the failed events are only returned with payload=true.
+		GET  /v1/outbox/lag
+		GET  /v1/outbox/failed?limit=N&payload=true
+		POST /v1/outbox/requeue/[event-id]
+*/
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	action := strings.TrimPrefix(req.URL.Path, DefaultOutboxPath)
+	klog.V(3).Infof("URL: %s\n", req.URL.String())
+	klog.V(3).Infof("Action: %s\n", action)
+
+	tenant := req.Header.Get(shared.HeaderTenant)
+	if len(tenant) == 0 {
+		tenant = shared.DefaultTenant
+	}
+
+	var result any
+	var err error
+
+	switch {
+	case action == "lag":
+		result, err = r.Lag(tenant)
+	case action == "failed":
+		limit := 0
+		if v := req.URL.Query().Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil {
+				str := fmt.Sprintf("Invalid limit: %s\n", v)
+				klog.V(1).Infof(str)
+				http.Error(w, str, http.StatusBadRequest)
+				return
+			}
+		}
+		payloads := req.URL.Query().Get("payload") == "true"
+		result, err = r.FailedEvents(tenant, limit, payloads)
+	case strings.HasPrefix(action, "requeue/"):
+		if req.Method != http.MethodPost {
+			http.Error(w, "requeue requires POST", http.StatusMethodNotAllowed)
+			return
+		}
+		err = r.Requeue(tenant, strings.TrimPrefix(action, "requeue/"))
+		if err == ErrEventNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	default:
+		str := fmt.Sprintf("Unknown outbox action: %s\n", action)
+		klog.V(1).Infof(str)
+		http.Error(w, str, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		str := fmt.Sprintf("Outbox %s failed. Err: %v\n", action, err)
+		klog.V(1).Infof(str)
+		http.Error(w, str, http.StatusInternalServerError)
+		return
+	}
+
+	if result == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		str := fmt.Sprintf("json.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof(str)
+		http.Error(w, str, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package outbox
+
+import (
+	"context"
+	"time"
+
+	uuid "github.com/google/uuid"
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	trace "go.opentelemetry.io/otel/trace"
+	klog "k8s.io/klog/v2"
+
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+// NewEventId creates the id for an event before it is written
+func NewEventId() string {
+	return uuid.New().String()
+}
+
+/*
+	Write runs all statements and records an outbox event in a single transaction. Either
+	everything is saved and the event will be published by the Relay, or nothing is saved and
+	the error is returned to the caller. The event is published to the exchange named by the
+	Type of the envelope and is kept with the conversation and tenant of the envelope.
+*/
+func Write(ctx context.Context, session *neo4j.SessionWithContext, statements []Statement, envelope *shared.Envelope, payload []byte) error {
+	if session == nil || envelope == nil || len(envelope.ID) == 0 || len(envelope.Type) == 0 {
+		klog.V(1).Infof("session or envelope is empty\n")
+		return ErrInvalidInput
+	}
+	eventId := envelope.ID
+	exchange := envelope.Type
+
+	ctx, span := tracing.Tracer().Start(ctx, "neo4j write "+exchange, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	start := time.Now()
+	_, err := (*session).ExecuteWrite(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			for _, statement := range statements {
+				result, err := tx.Run(ctx, statement.Query, statement.Params)
+				if err != nil {
+					klog.V(1).Infof("neo4j.Run failed. Err: %v\n", err)
+					return nil, err
+				}
+				_, err = result.Consume(ctx)
+				if err != nil {
+					klog.V(1).Infof("neo4j.Consume failed. Err: %v\n", err)
+					return nil, err
+				}
+			}
+
+			createEventQuery := utils.ReplaceIndexes(`
+				MERGE (e:OutboxEvent { #event_index#: $event_id })
+					ON CREATE SET
+						e.createdAt = datetime(),
+						e.lastAccessed = datetime()
+					ON MATCH SET
+						e.lastAccessed = datetime()
+				SET e += { #event_index#: $event_id, exchange: $exchange, conversationId: $conversation_id, tenantId: $tenant_id, payload: $payload, status: $status, attempts: 0, timestamp: $timestamp, nextAttemptAt: $timestamp, lastAccessed: datetime() }
+				`)
+			result, err := tx.Run(ctx, createEventQuery, map[string]any{
+				"event_id":        eventId,
+				"exchange":        exchange,
+				"conversation_id": envelope.ConversationID,
+				"tenant_id":       envelope.GetTenant(),
+				"payload":         string(payload),
+				"status":          StatusPending,
+				"timestamp":       time.Now().UnixNano(),
+			})
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed create outbox event. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
+	metrics.ObserveNeo4jWrite(exchange, start, err)
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+		tracing.Fail(span, err)
+		return err
+	}
+
+	klog.V(4).Infof("Outbox event %s recorded for %s\n", eventId, exchange)
+
+	return nil
+}
@@ -0,0 +1,589 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package outbox
+
+import (
+	"context"
+	"time"
+
+	uuid "github.com/google/uuid"
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	trace "go.opentelemetry.io/otel/trace"
+	klog "k8s.io/klog/v2"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	codec "github.com/dvonthenen/enterprise-conversation-application/pkg/codec"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+func NewRelay(options RelayOptions) (*Relay, error) {
+	if options.Driver == nil || options.Bus == nil {
+		klog.V(1).Infof("Driver or Bus is nil\n")
+		return nil, ErrInvalidInput
+	}
+	if !codec.Supported(options.ContentType) {
+		klog.V(1).Infof("ContentType %s is not supported\n", options.ContentType)
+		return nil, codec.ErrUnsupportedContentType
+	}
+	if options.PollInterval == 0 {
+		options.PollInterval = DefaultPollInterval
+	}
+	if options.BatchSize == 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.MaxAttempts == 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.InitialBackoff == 0 {
+		options.InitialBackoff = DefaultInitialBackoff
+	}
+	if options.MaxBackoff == 0 {
+		options.MaxBackoff = DefaultMaxBackoff
+	}
+	if options.Retention == 0 {
+		options.Retention = DefaultRetention
+	}
+	if options.ClaimTTL == 0 {
+		options.ClaimTTL = DefaultClaimTTL
+	}
+
+	r := &Relay{
+		options:    options,
+		id:         uuid.New().String(),
+		driver:     options.Driver,
+		bus:        options.Bus,
+		publishers: make(map[string]bool),
+		ticker:     time.NewTicker(options.PollInterval),
+		stopPoll:   make(chan struct{}),
+		kick:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	return r, nil
+}
+
+func (r *Relay) Start() error {
+	klog.V(6).Infof("Relay.Start ENTER\n")
+
+	relay := func(stopChan chan struct{}) {
+		defer close(r.done)
+
+		for {
+			select {
+			case <-r.ticker.C:
+				r.relayPending()
+				r.cleanup()
+				r.countFailed()
+			case <-r.kick:
+				r.relayPending()
+			case <-stopChan:
+				// one last pass to flush anything recorded during shutdown
+				r.relayPending()
+				return
+			}
+		}
+	}
+	go relay(r.stopPoll)
+
+	klog.V(4).Infof("Relay.Start Succeeded\n")
+	klog.V(6).Infof("Relay.Start LEAVE\n")
+
+	return nil
+}
+
+// Kick wakes up the relay after a transaction commits instead of waiting for the next poll
+func (r *Relay) Kick() {
+	select {
+	case r.kick <- struct{}{}:
+	default:
+	}
+}
+
+// relayPending publishes the pending events of every tenant database
+func (r *Relay) relayPending() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, database := range r.options.Neo4j.Databases() {
+		r.relayDatabase(database)
+	}
+}
+
+func (r *Relay) relayDatabase(database string) {
+	for {
+		events, err := r.claimPending(database)
+		if err != nil {
+			klog.V(1).Infof("claimPending failed. Err: %v\n", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		for _, event := range events {
+			err := r.publish(event)
+			if err != nil {
+				klog.V(1).Infof("publish event %s failed. Err: %v\n", event.EventId, err)
+
+				// only this conversation waits for the backoff, the others carry on
+				err = r.markFailedAttempt(database, event, err)
+				if err != nil {
+					klog.V(1).Infof("markFailedAttempt event %s failed. Err: %v\n", event.EventId, err)
+					return
+				}
+				continue
+			}
+
+			err = r.markPublished(database, event)
+			if err != nil {
+				klog.V(1).Infof("markPublished event %s failed. Err: %v\n", event.EventId, err)
+				return
+			}
+		}
+	}
+}
+
+/*
+	claimPending claims the oldest unpublished event of each conversation, whatever its
+	nextAttemptAt. A conversation whose oldest event is backing off, failed or claimed by
+	another relay is left out, so the events after it wait and are never published ahead of
+	it. The claim keeps the relays of other replicas away from the event for ClaimTTL. The
+	events are locked before the claim is checked again, so two relays can't both take one.
+*/
+func (r *Relay) claimPending(database string) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session := (*r.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: database})
+	defer session.Close(ctx)
+
+	now := time.Now()
+	claimQuery := utils.ReplaceIndexes(`
+		MATCH (e:OutboxEvent)
+		WHERE e.status IN [$pending, $failed]
+		WITH coalesce(e.conversationId, e.#event_index#) AS stream, e
+		ORDER BY e.timestamp ASC
+		WITH stream, collect(e)[0] AS head
+		WHERE head.status = $pending AND head.nextAttemptAt <= $now
+			AND (head.claimedUntil IS NULL OR head.claimedUntil < $now OR head.claimedBy = $relay_id)
+		WITH head
+		ORDER BY head.timestamp ASC
+		LIMIT $limit
+		SET head._lock = true
+		REMOVE head._lock
+		WITH head
+		WHERE head.status = $pending
+			AND (head.claimedUntil IS NULL OR head.claimedUntil < $now OR head.claimedBy = $relay_id)
+		SET head.claimedBy = $relay_id, head.claimedUntil = $claimed_until
+		RETURN head AS e
+		ORDER BY e.timestamp ASC
+		`)
+
+	start := time.Now()
+	result, err := session.ExecuteWrite(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			result, err := tx.Run(ctx, claimQuery, map[string]any{
+				"pending":       StatusPending,
+				"failed":        StatusFailed,
+				"now":           now.UnixNano(),
+				"relay_id":      r.id,
+				"claimed_until": now.Add(r.options.ClaimTTL).UnixNano(),
+				"limit":         r.options.BatchSize,
+			})
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed claim outbox events. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Collect(ctx)
+		})
+	metrics.ObserveNeo4jWrite("outbox-relay", start, err)
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	return toEvents(result.([]*neo4j.Record)), nil
+}
+
+func (r *Relay) publish(event Event) error {
+	if !r.publishers[event.Exchange] {
+		err := (*r.bus).CreatePublisher(businterfaces.PublisherOptions{
+			Name: event.Exchange,
+			Type: businterfaces.ChannelTypeBroadcast,
+		})
+		if err != nil {
+			klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", event.Exchange, err)
+			return err
+		}
+		r.publishers[event.Exchange] = true
+	}
+
+	// the payload is saved encrypted when the Dataminer encrypts the content
+	plaintext, err := r.options.Encryptors.Tenant(event.Tenant).Decrypt(context.Background(), event.Payload)
+	if err != nil {
+		klog.V(1).Infof("Decrypt %s failed. Err: %v\n", event.EventId, err)
+		return err
+	}
+
+	// continue the trace recorded with the event and pass the publish span on to the plugins
+	payload := []byte(plaintext)
+	envelope, err := shared.ReadEnvelope(payload)
+	if err != nil {
+		klog.V(1).Infof("shared.ReadEnvelope %s failed. Err: %v\n", event.EventId, err)
+	}
+	ctx, span := tracing.Tracer().Start(tracing.Extract(context.Background(), envelope), "publish "+event.Exchange, trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	if envelope != nil && span.SpanContext().IsValid() {
+		tracing.Inject(ctx, envelope)
+		payload, err = shared.WrapEnvelope(payload, envelope)
+		if err != nil {
+			klog.V(1).Infof("shared.WrapEnvelope %s failed. Err: %v\n", event.EventId, err)
+			return err
+		}
+	}
+
+	// events are recorded as JSON and encoded for the wire here
+	payload, err = codec.Transcode(event.Exchange, payload, r.options.ContentType)
+	if err != nil {
+		klog.V(1).Infof("codec.Transcode %s failed. Err: %v\n", event.EventId, err)
+		return err
+	}
+
+	// routed by tenant, the plugins only bind the tenants they serve
+	err = (*r.bus).PublishWithKey(event.Exchange, event.Tenant, payload)
+	if err != nil {
+		tracing.Fail(span, err)
+
+		// the channel might be dead, recreate the publisher on the next attempt
+		errDelete := (*r.bus).DeletePublisher(event.Exchange)
+		if errDelete != nil {
+			klog.V(1).Infof("DeletePublisher %s failed. Err: %v\n", event.Exchange, errDelete)
+		}
+		delete(r.publishers, event.Exchange)
+		return err
+	}
+	klog.V(3).Infof("Relay published event %s to %s\n", event.EventId, event.Exchange)
+
+	return nil
+}
+
+func (r *Relay) markPublished(database string, event Event) error {
+	return r.write(database, utils.ReplaceIndexes(`
+		MATCH (e:OutboxEvent { #event_index#: $event_id })
+		SET e += { status: $status, attempts: e.attempts + 1, publishedAt: datetime(), claimedBy: null, claimedUntil: null, lastAccessed: datetime() }
+		`), map[string]any{
+		"event_id": event.EventId,
+		"status":   StatusPublished,
+	})
+}
+
+func (r *Relay) markFailedAttempt(database string, event Event, cause error) error {
+	attempts := event.Attempts + 1
+
+	status, backoff := r.nextAttempt(attempts)
+	if status == StatusFailed {
+		klog.V(1).Infof("Event %s failed after %d attempts\n", event.EventId, attempts)
+	}
+
+	// release the claim, any relay may try again after the backoff
+	return r.write(database, utils.ReplaceIndexes(`
+		MATCH (e:OutboxEvent { #event_index#: $event_id })
+		SET e += { status: $status, attempts: $attempts, lastError: $last_error, nextAttemptAt: $next_attempt_at, claimedBy: null, claimedUntil: null, lastAccessed: datetime() }
+		`), map[string]any{
+		"event_id":        event.EventId,
+		"status":          status,
+		"attempts":        attempts,
+		"last_error":      cause.Error(),
+		"next_attempt_at": time.Now().Add(backoff).UnixNano(),
+	})
+}
+
+// nextAttempt is the status of an event after its failed attempts and how long it backs off
+func (r *Relay) nextAttempt(attempts int64) (string, time.Duration) {
+	status := StatusPending
+	if attempts >= int64(r.options.MaxAttempts) {
+		status = StatusFailed
+	}
+
+	// exponential backoff
+	backoff := r.options.InitialBackoff
+	for i := int64(1); i < attempts && backoff < r.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.options.MaxBackoff {
+		backoff = r.options.MaxBackoff
+	}
+
+	return status, backoff
+}
+
+func (r *Relay) cleanup() {
+	for _, database := range r.options.Neo4j.Databases() {
+		err := r.write(database, `
+			MATCH (e:OutboxEvent { status: $status })
+			WHERE e.timestamp < $before
+			DETACH DELETE e
+			`, map[string]any{
+			"status": StatusPublished,
+			"before": time.Now().Add(-r.options.Retention).UnixNano(),
+		})
+		if err != nil {
+			klog.V(1).Infof("cleanup of %s failed. Err: %v\n", database, err)
+		}
+	}
+}
+
+// countFailed sets metrics.OutboxFailedEvents so a conversation held back by a failed event is noticed without the outbox endpoint
+func (r *Relay) countFailed() {
+	for _, database := range r.options.Neo4j.Databases() {
+		records, err := r.read(database, `
+			MATCH (e:OutboxEvent { status: $status })
+			RETURN count(e) AS failed
+			`, map[string]any{
+			"status": StatusFailed,
+		})
+		if err != nil {
+			klog.V(1).Infof("count failed events of %s failed. Err: %v\n", database, err)
+			continue
+		}
+
+		failed := int64(0)
+		if len(records) > 0 {
+			value, _ := records[0].Get("failed")
+			failed, _ = value.(int64)
+		}
+		metrics.OutboxFailedEvents.WithLabelValues(database).Set(float64(failed))
+	}
+}
+
+// Lag reports the number of events of the tenant not yet published and the age of the oldest one
+func (r *Relay) Lag(tenant string) (*Lag, error) {
+	klog.V(6).Infof("Relay.Lag ENTER\n")
+
+	records, err := r.read(r.options.Neo4j.Database(tenant), `
+		MATCH (e:OutboxEvent)
+		WHERE e.status IN [$pending, $failed] AND coalesce(e.tenantId, $default_tenant) = $tenant_id
+		RETURN
+			count(CASE WHEN e.status = $pending THEN 1 END) AS pending,
+			count(CASE WHEN e.status = $failed THEN 1 END) AS failed,
+			min(CASE WHEN e.status = $pending THEN e.timestamp END) AS oldest
+		`, map[string]any{
+		"pending":        StatusPending,
+		"failed":         StatusFailed,
+		"tenant_id":      tenant,
+		"default_tenant": shared.DefaultTenant,
+	})
+	if err != nil {
+		klog.V(1).Infof("read failed. Err: %v\n", err)
+		klog.V(6).Infof("Relay.Lag LEAVE\n")
+		return nil, err
+	}
+
+	lag := &Lag{}
+	if len(records) > 0 {
+		pending, _ := records[0].Get("pending")
+		lag.Pending, _ = pending.(int64)
+		failed, _ := records[0].Get("failed")
+		lag.Failed, _ = failed.(int64)
+		if oldest, _ := records[0].Get("oldest"); oldest != nil {
+			if timestamp, ok := oldest.(int64); ok {
+				lag.OldestPending = time.Since(time.Unix(0, timestamp))
+			}
+		}
+	}
+
+	klog.V(4).Infof("Relay.Lag Succeeded\n")
+	klog.V(6).Infof("Relay.Lag LEAVE\n")
+
+	return lag, nil
+}
+
+// FailedEvents returns the events of the tenant that ran out of attempts, the payloads are left out unless asked for
+func (r *Relay) FailedEvents(tenant string, limit int, payloads bool) ([]Event, error) {
+	klog.V(6).Infof("Relay.FailedEvents ENTER\n")
+
+	if limit <= 0 {
+		limit = r.options.BatchSize
+	}
+
+	records, err := r.read(r.options.Neo4j.Database(tenant), `
+		MATCH (e:OutboxEvent { status: $status })
+		WHERE coalesce(e.tenantId, $default_tenant) = $tenant_id
+		RETURN e
+		ORDER BY e.timestamp ASC
+		LIMIT $limit
+		`, map[string]any{
+		"status":         StatusFailed,
+		"tenant_id":      tenant,
+		"default_tenant": shared.DefaultTenant,
+		"limit":          limit,
+	})
+	if err != nil {
+		klog.V(1).Infof("read failed. Err: %v\n", err)
+		klog.V(6).Infof("Relay.FailedEvents LEAVE\n")
+		return nil, err
+	}
+
+	events := toEvents(records)
+	for i := range events {
+		if !payloads {
+			events[i].Payload = ""
+			continue
+		}
+		events[i].Payload, err = r.options.Encryptors.Tenant(events[i].Tenant).Decrypt(context.Background(), events[i].Payload)
+		if err != nil {
+			klog.V(1).Infof("Decrypt %s failed. Err: %v\n", events[i].EventId, err)
+			klog.V(6).Infof("Relay.FailedEvents LEAVE\n")
+			return nil, err
+		}
+	}
+
+	klog.V(4).Infof("Relay.FailedEvents Succeeded\n")
+	klog.V(6).Infof("Relay.FailedEvents LEAVE\n")
+
+	return events, nil
+}
+
+// Requeue resets a failed event of the tenant so the relay tries to publish it again
+func (r *Relay) Requeue(tenant, eventId string) error {
+	klog.V(6).Infof("Relay.Requeue ENTER\n")
+
+	records, err := r.read(r.options.Neo4j.Database(tenant), utils.ReplaceIndexes(`
+		MATCH (e:OutboxEvent { #event_index#: $event_id, status: $status })
+		WHERE coalesce(e.tenantId, $default_tenant) = $tenant_id
+		RETURN e
+		`), map[string]any{
+		"event_id":       eventId,
+		"status":         StatusFailed,
+		"tenant_id":      tenant,
+		"default_tenant": shared.DefaultTenant,
+	})
+	if err != nil {
+		klog.V(1).Infof("read failed. Err: %v\n", err)
+		klog.V(6).Infof("Relay.Requeue LEAVE\n")
+		return err
+	}
+	if len(records) == 0 {
+		klog.V(1).Infof("Failed event %s of tenant %s not found\n", eventId, tenant)
+		klog.V(6).Infof("Relay.Requeue LEAVE\n")
+		return ErrEventNotFound
+	}
+
+	err = r.write(r.options.Neo4j.Database(tenant), utils.ReplaceIndexes(`
+		MATCH (e:OutboxEvent { #event_index#: $event_id })
+		SET e += { status: $status, attempts: 0, nextAttemptAt: $now, lastAccessed: datetime() }
+		`), map[string]any{
+		"event_id": eventId,
+		"status":   StatusPending,
+		"now":      time.Now().UnixNano(),
+	})
+	if err != nil {
+		klog.V(1).Infof("write failed. Err: %v\n", err)
+		klog.V(6).Infof("Relay.Requeue LEAVE\n")
+		return err
+	}
+
+	r.Kick()
+
+	klog.V(4).Infof("Relay.Requeue Succeeded\n")
+	klog.V(6).Infof("Relay.Requeue LEAVE\n")
+
+	return nil
+}
+
+func (r *Relay) read(database, query string, params map[string]any) ([]*neo4j.Record, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session := (*r.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: database})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			result, err := tx.Run(ctx, query, params)
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed read outbox events. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Collect(ctx)
+		})
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteRead failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	return result.([]*neo4j.Record), nil
+}
+
+func (r *Relay) write(database, query string, params map[string]any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session := (*r.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: database})
+	defer session.Close(ctx)
+
+	start := time.Now()
+	_, err := session.ExecuteWrite(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			result, err := tx.Run(ctx, query, params)
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed update outbox events. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
+	metrics.ObserveNeo4jWrite("outbox-relay", start, err)
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Relay) Stop() error {
+	klog.V(6).Infof("Relay.Stop ENTER\n")
+
+	// stop thread
+	r.ticker.Stop()
+	close(r.stopPoll)
+	<-r.done
+
+	klog.V(4).Infof("Relay.Stop Succeeded\n")
+	klog.V(6).Infof("Relay.Stop LEAVE\n")
+
+	return nil
+}
+
+func toEvents(records []*neo4j.Record) []Event {
+	events := make([]Event, 0)
+	for _, record := range records {
+		value, _ := record.Get("e")
+		node, ok := value.(neo4j.Node)
+		if !ok {
+			continue
+		}
+
+		event := Event{}
+		event.EventId, _ = node.Props[shared.DatabaseIndexOutboxEvent].(string)
+		event.Exchange, _ = node.Props["exchange"].(string)
+		event.ConversationId, _ = node.Props["conversationId"].(string)
+		event.Tenant, _ = node.Props["tenantId"].(string)
+		if len(event.Tenant) == 0 {
+			event.Tenant = shared.DefaultTenant
+		}
+		event.Payload, _ = node.Props["payload"].(string)
+		event.Status, _ = node.Props["status"].(string)
+		event.Attempts, _ = node.Props["attempts"].(int64)
+		event.LastError, _ = node.Props["lastError"].(string)
+		if timestamp, ok := node.Props["timestamp"].(int64); ok {
+			event.CreatedAt = time.Unix(0, timestamp)
+		}
+		events = append(events, event)
+	}
+	return events
+}
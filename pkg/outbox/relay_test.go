@@ -0,0 +1,301 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+// newRelay returns a relay that is never started
+func newRelay(t *testing.T, options RelayOptions) *Relay {
+	t.Helper()
+
+	if options.Driver == nil {
+		var driver neo4j.DriverWithContext
+		options.Driver = &driver
+	}
+	var bus businterfaces.Bus
+	options.Bus = &bus
+
+	r, err := NewRelay(options)
+	if err != nil {
+		t.Fatalf("NewRelay failed. Err: %v", err)
+	}
+	t.Cleanup(r.ticker.Stop)
+	return r
+}
+
+func TestNextAttempt(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  RelayOptions
+		attempts int64
+		status   string
+		backoff  time.Duration
+	}{
+		{name: "first failure", attempts: 1, status: StatusPending, backoff: time.Second},
+		{name: "doubles", attempts: 2, status: StatusPending, backoff: 2 * time.Second},
+		{name: "doubles again", attempts: 3, status: StatusPending, backoff: 4 * time.Second},
+		{name: "last attempt", attempts: 9, status: StatusPending, backoff: 256 * time.Second},
+		{name: "out of attempts", attempts: 10, status: StatusFailed, backoff: DefaultMaxBackoff},
+		{name: "past the max attempts", attempts: 12, status: StatusFailed, backoff: DefaultMaxBackoff},
+		{name: "capped", options: RelayOptions{InitialBackoff: 3 * time.Second, MaxBackoff: 10 * time.Second}, attempts: 3, status: StatusPending, backoff: 10 * time.Second},
+		{name: "one attempt", options: RelayOptions{MaxAttempts: 1}, attempts: 1, status: StatusFailed, backoff: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, backoff := newRelay(t, tt.options).nextAttempt(tt.attempts)
+			if status != tt.status || backoff != tt.backoff {
+				t.Errorf("nextAttempt(%d) got %s after %v, want %s after %v", tt.attempts, status, backoff, tt.status, tt.backoff)
+			}
+		})
+	}
+}
+
+func TestToEvents(t *testing.T) {
+	created := time.Date(2023, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	record := func(value any) *neo4j.Record {
+		return &neo4j.Record{Keys: []string{"e"}, Values: []any{value}}
+	}
+
+	tests := []struct {
+		name   string
+		record *neo4j.Record
+		want   []Event
+	}{
+		{
+			name: "event",
+			record: record(neo4j.Node{Props: map[string]any{
+				shared.DatabaseIndexOutboxEvent: "event-1",
+				"exchange":                      "topic",
+				"conversationId":                "conversation-1",
+				"tenantId":                      "acme",
+				"payload":                       "{}",
+				"status":                        StatusFailed,
+				"attempts":                      int64(10),
+				"lastError":                     "channel closed",
+				"timestamp":                     created.UnixNano(),
+			}}),
+			want: []Event{{
+				EventId:        "event-1",
+				Exchange:       "topic",
+				ConversationId: "conversation-1",
+				Tenant:         "acme",
+				Payload:        "{}",
+				Status:         StatusFailed,
+				Attempts:       10,
+				LastError:      "channel closed",
+				CreatedAt:      time.Unix(0, created.UnixNano()),
+			}},
+		},
+		{
+			name: "event saved before tenants",
+			record: record(neo4j.Node{Props: map[string]any{
+				shared.DatabaseIndexOutboxEvent: "event-2",
+				"status":                        StatusPending,
+			}}),
+			want: []Event{{EventId: "event-2", Tenant: shared.DefaultTenant, Status: StatusPending}},
+		},
+		{name: "not a node", record: record("event-3"), want: []Event{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toEvents([]*neo4j.Record{tt.record})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toEvents got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClaimPending needs a Neo4j database, it is skipped without NEO4J_CONNECTION
+func TestClaimPending(t *testing.T) {
+	if len(os.Getenv("NEO4J_CONNECTION")) == 0 {
+		t.Skip("NEO4J_CONNECTION isn't set")
+	}
+
+	config, err := dbconfig.FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed. Err: %v", err)
+	}
+	driver, err := config.NewDriver()
+	if err != nil {
+		t.Fatalf("NewDriver failed. Err: %v", err)
+	}
+	defer driver.Close(context.Background())
+
+	options := RelayOptions{
+		Driver:    &driver,
+		Neo4j:     config,
+		BatchSize: 10000, // the events of this test are claimed whatever else is in the database
+	}
+	relay := newRelay(t, options)
+	other := newRelay(t, options)
+	database := config.Database("")
+
+	// ids of this run so the test leaves the rest of the database alone
+	prefix := fmt.Sprintf("outbox-test-%d-", time.Now().UnixNano())
+	id := func(name string) string {
+		return prefix + name
+	}
+
+	now := time.Now()
+	later := now.Add(time.Hour).UnixNano()
+	seed := []struct {
+		name           string
+		conversationId string
+		status         string
+		nextAttemptAt  int64
+		claimedBy      string
+		claimedUntil   int64
+	}{
+		{name: "a1", conversationId: "a", status: StatusPending},
+		{name: "a2", conversationId: "a", status: StatusPending},
+		{name: "b1", conversationId: "b", status: StatusPending, nextAttemptAt: later},
+		{name: "b2", conversationId: "b", status: StatusPending},
+		{name: "c1", conversationId: "c", status: StatusFailed},
+		{name: "c2", conversationId: "c", status: StatusPending},
+		{name: "d1", conversationId: "d", status: StatusPending, claimedBy: "another relay", claimedUntil: later},
+		{name: "e1", status: StatusPending},
+		{name: "f1", conversationId: "f", status: StatusPublished},
+		{name: "f2", conversationId: "f", status: StatusPending},
+	}
+
+	defer relay.write(database, utils.ReplaceIndexes(`
+		MATCH (e:OutboxEvent)
+		WHERE e.#event_index# STARTS WITH $prefix
+		DETACH DELETE e
+		`), map[string]any{"prefix": prefix})
+	for i, event := range seed {
+		conversationId := any(nil)
+		if len(event.conversationId) > 0 {
+			conversationId = id(event.conversationId)
+		}
+		nextAttemptAt := event.nextAttemptAt
+		if nextAttemptAt == 0 {
+			nextAttemptAt = now.UnixNano()
+		}
+		claimedBy := any(nil)
+		claimedUntil := any(nil)
+		if len(event.claimedBy) > 0 {
+			claimedBy, claimedUntil = event.claimedBy, event.claimedUntil
+		}
+
+		err := relay.write(database, utils.ReplaceIndexes(`
+			CREATE (e:OutboxEvent { #event_index#: $event_id })
+			SET e += { exchange: "test", conversationId: $conversation_id, payload: "{}", status: $status, attempts: 0, timestamp: $timestamp, nextAttemptAt: $next_attempt_at, claimedBy: $claimed_by, claimedUntil: $claimed_until }
+			`), map[string]any{
+			"event_id":        id(event.name),
+			"conversation_id": conversationId,
+			"status":          event.status,
+			"timestamp":       now.Add(time.Duration(i-len(seed)) * time.Millisecond).UnixNano(),
+			"next_attempt_at": nextAttemptAt,
+			"claimed_by":      claimedBy,
+			"claimed_until":   claimedUntil,
+		})
+		if err != nil {
+			t.Fatalf("write failed. Err: %v", err)
+		}
+	}
+
+	claim := func(r *Relay) []string {
+		t.Helper()
+		events, err := r.claimPending(database)
+		if err != nil {
+			t.Fatalf("claimPending failed. Err: %v", err)
+		}
+		names := make([]string, 0)
+		for _, event := range events {
+			if strings.HasPrefix(event.EventId, prefix) {
+				names = append(names, strings.TrimPrefix(event.EventId, prefix))
+			}
+		}
+		return names
+	}
+	event := func(name string) Event {
+		return Event{EventId: id(name)}
+	}
+
+	tests := []struct {
+		name   string
+		before func() error
+		relay  *Relay
+		want   []string
+	}{
+		{
+			name:  "the oldest event of each conversation that is due",
+			relay: relay,
+			want:  []string{"a1", "e1", "f2"},
+		},
+		{
+			name:  "claimed by another relay",
+			relay: other,
+			want:  []string{},
+		},
+		{
+			name:  "claimed again by the same relay",
+			relay: relay,
+			want:  []string{"a1", "e1", "f2"},
+		},
+		{
+			name: "the next event once published",
+			before: func() error {
+				return relay.markPublished(database, event("a1"))
+			},
+			relay: relay,
+			want:  []string{"a2", "e1", "f2"},
+		},
+		{
+			name: "the conversation waits for the backoff",
+			before: func() error {
+				return relay.markFailedAttempt(database, event("a2"), errors.New("channel closed"))
+			},
+			relay: other,
+			want:  []string{},
+		},
+		{
+			name: "the claim is released after a failed attempt",
+			before: func() error {
+				return relay.write(database, utils.ReplaceIndexes(`
+					MATCH (e:OutboxEvent { #event_index#: $event_id })
+					SET e.nextAttemptAt = $now
+					`), map[string]any{"event_id": id("a2"), "now": time.Now().UnixNano()})
+			},
+			relay: other,
+			want:  []string{"a2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				err := tt.before()
+				if err != nil {
+					t.Fatalf("before failed. Err: %v", err)
+				}
+			}
+			got := claim(tt.relay)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("claimPending got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
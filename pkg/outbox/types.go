@@ -0,0 +1,82 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package outbox
+
+import (
+	"sync"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+)
+
+// Statement is a single query to run in the same transaction as the outbox event
+type Statement struct {
+	Query  string
+	Params map[string]any
+}
+
+// Event is a message waiting to be (or already) published to the bus
+type Event struct {
+	EventId        string    `json:"eventId"`
+	Exchange       string    `json:"exchange"`
+	ConversationId string    `json:"conversationId,omitempty"`
+	Tenant         string    `json:"tenant"`
+	Payload        string    `json:"payload,omitempty"`
+	Status         string    `json:"status"`
+	Attempts       int64     `json:"attempts"`
+	LastError      string    `json:"lastError,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// Lag describes how far behind the relay is
+type Lag struct {
+	Pending       int64         `json:"pending"`
+	Failed        int64         `json:"failed"`
+	OldestPending time.Duration `json:"oldestPending"`
+}
+
+// RelayOptions to init the relay
+type RelayOptions struct {
+	// objects
+	Driver *neo4j.DriverWithContext
+	Neo4j  *dbconfig.Config // the databases of the tenants, nil only uses dbconfig.DefaultDatabaseName
+	Bus    *businterfaces.Bus
+
+	// wire format for the insight events, defaults to JSON
+	ContentType string
+
+	// decrypts the payloads saved by the Dataminers with the keys of their tenant, nil publishes them as they are saved
+	Encryptors *encryption.Encryptors
+
+	// tuning
+	PollInterval   time.Duration
+	BatchSize      int
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Retention      time.Duration
+	ClaimTTL       time.Duration // how long other relays leave the claimed events alone
+}
+
+// Relay publishes outbox events to the bus
+type Relay struct {
+	options RelayOptions
+	id      string // claims the events this relay publishes
+
+	// objects
+	driver     *neo4j.DriverWithContext
+	bus        *businterfaces.Bus
+	publishers map[string]bool
+
+	// housekeeping
+	ticker   *time.Ticker
+	stopPoll chan struct{}
+	kick     chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+}
@@ -4,22 +4,28 @@
 package instance
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 
-	rabbit "github.com/dvonthenen/rabbitmq-manager/pkg"
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	sdkinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/streaming/v1/interfaces"
 	common "github.com/dvonthenen/websocketproxy/pkg/common"
 	halfproxy "github.com/dvonthenen/websocketproxy/pkg/half-duplex"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	sse "github.com/r3labs/sse/v2"
+	trace "go.opentelemetry.io/otel/trace"
 	klog "k8s.io/klog/v2"
 
+	auth "github.com/dvonthenen/enterprise-conversation-application/pkg/auth"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
 	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/interfaces"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
 	routing "github.com/dvonthenen/enterprise-conversation-application/pkg/proxy-dataminer/routing"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 func New(options ProxyOptions) *Proxy {
@@ -39,6 +45,19 @@ func (p *Proxy) GetProxyPort() int {
 	return p.options.ProxyPort
 }
 
+// GetOwner is the identity that opened the conversation, nil when authentication is off
+func (p *Proxy) GetOwner() *auth.Identity {
+	if p == nil {
+		return nil
+	}
+	return p.options.Owner
+}
+
+// GetTenant is the tenant the conversation belongs to
+func (p *Proxy) GetTenant() string {
+	return p.options.Tenant
+}
+
 func (p *Proxy) GetNotifyPort() int {
 	return p.options.NotifyPort
 }
@@ -46,30 +65,25 @@ func (p *Proxy) GetNotifyPort() int {
 func (p *Proxy) Init() error {
 	klog.V(6).Infof("Proxy.Init ENTER\n")
 
-	// init rabbit manager
-	rabbitMgr, err := rabbit.New(rabbitinterfaces.ManagerOptions{
-		RabbitURI: p.options.RabbitURI,
-	})
-	if err != nil {
-		klog.V(1).Infof("rabbit.New failed. Err: %v\n", err)
+	if p.options.Bus == nil || p.options.TLS == nil {
+		klog.V(1).Infof("Bus or TLS is nil\n")
 		klog.V(6).Infof("Proxy.Init LEAVE\n")
-		return err
+		return ErrInvalidInput
 	}
 
 	/*
 		Create Application Channel Subscriber
 
-		This implements the rabbit channel for receiving your High-level Application messages
+		This implements the channel for receiving your High-level Application messages
 		sent by the Analyzer component
 	*/
-	var rabbitHandler rabbitinterfaces.RabbitMessageHandler
-	rabbitHandler = p
-
-	_, err = (*rabbitMgr).CreateSubscriber(rabbitinterfaces.SubscriberOptions{
-		Name:        p.options.ConversationId,
-		AutoDeleted: true,
-		IfUnused:    true,
-		Handler:     &rabbitHandler,
+	var busHandler businterfaces.MessageHandler
+	busHandler = p
+
+	err := (*p.options.Bus).CreateSubscriber(businterfaces.SubscriberOptions{
+		Name:    p.options.ConversationId,
+		Type:    businterfaces.ChannelTypeConversation,
+		Handler: &busHandler,
 	})
 	if err != nil {
 		klog.V(1).Infof("CreateSubscriber %s failed. Err: %v\n", p.options.ConversationId, err)
@@ -83,14 +97,19 @@ func (p *Proxy) Init() error {
 
 	messageMgr, err := routing.NewHandler(routing.MessageHandlerOptions{
 		ConversationId:       p.options.ConversationId,
+		Tenant:               p.options.Tenant,
 		TranscriptionEnabled: p.options.TranscriptionEnabled,
 		MessagingEnabled:     p.options.MessagingEnabled,
+		RawPolicy:            p.options.RawPolicy,
+		Redaction:            p.options.Redaction,
 		Neo4jMgr:             p.neo4jMgr,
-		RabbitMgr:            rabbitMgr,
+		Encryptor:            p.options.Encryptor,
+		Outbox:               p.options.Outbox,
 		Callback:             &callback,
 	})
 	if err != nil {
 		klog.V(1).Infof("routing.NewHandler failed. Err: %v\n", err)
+		p.deleteSubscriber()
 		klog.V(6).Infof("Proxy.Init LEAVE\n")
 		return err
 	}
@@ -99,20 +118,17 @@ func (p *Proxy) Init() error {
 	err = messageMgr.Init()
 	if err != nil {
 		klog.V(1).Infof("messageMgr.Init failed. Err: %v\n", err)
-		klog.V(6).Infof("Proxy.Init LEAVE\n")
-		return err
-	}
-
-	// init rabbit
-	err = (*rabbitMgr).Init()
-	if err != nil {
-		klog.V(1).Infof("rabbitMgr.Init failed. Err: %v\n", err)
+		errTeardown := messageMgr.Teardown()
+		if errTeardown != nil {
+			klog.V(1).Infof("messageMgr.Teardown() failed. Err: %v\n", errTeardown)
+		}
+		p.deleteSubscriber()
 		klog.V(6).Infof("Proxy.Init LEAVE\n")
 		return err
 	}
 
 	// housekeeping
-	p.rabbitMgr = rabbitMgr
+	p.bus = p.options.Bus
 	p.messageMgr = messageMgr
 
 	klog.V(4).Infof("Proxy.Init Succeeded\n")
@@ -121,6 +137,14 @@ func (p *Proxy) Init() error {
 	return nil
 }
 
+// deleteSubscriber undoes the CreateSubscriber of a failed Init
+func (p *Proxy) deleteSubscriber() {
+	err := (*p.options.Bus).DeleteSubscriber(p.options.ConversationId)
+	if err != nil {
+		klog.V(1).Infof("bus.DeleteSubscriber() failed. Err: %v\n", err)
+	}
+}
+
 func (p *Proxy) Start() error {
 	klog.V(6).Infof("Proxy.Start ENTER\n")
 
@@ -153,12 +177,13 @@ func (p *Proxy) Start() error {
 			})
 			p.proxy = proxy
 
+			// the port is known once redirected to, so it is guarded the same as the entry point
 			p.serverSymbl = &http.Server{
 				Addr:    p.options.ProxyBindAddress,
-				Handler: proxy,
+				Handler: p.options.Guard.Handler(proxy, auth.ActionOpen, p.options.ConversationId, p.options.Owner),
 			}
 
-			err = p.serverSymbl.ListenAndServeTLS(p.options.CrtFile, p.options.KeyFile)
+			err = p.options.TLS.ListenAndServeTLS(p.serverSymbl)
 			if err != nil {
 				klog.V(1).Infof("ListenAndServeTLS server stopped. Err: %v\n", err)
 			}
@@ -198,10 +223,10 @@ func (p *Proxy) Start() error {
 
 				p.notifyServer = &http.Server{
 					Addr:    p.options.NotifyBindAddress,
-					Handler: mux,
+					Handler: p.options.Guard.Handler(mux, auth.ActionObserve, p.options.ConversationId, p.options.Owner),
 				}
 
-				err = p.notifyServer.ListenAndServeTLS(p.options.CrtFile, p.options.KeyFile)
+				err = p.options.TLS.ListenAndServeTLS(p.notifyServer)
 				if err != nil {
 					klog.V(1).Infof("ListenAndServeTLS server stopped. Err: %v\n", err)
 				}
@@ -227,14 +252,21 @@ func (p *Proxy) ProcessMessage(byData []byte) error {
 	*/
 	klog.V(7).Infof(" [x] %s\n", string(byData))
 
+	// finish the trace the plugin added to the notification, if any
+	envelope, _ := shared.ReadEnvelope(byData)
+	_, span := tracing.Tracer().Start(tracing.Extract(context.Background(), envelope), "notify client", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
 	switch p.options.NotifyType {
 	case ClientNotifyTypeWebSocket:
 		if p.options.NotifyType != ClientNotifyTypeWebSocket || p.proxy == nil {
 			return ErrInvalidNotifyConfig
 		}
 		err := p.proxy.SendMessage(byData)
+		metrics.ObserveNotification(shared.SourceProxyDataminer, metrics.TransportWebSocket, err)
 		if err != nil {
 			klog.V(1).Infof("SendMessage failed. Err: %v\n", err)
+			tracing.Fail(span, err)
 		}
 		return err
 	case ClientNotifyTypeServerSendEvent:
@@ -244,6 +276,7 @@ func (p *Proxy) ProcessMessage(byData []byte) error {
 		p.notifySse.Publish("messages", &sse.Event{
 			Data: []byte(byData),
 		})
+		metrics.ObserveNotification(shared.SourceProxyDataminer, metrics.TransportServerSentEvent, nil)
 		return nil
 	default:
 		klog.V(1).Infof("Unknown Message Type: %d\n", p.options.NotifyType)
@@ -276,7 +309,7 @@ func (p *Proxy) SendRecognition(r *interfaces.UserDefinedRecognition) error {
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("SendRecognition:\n%v\n\n", string(prettyJson))
+	logging.Conversation(p.options.ConversationId, interfaces.MessageTypeRecognitionResult).V(2).Info("SendRecognition", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	err = p.proxy.SendMessage(byData)
@@ -315,7 +348,7 @@ func (p *Proxy) SendMessages(m *interfaces.UserDefinedMessages) error {
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("SendMessages:\n%v\n\n", string(prettyJson))
+	logging.Conversation(p.options.ConversationId, interfaces.MessageTypeMessageResponse).V(2).Info("SendMessages", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	err = p.proxy.SendMessage(byData)
@@ -380,16 +413,16 @@ func (p *Proxy) Stop() error {
 	/*
 		Delete Application Channel Subscriber
 
-		This delete the rabbit channel for receiving your High-level Application messages
+		This delete the channel for receiving your High-level Application messages
 		sent by the Analyzer component
 	*/
-	if p.rabbitMgr != nil {
-		err := (*p.rabbitMgr).Teardown()
+	if p.bus != nil {
+		err := (*p.bus).DeleteSubscriber(p.options.ConversationId)
 		if err != nil {
-			klog.V(1).Infof("rabbitMgr.Teardown() failed. Err: %v\n", err)
+			klog.V(1).Infof("bus.DeleteSubscriber() failed. Err: %v\n", err)
 		}
 	}
-	p.rabbitMgr = nil
+	p.bus = nil
 
 	// close HTTP server
 	if p.notifyServer != nil {
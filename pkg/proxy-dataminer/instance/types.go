@@ -6,19 +6,25 @@ package instance
 import (
 	"net/http"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	halfproxy "github.com/dvonthenen/websocketproxy/pkg/half-duplex"
 	wsinterfaces "github.com/dvonthenen/websocketproxy/pkg/interfaces"
 	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	sse "github.com/r3labs/sse/v2"
 
+	auth "github.com/dvonthenen/enterprise-conversation-application/pkg/auth"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
 	routing "github.com/dvonthenen/enterprise-conversation-application/pkg/proxy-dataminer/routing"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
 )
 
 type ProxyOptions struct {
 	// housekeeping
 	ConversationId    string
-	RabbitURI         string
+	Tenant            string
 	ProxyBindAddress  string
 	NotifyBindAddress string
 	RedirectAddress   string
@@ -29,14 +35,22 @@ type ProxyOptions struct {
 	// functionality
 	TranscriptionEnabled bool
 	MessagingEnabled     bool
+	RawPolicy            rawevent.Policy
+	Redaction            *redaction.Options
 
 	// SSL Serve
-	CrtFile string
-	KeyFile string
+	TLS *tlsconfig.Reloader
+
+	// authn/authz, nil Guard lets any client connect
+	Guard *auth.Guard
+	Owner *auth.Identity
 
 	// objects
-	Neo4jMgr *neo4j.SessionWithContext
-	ProxyMgr *wsinterfaces.ManageCallback
+	Neo4jMgr  *neo4j.SessionWithContext
+	Encryptor *encryption.Encryptor
+	ProxyMgr  *wsinterfaces.ManageCallback
+	Outbox    *outbox.Relay
+	Bus       *businterfaces.Bus
 }
 
 type Proxy struct {
@@ -52,8 +66,8 @@ type Proxy struct {
 	serverSymbl *http.Server
 	symblChan   chan struct{}
 
-	// rabbit notifications
-	rabbitMgr *rabbitinterfaces.Manager
+	// application notifications
+	bus *businterfaces.Bus
 
 	// server send events
 	notifySse    *sse.Server
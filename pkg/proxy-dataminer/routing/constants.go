@@ -7,10 +7,21 @@ import (
 	"errors"
 )
 
+const (
+	// response types numbered by Symbl, each has its own sequence numbers
+	sequenceMessage string = "message"
+	sequenceInsight string = "insight"
+	sequenceTracker string = "tracker"
+	sequenceEntity  string = "entity"
+)
+
 var (
 	// ErrInvalidInput required input was not found
 	ErrInvalidInput = errors.New("required input was not found")
 
 	// ErrChannelNotFound rabbit channel was not found
 	ErrChannelNotFound = errors.New("rabbit channel was not found")
+
+	// ErrTenantMismatch the conversation was created by another tenant
+	ErrTenantMismatch = errors.New("conversation belongs to another tenant")
 )
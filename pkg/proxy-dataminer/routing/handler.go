@@ -6,18 +6,21 @@ package routing
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"strings"
 	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	sdkinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/streaming/v1/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
-	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	klog "k8s.io/klog/v2"
 
 	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/interfaces"
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
 )
 
@@ -26,24 +29,46 @@ func NewHandler(options MessageHandlerOptions) (*MessageHandler, error) {
 		klog.V(1).Infof("conversationId is empty\n")
 		return nil, ErrInvalidInput
 	}
+	if len(options.Tenant) == 0 {
+		options.Tenant = shared.DefaultTenant
+	}
+
+	var redactor *redaction.Redactor
+	if options.Redaction != nil {
+		var err error
+		redactor, err = redaction.New(*options.Redaction)
+		if err != nil {
+			klog.V(1).Infof("redaction.New failed. Err: %v\n", err)
+			return nil, err
+		}
+	}
 
 	mh := &MessageHandler{
 		conversationId: options.ConversationId,
+		sequences:      make(map[string]int),
 		callback:       options.Callback,
 		options:        options,
 		neo4jMgr:       options.Neo4jMgr,
-		rabbitMgr:      options.RabbitMgr,
+		encryptor:      options.Encryptor,
+		outbox:         options.Outbox,
+		redactor:       redactor,
+		traceCtx:       context.Background(),
 	}
 	return mh, nil
 }
 
+// SetTraceContext is called by the MessageRouter with the span of the Symbl message about to be handled
+func (mh *MessageHandler) SetTraceContext(ctx context.Context) {
+	mh.traceCtx = ctx
+}
+
 func (mh *MessageHandler) Init() error {
 	klog.V(6).Infof("MessageHandler.Init ENTER\n")
 
-	// init all rabbit channels
-	err := mh.setupRabbitChannels()
+	// another tenant can't stream into an existing conversation
+	err := mh.checkTenant()
 	if err != nil {
-		klog.V(1).Infof("setupRabbitChannels failed. Err: %v\n", err)
+		klog.V(1).Infof("checkTenant failed. Err: %v\n", err)
 		klog.V(6).Infof("MessageHandler.Init LEAVE\n")
 		return err
 	}
@@ -54,88 +79,87 @@ func (mh *MessageHandler) Init() error {
 	return nil
 }
 
-func (mh *MessageHandler) setupRabbitChannels() error {
-	/*
-		Setup Publishers...
-	*/
-	_, err := (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitRealTimeConversationInit,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
+func (mh *MessageHandler) Teardown() error {
+	klog.V(6).Infof("MessageHandler.Teardown ENTER\n")
+	klog.V(4).Infof("MessageHandler.Teardown Succeeded\n") // This Teardown() currently is a NOOP
+	klog.V(6).Infof("MessageHandler.Teardown LEAVE\n")
+
+	return nil
+}
+
+// redact masks the PII in v before it is saved or published, the statements record what was masked
+func (mh *MessageHandler) redact(v any, eventType string) ([]outbox.Statement, error) {
+	report, err := mh.redactor.Redact(v)
 	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitRealTimeConversationInit, err)
-		return err
+		klog.V(1).Infof("Redact failed. Err: %v\n", err)
+		return nil, err
 	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitRealTimeMessage,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
+
+	// the database can't fix messages it only has encrypted
+	statements, err := redaction.EncryptedStatements(mh.traceCtx, mh.neo4jMgr, mh.encryptor, report)
 	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitRealTimeMessage, err)
-		return err
+		klog.V(1).Infof("EncryptedStatements failed. Err: %v\n", err)
+		return nil, err
 	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitRealTimeTopic,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
+	return append(redaction.Statements(report, mh.conversationId, eventType), statements...), nil
+}
+
+// encrypt the content before it is saved, it is kept as it is when encryption is off
+func (mh *MessageHandler) encrypt(content string) (string, error) {
+	content, err := mh.encryptor.Encrypt(mh.traceCtx, content)
 	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitRealTimeTopic, err)
-		return err
+		klog.V(1).Infof("Encrypt failed. Err: %v\n", err)
+		return "", err
 	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitRealTimeTracker,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
+	return content, nil
+}
+
+// rawEvent prepares the encrypted payload for eventType to be saved according to the raw policy
+func (mh *MessageHandler) rawEvent(eventType string, data []byte) (*rawevent.RawEvent, error) {
+	if mh.options.RawPolicy == rawevent.PolicyNone {
+		return rawevent.New(mh.options.RawPolicy, mh.conversationId, eventType, nil), nil
+	}
+
+	raw, err := mh.encrypt(string(data))
 	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitRealTimeTracker, err)
-		return err
+		return nil, err
 	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitRealTimeEntity,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
+	return rawevent.New(mh.options.RawPolicy, mh.conversationId, eventType, []byte(raw)), nil
+}
+
+// commit saves all statements and the event for the exchange in one transaction and lets the relay know
+func (mh *MessageHandler) commit(statements []outbox.Statement, exchange string, data []byte) error {
+	ctx, cancel := context.WithTimeout(mh.traceCtx, 5*time.Second)
+	defer cancel()
+
+	// describe the event next to the payload, including the trace it belongs to
+	eventId := outbox.NewEventId()
+	envelope := shared.NewEnvelope(eventId, exchange, mh.conversationId, shared.SourceProxyDataminer)
+	envelope.Tenant = mh.options.Tenant
+	tracing.Inject(ctx, envelope)
+
+	data, err := shared.WrapEnvelope(data, envelope)
 	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitRealTimeEntity, err)
+		klog.V(1).Infof("shared.WrapEnvelope failed. Err: %v\n", err)
 		return err
 	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitRealTimeInsight,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
+
+	// the event holds the whole payload, it is encrypted like the content
+	payload, err := mh.encrypt(string(data))
 	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitRealTimeInsight, err)
 		return err
 	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitRealTimeConversationTeardown,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
+
+	err = outbox.Write(ctx, mh.neo4jMgr, statements, envelope, []byte(payload))
 	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitRealTimeConversationTeardown, err)
+		klog.V(1).Infof("outbox.Write failed. Err: %v\n", err)
 		return err
 	}
+	logging.Conversation(mh.conversationId, exchange).V(4).Info("Event queued", logging.KeyEventId, eventId)
 
-	return nil
-}
-
-func (mh *MessageHandler) Teardown() error {
-	klog.V(6).Infof("MessageHandler.Teardown ENTER\n")
-	klog.V(4).Infof("MessageHandler.Teardown Succeeded\n") // This Teardown() currently is a NOOP
-	klog.V(6).Infof("MessageHandler.Teardown LEAVE\n")
+	if mh.outbox != nil {
+		mh.outbox.Kick()
+	}
 
 	return nil
 }
@@ -146,6 +170,9 @@ func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.Initializati
 	// set conversation id
 	im.Message.Data.ConversationID = mh.conversationId
 
+	// sent again when the SDK reconnects, the new connection numbers its responses from the start
+	mh.resetSequences()
+
 	data, err := json.Marshal(im)
 	if err != nil {
 		klog.V(1).Infof("json.Marshal failed. Err: %v\n", err)
@@ -161,39 +188,30 @@ func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.Initializati
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("InitializationMessage:\n%v\n\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitRealTimeConversationInit).V(2).Info("InitializationMessage", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
-	// context
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
 
 	// neo4j create conversation object
-	_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-		func(tx neo4j.ManagedTransaction) (any, error) {
-			createConversationQuery := utils.ReplaceIndexes(`
-				MERGE (c:Conversation { #conversation_index#: $conversation_id })
-					ON CREATE SET
-						c.createdAt = datetime(),
-						c.lastAccessed = datetime()
-					ON MATCH SET
-						c.lastAccessed = datetime()
-				SET c = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime() }
-				`)
-			result, err := tx.Run(ctx, createConversationQuery, map[string]any{
-				"conversation_id": mh.conversationId,
-			})
-			if err != nil {
-				klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-				return nil, err
-			}
-			return result.Collect(ctx)
-		})
-	if err != nil {
-		klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-		klog.V(6).Infof("MessageHandler.Init LEAVE\n")
-		return err
-	}
+	createConversationQuery := utils.ReplaceIndexes(`
+		MERGE (c:Conversation { #conversation_index#: $conversation_id })
+			ON CREATE SET
+				c.tenantId = $tenant_id,
+				c.createdAt = datetime(),
+				c.lastAccessed = datetime()
+			ON MATCH SET
+				c.lastAccessed = datetime()
+		SET c += { #conversation_index#: $conversation_id, lastAccessed: datetime() }
+		`)
+	statements = append(statements, outbox.Statement{
+		Query: createConversationQuery,
+		Params: map[string]any{
+			"conversation_id": mh.conversationId,
+			"tenant_id":       mh.options.Tenant,
+		},
+	})
 
 	// rabbitmq
 	wrapperStruct := shared.InitializationResponse{
@@ -207,13 +225,15 @@ func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.Initializati
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitRealTimeConversationInit, data)
+	err = mh.commit(statements, shared.RabbitRealTimeConversationInit, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("InitializedConversation.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("InitializedConversation.Outbox:\n%s\n", string(data))
+
+	metrics.ConversationsStarted.WithLabelValues(shared.SourceProxyDataminer).Inc()
 
 	klog.V(4).Infof("InitializedConversation Succeeded\n")
 	klog.V(6).Infof("InitializedConversation LEAVE\n")
@@ -267,6 +287,12 @@ func (mh *MessageHandler) RecognitionResultMessage(rr *sdkinterfaces.Recognition
 func (mh *MessageHandler) MessageResponseMessage(mr *sdkinterfaces.MessageResponse) error {
 	klog.V(6).Infof("MessageResponseMessage ENTER\n")
 
+	// skip stale or duplicate deliveries
+	if mh.isStale(sequenceMessage, mr.SequenceNumber) {
+		klog.V(6).Infof("MessageResponseMessage LEAVE\n")
+		return nil
+	}
+
 	data, err := json.Marshal(mr)
 	if err != nil {
 		klog.V(1).Infof("MessageResponse json.Marshal failed. Err: %v\n", err)
@@ -282,7 +308,7 @@ func (mh *MessageHandler) MessageResponseMessage(mr *sdkinterfaces.MessageRespon
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("MessageResponseMessage:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitRealTimeMessage).V(2).Info("MessageResponseMessage", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// pass-through?
@@ -319,71 +345,92 @@ func (mh *MessageHandler) MessageResponseMessage(mr *sdkinterfaces.MessageRespon
 		}
 	}
 
-	// write the object to the database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// mask PII before it is saved or published, the pass-through above is the client's own transcript
+	redactions, err := mh.redact(mr, shared.RabbitRealTimeMessage)
+	if err != nil {
+		klog.V(6).Infof("MessageResponseMessage LEAVE\n")
+		return err
+	}
+
+	data, err = json.Marshal(mr)
+	if err != nil {
+		klog.V(1).Infof("MessageResponse json.Marshal failed. Err: %v\n", err)
+		klog.V(6).Infof("MessageResponseMessage LEAVE\n")
+		return err
+	}
+
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy
+	raw, err := mh.rawEvent(shared.RabbitRealTimeMessage, data)
+	if err != nil {
+		klog.V(6).Infof("MessageResponseMessage LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil {
+		statements = append(statements, *statement)
+	}
 
 	// if we need to do something with them
 	// for records, message := range mr.Messages {
 	for _, message := range mr.Messages {
-		_, err := (*mh.neo4jMgr).ExecuteWrite(ctx,
-			func(tx neo4j.ManagedTransaction) (any, error) {
-				createMessageToPeopleQuery := utils.ReplaceIndexes(`
-					MATCH (c:Conversation { #conversation_index#: $conversation_id })
-					MERGE (m:Message { #message_index#: $message_id })
-						ON CREATE SET
-							m.createdAt = datetime(),
-							m.lastAccessed = datetime()
-						ON MATCH SET
-							m.lastAccessed = datetime()
-					SET m = { #message_index#: $message_id, content: $content, startTime: $start_time, endTime: $end_time, timeOffset: $time_offset, duration: $duration, sequenceNumber: $sequence_number, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-					MERGE (u:User { #user_index#: $user_id })
-						ON CREATE SET
-							u.createdAt = datetime(),
-							u.lastAccessed = datetime()
-						ON MATCH SET
-							u.lastAccessed = datetime()
-					SET u = { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, createdAt: datetime(), lastAccessed: datetime() }
-					MERGE (c)-[x:MESSAGES { #conversation_index#: $conversation_id }]-(m)
-						ON CREATE SET
-							x.createdAt = datetime(),
-							x.lastAccessed = datetime()
-						ON MATCH SET
-							x.lastAccessed = datetime()
-					SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-					MERGE (m)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
-						ON CREATE SET
-							y.createdAt = datetime(),
-							y.lastAccessed = datetime()
-						ON MATCH SET
-							y.lastAccessed = datetime()
-					SET y = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-					`)
-				result, err := tx.Run(ctx, createMessageToPeopleQuery, map[string]any{
-					"conversation_id": mh.conversationId,
-					"message_id":      message.ID,
-					"content":         message.Payload.Content,
-					"start_time":      message.Duration.StartTime,
-					"end_time":        message.Duration.EndTime,
-					"time_offset":     message.Duration.TimeOffset,
-					"duration":        message.Duration.Duration,
-					"sequence_number": mr.SequenceNumber,
-					"user_real_id":    message.From.ID,
-					"user_name":       message.From.Name,
-					"user_id":         message.From.UserID,
-					"raw":             string(data),
-				})
-				if err != nil {
-					klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-					return nil, err
-				}
-				return result.Collect(ctx)
-			})
+		content, err := mh.encrypt(message.Payload.Content)
 		if err != nil {
-			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
 			klog.V(6).Infof("MessageResponseMessage LEAVE\n")
 			return err
 		}
+
+		createMessageToPeopleQuery := utils.ReplaceIndexes(`
+			MATCH (c:Conversation { #conversation_index#: $conversation_id })
+			MERGE (m:Message { #message_index#: $message_id })
+				ON CREATE SET
+					m.createdAt = datetime(),
+					m.lastAccessed = datetime()
+				ON MATCH SET
+					m.lastAccessed = datetime()
+			SET m += { #message_index#: $message_id, content: $content, startTime: $start_time, endTime: $end_time, timeOffset: $time_offset, duration: $duration, sequenceNumber: $sequence_number, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+			MERGE (u:User { #user_index#: $user_id })
+				ON CREATE SET
+					u.createdAt = datetime(),
+					u.lastAccessed = datetime()
+				ON MATCH SET
+					u.lastAccessed = datetime()
+			SET u += { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, lastAccessed: datetime() }
+			MERGE (c)-[x:MESSAGES { #conversation_index#: $conversation_id }]-(m)
+				ON CREATE SET
+					x.createdAt = datetime(),
+					x.lastAccessed = datetime()
+				ON MATCH SET
+					x.lastAccessed = datetime()
+			SET x += { #conversation_index#: $conversation_id, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+			MERGE (m)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
+				ON CREATE SET
+					y.createdAt = datetime(),
+					y.lastAccessed = datetime()
+				ON MATCH SET
+					y.lastAccessed = datetime()
+			SET y += { #conversation_index#: $conversation_id, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: createMessageToPeopleQuery,
+			Params: map[string]any{
+				"conversation_id": mh.conversationId,
+				"message_id":      message.ID,
+				"content":         content,
+				"start_time":      message.Duration.StartTime,
+				"end_time":        message.Duration.EndTime,
+				"time_offset":     message.Duration.TimeOffset,
+				"duration":        message.Duration.Duration,
+				"sequence_number": mr.SequenceNumber,
+				"user_real_id":    message.From.ID,
+				"user_name":       message.From.Name,
+				"user_id":         message.From.UserID,
+				"raw":             raw.Inline(),
+				"raw_event_id":    raw.Id(),
+			},
+		})
 	}
 
 	// rabbitmq
@@ -399,13 +446,16 @@ func (mh *MessageHandler) MessageResponseMessage(mr *sdkinterfaces.MessageRespon
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitRealTimeMessage, data)
+	err = mh.commit(statements, shared.RabbitRealTimeMessage, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("MessageResponseMessage.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("MessageResponseMessage.Outbox:\n%s\n", string(data))
+
+	// only advance once the transaction committed
+	mh.applySequence(sequenceMessage, mr.SequenceNumber)
 
 	klog.V(4).Infof("MessageResponseMessage Succeeded\n")
 	klog.V(6).Infof("MessageResponseMessage LEAVE\n")
@@ -414,26 +464,44 @@ func (mh *MessageHandler) MessageResponseMessage(mr *sdkinterfaces.MessageRespon
 }
 
 func (mh *MessageHandler) InsightResponseMessage(ir *sdkinterfaces.InsightResponse) error {
+	// skip stale or duplicate deliveries
+	if mh.isStale(sequenceInsight, ir.SequenceNumber) {
+		return nil
+	}
+
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(ir, shared.RabbitRealTimeInsight)
+	if err != nil {
+		return err
+	}
+
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
 	for _, insight := range ir.Insights {
 		switch insight.Type {
 		case sdkinterfaces.InsightTypeQuestion:
-			err := mh.HandleQuestion(&insight, ir.SequenceNumber)
+			insightStatements, err := mh.HandleQuestion(&insight, ir.SequenceNumber)
 			if err != nil {
 				klog.V(1).Infof("HandleQuestion failed. Err: %v\n", err)
 				return err
 			}
+			statements = append(statements, insightStatements...)
 		case sdkinterfaces.InsightTypeFollowUp:
-			err := mh.HandleFollowUp(&insight, ir.SequenceNumber)
+			insightStatements, err := mh.HandleFollowUp(&insight, ir.SequenceNumber)
 			if err != nil {
 				klog.V(1).Infof("HandleFollowUp failed. Err: %v\n", err)
 				return err
 			}
+			statements = append(statements, insightStatements...)
 		case sdkinterfaces.InsightTypeActionItem:
-			err := mh.HandleActionItem(&insight, ir.SequenceNumber)
+			insightStatements, err := mh.HandleActionItem(&insight, ir.SequenceNumber)
 			if err != nil {
 				klog.V(1).Infof("HandleActionItem failed. Err: %v\n", err)
 				return err
 			}
+			statements = append(statements, insightStatements...)
 		default:
 			data, err := json.Marshal(ir)
 			if err != nil {
@@ -462,13 +530,16 @@ func (mh *MessageHandler) InsightResponseMessage(ir *sdkinterfaces.InsightRespon
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitRealTimeInsight, data)
+	err = mh.commit(statements, shared.RabbitRealTimeInsight, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("handleInsight.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("InsightResponseMessage.Outbox:\n%s\n", string(data))
+
+	// only advance once the transaction committed
+	mh.applySequence(sequenceInsight, ir.SequenceNumber)
 
 	return nil
 }
@@ -476,6 +547,13 @@ func (mh *MessageHandler) InsightResponseMessage(ir *sdkinterfaces.InsightRespon
 func (mh *MessageHandler) TopicResponseMessage(tr *sdkinterfaces.TopicResponse) error {
 	klog.V(6).Infof("TopicResponseMessage ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(tr, shared.RabbitRealTimeTopic)
+	if err != nil {
+		klog.V(6).Infof("TopicResponseMessage LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(tr)
 	if err != nil {
 		klog.V(1).Infof("TopicResponseMessage json.Marshal failed. Err: %v\n", err)
@@ -491,88 +569,124 @@ func (mh *MessageHandler) TopicResponseMessage(tr *sdkinterfaces.TopicResponse)
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TopicResponseMessage:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitRealTimeTopic).V(2).Info("TopicResponseMessage", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
-	// write the object to the database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy
+	raw, err := mh.rawEvent(shared.RabbitRealTimeTopic, data)
+	if err != nil {
+		klog.V(6).Infof("TopicResponseMessage LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil {
+		statements = append(statements, *statement)
+	}
 
 	for _, topic := range tr.Topics {
-		_, err := (*mh.neo4jMgr).ExecuteWrite(ctx,
-			func(tx neo4j.ManagedTransaction) (any, error) {
-				createTopicsQuery := utils.ReplaceIndexes(`
-					MATCH (c:Conversation { #conversation_index#: $conversation_id })
-					MERGE (t:Topic { #topic_index#: $topic_id })
-						ON CREATE SET
-							t.createdAt = datetime(),
-							t.lastAccessed = datetime()
-						ON MATCH SET
-							t.lastAccessed = datetime()
-					SET t = { #topic_index#: $topic_id, phrases: $phrases, score: $score, type: $type, messageIndex: $symbl_message_index, rootWords: $root_words, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-					MERGE (c)-[x:TOPICS { #conversation_index#: $conversation_id }]-(t)
-						ON CREATE SET
-							x.createdAt = datetime(),
-							x.lastAccessed = datetime()
-						ON MATCH SET
-							x.lastAccessed = datetime()
-					SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-					`)
-				result, err := tx.Run(ctx, createTopicsQuery, map[string]any{
-					"conversation_id":     mh.conversationId,
-					"topic_id":            topic.ID,
-					"phrases":             strings.ToLower(topic.Phrases),
-					"score":               topic.Score,
-					"type":                topic.Type,
-					"symbl_message_index": topic.MessageIndex,
-					"root_words":          convertRootWordToString(topic.RootWords),
-					"raw":                 string(data),
-				})
-				if err != nil {
-					klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-					return nil, err
-				}
-				return result.Collect(ctx)
+		createTopicsQuery := utils.ReplaceIndexes(`
+			MATCH (c:Conversation { #conversation_index#: $conversation_id })
+			MERGE (t:Topic { #topic_index#: $topic_id })
+				ON CREATE SET
+					t.createdAt = datetime(),
+					t.lastAccessed = datetime()
+				ON MATCH SET
+					t.lastAccessed = datetime()
+			SET t += { #topic_index#: $topic_id, phrases: $phrases, score: $score, type: $type, messageIndex: $symbl_message_index, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+			MERGE (c)-[x:TOPICS { #conversation_index#: $conversation_id }]-(t)
+				ON CREATE SET
+					x.createdAt = datetime(),
+					x.lastAccessed = datetime()
+				ON MATCH SET
+					x.lastAccessed = datetime()
+			SET x += { #conversation_index#: $conversation_id, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+			MERGE (p:TopicPhrase { #phrase_index#: $phrase_id })
+				ON CREATE SET
+					p.createdAt = datetime(),
+					p.lastAccessed = datetime()
+				ON MATCH SET
+					p.lastAccessed = datetime()
+			SET p += { #phrase_index#: $phrase_id, phrase: $phrases, lastAccessed: datetime() }
+			MERGE (t)-[y:TOPIC_PHRASE { #conversation_index#: $conversation_id }]-(p)
+				ON CREATE SET
+					y.createdAt = datetime(),
+					y.lastAccessed = datetime()
+				ON MATCH SET
+					y.lastAccessed = datetime()
+			SET y += { #conversation_index#: $conversation_id, lastAccessed: datetime() }
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: createTopicsQuery,
+			Params: map[string]any{
+				"conversation_id":     mh.conversationId,
+				"topic_id":            topic.ID,
+				"phrase_id":           utils.NormalizeId(topic.Phrases),
+				"phrases":             strings.ToLower(topic.Phrases),
+				"score":               topic.Score,
+				"type":                topic.Type,
+				"symbl_message_index": topic.MessageIndex,
+				"raw":                 raw.Inline(),
+				"raw_event_id":        raw.Id(),
+			},
+		})
+
+		// associate topic to root words
+		for _, word := range topic.RootWords {
+			createRootWordQuery := utils.ReplaceIndexes(`
+				MATCH (t:Topic { #topic_index#: $topic_id })
+				MERGE (r:RootWord { #root_word_index#: $root_word_id })
+					ON CREATE SET
+						r.createdAt = datetime(),
+						r.lastAccessed = datetime()
+					ON MATCH SET
+						r.lastAccessed = datetime()
+				SET r += { #root_word_index#: $root_word_id, text: $text, lastAccessed: datetime() }
+				MERGE (t)-[x:TOPIC_ROOT_WORD { #conversation_index#: $conversation_id }]-(r)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, lastAccessed: datetime() }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createRootWordQuery,
+				Params: map[string]any{
+					"conversation_id": mh.conversationId,
+					"topic_id":        topic.ID,
+					"root_word_id":    utils.NormalizeId(word.Text),
+					"text":            strings.ToLower(word.Text),
+				},
 			})
-		if err != nil {
-			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-			klog.V(6).Infof("TopicResponseMessage LEAVE\n")
-			return err
 		}
 
 		// associate topic to message
 		for _, ref := range topic.MessageReferences {
-			_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-				func(tx neo4j.ManagedTransaction) (any, error) {
-					createTopicsQuery := utils.ReplaceIndexes(`
-						MATCH (t:Topic { topicId: $topic_id })
-						MATCH (m:Message { #message_index#: $message_id })
-						MERGE (t)-[x:TOPIC_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
-							ON CREATE SET
-								x.createdAt = datetime(),
-								x.lastAccessed = datetime()
-							ON MATCH SET
-								x.lastAccessed = datetime()
-						SET x = { #conversation_index#: $conversation_id, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						`)
-					result, err := tx.Run(ctx, createTopicsQuery, map[string]any{
-						"conversation_id": mh.conversationId,
-						"topic_id":        topic.ID,
-						"message_id":      ref.ID,
-						"value":           strings.ToLower(topic.Phrases),
-						"raw":             string(data),
-					})
-					if err != nil {
-						klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-						return nil, err
-					}
-					return result.Collect(ctx)
-				})
-			if err != nil {
-				klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-				klog.V(6).Infof("TopicResponseMessage LEAVE\n")
-				return err
-			}
+			createTopicsQuery := utils.ReplaceIndexes(`
+				MATCH (t:Topic { topicId: $topic_id })
+				MATCH (m:Message { #message_index#: $message_id })
+				MERGE (t)-[x:TOPIC_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, value: $value, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createTopicsQuery,
+				Params: map[string]any{
+					"conversation_id": mh.conversationId,
+					"topic_id":        topic.ID,
+					"message_id":      ref.ID,
+					"value":           strings.ToLower(topic.Phrases),
+					"raw":             raw.Inline(),
+					"raw_event_id":    raw.Id(),
+				},
+			})
 		}
 	}
 
@@ -589,13 +703,13 @@ func (mh *MessageHandler) TopicResponseMessage(tr *sdkinterfaces.TopicResponse)
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitRealTimeTopic, data)
+	err = mh.commit(statements, shared.RabbitRealTimeTopic, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("TopicResponseMessage.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("TopicResponseMessage.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("TopicResponseMessage Succeeded\n")
 	klog.V(6).Infof("TopicResponseMessage LEAVE\n")
@@ -605,6 +719,19 @@ func (mh *MessageHandler) TopicResponseMessage(tr *sdkinterfaces.TopicResponse)
 func (mh *MessageHandler) TrackerResponseMessage(tr *sdkinterfaces.TrackerResponse) error {
 	klog.V(6).Infof("TrackerResponseMessage ENTER\n")
 
+	// skip stale or duplicate deliveries
+	if mh.isStale(sequenceTracker, tr.SequenceNumber) {
+		klog.V(6).Infof("TrackerResponseMessage LEAVE\n")
+		return nil
+	}
+
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(tr, shared.RabbitRealTimeTracker)
+	if err != nil {
+		klog.V(6).Infof("TrackerResponseMessage LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(tr)
 	if err != nil {
 		klog.V(1).Infof("TrackerResponseMessage json.Marshal failed. Err: %v\n", err)
@@ -620,124 +747,107 @@ func (mh *MessageHandler) TrackerResponseMessage(tr *sdkinterfaces.TrackerRespon
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TrackerResponseMessage:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitRealTimeTracker).V(2).Info("TrackerResponseMessage", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
-	// write the object to the database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy
+	raw, err := mh.rawEvent(shared.RabbitRealTimeTracker, data)
+	if err != nil {
+		klog.V(6).Infof("TrackerResponseMessage LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil {
+		statements = append(statements, *statement)
+	}
 
 	for _, tracker := range tr.Trackers {
-		_, err := (*mh.neo4jMgr).ExecuteWrite(ctx,
-			func(tx neo4j.ManagedTransaction) (any, error) {
-				createTrackersQuery := utils.ReplaceIndexes(`
-					MATCH (c:Conversation { #conversation_index#: $conversation_id })
-					MERGE (t:Tracker { #tracker_index#: $tracker_id })
-						ON CREATE SET
-							t.createdAt = datetime(),
-							t.lastAccessed = datetime()
-						ON MATCH SET
-							t.lastAccessed = datetime()
-					SET t = { #tracker_index#: $tracker_id, name: $tracker_name, createdAt: datetime(), lastAccessed: datetime() }
-					MERGE (c)-[x:TRACKER { #conversation_index#: $conversation_id }]-(t)
+		createTrackersQuery := utils.ReplaceIndexes(`
+			MATCH (c:Conversation { #conversation_index#: $conversation_id })
+			MERGE (t:Tracker { #tracker_index#: $tracker_id })
+				ON CREATE SET
+					t.createdAt = datetime(),
+					t.lastAccessed = datetime()
+				ON MATCH SET
+					t.lastAccessed = datetime()
+			SET t += { #tracker_index#: $tracker_id, name: $tracker_name, lastAccessed: datetime() }
+			MERGE (c)-[x:TRACKER { #conversation_index#: $conversation_id }]-(t)
+				ON CREATE SET
+					x.createdAt = datetime(),
+					x.lastAccessed = datetime()
+				ON MATCH SET
+					x.lastAccessed = datetime()
+			SET x += { #conversation_index#: $conversation_id, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: createTrackersQuery,
+			Params: map[string]any{
+				"conversation_id": mh.conversationId,
+				"tracker_id":      tracker.ID,
+				"tracker_name":    strings.ToLower(tracker.Name),
+				"raw":             raw.Inline(),
+				"raw_event_id":    raw.Id(),
+			},
+		})
+
+		// associate tracker to messages and insights
+		for _, match := range tracker.Matches {
+
+			// messages
+			for _, msgRef := range match.MessageRefs {
+				createTopicsQuery := utils.ReplaceIndexes(`
+					MATCH (t:Tracker { #tracker_index#: $tracker_id })
+					MATCH (m:Message { #message_index#: $message_id })
+					MERGE (t)-[x:TRACKER_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
 						ON CREATE SET
 							x.createdAt = datetime(),
 							x.lastAccessed = datetime()
 						ON MATCH SET
 							x.lastAccessed = datetime()
-					SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
+					SET x += { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
 					`)
-				result, err := tx.Run(ctx, createTrackersQuery, map[string]any{
-					"conversation_id": mh.conversationId,
-					"tracker_id":      tracker.ID,
-					"tracker_name":    strings.ToLower(tracker.Name),
-					"raw":             string(data),
+				statements = append(statements, outbox.Statement{
+					Query: createTopicsQuery,
+					Params: map[string]any{
+						"conversation_id": mh.conversationId,
+						"tracker_id":      tracker.ID,
+						"message_id":      msgRef.ID,
+						"tracker_name":    strings.ToLower(tracker.Name),
+						"value":           strings.ToLower(match.Value),
+						"raw":             raw.Inline(),
+						"raw_event_id":    raw.Id(),
+					},
 				})
-				if err != nil {
-					klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-					return nil, err
-				}
-				return result.Collect(ctx)
-			})
-		if err != nil {
-			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-			klog.V(6).Infof("TrackerResponseMessage LEAVE\n")
-			return err
-		}
-
-		// associate tracker to messages and insights
-		for _, match := range tracker.Matches {
-
-			// messages
-			for _, msgRef := range match.MessageRefs {
-				_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-					func(tx neo4j.ManagedTransaction) (any, error) {
-						createTopicsQuery := utils.ReplaceIndexes(`
-							MATCH (t:Tracker { #tracker_index#: $tracker_id })
-							MATCH (m:Message { #message_index#: $message_id })
-							MERGE (t)-[x:TRACKER_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
-								ON CREATE SET
-									x.createdAt = datetime(),
-									x.lastAccessed = datetime()
-								ON MATCH SET
-									x.lastAccessed = datetime()
-							SET x = { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-							`)
-						result, err := tx.Run(ctx, createTopicsQuery, map[string]any{
-							"conversation_id": mh.conversationId,
-							"tracker_id":      tracker.ID,
-							"message_id":      msgRef.ID,
-							"tracker_name":    strings.ToLower(tracker.Name),
-							"value":           strings.ToLower(match.Value),
-							"raw":             string(data),
-						})
-						if err != nil {
-							klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-							return nil, err
-						}
-						return result.Collect(ctx)
-					})
-				if err != nil {
-					klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-					klog.V(6).Infof("TrackerResponseMessage LEAVE\n")
-					return err
-				}
 			}
 
 			// insights
 			for _, inRef := range match.InsightRefs {
-				_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-					func(tx neo4j.ManagedTransaction) (any, error) {
-						createTrackerMatchQuery := utils.ReplaceIndexes(`
-							MATCH (t:Tracker { #tracker_index#: $tracker_id })
-							MATCH (i:Insight { #insight_index#: $insight_id })
-							MERGE (t)-[x:TRACKER_INSIGHT_REF { #conversation_index#: $conversation_id }]-(i)
-								ON CREATE SET
-									x.createdAt = datetime(),
-									x.lastAccessed = datetime()
-								ON MATCH SET
-									x.lastAccessed = datetime()
-							SET x = { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-							`)
-						result, err := tx.Run(ctx, createTrackerMatchQuery, map[string]any{
-							"conversation_id": mh.conversationId,
-							"tracker_id":      tracker.ID,
-							"insight_id":      inRef.ID,
-							"tracker_name":    strings.ToLower(tracker.Name),
-							"value":           strings.ToLower(match.Value),
-							"raw":             string(data),
-						})
-						if err != nil {
-							klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-							return nil, err
-						}
-						return result.Collect(ctx)
-					})
-				if err != nil {
-					klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-					klog.V(6).Infof("TrackerResponseMessage LEAVE\n")
-					return err
-				}
+				createTrackerMatchQuery := utils.ReplaceIndexes(`
+					MATCH (t:Tracker { #tracker_index#: $tracker_id })
+					MATCH (i:Insight { #insight_index#: $insight_id })
+					MERGE (t)-[x:TRACKER_INSIGHT_REF { #conversation_index#: $conversation_id }]-(i)
+						ON CREATE SET
+							x.createdAt = datetime(),
+							x.lastAccessed = datetime()
+						ON MATCH SET
+							x.lastAccessed = datetime()
+					SET x += { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+					`)
+				statements = append(statements, outbox.Statement{
+					Query: createTrackerMatchQuery,
+					Params: map[string]any{
+						"conversation_id": mh.conversationId,
+						"tracker_id":      tracker.ID,
+						"insight_id":      inRef.ID,
+						"tracker_name":    strings.ToLower(tracker.Name),
+						"value":           strings.ToLower(match.Value),
+						"raw":             raw.Inline(),
+						"raw_event_id":    raw.Id(),
+					},
+				})
 			}
 		}
 	}
@@ -755,13 +865,16 @@ func (mh *MessageHandler) TrackerResponseMessage(tr *sdkinterfaces.TrackerRespon
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitRealTimeTracker, data)
+	err = mh.commit(statements, shared.RabbitRealTimeTracker, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("TrackerResponseMessage.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("TrackerResponseMessage.Outbox:\n%s\n", string(data))
+
+	// only advance once the transaction committed
+	mh.applySequence(sequenceTracker, tr.SequenceNumber)
 
 	klog.V(4).Infof("TrackerResponseMessage Succeeded\n")
 	klog.V(6).Infof("TrackerResponseMessage LEAVE\n")
@@ -772,6 +885,19 @@ func (mh *MessageHandler) TrackerResponseMessage(tr *sdkinterfaces.TrackerRespon
 func (mh *MessageHandler) EntityResponseMessage(er *sdkinterfaces.EntityResponse) error {
 	klog.V(6).Infof("EntityResponseMessage ENTER\n")
 
+	// skip stale or duplicate deliveries
+	if mh.isStale(sequenceEntity, er.SequenceNumber) {
+		klog.V(6).Infof("EntityResponseMessage LEAVE\n")
+		return nil
+	}
+
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(er, shared.RabbitRealTimeEntity)
+	if err != nil {
+		klog.V(6).Infof("EntityResponseMessage LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(er)
 	if err != nil {
 		klog.V(1).Infof("EntityResponseMessage json.Marshal failed. Err: %v\n", err)
@@ -787,12 +913,22 @@ func (mh *MessageHandler) EntityResponseMessage(er *sdkinterfaces.EntityResponse
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("EntityResponseMessage:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitRealTimeEntity).V(2).Info("EntityResponseMessage", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
-	// write the object to the database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy
+	raw, err := mh.rawEvent(shared.RabbitRealTimeEntity, data)
+	if err != nil {
+		klog.V(6).Infof("EntityResponseMessage LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil {
+		statements = append(statements, *statement)
+	}
 
 	for _, entity := range er.Entities {
 
@@ -800,86 +936,64 @@ func (mh *MessageHandler) EntityResponseMessage(er *sdkinterfaces.EntityResponse
 		for _, match := range entity.Matches {
 
 			// entity id
-			entityCategory := strings.ToLower(strings.ReplaceAll(entity.Category, " ", "_"))
-			entityType := strings.ToLower(strings.ReplaceAll(entity.Type, " ", "_"))
-			entitySubType := strings.ToLower(strings.ReplaceAll(entity.SubType, " ", "_"))
-			entityValue := strings.ToLower(strings.ReplaceAll(match.DetectedValue, " ", "_"))
-			entityId := fmt.Sprintf("%s/%s/%s/%s", entityCategory, entityType, entitySubType, entityValue)
+			entityId := utils.EntityId(entity.Category, entity.Type, entity.SubType, match.DetectedValue)
 
 			// entity
-			_, err := (*mh.neo4jMgr).ExecuteWrite(ctx,
-				func(tx neo4j.ManagedTransaction) (any, error) {
-					createEntitiesQuery := utils.ReplaceIndexes(`
-						MATCH (c:Conversation { #conversation_index#: $conversation_id })
-						MERGE (e:Entity { #entity_index#: $entity_id })
-							ON CREATE SET
-								e.createdAt = datetime(),
-								e.lastAccessed = datetime()
-							ON MATCH SET
-								e.lastAccessed = datetime()
-						SET e = { #entity_index#: $entity_id, type: $type, subType: $sub_type, category: $category, value: $value, createdAt: datetime(), lastAccessed: datetime() }
-						MERGE (c)-[x:ENTITY { #conversation_index#: $conversation_id }]-(e)
-							ON CREATE SET
-								x.createdAt = datetime(),
-								x.lastAccessed = datetime()
-							ON MATCH SET
-								x.lastAccessed = datetime()
-						SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						`)
-					result, err := tx.Run(ctx, createEntitiesQuery, map[string]any{
+			createEntitiesQuery := utils.ReplaceIndexes(`
+				MATCH (c:Conversation { #conversation_index#: $conversation_id })
+				MERGE (e:Entity { #entity_index#: $entity_id })
+					ON CREATE SET
+						e.createdAt = datetime(),
+						e.lastAccessed = datetime()
+					ON MATCH SET
+						e.lastAccessed = datetime()
+				SET e += { #entity_index#: $entity_id, type: $type, subType: $sub_type, category: $category, value: $value, lastAccessed: datetime() }
+				MERGE (c)-[x:ENTITY { #conversation_index#: $conversation_id }]-(e)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createEntitiesQuery,
+				Params: map[string]any{
+					"conversation_id": mh.conversationId,
+					"entity_id":       entityId,
+					"type":            strings.ToLower(entity.Type),
+					"sub_type":        strings.ToLower(entity.SubType),
+					"category":        strings.ToLower(entity.Category),
+					"value":           strings.ToLower(match.DetectedValue),
+					"raw":             raw.Inline(),
+					"raw_event_id":    raw.Id(),
+				},
+			})
+
+			// message
+			for _, msgRef := range match.MessageRefs {
+				createEntitiesQuery := utils.ReplaceIndexes(`
+					MATCH (e:Entity { #entity_index#: $entity_id })
+					MATCH (m:Message { #message_index#: $message_id })
+					MERGE (e)-[x:ENTITY_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
+						ON CREATE SET
+							x.createdAt = datetime(),
+							x.lastAccessed = datetime()
+						ON MATCH SET
+							x.lastAccessed = datetime()
+					SET x += { #conversation_index#: $conversation_id, value: $value, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+					`)
+				statements = append(statements, outbox.Statement{
+					Query: createEntitiesQuery,
+					Params: map[string]any{
 						"conversation_id": mh.conversationId,
 						"entity_id":       entityId,
-						"type":            strings.ToLower(entity.Type),
-						"sub_type":        strings.ToLower(entity.SubType),
-						"category":        strings.ToLower(entity.Category),
+						"message_id":      msgRef.ID,
 						"value":           strings.ToLower(match.DetectedValue),
-						"raw":             string(data),
-					})
-					if err != nil {
-						klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-						return nil, err
-					}
-					return result.Collect(ctx)
+						"raw":             raw.Inline(),
+						"raw_event_id":    raw.Id(),
+					},
 				})
-			if err != nil {
-				klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-				klog.V(6).Infof("EntityResponseMessage LEAVE\n")
-				return err
-			}
-
-			// message
-			for _, msgRef := range match.MessageRefs {
-				_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-					func(tx neo4j.ManagedTransaction) (any, error) {
-						createEntitiesQuery := utils.ReplaceIndexes(`
-							MATCH (e:Entity { #entity_index#: $entity_id })
-							MATCH (m:Message { #message_index#: $message_id })
-							MERGE (e)-[x:ENTITY_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
-								ON CREATE SET
-									x.createdAt = datetime(),
-									x.lastAccessed = datetime()
-								ON MATCH SET
-									x.lastAccessed = datetime()
-							SET x = { #conversation_index#: $conversation_id, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-							`)
-						result, err := tx.Run(ctx, createEntitiesQuery, map[string]any{
-							"conversation_id": mh.conversationId,
-							"entity_id":       entityId,
-							"message_id":      msgRef.ID,
-							"value":           strings.ToLower(match.DetectedValue),
-							"raw":             string(data),
-						})
-						if err != nil {
-							klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-							return nil, err
-						}
-						return result.Collect(ctx)
-					})
-				if err != nil {
-					klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-					klog.V(6).Infof("EntityResponseMessage LEAVE\n")
-					return err
-				}
 			}
 		}
 	}
@@ -897,13 +1011,16 @@ func (mh *MessageHandler) EntityResponseMessage(er *sdkinterfaces.EntityResponse
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitRealTimeEntity, data)
+	err = mh.commit(statements, shared.RabbitRealTimeEntity, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("EntityResponseMessage.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("EntityResponseMessage.Outbox:\n%s\n", string(data))
+
+	// only advance once the transaction committed
+	mh.applySequence(sequenceEntity, er.SequenceNumber)
 
 	klog.V(4).Infof("EntityResponseMessage Succeeded\n")
 	klog.V(6).Infof("EntityResponseMessage LEAVE\n")
@@ -935,7 +1052,7 @@ func (mh *MessageHandler) TeardownConversation(tm *sdkinterfaces.TeardownMessage
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TeardownConversation:\n%v\n\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitRealTimeConversationTeardown).V(2).Info("TeardownConversation", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// rabbitmq
@@ -950,17 +1067,19 @@ func (mh *MessageHandler) TeardownConversation(tm *sdkinterfaces.TeardownMessage
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitRealTimeConversationTeardown, data)
+	err = mh.commit(nil, shared.RabbitRealTimeConversationTeardown, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("TeardownConversation.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("TeardownConversation.Outbox:\n%s\n", string(data))
 
 	// mark as teardown message sent
 	mh.terminationSent = true
 
+	metrics.ConversationsTornDown.WithLabelValues(shared.SourceProxyDataminer).Inc()
+
 	klog.V(4).Infof("TeardownConversation Succeeded\n")
 	klog.V(6).Infof("TeardownConversation LEAVE\n")
 
@@ -983,26 +1102,26 @@ func (mh *MessageHandler) UserDefinedMessage(byMsg []byte) error {
 	return nil
 }
 
-func (mh *MessageHandler) HandleQuestion(insight *sdkinterfaces.Insight, number int) error {
+func (mh *MessageHandler) HandleQuestion(insight *sdkinterfaces.Insight, number int) ([]outbox.Statement, error) {
 	return mh.handleInsight(insight, number)
 }
 
-func (mh *MessageHandler) HandleActionItem(insight *sdkinterfaces.Insight, number int) error {
+func (mh *MessageHandler) HandleActionItem(insight *sdkinterfaces.Insight, number int) ([]outbox.Statement, error) {
 	return mh.handleInsight(insight, number)
 }
 
-func (mh *MessageHandler) HandleFollowUp(insight *sdkinterfaces.Insight, number int) error {
+func (mh *MessageHandler) HandleFollowUp(insight *sdkinterfaces.Insight, number int) ([]outbox.Statement, error) {
 	return mh.handleInsight(insight, number)
 }
 
-func (mh *MessageHandler) handleInsight(insight *sdkinterfaces.Insight, squenceNumber int) error {
+func (mh *MessageHandler) handleInsight(insight *sdkinterfaces.Insight, squenceNumber int) ([]outbox.Statement, error) {
 	klog.V(6).Infof("handleInsight ENTER\n")
 
 	data, err := json.Marshal(insight)
 	if err != nil {
 		klog.V(1).Infof("handleInsight json.Marshal failed. Err: %v\n", err)
 		klog.V(6).Infof("handleInsight LEAVE\n")
-		return err
+		return nil, err
 	}
 
 	// pretty print
@@ -1010,88 +1129,81 @@ func (mh *MessageHandler) handleInsight(insight *sdkinterfaces.Insight, squenceN
 	if err != nil {
 		klog.V(1).Infof("prettyjson.Marshal failed. Err: %v\n", err)
 		klog.V(6).Infof("handleInsight LEAVE\n")
-		return err
+		return nil, err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("handleInsight:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitRealTimeInsight).V(2).Info("handleInsight", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
-	// write the object to the database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
 
-	// if we need to do something with them
-	// for records, message := range mr.Messages {
-	_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-		func(tx neo4j.ManagedTransaction) (any, error) {
-			createInsightQuery := utils.ReplaceIndexes(`
-				MATCH (c:Conversation { #conversation_index#: $conversation_id })
-				MERGE (i:Insight { #insight_index#: $insight_id })
-					ON CREATE SET
-						i.createdAt = datetime(),
-						i.lastAccessed = datetime()
-					ON MATCH SET
-						i.lastAccessed = datetime()
-				SET i = { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-				MERGE (u:User { #user_index#: $user_id })
-					ON CREATE SET
-						u.createdAt = datetime(),
-						u.lastAccessed = datetime()
-					ON MATCH SET
-						u.lastAccessed = datetime()
-				SET u = { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, createdAt: datetime(), lastAccessed: datetime() }
-				MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
-					ON CREATE SET
-						x.createdAt = datetime(),
-						x.lastAccessed = datetime()
-					ON MATCH SET
-						x.lastAccessed = datetime()
-				SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-				MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
-					ON CREATE SET
-						y.createdAt = datetime(),
-						y.lastAccessed = datetime()
-					ON MATCH SET
-						y.lastAccessed = datetime()
-				SET y = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime() }
-				`)
-			result, err := tx.Run(ctx, createInsightQuery, map[string]any{
-				"conversation_id": mh.conversationId,
-				"insight_id":      insight.ID,
-				"type":            strings.ToLower(insight.Type),
-				"content":         insight.Payload.Content,
-				"sequence_number": squenceNumber,
-				"assignee_id":     insight.Assignee.UserID,
-				"user_real_id":    insight.From.ID,
-				"user_id":         insight.From.UserID,
-				"user_name":       insight.From.Name,
-				"raw":             string(data),
-			})
-			if err != nil {
-				klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-				return nil, err
-			}
-			return result.Collect(ctx)
-		})
+	// keep the raw payload according to the policy
+	raw, err := mh.rawEvent(shared.RabbitRealTimeInsight, data)
 	if err != nil {
-		klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
 		klog.V(6).Infof("handleInsight LEAVE\n")
-		return err
+		return nil, err
+	}
+	if statement := raw.Statement(); statement != nil {
+		statements = append(statements, *statement)
+	}
+
+	content, err := mh.encrypt(insight.Payload.Content)
+	if err != nil {
+		klog.V(6).Infof("handleInsight LEAVE\n")
+		return nil, err
 	}
 
+	createInsightQuery := utils.ReplaceIndexes(`
+		MATCH (c:Conversation { #conversation_index#: $conversation_id })
+		MERGE (i:Insight { #insight_index#: $insight_id })
+			ON CREATE SET
+				i.createdAt = datetime(),
+				i.lastAccessed = datetime()
+			ON MATCH SET
+				i.lastAccessed = datetime()
+		SET i += { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+		MERGE (u:User { #user_index#: $user_id })
+			ON CREATE SET
+				u.createdAt = datetime(),
+				u.lastAccessed = datetime()
+			ON MATCH SET
+				u.lastAccessed = datetime()
+		SET u += { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, lastAccessed: datetime() }
+		MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
+			ON CREATE SET
+				x.createdAt = datetime(),
+				x.lastAccessed = datetime()
+			ON MATCH SET
+				x.lastAccessed = datetime()
+		SET x += { #conversation_index#: $conversation_id, lastAccessed: datetime(), raw: $raw, rawEventId: $raw_event_id }
+		MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
+			ON CREATE SET
+				y.createdAt = datetime(),
+				y.lastAccessed = datetime()
+			ON MATCH SET
+				y.lastAccessed = datetime()
+		SET y += { #conversation_index#: $conversation_id, lastAccessed: datetime() }
+		`)
+	statements = append(statements, outbox.Statement{
+		Query: createInsightQuery,
+		Params: map[string]any{
+			"conversation_id": mh.conversationId,
+			"insight_id":      insight.ID,
+			"type":            strings.ToLower(insight.Type),
+			"content":         content,
+			"sequence_number": squenceNumber,
+			"assignee_id":     insight.Assignee.UserID,
+			"user_real_id":    insight.From.ID,
+			"user_id":         insight.From.UserID,
+			"user_name":       insight.From.Name,
+			"raw":             raw.Inline(),
+			"raw_event_id":    raw.Id(),
+		},
+	})
+
 	klog.V(4).Infof("handleInsight Succeeded\n")
 	klog.V(6).Infof("handleInsight LEAVE\n")
 
-	return nil
-}
-
-func convertRootWordToString(words []sdkinterfaces.RootWord) string {
-	tmp := ""
-	for _, word := range words {
-		if len(tmp) > 0 {
-			tmp = "," + tmp
-		}
-		tmp = tmp + word.Text
-	}
-	return tmp
+	return statements, nil
 }
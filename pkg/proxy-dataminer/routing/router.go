@@ -4,8 +4,17 @@
 package routing
 
 import (
+	"context"
+	"encoding/json"
+
 	streaming "github.com/dvonthenen/symbl-go-sdk/pkg/api/streaming/v1"
+	sdkinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/streaming/v1/interfaces"
+	trace "go.opentelemetry.io/otel/trace"
 	klog "k8s.io/klog/v2"
+
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 func NewRouter(options MessageRouterOptions) *MessageRouter {
@@ -18,10 +27,22 @@ func NewRouter(options MessageRouterOptions) *MessageRouter {
 func (mr *MessageRouter) HandleMessage(byMsg []byte) error {
 	klog.V(6).Infof("MessageRouter.HandleMessage ENTER\n")
 
+	msgType := messageType(byMsg)
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceProxyDataminer, msgType).Inc()
+
+	// the trace follows the message through Neo4j and the bus to the plugins and back to the client
+	ctx, span := tracing.Tracer().Start(context.Background(), "symbl "+msgType, trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	if traceAware, ok := (*mr.callback).(TraceAware); ok {
+		traceAware.SetTraceContext(ctx)
+	}
+
 	router := streaming.New(*mr.callback)
 	err := router.Message(byMsg)
 	if err != nil {
 		klog.V(1).Infof("HandleMessage Failed. Err: %v\n", err)
+		tracing.Fail(span, err)
 		klog.V(6).Infof("MessageRouter.HandleMessage LEAVE\n")
 
 		return err
@@ -32,3 +53,37 @@ func (mr *MessageRouter) HandleMessage(byMsg []byte) error {
 
 	return nil
 }
+
+// messageType is the label for the Symbl message, platform messages use the type of the inner message
+func messageType(byMsg []byte) string {
+	var smt streaming.SybmlMessageType
+	err := json.Unmarshal(byMsg, &smt)
+	if err != nil {
+		return metrics.TypeUnknown
+	}
+
+	msgType := smt.Type
+	if msgType == streaming.MessageTypeMessage {
+		msgType = smt.Message.Type
+	}
+
+	// keep the label values bounded
+	switch msgType {
+	case streaming.MessageTypeError,
+		streaming.MessageTypeInitListening,
+		streaming.MessageTypeInitConversation,
+		streaming.MessageTypeInitRecognition,
+		streaming.MessageTypeSessionModified,
+		streaming.MessageTypeTeardownConversation,
+		streaming.MessageTypeTeardownRecognition,
+		sdkinterfaces.MessageTypeRecognitionResult,
+		sdkinterfaces.MessageTypeMessageResponse,
+		sdkinterfaces.MessageTypeInsightResponse,
+		sdkinterfaces.MessageTypeTopicResponse,
+		sdkinterfaces.MessageTypeTrackerResponse,
+		sdkinterfaces.MessageTypeEntityResponse,
+		sdkinterfaces.MessageTypeUserDefined:
+		return msgType
+	}
+	return metrics.TypeUnknown
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package routing
+
+import (
+	klog "k8s.io/klog/v2"
+
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+)
+
+// resetSequences forgets the applied sequence numbers when Symbl starts a new connection
+func (mh *MessageHandler) resetSequences() {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+
+	if len(mh.sequences) > 0 {
+		klog.V(4).Infof("Conversation %s restarting its sequence numbers\n", mh.conversationId)
+	}
+	mh.sequences = make(map[string]int)
+}
+
+// isStale reports whether a response at or below the highest sequence number applied on this connection was received again
+func (mh *MessageHandler) isStale(kind string, number int) bool {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+
+	last, ok := mh.sequences[kind]
+	if !ok || number > last {
+		return false
+	}
+
+	reason := metrics.ReasonStale
+	if number == last {
+		reason = metrics.ReasonDuplicate
+	}
+	metrics.SkippedMessages.WithLabelValues(reason).Inc()
+	klog.V(3).Infof("Skipping %s %d for conversation %s. Already applied %d\n", kind, number, mh.conversationId, last)
+	return true
+}
+
+func (mh *MessageHandler) applySequence(kind string, number int) {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+
+	if last, ok := mh.sequences[kind]; !ok || number > last {
+		mh.sequences[kind] = number
+	}
+}
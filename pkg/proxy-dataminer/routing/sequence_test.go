@@ -0,0 +1,127 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package routing
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+)
+
+func skipped(t *testing.T, reason string) float64 {
+	t.Helper()
+
+	var m dto.Metric
+	err := metrics.SkippedMessages.WithLabelValues(reason).Write(&m)
+	if err != nil {
+		t.Fatalf("Write failed. Err: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestIsStale(t *testing.T) {
+	type delivery struct {
+		kind   string
+		number int
+		reset  bool // a new Symbl connection before the delivery
+	}
+
+	tests := []struct {
+		name       string
+		deliveries []delivery
+		want       []bool
+		duplicates float64
+		stale      float64
+	}{
+		{
+			name: "in order",
+			deliveries: []delivery{
+				{kind: sequenceMessage, number: 0},
+				{kind: sequenceMessage, number: 1},
+				{kind: sequenceMessage, number: 2},
+			},
+			want: []bool{false, false, false},
+		},
+		{
+			name: "duplicate",
+			deliveries: []delivery{
+				{kind: sequenceMessage, number: 1},
+				{kind: sequenceMessage, number: 1},
+			},
+			want:       []bool{false, true},
+			duplicates: 1,
+		},
+		{
+			name: "out of order",
+			deliveries: []delivery{
+				{kind: sequenceInsight, number: 3},
+				{kind: sequenceInsight, number: 2},
+				{kind: sequenceInsight, number: 4},
+			},
+			want:  []bool{false, true, false},
+			stale: 1,
+		},
+		{
+			name: "response types numbered separately",
+			deliveries: []delivery{
+				{kind: sequenceMessage, number: 5},
+				{kind: sequenceTracker, number: 1},
+				{kind: sequenceEntity, number: 1},
+				{kind: sequenceEntity, number: 0},
+			},
+			want:  []bool{false, false, false, true},
+			stale: 1,
+		},
+		{
+			name: "new connection starts over",
+			deliveries: []delivery{
+				{kind: sequenceMessage, number: 0},
+				{kind: sequenceMessage, number: 7},
+				{kind: sequenceMessage, number: 0, reset: true},
+				{kind: sequenceMessage, number: 1},
+				{kind: sequenceMessage, number: 1},
+			},
+			want:       []bool{false, false, false, false, true},
+			duplicates: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mh, err := NewHandler(MessageHandlerOptions{
+				ConversationId: "conversation",
+			})
+			if err != nil {
+				t.Fatalf("NewHandler failed. Err: %v", err)
+			}
+
+			duplicates := skipped(t, metrics.ReasonDuplicate)
+			stale := skipped(t, metrics.ReasonStale)
+
+			for i, d := range tt.deliveries {
+				if d.reset {
+					mh.resetSequences()
+				}
+				got := mh.isStale(d.kind, d.number)
+				if got != tt.want[i] {
+					t.Errorf("delivery %d: %s %d got %t, want %t", i, d.kind, d.number, got, tt.want[i])
+				}
+				if !got {
+					mh.applySequence(d.kind, d.number)
+				}
+			}
+
+			got := skipped(t, metrics.ReasonDuplicate) - duplicates
+			if got != tt.duplicates {
+				t.Errorf("duplicates got %v, want %v", got, tt.duplicates)
+			}
+			got = skipped(t, metrics.ReasonStale) - stale
+			if got != tt.stale {
+				t.Errorf("stale got %v, want %v", got, tt.stale)
+			}
+		})
+	}
+}
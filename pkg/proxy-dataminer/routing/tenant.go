@@ -0,0 +1,61 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package routing
+
+import (
+	"context"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+// checkTenant fails when the conversation already exists for another tenant
+func (mh *MessageHandler) checkTenant() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := (*mh.neo4jMgr).ExecuteRead(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			findConversationQuery := utils.ReplaceIndexes(`
+				MATCH (c:Conversation { #conversation_index#: $conversation_id })
+				RETURN c
+				`)
+			result, err := tx.Run(ctx, findConversationQuery, map[string]any{
+				"conversation_id": mh.conversationId,
+			})
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed find conversation object. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Collect(ctx)
+		})
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteRead failed. Err: %v\n", err)
+		return err
+	}
+
+	records := result.([]*neo4j.Record)
+	if len(records) == 0 {
+		klog.V(4).Infof("Conversation %s is new\n", mh.conversationId)
+		return nil
+	}
+
+	value, _ := records[0].Get("c")
+	node, ok := value.(neo4j.Node)
+	if !ok {
+		return nil
+	}
+
+	// another tenant can't stream into the conversation, even with the same conversationId
+	tenant, _ := node.Props["tenantId"].(string)
+	if tenant != mh.options.Tenant {
+		klog.V(1).Infof("Conversation %s belongs to tenant %s, not %s\n", mh.conversationId, tenant, mh.options.Tenant)
+		return ErrTenantMismatch
+	}
+
+	return nil
+}
@@ -4,21 +4,32 @@
 package routing
 
 import (
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	"context"
+	"sync"
+
 	sdkinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/streaming/v1/interfaces"
 	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
 	interfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/interfaces"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
 )
 
 /*
-	MessagePassthrough used mainly for chat and closed captioning
+MessagePassthrough used mainly for chat and closed captioning
 */
 type MessagePassthrough interface {
 	SendRecognition(r *interfaces.UserDefinedRecognition) error
 	SendMessages(m *interfaces.UserDefinedMessages) error
 }
 
+// TraceAware callbacks are handed the span of each Symbl message before it is routed to them
+type TraceAware interface {
+	SetTraceContext(ctx context.Context)
+}
+
 /*
 	MessageRouter objects...
 */
@@ -40,19 +51,23 @@ type MessageRouter struct {
 type MessageHandlerOptions struct {
 	//housekeeping
 	ConversationId string
+	Tenant         string // shared.DefaultTenant when empty
 
 	// features
 	TranscriptionEnabled bool
 	MessagingEnabled     bool
+	RawPolicy            rawevent.Policy
+	Redaction            *redaction.Options // nil disables redaction
 
 	// callback
 	Callback *MessagePassthrough
 
 	// neo4j
-	Neo4jMgr *neo4j.SessionWithContext
+	Neo4jMgr  *neo4j.SessionWithContext
+	Encryptor *encryption.Encryptor // nil stores the content in plaintext
 
-	// neo4j session
-	RabbitMgr *rabbitinterfaces.Manager
+	// publishes the events recorded by the handler
+	Outbox *outbox.Relay
 }
 
 // MessageHandler takes the Symbl objects and performs an action with them
@@ -61,15 +76,26 @@ type MessageHandler struct {
 	conversationId  string
 	terminationSent bool
 
+	// replay protection, the highest sequence number applied per response type on the Symbl connection
+	sequences map[string]int
+	mu        sync.Mutex
+
 	// features
 	options MessageHandlerOptions
 
 	// callback
 	callback *MessagePassthrough
 
+	// masks PII before it is saved or published
+	redactor *redaction.Redactor
+
 	// neo4j
-	neo4jMgr *neo4j.SessionWithContext
+	neo4jMgr  *neo4j.SessionWithContext
+	encryptor *encryption.Encryptor
+
+	// outbox
+	outbox *outbox.Relay
 
-	// rabbitmq
-	rabbitMgr *rabbitinterfaces.Manager
+	// span of the Symbl message being handled
+	traceCtx context.Context
 }
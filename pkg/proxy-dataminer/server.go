@@ -5,18 +5,32 @@ package dataminer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	wsinterfaces "github.com/dvonthenen/websocketproxy/pkg/interfaces"
-	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	klog "k8s.io/klog/v2"
 
+	auth "github.com/dvonthenen/enterprise-conversation-application/pkg/auth"
+	messagebus "github.com/dvonthenen/enterprise-conversation-application/pkg/bus"
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	health "github.com/dvonthenen/enterprise-conversation-application/pkg/health"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	migrations "github.com/dvonthenen/enterprise-conversation-application/pkg/migrations"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
 	instance "github.com/dvonthenen/enterprise-conversation-application/pkg/proxy-dataminer/instance"
+	routing "github.com/dvonthenen/enterprise-conversation-application/pkg/proxy-dataminer/routing"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	supervisor "github.com/dvonthenen/enterprise-conversation-application/pkg/supervisor"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
 func New(options ServerOptions) (*Server, error) {
@@ -27,57 +41,110 @@ func New(options ServerOptions) (*Server, error) {
 		options.EndPort = DefaultEndPort
 	}
 
-	var connectionStr string
-	if v := os.Getenv("NEO4J_CONNECTION"); v != "" {
-		klog.V(4).Info("NEO4J_CONNECTION found")
-		connectionStr = v
-	} else {
-		klog.Errorf("NEO4J_CONNECTION not found\n")
-		return nil, ErrInvalidInput
-	}
-	var username string
-	if v := os.Getenv("NEO4J_USERNAME"); v != "" {
-		klog.V(4).Info("NEO4J_USERNAME found")
-		username = v
-	} else {
-		klog.Errorf("NEO4J_USERNAME not found\n")
-		return nil, ErrInvalidInput
-	}
-	var password string
-	if v := os.Getenv("NEO4J_PASSWORD"); v != "" {
-		klog.V(4).Info("NEO4J_PASSWORD found")
-		password = v
-	} else {
-		klog.Errorf("NEO4J_PASSWORD not found\n")
-		return nil, ErrInvalidInput
+	// neo4j
+	if options.Neo4j == nil {
+		neo4jConfig, err := dbconfig.FromEnv()
+		if err != nil {
+			klog.Errorf("dbconfig.FromEnv failed. Err: %v\n", err)
+			return nil, err
+		}
+		options.Neo4j = neo4jConfig
+	} else if err := options.Neo4j.Validate(); err != nil {
+		klog.Errorf("Neo4j config is invalid. Err: %v\n", err)
+		return nil, err
 	}
 
 	// options
-	if v := os.Getenv("ERI_TRANSCRIPTION"); v != "" {
-		klog.V(4).Info("ERI_TRANSCRIPTION found")
-		options.TranscriptionEnabled = StringParameterBoolValue(v)
+	if !rawevent.Supported(options.RawPolicy) {
+		klog.Errorf("RawPolicy %s is not supported\n", options.RawPolicy)
+		return nil, rawevent.ErrUnsupportedPolicy
 	}
-	if v := os.Getenv("ERI_MESSAGING"); v != "" {
-		klog.V(4).Info("ERI_MESSAGING found")
-		options.MessagingEnabled = StringParameterBoolValue(v)
+	if options.Redaction != nil {
+		_, err := redaction.New(*options.Redaction)
+		if err != nil {
+			klog.Errorf("Redaction options are invalid. Err: %v\n", err)
+			return nil, err
+		}
+	}
+	var encryptors *encryption.Encryptors
+	if options.Encryption != nil {
+		var err error
+		encryptors, err = encryption.NewEncryptors(*options.Encryption)
+		if err != nil {
+			klog.Errorf("Encryption options are invalid. Err: %v\n", err)
+			return nil, err
+		}
+	}
+	var guard *auth.Guard
+	if options.Auth != nil {
+		var err error
+		guard, err = auth.New(*options.Auth)
+		if err != nil {
+			klog.Errorf("Auth options are invalid. Err: %v\n", err)
+			return nil, err
+		}
 	}
 
-	// DB Creds
-	creds := Credentials{
-		ConnectionStr: connectionStr,
-		Username:      username,
-		Password:      password,
+	// tls, shared by the entry point and the instances so they all pick up new certificates
+	tlsOptions := tlsconfig.Options{
+		CrtFile: options.CrtFile,
+		KeyFile: options.KeyFile,
+	}
+	if options.TLS != nil {
+		tlsOptions = *options.TLS
+	}
+	reloader, err := tlsconfig.New(tlsOptions)
+	if err != nil {
+		klog.Errorf("TLS options are invalid. Err: %v\n", err)
+		return nil, err
 	}
 
 	// server
 	server := &Server{
 		options:        options,
-		creds:          creds,
+		tls:            reloader,
+		encryptors:     encryptors,
+		guard:          guard,
 		instanceById:   make(map[string]*instance.Proxy),
 		instanceByPort: make(map[int]*instance.Proxy),
 		ticker:         time.NewTicker(time.Minute),
 		stopPoll:       make(chan struct{}),
+		health:         health.New(health.CheckerOptions{}),
 	}
+
+	// reconnect the database and bus when they drop, the Neo4j driver reopens its own connections
+	neo4jSupervisor, err := supervisor.New(supervisor.SupervisorOptions{
+		Component:  shared.SourceProxyDataminer,
+		Connection: supervisor.ConnectionNeo4j,
+		Check: func(ctx context.Context) error {
+			return health.Neo4j(ctx, server.driver)
+		},
+	})
+	if err != nil {
+		klog.Errorf("supervisor.New failed. Err: %v\n", err)
+		return nil, err
+	}
+	busSupervisor, err := supervisor.New(supervisor.SupervisorOptions{
+		Component:  shared.SourceProxyDataminer,
+		Connection: supervisor.ConnectionBus,
+		Check: func(ctx context.Context) error {
+			return health.Bus(server.bus)
+		},
+		Reconnect: func() error {
+			if server.bus == nil {
+				return health.ErrNotConnected
+			}
+			return (*server.bus).Reconnect()
+		},
+	})
+	if err != nil {
+		klog.Errorf("supervisor.New failed. Err: %v\n", err)
+		return nil, err
+	}
+	server.supervisors = []*supervisor.Supervisor{neo4jSupervisor, busSupervisor}
+
+	server.health.Add(health.CheckNeo4j, neo4jSupervisor.Healthy)
+	server.health.Add(health.CheckBus, busSupervisor.Healthy)
 	return server, nil
 }
 
@@ -94,11 +161,31 @@ func (s *Server) redirectProxy(w http.ResponseWriter, r *http.Request) {
 	// 	}
 	// }
 
-	// does the server already exist, return the serverInstance
+	// reject the client before a port or instance is allocated
 	serverInstance := s.instanceById[conversationId]
+	identity, err := s.guard.Check(r, auth.ActionOpen, conversationId, serverInstance.GetOwner())
+	if err != nil {
+		klog.V(2).Infof("Open conversationId (%s) rejected. Err: %v\n", conversationId, err)
+		auth.Reject(w, err)
+		return
+	}
+
+	// the conversation belongs to the tenant of the client
+	tenant, err := auth.Tenant(r, identity)
+	if err == nil && serverInstance != nil && serverInstance.GetTenant() != tenant {
+		err = auth.ErrForbidden
+	}
+	if err != nil {
+		klog.V(2).Infof("Tenant for conversationId (%s) rejected. Err: %v\n", conversationId, err)
+		auth.Reject(w, err)
+		return
+	}
+
+	// does the server already exist, return the serverInstance
 	if serverInstance != nil {
 		klog.V(3).Infof("Server for conversationId (%s) already exists\n", conversationId)
-		http.Redirect(w, r, serverInstance.GetRedirectAddress(), http.StatusSeeOther)
+		http.Redirect(w, r, withQuery(serverInstance.GetRedirectAddress(), r), http.StatusSeeOther)
+		return
 	}
 
 	// get HTTP header options
@@ -112,7 +199,7 @@ func (s *Server) redirectProxy(w http.ResponseWriter, r *http.Request) {
 	// Create a neo4j session to run transactions in. Sessions are lightweight to
 	// create and us. Sessions are NOT thread safe.
 	ctx := context.Background()
-	session := (*s.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := (*s.driver).NewSession(ctx, s.options.Neo4j.SessionConfig(tenant))
 
 	// get random port
 	diff := s.options.EndPort - s.options.StartPort
@@ -143,21 +230,36 @@ func (s *Server) redirectProxy(w http.ResponseWriter, r *http.Request) {
 
 	server := instance.New(instance.ProxyOptions{
 		ConversationId:       conversationId,
-		RabbitURI:            s.options.RabbitURI,
+		Tenant:               tenant,
 		ProxyPort:            random,
 		NotifyPort:           (random + DefaultNotificationPortOffset),
 		ProxyBindAddress:     newProxyServer,
 		NotifyBindAddress:    newNotifyServer,
 		RedirectAddress:      newRedirect,
-		CrtFile:              s.options.CrtFile,
-		KeyFile:              s.options.KeyFile,
+		TLS:                  s.tls,
 		TranscriptionEnabled: transcriptionEnable,
 		MessagingEnabled:     messagingEnable,
+		RawPolicy:            s.options.RawPolicy,
+		Redaction:            s.options.Redaction,
+		Guard:                s.guard,
+		Owner:                identity,
 		Neo4jMgr:             &session,
+		Encryptor:            s.encryptors.Tenant(tenant),
 		ProxyMgr:             &manager,
+		Outbox:               s.outbox,
+		Bus:                  s.bus,
 	})
 
-	err := server.Init()
+	// the session is only kept by an instance that is running
+	err = server.Init()
+	if err != nil {
+		session.Close(ctx)
+	}
+	if errors.Is(err, routing.ErrTenantMismatch) {
+		klog.V(1).Infof("Conversation %s belongs to another tenant than %s\n", conversationId, tenant)
+		auth.Reject(w, auth.ErrForbidden)
+		return
+	}
 	if err != nil {
 		klog.V(1).Infof("server.Init failed. Err: %v\n", err)
 		http.Error(w, "Failed to init server instance", http.StatusBadRequest)
@@ -167,6 +269,11 @@ func (s *Server) redirectProxy(w http.ResponseWriter, r *http.Request) {
 	err = server.Start()
 	if err != nil {
 		klog.V(1).Infof("server.Start failed. Err: %v\n", err)
+		errStop := server.Stop()
+		if errStop != nil {
+			klog.V(1).Infof("server.Stop failed. Err: %v\n", errStop)
+		}
+		session.Close(ctx)
 		http.Error(w, "Failed to start server instance", http.StatusBadRequest)
 		return
 	}
@@ -174,10 +281,11 @@ func (s *Server) redirectProxy(w http.ResponseWriter, r *http.Request) {
 	s.mu.Lock()
 	s.instanceById[conversationId] = server
 	s.instanceByPort[random] = server
+	metrics.ProxyInstances.Set(float64(len(s.instanceById)))
 	s.mu.Unlock()
 
 	// redirect
-	http.Redirect(w, r, newRedirect, http.StatusSeeOther)
+	http.Redirect(w, r, withQuery(newRedirect, r), http.StatusSeeOther)
 }
 
 func (s *Server) redirectNotification(w http.ResponseWriter, r *http.Request) {
@@ -196,8 +304,25 @@ func (s *Server) redirectNotification(w http.ResponseWriter, r *http.Request) {
 	// 	}
 	// }
 
-	// does the server already exist, return the serverInstance
+	// only clients allowed to observe the conversation learn if it is running
 	serverInstance := s.instanceById[conversationId]
+	identity, err := s.guard.Check(r, auth.ActionObserve, conversationId, serverInstance.GetOwner())
+	if err != nil {
+		klog.V(2).Infof("Observe conversationId (%s) rejected. Err: %v\n", conversationId, err)
+		auth.Reject(w, err)
+		return
+	}
+	tenant, err := auth.Tenant(r, identity)
+	if err == nil && serverInstance != nil && serverInstance.GetTenant() != tenant {
+		err = auth.ErrForbidden
+	}
+	if err != nil {
+		klog.V(2).Infof("Tenant for conversationId (%s) rejected. Err: %v\n", conversationId, err)
+		auth.Reject(w, err)
+		return
+	}
+
+	// does the server already exist, return the serverInstance
 	if serverInstance == nil {
 		klog.V(2).Infof("Server for conversationId (%s) doesn't exists\n", conversationId)
 		http.Error(w, "Failed to find conversationId instance", http.StatusNotFound)
@@ -212,7 +337,7 @@ func (s *Server) redirectNotification(w http.ResponseWriter, r *http.Request) {
 	newRedirect := fmt.Sprintf("https://%s:%d%s", redirect, serverInstance.GetNotifyPort(), r.URL.Path)
 	klog.V(3).Infof("Notify Redirect: %s\n", newRedirect)
 
-	http.Redirect(w, r, newRedirect, http.StatusSeeOther)
+	http.Redirect(w, r, withQuery(newRedirect, r), http.StatusSeeOther)
 }
 
 func (s *Server) redirectToInstance(w http.ResponseWriter, r *http.Request) {
@@ -241,8 +366,107 @@ func (s *Server) Start() error {
 		}
 	}
 
+	// upgrade existing data, in the database of every tenant
+	for _, database := range s.options.Neo4j.Databases() {
+		migrator, err := migrations.New(migrations.MigratorOptions{
+			Driver:       s.driver,
+			DatabaseName: database,
+			Tenant:       s.options.Neo4j.Tenant(database),
+		})
+		if err != nil {
+			klog.V(1).Infof("migrations.New failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+
+		err = migrator.Run()
+		if err != nil {
+			klog.V(1).Infof("migrator.Run(%s) failed. Err: %v\n", database, err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+	}
+
+	// message bus, picked by the RabbitURI scheme
+	if s.options.Bus == nil {
+		bus, err := messagebus.New(messagebus.BusOptions{
+			URI: s.options.RabbitURI,
+		})
+		if err != nil {
+			klog.V(1).Infof("messagebus.New failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.options.Bus = bus
+	}
+
+	err := (*s.options.Bus).Init()
+	if err != nil {
+		klog.V(1).Infof("bus.Init failed. Err: %v\n", err)
+		klog.V(6).Infof("Server.Start LEAVE\n")
+		return err
+	}
+
+	// outbox relay
+	relay, err := outbox.NewRelay(outbox.RelayOptions{
+		Driver:      s.driver,
+		Neo4j:       s.options.Neo4j,
+		Bus:         s.options.Bus,
+		ContentType: s.options.ContentType,
+		Encryptors:  s.encryptors,
+	})
+	if err != nil {
+		klog.V(1).Infof("outbox.NewRelay failed. Err: %v\n", err)
+		klog.V(6).Infof("Server.Start LEAVE\n")
+		return err
+	}
+
+	err = relay.Start()
+	if err != nil {
+		klog.V(1).Infof("relay.Start failed. Err: %v\n", err)
+		klog.V(6).Infof("Server.Start LEAVE\n")
+		return err
+	}
+
+	s.bus = s.options.Bus
+	s.outbox = relay
+
+	// tracing
+	if s.tracer == nil && s.options.Tracing != nil {
+		tracer, err := tracing.NewProvider(*s.options.Tracing)
+		if err != nil {
+			klog.V(1).Infof("tracing.NewProvider failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		err = tracer.Start()
+		if err != nil {
+			klog.V(1).Infof("tracer.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.tracer = tracer
+	}
+
+	// metrics endpoint
+	if s.metrics == nil && s.options.Metrics != nil {
+		metricsServer := metrics.NewServer(*s.options.Metrics)
+		err := metricsServer.Start()
+		if err != nil {
+			klog.V(1).Infof("metrics.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.metrics = metricsServer
+	}
+
 	// redirect
 	mux := http.NewServeMux()
+	if s.guard != nil {
+		// the outbox holds the events of every tenant, admins only see their own
+		mux.Handle(outbox.DefaultOutboxPath, s.guard.AdminHandler(s.outbox))
+	}
+	s.health.Register(mux)
 	mux.HandleFunc("/", s.redirectToInstance)
 
 	s.server = &http.Server{
@@ -266,12 +490,23 @@ func (s *Server) Start() error {
 	// start the main entry endpoint to direct traffic
 	go func() {
 		// this is a blocking call
-		err := s.server.ListenAndServeTLS(s.options.CrtFile, s.options.KeyFile)
+		err := s.tls.ListenAndServeTLS(s.server)
 		if err != nil {
 			klog.V(6).Infof("ListenAndServeTLS server stopped. Err: %v\n", err)
 		}
 	}()
 
+	// reconnect with backoff from now on
+	for _, sup := range s.supervisors {
+		err := sup.Start()
+		if err != nil {
+			klog.V(1).Infof("supervisor.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+	}
+	s.health.Started()
+
 	klog.V(4).Infof("Server.Start Succeeded\n")
 	klog.V(6).Infof("Server.Start LEAVE\n")
 
@@ -288,15 +523,12 @@ func (s *Server) RebuildDatabase() error {
 		s.driver = nil
 	}
 
-	// init neo4j
-	auth := neo4j.BasicAuth(s.creds.Username, s.creds.Password, "")
-
 	// You typically have one driver instance for the entire application. The
 	// driver maintains a pool of database connections to be used by the sessions.
 	// The driver is thread safe.
-	driver, err := neo4j.NewDriverWithContext(s.creds.ConnectionStr, auth)
+	driver, err := s.options.Neo4j.NewDriver()
 	if err != nil {
-		klog.V(1).Infof("NewDriverWithContext failed. Err: %v\n", err)
+		klog.V(1).Infof("NewDriver failed. Err: %v\n", err)
 		klog.V(6).Infof("Server.RebuildDatabase LEAVE\n")
 		return err
 	}
@@ -333,6 +565,7 @@ func (s *Server) RemoveConnection(uniqueId string) {
 
 	delete(s.instanceById, uniqueId)
 	delete(s.instanceByPort, port)
+	metrics.ProxyInstances.Set(float64(len(s.instanceById)))
 
 	klog.V(3).Infof("RemoveConnection(%s) Successful\n", uniqueId)
 	klog.V(6).Infof("Server.RemoveConnection LEAVE\n")
@@ -353,12 +586,24 @@ func (s *Server) CheckForDeadInstances() {
 			delete(s.instanceByPort, instance.GetProxyPort())
 		}
 	}
+	metrics.ProxyInstances.Set(float64(len(s.instanceById)))
 	s.mu.Unlock()
 }
 
 func (s *Server) Stop() error {
 	klog.V(6).Infof("Server.Stop ENTER\n")
 
+	// readiness turns false while everything below shuts down
+	s.health.Drain()
+
+	// no reconnects while shutting down
+	for _, sup := range s.supervisors {
+		err := sup.Stop()
+		if err != nil {
+			klog.V(1).Infof("supervisor.Stop failed. Err: %v\n", err)
+		}
+	}
+
 	// stop thread
 	close(s.stopPoll)
 	<-s.stopPoll
@@ -373,8 +618,26 @@ func (s *Server) Stop() error {
 	}
 	s.instanceById = make(map[string]*instance.Proxy)
 	s.instanceByPort = make(map[int]*instance.Proxy)
+	metrics.ProxyInstances.Set(float64(len(s.instanceById)))
 	s.mu.Unlock()
 
+	// flush and stop the outbox relay
+	if s.outbox != nil {
+		err := s.outbox.Stop()
+		if err != nil {
+			klog.V(1).Infof("outbox.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.outbox = nil
+
+	if s.bus != nil {
+		err := (*s.bus).Teardown()
+		if err != nil {
+			klog.V(1).Infof("bus.Teardown() failed. Err: %v\n", err)
+		}
+	}
+	s.bus = nil
+
 	// clean up neo4j driver
 	if s.driver != nil {
 		ctx := context.Background()
@@ -391,12 +654,38 @@ func (s *Server) Stop() error {
 	}
 	s.server = nil
 
+	// stop the metrics endpoint
+	if s.metrics != nil {
+		err := s.metrics.Stop()
+		if err != nil {
+			klog.V(1).Infof("metrics.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.metrics = nil
+
+	// flush the spans
+	if s.tracer != nil {
+		err := s.tracer.Stop()
+		if err != nil {
+			klog.V(1).Infof("tracer.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.tracer = nil
+
 	klog.V(4).Infof("Server.Stop Succeeded\n")
 	klog.V(6).Infof("Server.Stop LEAVE\n")
 
 	return nil
 }
 
+// withQuery keeps the query of the request, ie the access_token, on the redirect
+func withQuery(address string, r *http.Request) string {
+	if len(r.URL.RawQuery) == 0 {
+		return address
+	}
+	return address + "?" + r.URL.RawQuery
+}
+
 func StringParameterBoolValue(value string) bool {
 	lower := strings.ToLower(value)
 	return strings.EqualFold(lower, "true")
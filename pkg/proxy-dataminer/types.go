@@ -10,31 +10,45 @@ import (
 
 	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 
+	auth "github.com/dvonthenen/enterprise-conversation-application/pkg/auth"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	health "github.com/dvonthenen/enterprise-conversation-application/pkg/health"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
 	instance "github.com/dvonthenen/enterprise-conversation-application/pkg/proxy-dataminer/instance"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
+	supervisor "github.com/dvonthenen/enterprise-conversation-application/pkg/supervisor"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 )
 
-// Credentials is the input needed to login to neo4j
-type Credentials struct {
-	ConnectionStr string
-	Username      string
-	Password      string
-}
-
 // ServerOptions for the main HTTP endpoint
 type ServerOptions struct {
 	CrtFile              string
 	KeyFile              string
+	TLS                  *tlsconfig.Options // nil serves CrtFile and KeyFile with the defaults
 	StartPort            int
 	EndPort              int
+	Neo4j                *dbconfig.Config // defaults to dbconfig.FromEnv()
 	RabbitURI            string
+	Bus                  *businterfaces.Bus  // defaults to the bus for the RabbitURI scheme
+	ContentType          string              // codec.ContentTypeJSON (default) or codec.ContentTypeProtobuf
+	RawPolicy            rawevent.Policy     // rawevent.DefaultPolicy when empty
+	Redaction            *redaction.Options  // nil saves and publishes the payloads as received
+	Encryption           *encryption.Options // nil stores the content in plaintext
+	Auth                 *auth.Options       // nil lets any client open and observe conversations of the default tenant, and turns off the outbox endpoint
 	TranscriptionEnabled bool
 	MessagingEnabled     bool
+	Metrics              *metrics.ServerOptions   // nil disables the metrics endpoint
+	Tracing              *tracing.ProviderOptions // nil disables tracing
 }
 
 type Server struct {
 	// server versions
 	options ServerOptions
-	creds   Credentials
 
 	// bookkeeping
 	instanceById   map[string]*instance.Proxy
@@ -44,6 +58,27 @@ type Server struct {
 	ticker         *time.Ticker
 	stopPoll       chan struct{}
 
+	// tls for every listener
+	tls *tlsconfig.Reloader
+
 	// neo4j
-	driver *neo4j.DriverWithContext
+	driver     *neo4j.DriverWithContext
+	encryptors *encryption.Encryptors
+
+	// authn/authz
+	guard *auth.Guard
+
+	// outbox
+	bus    *businterfaces.Bus
+	outbox *outbox.Relay
+
+	// metrics and tracing
+	metrics *metrics.Server
+	tracer  *tracing.Provider
+
+	// health
+	health *health.Checker
+
+	// reconnects
+	supervisors []*supervisor.Supervisor
 }
@@ -0,0 +1,195 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rawevent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+func NewCompactor(options CompactorOptions) (*Compactor, error) {
+	if options.Driver == nil {
+		klog.V(1).Infof("Driver is nil\n")
+		return nil, ErrInvalidInput
+	}
+	if len(options.DatabaseName) == 0 {
+		options.DatabaseName = dbconfig.DefaultDatabaseName
+	}
+	if len(options.Policy) == 0 {
+		options.Policy = DefaultPolicy
+	}
+	if options.Policy != PolicyReference && options.Policy != PolicyNone {
+		klog.V(1).Infof("Policy %s can't be compacted to\n", options.Policy)
+		return nil, ErrUnsupportedPolicy
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+
+	c := &Compactor{
+		options: options,
+		driver:  options.Driver,
+	}
+	return c, nil
+}
+
+/*
+	Compact moves the inline raw properties on nodes and relationships into RawEvent nodes,
+	or removes them for PolicyNone. Copies of the same payload share a RawEvent since its
+	ID is the hash of the payload, so running it again or after an interruption is safe.
+*/
+func (c *Compactor) Compact() (*CompactResult, error) {
+	klog.V(6).Infof("Compactor.Compact ENTER\n")
+
+	ctx := context.Background()
+	session := (*c.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: c.options.DatabaseName})
+	defer session.Close(ctx)
+
+	findNodesQuery := utils.ReplaceIndexes(`
+		MATCH (n)
+		WHERE n.raw IS NOT NULL AND NOT n:RawEvent
+		RETURN elementId(n) AS id, n.raw AS raw, n.#conversation_index# AS conversationId
+		LIMIT $batch_size
+		`)
+	updateNodesQuery := `
+		UNWIND $items AS item
+		MATCH (n)
+		WHERE elementId(n) = item.id
+		SET n.rawEventId = item.rawEventId
+		REMOVE n.raw
+		`
+	nodes, err := c.compact(ctx, session, findNodesQuery, updateNodesQuery)
+	if err != nil {
+		klog.V(1).Infof("compact nodes failed. Err: %v\n", err)
+		klog.V(6).Infof("Compactor.Compact LEAVE\n")
+		return nil, err
+	}
+
+	findRelationshipsQuery := utils.ReplaceIndexes(`
+		MATCH ()-[x]->()
+		WHERE x.raw IS NOT NULL
+		RETURN elementId(x) AS id, x.raw AS raw, x.#conversation_index# AS conversationId
+		LIMIT $batch_size
+		`)
+	updateRelationshipsQuery := `
+		UNWIND $items AS item
+		MATCH ()-[x]->()
+		WHERE elementId(x) = item.id
+		SET x.rawEventId = item.rawEventId
+		REMOVE x.raw
+		`
+	relationships, err := c.compact(ctx, session, findRelationshipsQuery, updateRelationshipsQuery)
+	if err != nil {
+		klog.V(1).Infof("compact relationships failed. Err: %v\n", err)
+		klog.V(6).Infof("Compactor.Compact LEAVE\n")
+		return nil, err
+	}
+
+	klog.V(4).Infof("Compactor.Compact Succeeded\n")
+	klog.V(6).Infof("Compactor.Compact LEAVE\n")
+
+	return &CompactResult{
+		Nodes:         nodes,
+		Relationships: relationships,
+	}, nil
+}
+
+// compact works through the matches of findQuery one batch per transaction until none are left
+func (c *Compactor) compact(ctx context.Context, session neo4j.SessionWithContext, findQuery, updateQuery string) (int, error) {
+	createRawEventsQuery := utils.ReplaceIndexes(`
+		UNWIND $items AS item
+		MERGE (r:RawEvent { #raw_event_index#: item.rawEventId })
+			ON CREATE SET
+				r.createdAt = datetime(),
+				r.lastAccessed = datetime()
+			ON MATCH SET
+				r.lastAccessed = datetime()
+		SET r += { #raw_event_index#: item.rawEventId, raw: item.raw, lastAccessed: datetime() }
+		SET r.#conversation_index# = coalesce(r.#conversation_index#, item.conversationId)
+		`)
+
+	total := 0
+	for {
+		batchCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		count, err := session.ExecuteWrite(batchCtx,
+			func(tx neo4j.ManagedTransaction) (any, error) {
+				result, err := tx.Run(batchCtx, findQuery, map[string]any{
+					"batch_size": c.options.BatchSize,
+				})
+				if err != nil {
+					klog.V(1).Infof("neo4j.Run failed find raw properties. Err: %v\n", err)
+					return 0, err
+				}
+				records, err := result.Collect(batchCtx)
+				if err != nil {
+					klog.V(1).Infof("neo4j.Collect failed. Err: %v\n", err)
+					return 0, err
+				}
+				if len(records) == 0 {
+					return 0, nil
+				}
+
+				items := make([]map[string]any, 0, len(records))
+				for _, record := range records {
+					id, _ := record.Get("id")
+					raw, _ := record.Get("raw")
+					conversationId, _ := record.Get("conversationId")
+
+					str, _ := raw.(string)
+					hash := sha256.Sum256([]byte(str))
+					items = append(items, map[string]any{
+						"id":             id,
+						"raw":            str,
+						"rawEventId":     hex.EncodeToString(hash[:]),
+						"conversationId": conversationId,
+					})
+				}
+
+				if c.options.Policy == PolicyReference {
+					_, err = tx.Run(batchCtx, createRawEventsQuery, map[string]any{
+						"items": items,
+					})
+					if err != nil {
+						klog.V(1).Infof("neo4j.Run failed create raw events. Err: %v\n", err)
+						return 0, err
+					}
+				} else {
+					for _, item := range items {
+						item["rawEventId"] = nil
+					}
+				}
+
+				_, err = tx.Run(batchCtx, updateQuery, map[string]any{
+					"items": items,
+				})
+				if err != nil {
+					klog.V(1).Infof("neo4j.Run failed remove raw properties. Err: %v\n", err)
+					return 0, err
+				}
+
+				return len(items), nil
+			})
+		cancel()
+		if err != nil {
+			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+			return total, err
+		}
+
+		if count.(int) == 0 {
+			break
+		}
+		total += count.(int)
+		klog.V(3).Infof("Compacted %d raw properties\n", total)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rawevent
+
+import (
+	"errors"
+)
+
+const (
+	// PolicyNone doesn't keep the raw Symbl payloads
+	PolicyNone Policy = "none"
+
+	// PolicyReference saves each payload once in a RawEvent node referenced by rawEventId
+	PolicyReference Policy = "reference"
+
+	// PolicyInline copies the payload into a raw property on every node and relationship
+	PolicyInline Policy = "inline"
+
+	// DefaultPolicy is used when no policy is given
+	DefaultPolicy Policy = PolicyReference
+
+	// DefaultBatchSize number of raw properties the compactor moves per transaction
+	DefaultBatchSize int = 500
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnsupportedPolicy the raw payload policy is not known
+	ErrUnsupportedPolicy = errors.New("raw payload policy is not supported")
+)
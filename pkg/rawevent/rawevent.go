@@ -0,0 +1,87 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rawevent
+
+import (
+	uuid "github.com/google/uuid"
+
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+/*
+	The dataminers used to copy the full Symbl payload into a raw property on every node and
+	relationship created from it. A TrackerResponse with many matches was stored once per
+	match, so the database grew much faster than the conversations did.
+
+	The handlers now build a RawEvent for each payload and use Inline() and Id() for the raw
+	and rawEventId properties. Depending on the Policy one of them holds the value and the
+	other is nil, which removes the property when the node or relationship is updated.
+*/
+
+// Supported is true for the known policies, an empty policy is the DefaultPolicy
+func Supported(policy Policy) bool {
+	switch policy {
+	case "", PolicyNone, PolicyReference, PolicyInline:
+		return true
+	}
+	return false
+}
+
+// New prepares the Symbl payload for eventType to be saved according to the policy
+func New(policy Policy, conversationId, eventType string, data []byte) *RawEvent {
+	if len(policy) == 0 {
+		policy = DefaultPolicy
+	}
+
+	return &RawEvent{
+		policy:         policy,
+		id:             uuid.New().String(),
+		conversationId: conversationId,
+		eventType:      eventType,
+		data:           string(data),
+	}
+}
+
+// Statement saves the payload in a RawEvent node, it is nil unless the policy is PolicyReference
+func (r *RawEvent) Statement() *outbox.Statement {
+	if r.policy != PolicyReference {
+		return nil
+	}
+
+	createRawEventQuery := utils.ReplaceIndexes(`
+		MERGE (r:RawEvent { #raw_event_index#: $raw_event_id })
+			ON CREATE SET
+				r.createdAt = datetime(),
+				r.lastAccessed = datetime()
+			ON MATCH SET
+				r.lastAccessed = datetime()
+		SET r += { #raw_event_index#: $raw_event_id, #conversation_index#: $conversation_id, type: $type, raw: $raw, lastAccessed: datetime() }
+		`)
+	return &outbox.Statement{
+		Query: createRawEventQuery,
+		Params: map[string]any{
+			"raw_event_id":    r.id,
+			"conversation_id": r.conversationId,
+			"type":            r.eventType,
+			"raw":             r.data,
+		},
+	}
+}
+
+// Inline is the value for the raw property, nil unless the policy is PolicyInline
+func (r *RawEvent) Inline() any {
+	if r.policy != PolicyInline {
+		return nil
+	}
+	return r.data
+}
+
+// Id is the value for the rawEventId property, nil unless the policy is PolicyReference
+func (r *RawEvent) Id() any {
+	if r.policy != PolicyReference {
+		return nil
+	}
+	return r.id
+}
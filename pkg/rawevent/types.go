@@ -0,0 +1,40 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package rawevent
+
+import (
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Policy decides how the raw Symbl payloads are kept in the database
+type Policy string
+
+// RawEvent is one Symbl payload saved according to a Policy
+type RawEvent struct {
+	policy         Policy
+	id             string
+	conversationId string
+	eventType      string
+	data           string
+}
+
+// CompactorOptions to init the compactor
+type CompactorOptions struct {
+	Driver       *neo4j.DriverWithContext
+	DatabaseName string // dbconfig.DefaultDatabaseName when empty
+	Policy       Policy // PolicyReference or PolicyNone
+	BatchSize    int
+}
+
+// CompactResult counts the raw properties that were moved or removed
+type CompactResult struct {
+	Nodes         int
+	Relationships int
+}
+
+// Compactor moves inline raw properties written by older versions into RawEvent nodes
+type Compactor struct {
+	options CompactorOptions
+	driver  *neo4j.DriverWithContext
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package redaction
+
+import (
+	"errors"
+)
+
+// Action is what a policy does with a sensitive value
+type Action string
+
+const (
+	// ActionKeep leaves the value as it is
+	ActionKeep Action = "keep"
+
+	// ActionMask replaces the value with a label naming the rule or field, ie [EMAIL]
+	ActionMask Action = "mask"
+
+	// ActionHash replaces the value with a hash so equal values still match
+	ActionHash Action = "hash"
+
+	// ActionDrop removes the value
+	ActionDrop Action = "drop"
+)
+
+// Field groups the fields of the Symbl payloads sharing a policy
+type Field string
+
+const (
+	// FieldContent is the transcripts, insights and phrases, only the PII found in them is redacted
+	FieldContent Field = "content"
+
+	// FieldUser is the userId and email of the speakers and assignees
+	FieldUser Field = "user"
+
+	// FieldName is the name of the speakers and assignees
+	FieldName Field = "name"
+
+	// FieldEntity is the detectedValue and value of the PII entities found by Symbl
+	FieldEntity Field = "entity"
+)
+
+const (
+	// rules finding PII in the content
+	RuleEmail      string = "email"
+	RuleCreditCard string = "credit_card"
+	RuleSSN        string = "ssn"
+	RulePhone      string = "phone_number"
+
+	// RuleField is the rule of values redacted because of the field they are in
+	RuleField string = "field"
+
+	// RuleEntityPrefix starts the rule of values found by Symbl, ie entity:phone_number
+	RuleEntityPrefix string = "entity:"
+
+	// HashPrefix starts the values replaced by ActionHash
+	HashPrefix string = "sha256:"
+)
+
+var (
+	// DefaultPolicies are used for the fields without a policy
+	DefaultPolicies = map[Field]Action{
+		FieldContent: ActionMask,
+		FieldUser:    ActionHash,
+		FieldName:    ActionKeep,
+		FieldEntity:  ActionHash,
+	}
+
+	// DefaultEntityTypes are the Symbl entity types treated as PII
+	DefaultEntityTypes = []string{
+		"phone_number",
+		"email_address",
+		"credit_card_number",
+		"ssn",
+		"bank_account_number",
+	}
+
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnsupportedAction the action isn't one of the Action values
+	ErrUnsupportedAction = errors.New("redaction action must be keep, mask, hash or drop")
+
+	// ErrUnsupportedField the field isn't one of the Field values
+	ErrUnsupportedField = errors.New("redaction field must be content, user, name or entity")
+
+	// ErrInvalidRule the rule has no name or its pattern doesn't compile
+	ErrInvalidRule = errors.New("redaction rule needs a name and a valid pattern")
+)
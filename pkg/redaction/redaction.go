@@ -0,0 +1,566 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package redaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+/*
+	The dataminers pass each Symbl payload to Redact before saving or publishing it, so
+	Neo4j, the raw payloads and every plugin only see the redacted values. Each Field has
+	its own Action:
+
+		content     the PII found by Symbl or by the rules is replaced, the rest is kept
+		user        userId and email, hashed by default so the User nodes still merge
+		name        speaker and assignee names, kept by default
+		entity      detectedValue and value of the PII entities, hashed by default
+
+	Symbl's entity detections are used first. They are remembered for the rest of the
+	conversation, and the messages they reference are returned as Replacements because
+	Symbl usually reports the entity after the message was saved. The regular expressions
+	of the built-in rules and Options.Rules find the PII Symbl didn't.
+*/
+func New(options Options) (*Redactor, error) {
+	policies := make(map[Field]Action)
+	for field, action := range DefaultPolicies {
+		policies[field] = action
+	}
+	for field, action := range options.Policies {
+		if !SupportedField(field) {
+			klog.V(1).Infof("Redaction field %s is not supported\n", field)
+			return nil, ErrUnsupportedField
+		}
+		if !Supported(action) {
+			klog.V(1).Infof("Redaction action %s is not supported\n", action)
+			return nil, ErrUnsupportedAction
+		}
+		policies[field] = action
+	}
+
+	rules := []rule{
+		{name: RuleEmail, re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+		{name: RuleCreditCard, re: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), valid: luhn},
+		{name: RuleSSN, re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+		{name: RulePhone, re: regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{3}\)|\b\d{3})[\s.\-]?\d{3}[\s.\-]?\d{4}\b`)},
+	}
+	for _, custom := range options.Rules {
+		re, err := regexp.Compile(custom.Pattern)
+		if len(custom.Name) == 0 || err != nil {
+			klog.V(1).Infof("Redaction rule %s is invalid. Err: %v\n", custom.Name, err)
+			return nil, ErrInvalidRule
+		}
+		rules = append(rules, rule{name: custom.Name, re: re})
+	}
+
+	entityTypes := options.EntityTypes
+	if len(entityTypes) == 0 {
+		entityTypes = DefaultEntityTypes
+	}
+
+	r := &Redactor{
+		options:     options,
+		policies:    policies,
+		rules:       rules,
+		entityTypes: make(map[string]bool),
+		detected:    make(map[string]detection),
+	}
+	for _, entityType := range entityTypes {
+		r.entityTypes[snake(entityType)] = true
+	}
+	return r, nil
+}
+
+// Supported is true for the known actions
+func Supported(action Action) bool {
+	switch action {
+	case ActionKeep, ActionMask, ActionHash, ActionDrop:
+		return true
+	}
+	return false
+}
+
+// SupportedField is true for the known fields
+func SupportedField(field Field) bool {
+	switch field {
+	case FieldContent, FieldUser, FieldName, FieldEntity:
+		return true
+	}
+	return false
+}
+
+/*
+	Redact replaces the sensitive values in v, a pointer to a Symbl payload, and reports
+	what it replaced. A nil Redactor leaves v as it is.
+*/
+func (r *Redactor) Redact(v any) (*Report, error) {
+	report := &Report{}
+	if r == nil {
+		return report, nil
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		klog.V(1).Infof("Redact needs a pointer\n")
+		return nil, ErrInvalidInput
+	}
+
+	// the payload is redacted as JSON so the same fields are found in every Symbl type
+	data, err := json.Marshal(v)
+	if err != nil {
+		klog.V(1).Infof("json.Marshal failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	var doc any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&doc)
+	if err != nil {
+		klog.V(1).Infof("Decode failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	// Symbl's detections first, they apply to the content anywhere in the payload
+	r.detect(doc, "", report)
+
+	counts := make(map[Finding]int)
+	doc = r.walk(doc, "", "", "", counts)
+
+	data, err = json.Marshal(doc)
+	if err != nil {
+		klog.V(1).Infof("json.Marshal failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	// start from zero so the fields emptied by ActionDrop are emptied in v too
+	value.Elem().Set(reflect.Zero(value.Elem().Type()))
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		klog.V(1).Infof("json.Unmarshal failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	for finding, count := range counts {
+		finding.Count = count
+		report.Findings = append(report.Findings, finding)
+		metrics.ObserveRedactions(string(finding.Field), finding.Rule, count)
+	}
+	sort.Slice(report.Findings, func(i, j int) bool {
+		if report.Findings[i].Field != report.Findings[j].Field {
+			return report.Findings[i].Field < report.Findings[j].Field
+		}
+		return report.Findings[i].Rule < report.Findings[j].Rule
+	})
+
+	return report, nil
+}
+
+// entityType is the type of the entity described by n, or the one inherited from its parents
+func entityType(n map[string]any, inherited string) string {
+	t, ok := n["type"].(string)
+	if !ok {
+		return inherited
+	}
+	if _, ok := n["detectedValue"]; ok {
+		return t
+	}
+	if _, ok := n["matches"]; ok {
+		return t
+	}
+	return inherited
+}
+
+// detect remembers the PII entities and the messages they were found in
+func (r *Redactor) detect(node any, inherited string, report *Report) {
+	switch n := node.(type) {
+	case map[string]any:
+		t := entityType(n, inherited)
+
+		value, ok := n["detectedValue"].(string)
+		if ok && len(value) > 0 && r.entityTypes[snake(t)] {
+			name := RuleEntityPrefix + snake(t)
+			with := r.replace(r.policies[FieldContent], name, value)
+
+			r.mu.Lock()
+			r.detected[value] = detection{
+				rule: name,
+				with: with,
+			}
+			r.mu.Unlock()
+
+			if r.policies[FieldContent] != ActionKeep {
+				refs, _ := n["messageRefs"].([]any)
+				for _, ref := range refs {
+					messageRef, _ := ref.(map[string]any)
+					id, _ := messageRef["id"].(string)
+					if len(id) > 0 {
+						report.Replacements = append(report.Replacements, Replacement{
+							MessageId: id,
+							Value:     value,
+							With:      with,
+						})
+					}
+				}
+			}
+		}
+
+		for _, child := range n {
+			r.detect(child, t, report)
+		}
+	case []any:
+		for _, child := range n {
+			r.detect(child, inherited, report)
+		}
+	}
+}
+
+// walk redacts the strings in node, key is the field holding node and parent the one holding key
+func (r *Redactor) walk(node any, key, parent, inherited string, counts map[Finding]int) any {
+	switch n := node.(type) {
+	case map[string]any:
+		t := entityType(n, inherited)
+		for k, child := range n {
+			n[k] = r.walk(child, k, key, t, counts)
+		}
+		return n
+	case []any:
+		for i, child := range n {
+			n[i] = r.walk(child, key, parent, inherited, counts)
+		}
+		return n
+	case string:
+		field, ok := classify(key, parent)
+		if !ok || len(n) == 0 {
+			return n
+		}
+		if field == FieldEntity && !r.entityTypes[snake(inherited)] {
+			return n
+		}
+		if field == FieldContent {
+			return r.content(n, counts)
+		}
+
+		action := r.policies[field]
+		if action == ActionKeep || strings.HasPrefix(n, HashPrefix) {
+			return n
+		}
+		name := RuleField
+		label := string(field)
+		if field == FieldEntity {
+			name = RuleEntityPrefix + snake(inherited)
+			label = name
+		}
+		counts[Finding{Field: field, Rule: name}]++
+		return r.replace(action, label, n)
+	}
+	return node
+}
+
+// classify finds the policy of a string from the field holding it
+func classify(key, parent string) (Field, bool) {
+	switch {
+	case contains(shared.ContentKeys, key):
+		return FieldContent, true
+	case contains(shared.UserKeys, key):
+		return FieldUser, true
+	case contains(shared.EntityKeys, key):
+		return FieldEntity, true
+	case contains(shared.NameKeys, key):
+		switch parent {
+		case "from", "user", "assignee", "speaker":
+			return FieldName, true
+		}
+	}
+	return "", false
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// content replaces Symbl's detections and then the rule matches in str
+func (r *Redactor) content(str string, counts map[Finding]int) string {
+	action := r.policies[FieldContent]
+	if action == ActionKeep {
+		return str
+	}
+
+	// longest first so a detection inside another one doesn't split it
+	r.mu.Lock()
+	values := make([]string, 0, len(r.detected))
+	for value := range r.detected {
+		values = append(values, value)
+	}
+	detected := make(map[string]detection, len(r.detected))
+	for value, d := range r.detected {
+		detected[value] = d
+	}
+	r.mu.Unlock()
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	for _, value := range values {
+		count := strings.Count(str, value)
+		if count == 0 {
+			continue
+		}
+		str = strings.ReplaceAll(str, value, detected[value].with)
+		counts[Finding{Field: FieldContent, Rule: detected[value].rule}] += count
+	}
+
+	for _, rl := range r.rules {
+		str = rl.re.ReplaceAllStringFunc(str, func(match string) string {
+			if rl.valid != nil && !rl.valid(match) {
+				return match
+			}
+			counts[Finding{Field: FieldContent, Rule: rl.name}]++
+			return r.replace(action, rl.name, match)
+		})
+	}
+
+	return str
+}
+
+// replace is what value becomes, a mask is the name in capitals without the entity prefix
+func (r *Redactor) replace(action Action, name, value string) string {
+	switch action {
+	case ActionKeep:
+		return value
+	case ActionHash:
+		var h hash.Hash
+		if len(r.options.HashKey) > 0 {
+			h = hmac.New(sha256.New, []byte(r.options.HashKey))
+		} else {
+			h = sha256.New()
+		}
+		h.Write([]byte(value))
+		return HashPrefix + hex.EncodeToString(h.Sum(nil))[:32]
+	case ActionDrop:
+		return ""
+	}
+	return "[" + strings.ToUpper(strings.TrimPrefix(name, RuleEntityPrefix)) + "]"
+}
+
+// snake turns the Symbl entity types phoneNumber, PHONE_NUMBER and phone number into phone_number
+func snake(str string) string {
+	var b strings.Builder
+	var prev rune
+	for _, c := range str {
+		switch {
+		case c == ' ' || c == '-' || c == '_':
+			if prev != '_' {
+				b.WriteRune('_')
+			}
+			c = '_'
+		case unicode.IsUpper(c):
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(c))
+		default:
+			b.WriteRune(c)
+		}
+		prev = c
+	}
+	return b.String()
+}
+
+// luhn checks the card number checksum
+func luhn(match string) bool {
+	digits := make([]int, 0, len(match))
+	for _, c := range match {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := range digits {
+		digit := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// Statements save the audit of the report and fix the messages saved before Symbl's detections
+func Statements(report *Report, conversationId, eventType string) []outbox.Statement {
+	statements := make([]outbox.Statement, 0)
+	if report == nil {
+		return statements
+	}
+
+	// what was redacted, never the values
+	if len(report.Findings) > 0 {
+		findings := make([]map[string]any, 0)
+		for _, finding := range report.Findings {
+			findings = append(findings, map[string]any{
+				"field": string(finding.Field),
+				"rule":  finding.Rule,
+				"count": finding.Count,
+			})
+		}
+
+		createRedactionQuery := utils.ReplaceIndexes(`
+			MATCH (c:Conversation { #conversation_index#: $conversation_id })
+			UNWIND $findings AS finding
+			CREATE (r:Redaction { #conversation_index#: $conversation_id, type: $type, field: finding.field, rule: finding.rule, count: finding.count, createdAt: datetime() })
+			CREATE (c)-[:REDACTED]->(r)
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: createRedactionQuery,
+			Params: map[string]any{
+				"conversation_id": conversationId,
+				"type":            eventType,
+				"findings":        findings,
+			},
+		})
+	}
+
+	if len(report.Replacements) > 0 {
+		replacements := make([]map[string]any, 0)
+		for _, replacement := range report.Replacements {
+			replacements = append(replacements, map[string]any{
+				"message_id": replacement.MessageId,
+				"value":      replacement.Value,
+				"with":       replacement.With,
+			})
+		}
+
+		updateMessagesQuery := utils.ReplaceIndexes(`
+			UNWIND $replacements AS replacement
+			MATCH (m:Message { #message_index#: replacement.message_id })
+			SET m.content = replace(m.content, replacement.value, replacement.with)
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: updateMessagesQuery,
+			Params: map[string]any{
+				"replacements": replacements,
+			},
+		})
+	}
+
+	return statements
+}
+
+/*
+	EncryptedStatements fixes the messages of the report for Dataminers that encrypt the
+	content. The database can't replace inside an encrypted value, so the messages are read,
+	decrypted, fixed and encrypted again here. The replacements are taken out of the report,
+	pass it to Statements afterwards for the audit.
+*/
+func EncryptedStatements(ctx context.Context, session *neo4j.SessionWithContext, encryptor *encryption.Encryptor, report *Report) ([]outbox.Statement, error) {
+	statements := make([]outbox.Statement, 0)
+	if encryptor == nil || report == nil || len(report.Replacements) == 0 {
+		return statements, nil
+	}
+	if session == nil {
+		klog.V(1).Infof("session is nil\n")
+		return nil, ErrInvalidInput
+	}
+
+	messageIds := make([]string, 0)
+	for _, replacement := range report.Replacements {
+		messageIds = append(messageIds, replacement.MessageId)
+	}
+
+	result, err := (*session).ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		findMessagesQuery := utils.ReplaceIndexes(`
+			MATCH (m:Message)
+			WHERE m.#message_index# IN $message_ids
+			RETURN m.#message_index# AS id, m.content AS content
+			`)
+		result, err := tx.Run(ctx, findMessagesQuery, map[string]any{
+			"message_ids": messageIds,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result.Collect(ctx)
+	})
+	if err != nil {
+		klog.V(1).Infof("ExecuteRead failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	messages := make([]map[string]any, 0)
+	for _, record := range result.([]*neo4j.Record) {
+		id, _ := record.Get("id")
+		value, _ := record.Get("content")
+		content, _ := value.(string)
+
+		content, err := encryptor.Decrypt(ctx, content)
+		if err != nil {
+			klog.V(1).Infof("Decrypt message %v failed. Err: %v\n", id, err)
+			return nil, err
+		}
+		for _, replacement := range report.Replacements {
+			if replacement.MessageId == id {
+				content = strings.ReplaceAll(content, replacement.Value, replacement.With)
+			}
+		}
+		content, err = encryptor.Encrypt(ctx, content)
+		if err != nil {
+			klog.V(1).Infof("Encrypt message %v failed. Err: %v\n", id, err)
+			return nil, err
+		}
+
+		messages = append(messages, map[string]any{
+			"message_id": id,
+			"content":    content,
+		})
+	}
+	report.Replacements = nil
+
+	if len(messages) > 0 {
+		updateMessagesQuery := utils.ReplaceIndexes(`
+			UNWIND $messages AS message
+			MATCH (m:Message { #message_index#: message.message_id })
+			SET m.content = message.content
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: updateMessagesQuery,
+			Params: map[string]any{
+				"messages": messages,
+			},
+		})
+	}
+
+	return statements, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package redaction
+
+import (
+	"regexp"
+	"sync"
+)
+
+// Rule finds PII in the content using a regular expression
+type Rule struct {
+	Name    string `json:"name" yaml:"name"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+}
+
+// Options for New
+type Options struct {
+	Policies    map[Field]Action // DefaultPolicies for the fields left out
+	Rules       []Rule           // applied after the built-in rules
+	EntityTypes []string         // DefaultEntityTypes when empty
+	HashKey     string           // ActionHash uses HMAC-SHA256 with this key, plain SHA-256 when empty
+}
+
+// Finding counts the values of a field redacted by a rule
+type Finding struct {
+	Field Field
+	Rule  string
+	Count int
+}
+
+// Replacement of PII in a message saved before Symbl detected it
+type Replacement struct {
+	MessageId string
+	Value     string
+	With      string
+}
+
+// Report of a payload passed to Redact
+type Report struct {
+	Findings     []Finding
+	Replacements []Replacement
+}
+
+// rule is a compiled Rule, valid drops matches like card numbers failing the checksum
+type rule struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// detection is PII found by Symbl and what it is replaced with in the content
+type detection struct {
+	rule string
+	with string
+}
+
+// Redactor redacts the payloads of a conversation and remembers the PII Symbl found in it
+type Redactor struct {
+	options     Options
+	policies    map[Field]Action
+	rules       []rule
+	entityTypes map[string]bool
+
+	// PII found by Symbl so far, by value
+	detected map[string]detection
+	mu       sync.Mutex
+}
@@ -15,6 +15,11 @@ const (
 	AuthTypeEnvVars             = 2
 )
 
+const (
+	// trend analytics endpoint
+	DefaultTrendsPath string = "/v1/trends/"
+)
+
 var (
 	// ErrInvalidInput required input was not found
 	ErrInvalidInput = errors.New("required input was not found")
@@ -13,4 +13,7 @@ var (
 
 	// ErrChannelNotFound rabbit channel was not found
 	ErrChannelNotFound = errors.New("rabbit channel was not found")
+
+	// ErrTenantMismatch the conversation was created by another tenant
+	ErrTenantMismatch = errors.New("conversation belongs to another tenant")
 )
@@ -10,14 +10,19 @@ import (
 	"strings"
 	"time"
 
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	asyncinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/async/v1/interfaces"
 	sdkinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/async/v1/interfaces"
 	prettyjson "github.com/hokaccha/go-prettyjson"
 	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	klog "k8s.io/klog/v2"
 
+	logging "github.com/dvonthenen/enterprise-conversation-application/pkg/logging"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
 	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
 	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
 )
 
@@ -26,12 +31,27 @@ func NewHandler(options MessageHandlerOptions) (*MessageHandler, error) {
 		klog.V(1).Infof("conversationId is empty\n")
 		return nil, ErrInvalidInput
 	}
+	if len(options.Tenant) == 0 {
+		options.Tenant = shared.DefaultTenant
+	}
+
+	var redactor *redaction.Redactor
+	if options.Redaction != nil {
+		var err error
+		redactor, err = redaction.New(*options.Redaction)
+		if err != nil {
+			klog.V(1).Infof("redaction.New failed. Err: %v\n", err)
+			return nil, err
+		}
+	}
 
 	mh := &MessageHandler{
 		conversationId: options.ConversationId,
 		options:        options,
 		neo4jMgr:       options.Neo4jMgr,
-		rabbitMgr:      options.RabbitMgr,
+		encryptor:      options.Encryptor,
+		outbox:         options.Outbox,
+		redactor:       redactor,
 	}
 	return mh, nil
 }
@@ -39,118 +59,13 @@ func NewHandler(options MessageHandlerOptions) (*MessageHandler, error) {
 func (mh *MessageHandler) Init() error {
 	klog.V(6).Infof("MessageHandler.Init ENTER\n")
 
-	// init all rabbit channels
-	err := mh.setupRabbitChannels()
-	if err != nil {
-		klog.V(1).Infof("setupRabbitChannels failed. Err: %v\n", err)
-		klog.V(6).Infof("MessageHandler.Init LEAVE\n")
-		return err
-	}
-
+	// rabbit publishing is handled by the outbox relay
 	klog.V(4).Infof("MessageHandler.Init Succeeded\n")
 	klog.V(6).Infof("MessageHandler.Init LEAVE\n")
 
 	return nil
 }
 
-func (mh *MessageHandler) setupRabbitChannels() error {
-	/*
-		Setup Publishers...
-	*/
-	_, err := (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncConversationInit,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncConversationInit, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncMessage,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncMessage, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncQuestion,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncQuestion, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncFollowUp,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncFollowUp, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncActionItem,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncActionItem, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncTopic,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncTopic, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncTracker,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncTracker, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncEntity,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncEntity, err)
-		return err
-	}
-	_, err = (*mh.rabbitMgr).CreatePublisher(rabbitinterfaces.PublisherOptions{
-		Name:        shared.RabbitAsyncConversationTeardown,
-		Type:        rabbitinterfaces.ExchangeTypeFanout,
-		AutoDeleted: true,
-		IfUnused:    true,
-	})
-	if err != nil {
-		klog.V(1).Infof("CreatePublisher %s failed. Err: %v\n", shared.RabbitAsyncConversationTeardown, err)
-		return err
-	}
-
-	return nil
-}
-
 func (mh *MessageHandler) DoesConversationExist() (bool, error) {
 	if mh.existsSet {
 		return mh.conversationExists, nil
@@ -159,12 +74,13 @@ func (mh *MessageHandler) DoesConversationExist() (bool, error) {
 	ctx := context.Background()
 
 	var retValue int64
+	var tenants []any
 
 	_, err := (*mh.neo4jMgr).ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		myQuery := utils.ReplaceIndexes(`
 			MATCH (c:Conversation)
 			WHERE c.#conversation_index# = $conversation_id
-			RETURN count(c)`)
+			RETURN count(c), collect(c.tenantId)`)
 		result, err := tx.Run(ctx, myQuery, map[string]any{
 			"conversation_id": mh.conversationId,
 		})
@@ -174,6 +90,7 @@ func (mh *MessageHandler) DoesConversationExist() (bool, error) {
 
 		for result.Next(ctx) {
 			retValue = result.Record().Values[0].(int64)
+			tenants, _ = result.Record().Values[1].([]any)
 		}
 
 		return nil, result.Err()
@@ -183,6 +100,14 @@ func (mh *MessageHandler) DoesConversationExist() (bool, error) {
 		return false, err
 	}
 
+	// another tenant can't add to the conversation or find out it was processed
+	for _, tenant := range tenants {
+		if tenant != mh.options.Tenant {
+			klog.V(1).Infof("Conversation %s belongs to tenant %v, not %s\n", mh.conversationId, tenant, mh.options.Tenant)
+			return false, ErrTenantMismatch
+		}
+	}
+
 	mh.conversationExists = (retValue > 0)
 	mh.existsSet = true
 
@@ -197,6 +122,90 @@ func (mh *MessageHandler) Teardown() error {
 	return nil
 }
 
+// redact masks the PII in v before it is saved or published, the statements record what was masked
+func (mh *MessageHandler) redact(v any, eventType string) ([]outbox.Statement, error) {
+	report, err := mh.redactor.Redact(v)
+	if err != nil {
+		klog.V(1).Infof("Redact failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	// the database can't fix messages it only has encrypted
+	statements, err := redaction.EncryptedStatements(context.Background(), mh.neo4jMgr, mh.encryptor, report)
+	if err != nil {
+		klog.V(1).Infof("EncryptedStatements failed. Err: %v\n", err)
+		return nil, err
+	}
+	return append(redaction.Statements(report, mh.conversationId, eventType), statements...), nil
+}
+
+// encrypt the content before it is saved, it is kept as it is when encryption is off
+func (mh *MessageHandler) encrypt(content string) (string, error) {
+	content, err := mh.encryptor.Encrypt(context.Background(), content)
+	if err != nil {
+		klog.V(1).Infof("Encrypt failed. Err: %v\n", err)
+		return "", err
+	}
+	return content, nil
+}
+
+// rawEvent prepares the encrypted payload for eventType to be saved according to the raw policy
+func (mh *MessageHandler) rawEvent(eventType string, data []byte) (*rawevent.RawEvent, error) {
+	if mh.options.RawPolicy == rawevent.PolicyNone {
+		return rawevent.New(mh.options.RawPolicy, mh.conversationId, eventType, nil), nil
+	}
+
+	raw, err := mh.encrypt(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return rawevent.New(mh.options.RawPolicy, mh.conversationId, eventType, []byte(raw)), nil
+}
+
+// commit saves all statements and the event for the exchange in one transaction and lets the relay know
+func (mh *MessageHandler) commit(statements []outbox.Statement, exchange string, data []byte) error {
+	// each insight starts its own trace, there is no Symbl message to follow
+	ctx, span := tracing.Tracer().Start(context.Background(), "save "+exchange)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// describe the event next to the payload, including the trace it belongs to
+	eventId := outbox.NewEventId()
+	envelope := shared.NewEnvelope(eventId, exchange, mh.conversationId, shared.SourceRestDataminer)
+	envelope.Tenant = mh.options.Tenant
+	tracing.Inject(ctx, envelope)
+
+	data, err := shared.WrapEnvelope(data, envelope)
+	if err != nil {
+		klog.V(1).Infof("shared.WrapEnvelope failed. Err: %v\n", err)
+		tracing.Fail(span, err)
+		return err
+	}
+
+	// the event holds the whole payload, it is encrypted like the content
+	payload, err := mh.encrypt(string(data))
+	if err != nil {
+		tracing.Fail(span, err)
+		return err
+	}
+
+	err = outbox.Write(ctx, mh.neo4jMgr, statements, envelope, []byte(payload))
+	if err != nil {
+		klog.V(1).Infof("outbox.Write failed. Err: %v\n", err)
+		tracing.Fail(span, err)
+		return err
+	}
+	logging.Conversation(mh.conversationId, exchange).V(4).Info("Event queued", logging.KeyEventId, eventId)
+
+	if mh.outbox != nil {
+		mh.outbox.Kick()
+	}
+
+	return nil
+}
+
 func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.InitializationMessage) error {
 	klog.V(6).Infof("InitializedConversation ENTER\n")
 
@@ -218,7 +227,7 @@ func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.Initializati
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("InitializationMessage:\n%v\n\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncConversationInit).V(2).Info("InitializationMessage", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// insights already created
@@ -229,38 +238,28 @@ func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.Initializati
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+
 	// only add to DB if new
 	if !exists {
-		// context
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		// neo4j create conversation object
-		_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-			func(tx neo4j.ManagedTransaction) (any, error) {
-				createConversationQuery := utils.ReplaceIndexes(`
-					MERGE (c:Conversation { #conversation_index#: $conversation_id })
-						ON CREATE SET
-							c.createdAt = datetime(),
-							c.lastAccessed = datetime()
-						ON MATCH SET
-							c.lastAccessed = datetime()
-					SET c = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime() }
-					`)
-				result, err := tx.Run(ctx, createConversationQuery, map[string]any{
-					"conversation_id": mh.conversationId,
-				})
-				if err != nil {
-					klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-					return nil, err
-				}
-				return result.Collect(ctx)
-			})
-		if err != nil {
-			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-			klog.V(6).Infof("MessageHandler.Init LEAVE\n")
-			return err
-		}
+		createConversationQuery := utils.ReplaceIndexes(`
+			MERGE (c:Conversation { #conversation_index#: $conversation_id })
+				ON CREATE SET
+					c.createdAt = datetime(),
+					c.lastAccessed = datetime()
+				ON MATCH SET
+					c.lastAccessed = datetime()
+			SET c += { #conversation_index#: $conversation_id, tenantId: $tenant_id }
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: createConversationQuery,
+			Params: map[string]any{
+				"conversation_id": mh.conversationId,
+				"tenant_id":       mh.options.Tenant,
+			},
+		})
 	}
 
 	// rabbitmq
@@ -278,13 +277,15 @@ func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.Initializati
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncConversationInit, data)
+	err = mh.commit(statements, shared.RabbitAsyncConversationInit, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("InitializedConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("InitializedConversation.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("InitializedConversation.Outbox:\n%s\n", string(data))
+
+	metrics.ConversationsStarted.WithLabelValues(shared.SourceRestDataminer).Inc()
 
 	klog.V(4).Infof("InitializedConversation Succeeded\n")
 	klog.V(6).Infof("InitializedConversation LEAVE\n")
@@ -295,6 +296,13 @@ func (mh *MessageHandler) InitializedConversation(im *sdkinterfaces.Initializati
 func (mh *MessageHandler) MessageResult(mr *sdkinterfaces.MessageResult) error {
 	klog.V(6).Infof("MessageResult ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(mr, shared.RabbitAsyncMessage)
+	if err != nil {
+		klog.V(6).Infof("MessageResult LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(mr)
 	if err != nil {
 		klog.V(1).Infof("MessageResult json.Marshal failed. Err: %v\n", err)
@@ -310,7 +318,7 @@ func (mh *MessageHandler) MessageResult(mr *sdkinterfaces.MessageResult) error {
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("MessageResult:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncMessage).V(2).Info("MessageResult", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// any insights?
@@ -328,72 +336,79 @@ func (mh *MessageHandler) MessageResult(mr *sdkinterfaces.MessageResult) error {
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy, nothing is added for existing conversations
+	raw, err := mh.rawEvent(shared.RabbitAsyncMessage, data)
+	if err != nil {
+		klog.V(6).Infof("MessageResult LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil && !exists {
+		statements = append(statements, *statement)
+	}
+
 	// only add to DB if new
 	if !exists {
-		// write the object to the database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		// process messages
 		for _, message := range mr.Messages {
-			_, err := (*mh.neo4jMgr).ExecuteWrite(ctx,
-				func(tx neo4j.ManagedTransaction) (any, error) {
-					createMessageToPeopleQuery := utils.ReplaceIndexes(`
-						MATCH (c:Conversation { #conversation_index#: $conversation_id })
-						MERGE (m:Message { #message_index#: $message_id })
-							ON CREATE SET
-								m.createdAt = datetime(),
-								m.lastAccessed = datetime()
-							ON MATCH SET
-								m.lastAccessed = datetime()
-						SET m = { #message_index#: $message_id, content: $content, startTime: $start_time, endTime: $end_time, timeOffset: $time_offset, duration: $duration, sequenceNumber: $sequence_number, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (u:User { #user_index#: $user_id })
-							ON CREATE SET
-								u.createdAt = datetime(),
-								u.lastAccessed = datetime()
-							ON MATCH SET
-								u.lastAccessed = datetime()
-						SET u = { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, createdAt: datetime(), lastAccessed: datetime() }
-						MERGE (c)-[x:MESSAGES { #conversation_index#: $conversation_id }]-(m)
-							ON CREATE SET
-								x.createdAt = datetime(),
-								x.lastAccessed = datetime()
-							ON MATCH SET
-								x.lastAccessed = datetime()
-						SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (m)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
-							ON CREATE SET
-								y.createdAt = datetime(),
-								y.lastAccessed = datetime()
-							ON MATCH SET
-								y.lastAccessed = datetime()
-						SET y = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						`)
-					result, err := tx.Run(ctx, createMessageToPeopleQuery, map[string]any{
-						"conversation_id": mh.conversationId,
-						"message_id":      message.ID,
-						"content":         message.Text,
-						"start_time":      message.StartTime,
-						"end_time":        message.EndTime,
-						"time_offset":     message.TimeOffset,
-						"duration":        message.Duration,
-						"sequence_number": "TODO", // TODO
-						"user_real_id":    message.From.ID,
-						"user_name":       message.From.Name,
-						"user_id":         message.From.ID, // TODO: Look into it
-						"raw":             string(data),
-					})
-					if err != nil {
-						klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-						return nil, err
-					}
-					return result.Collect(ctx)
-				})
+			content, err := mh.encrypt(message.Text)
 			if err != nil {
-				klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
 				klog.V(6).Infof("MessageResult LEAVE\n")
 				return err
 			}
+
+			createMessageToPeopleQuery := utils.ReplaceIndexes(`
+				MATCH (c:Conversation { #conversation_index#: $conversation_id })
+				MERGE (m:Message { #message_index#: $message_id })
+					ON CREATE SET
+						m.createdAt = datetime(),
+						m.lastAccessed = datetime()
+					ON MATCH SET
+						m.lastAccessed = datetime()
+				SET m += { #message_index#: $message_id, content: $content, startTime: $start_time, endTime: $end_time, timeOffset: $time_offset, duration: $duration, sequenceNumber: $sequence_number, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (u:User { #user_index#: $user_id })
+					ON CREATE SET
+						u.createdAt = datetime(),
+						u.lastAccessed = datetime()
+					ON MATCH SET
+						u.lastAccessed = datetime()
+				SET u += { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id }
+				MERGE (c)-[x:MESSAGES { #conversation_index#: $conversation_id }]-(m)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (m)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
+					ON CREATE SET
+						y.createdAt = datetime(),
+						y.lastAccessed = datetime()
+					ON MATCH SET
+						y.lastAccessed = datetime()
+				SET y += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createMessageToPeopleQuery,
+				Params: map[string]any{
+					"conversation_id": mh.conversationId,
+					"message_id":      message.ID,
+					"content":         content,
+					"start_time":      message.StartTime,
+					"end_time":        message.EndTime,
+					"time_offset":     message.TimeOffset,
+					"duration":        message.Duration,
+					"sequence_number": "TODO", // TODO
+					"user_real_id":    message.From.ID,
+					"user_name":       message.From.Name,
+					"user_id":         message.From.ID, // TODO: Look into it
+					"raw":             raw.Inline(),
+					"raw_event_id":    raw.Id(),
+				},
+			})
 		}
 	}
 
@@ -411,13 +426,13 @@ func (mh *MessageHandler) MessageResult(mr *sdkinterfaces.MessageResult) error {
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncMessage, data)
+	err = mh.commit(statements, shared.RabbitAsyncMessage, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("MessageResult LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("MessageResult.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("MessageResult.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("MessageResult Succeeded\n")
 	klog.V(6).Infof("MessageResult LEAVE\n")
@@ -428,6 +443,13 @@ func (mh *MessageHandler) MessageResult(mr *sdkinterfaces.MessageResult) error {
 func (mh *MessageHandler) QuestionResult(qr *sdkinterfaces.QuestionResult) error {
 	klog.V(6).Infof("QuestionResult ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(qr, shared.RabbitAsyncQuestion)
+	if err != nil {
+		klog.V(6).Infof("QuestionResult LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(qr)
 	if err != nil {
 		klog.V(1).Infof("QuestionResult json.Marshal failed. Err: %v\n", err)
@@ -443,7 +465,7 @@ func (mh *MessageHandler) QuestionResult(qr *sdkinterfaces.QuestionResult) error
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("QuestionResult:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncQuestion).V(2).Info("QuestionResult", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// any insights?
@@ -461,73 +483,80 @@ func (mh *MessageHandler) QuestionResult(qr *sdkinterfaces.QuestionResult) error
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy, nothing is added for existing conversations
+	raw, err := mh.rawEvent(shared.RabbitAsyncQuestion, data)
+	if err != nil {
+		klog.V(6).Infof("QuestionResult LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil && !exists {
+		statements = append(statements, *statement)
+	}
+
 	// only add to DB if new
 	if !exists {
-		// write the object to the database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		// if we need to do something with them
 		cnt := 0
 
 		for _, question := range qr.Questions {
-			_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-				func(tx neo4j.ManagedTransaction) (any, error) {
-					createInsightQuery := utils.ReplaceIndexes(`
-						MATCH (c:Conversation { #conversation_index#: $conversation_id })
-						MERGE (i:Insight { #insight_index#: $insight_id })
-							ON CREATE SET
-								i.createdAt = datetime(),
-								i.lastAccessed = datetime()
-							ON MATCH SET
-								i.lastAccessed = datetime()
-						SET i = { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (u:User { #user_index#: $user_id })
-							ON CREATE SET
-								u.createdAt = datetime(),
-								u.lastAccessed = datetime()
-							ON MATCH SET
-								u.lastAccessed = datetime()
-						SET u = { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, createdAt: datetime(), lastAccessed: datetime() }
-						MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
-							ON CREATE SET
-								x.createdAt = datetime(),
-								x.lastAccessed = datetime()
-							ON MATCH SET
-								x.lastAccessed = datetime()
-						SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
-							ON CREATE SET
-								y.createdAt = datetime(),
-								y.lastAccessed = datetime()
-							ON MATCH SET
-								y.lastAccessed = datetime()
-						SET y = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime() }
-						`)
-					result, err := tx.Run(ctx, createInsightQuery, map[string]any{
-						"conversation_id": mh.conversationId,
-						"insight_id":      question.ID,
-						"type":            strings.ToLower(question.Type),
-						"content":         question.Text,
-						"sequence_number": cnt,
-						"assignee_id":     "TODO", // TODO
-						"user_real_id":    question.From.ID,
-						"user_id":         question.From.ID, // TODO: Look into it
-						"user_name":       question.From.Name,
-						"raw":             string(data),
-					})
-					if err != nil {
-						klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-						return nil, err
-					}
-					return result.Collect(ctx)
-				})
+			content, err := mh.encrypt(question.Text)
 			if err != nil {
-				klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
 				klog.V(6).Infof("QuestionResult LEAVE\n")
 				return err
 			}
 
+			createInsightQuery := utils.ReplaceIndexes(`
+				MATCH (c:Conversation { #conversation_index#: $conversation_id })
+				MERGE (i:Insight { #insight_index#: $insight_id })
+					ON CREATE SET
+						i.createdAt = datetime(),
+						i.lastAccessed = datetime()
+					ON MATCH SET
+						i.lastAccessed = datetime()
+				SET i += { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (u:User { #user_index#: $user_id })
+					ON CREATE SET
+						u.createdAt = datetime(),
+						u.lastAccessed = datetime()
+					ON MATCH SET
+						u.lastAccessed = datetime()
+				SET u += { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id }
+				MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
+					ON CREATE SET
+						y.createdAt = datetime(),
+						y.lastAccessed = datetime()
+					ON MATCH SET
+						y.lastAccessed = datetime()
+				SET y += { #conversation_index#: $conversation_id }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createInsightQuery,
+				Params: map[string]any{
+					"conversation_id": mh.conversationId,
+					"insight_id":      question.ID,
+					"type":            strings.ToLower(question.Type),
+					"content":         content,
+					"sequence_number": cnt,
+					"assignee_id":     "TODO", // TODO
+					"user_real_id":    question.From.ID,
+					"user_id":         question.From.ID, // TODO: Look into it
+					"user_name":       question.From.Name,
+					"raw":             raw.Inline(),
+					"raw_event_id":    raw.Id(),
+				},
+			})
+
 			cnt++
 		}
 	}
@@ -546,13 +575,13 @@ func (mh *MessageHandler) QuestionResult(qr *sdkinterfaces.QuestionResult) error
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncQuestion, data)
+	err = mh.commit(statements, shared.RabbitAsyncQuestion, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("QuestionResult LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("QuestionResult.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("QuestionResult.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("QuestionResult Succeeded\n")
 	klog.V(6).Infof("QuestionResult LEAVE\n")
@@ -563,6 +592,13 @@ func (mh *MessageHandler) QuestionResult(qr *sdkinterfaces.QuestionResult) error
 func (mh *MessageHandler) FollowUpResult(fur *sdkinterfaces.FollowUpResult) error {
 	klog.V(6).Infof("FollowUpResult ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(fur, shared.RabbitAsyncFollowUp)
+	if err != nil {
+		klog.V(6).Infof("FollowUpResult LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(fur)
 	if err != nil {
 		klog.V(1).Infof("FollowUpResult json.Marshal failed. Err: %v\n", err)
@@ -578,7 +614,7 @@ func (mh *MessageHandler) FollowUpResult(fur *sdkinterfaces.FollowUpResult) erro
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("FollowUpResult:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncFollowUp).V(2).Info("FollowUpResult", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// any insights?
@@ -596,73 +632,80 @@ func (mh *MessageHandler) FollowUpResult(fur *sdkinterfaces.FollowUpResult) erro
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy, nothing is added for existing conversations
+	raw, err := mh.rawEvent(shared.RabbitAsyncFollowUp, data)
+	if err != nil {
+		klog.V(6).Infof("FollowUpResult LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil && !exists {
+		statements = append(statements, *statement)
+	}
+
 	// only add to DB if new
 	if !exists {
-		// write the object to the database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		// if we need to do something with them
 		cnt := 0
 
 		for _, followUps := range fur.FollowUps {
-			_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-				func(tx neo4j.ManagedTransaction) (any, error) {
-					createInsightQuery := utils.ReplaceIndexes(`
-						MATCH (c:Conversation { #conversation_index#: $conversation_id })
-						MERGE (i:Insight { #insight_index#: $insight_id })
-							ON CREATE SET
-								i.createdAt = datetime(),
-								i.lastAccessed = datetime()
-							ON MATCH SET
-								i.lastAccessed = datetime()
-						SET i = { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (u:User { #user_index#: $user_id })
-							ON CREATE SET
-								u.createdAt = datetime(),
-								u.lastAccessed = datetime()
-							ON MATCH SET
-								u.lastAccessed = datetime()
-						SET u = { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, createdAt: datetime(), lastAccessed: datetime() }
-						MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
-							ON CREATE SET
-								x.createdAt = datetime(),
-								x.lastAccessed = datetime()
-							ON MATCH SET
-								x.lastAccessed = datetime()
-						SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
-							ON CREATE SET
-								y.createdAt = datetime(),
-								y.lastAccessed = datetime()
-							ON MATCH SET
-								y.lastAccessed = datetime()
-						SET y = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime() }
-						`)
-					result, err := tx.Run(ctx, createInsightQuery, map[string]any{
-						"conversation_id": mh.conversationId,
-						"insight_id":      followUps.ID,
-						"type":            strings.ToLower(followUps.Type),
-						"content":         followUps.Text,
-						"sequence_number": cnt,
-						"assignee_id":     followUps.Assignee.ID, // TODO: Look into it ID or Name
-						"user_real_id":    followUps.From.ID,
-						"user_id":         followUps.From.ID, // TODO: Look into it
-						"user_name":       followUps.From.Name,
-						"raw":             string(data),
-					})
-					if err != nil {
-						klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-						return nil, err
-					}
-					return result.Collect(ctx)
-				})
+			content, err := mh.encrypt(followUps.Text)
 			if err != nil {
-				klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
 				klog.V(6).Infof("FollowUpResult LEAVE\n")
 				return err
 			}
 
+			createInsightQuery := utils.ReplaceIndexes(`
+				MATCH (c:Conversation { #conversation_index#: $conversation_id })
+				MERGE (i:Insight { #insight_index#: $insight_id })
+					ON CREATE SET
+						i.createdAt = datetime(),
+						i.lastAccessed = datetime()
+					ON MATCH SET
+						i.lastAccessed = datetime()
+				SET i += { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (u:User { #user_index#: $user_id })
+					ON CREATE SET
+						u.createdAt = datetime(),
+						u.lastAccessed = datetime()
+					ON MATCH SET
+						u.lastAccessed = datetime()
+				SET u += { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id }
+				MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
+					ON CREATE SET
+						y.createdAt = datetime(),
+						y.lastAccessed = datetime()
+					ON MATCH SET
+						y.lastAccessed = datetime()
+				SET y += { #conversation_index#: $conversation_id }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createInsightQuery,
+				Params: map[string]any{
+					"conversation_id": mh.conversationId,
+					"insight_id":      followUps.ID,
+					"type":            strings.ToLower(followUps.Type),
+					"content":         content,
+					"sequence_number": cnt,
+					"assignee_id":     followUps.Assignee.ID, // TODO: Look into it ID or Name
+					"user_real_id":    followUps.From.ID,
+					"user_id":         followUps.From.ID, // TODO: Look into it
+					"user_name":       followUps.From.Name,
+					"raw":             raw.Inline(),
+					"raw_event_id":    raw.Id(),
+				},
+			})
+
 			cnt++
 		}
 	}
@@ -681,13 +724,13 @@ func (mh *MessageHandler) FollowUpResult(fur *sdkinterfaces.FollowUpResult) erro
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncFollowUp, data)
+	err = mh.commit(statements, shared.RabbitAsyncFollowUp, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("FollowUpResult LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("FollowUpResult.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("FollowUpResult.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("FollowUpResult Succeeded\n")
 	klog.V(6).Infof("FollowUpResult LEAVE\n")
@@ -698,6 +741,13 @@ func (mh *MessageHandler) FollowUpResult(fur *sdkinterfaces.FollowUpResult) erro
 func (mh *MessageHandler) ActionItemResult(air *sdkinterfaces.ActionItemResult) error {
 	klog.V(6).Infof("ActionItemResult ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(air, shared.RabbitAsyncActionItem)
+	if err != nil {
+		klog.V(6).Infof("ActionItemResult LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(air)
 	if err != nil {
 		klog.V(1).Infof("ActionItemResult json.Marshal failed. Err: %v\n", err)
@@ -713,7 +763,7 @@ func (mh *MessageHandler) ActionItemResult(air *sdkinterfaces.ActionItemResult)
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("ActionItemResult:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncActionItem).V(2).Info("ActionItemResult", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// any insights?
@@ -731,72 +781,79 @@ func (mh *MessageHandler) ActionItemResult(air *sdkinterfaces.ActionItemResult)
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy, nothing is added for existing conversations
+	raw, err := mh.rawEvent(shared.RabbitAsyncActionItem, data)
+	if err != nil {
+		klog.V(6).Infof("ActionItemResult LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil && !exists {
+		statements = append(statements, *statement)
+	}
+
 	// only add to DB if new
 	if !exists {
-		// write the object to the database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		// if we need to do something with them
 		cnt := 0
 		for _, actionItem := range air.ActionItems {
-			_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-				func(tx neo4j.ManagedTransaction) (any, error) {
-					createInsightQuery := utils.ReplaceIndexes(`
-						MATCH (c:Conversation { #conversation_index#: $conversation_id })
-						MERGE (i:Insight { #insight_index#: $insight_id })
-							ON CREATE SET
-								i.createdAt = datetime(),
-								i.lastAccessed = datetime()
-							ON MATCH SET
-								i.lastAccessed = datetime()
-						SET i = { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (u:User { #user_index#: $user_id })
-							ON CREATE SET
-								u.createdAt = datetime(),
-								u.lastAccessed = datetime()
-							ON MATCH SET
-								u.lastAccessed = datetime()
-						SET u = { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id, createdAt: datetime(), lastAccessed: datetime() }
-						MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
-							ON CREATE SET
-								x.createdAt = datetime(),
-								x.lastAccessed = datetime()
-							ON MATCH SET
-								x.lastAccessed = datetime()
-						SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
-							ON CREATE SET
-								y.createdAt = datetime(),
-								y.lastAccessed = datetime()
-							ON MATCH SET
-								y.lastAccessed = datetime()
-						SET y = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime() }
-						`)
-					result, err := tx.Run(ctx, createInsightQuery, map[string]any{
-						"conversation_id": mh.conversationId,
-						"insight_id":      actionItem.ID,
-						"type":            strings.ToLower(actionItem.Type),
-						"content":         actionItem.Text,
-						"sequence_number": cnt,
-						"assignee_id":     actionItem.Assignee.ID, // TODO: Look into it ID or Name
-						"user_real_id":    actionItem.From.ID,
-						"user_id":         actionItem.From.ID, // TODO: Look into it
-						"user_name":       actionItem.From.Name,
-						"raw":             string(data),
-					})
-					if err != nil {
-						klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-						return nil, err
-					}
-					return result.Collect(ctx)
-				})
+			content, err := mh.encrypt(actionItem.Text)
 			if err != nil {
-				klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
 				klog.V(6).Infof("ActionItemResult LEAVE\n")
 				return err
 			}
 
+			createInsightQuery := utils.ReplaceIndexes(`
+				MATCH (c:Conversation { #conversation_index#: $conversation_id })
+				MERGE (i:Insight { #insight_index#: $insight_id })
+					ON CREATE SET
+						i.createdAt = datetime(),
+						i.lastAccessed = datetime()
+					ON MATCH SET
+						i.lastAccessed = datetime()
+				SET i += { #insight_index#: $insight_id, type: $type, content: $content, sequenceNumber: $sequence_number, assigneeId: $assignee_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (u:User { #user_index#: $user_id })
+					ON CREATE SET
+						u.createdAt = datetime(),
+						u.lastAccessed = datetime()
+					ON MATCH SET
+						u.lastAccessed = datetime()
+				SET u += { realId: $user_real_id, #user_index#: $user_id, name: $user_name, email: $user_id }
+				MERGE (c)-[x:INSIGHT { #conversation_index#: $conversation_id }]-(i)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (i)-[y:SPOKE { #conversation_index#: $conversation_id }]-(u)
+					ON CREATE SET
+						y.createdAt = datetime(),
+						y.lastAccessed = datetime()
+					ON MATCH SET
+						y.lastAccessed = datetime()
+				SET y += { #conversation_index#: $conversation_id }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createInsightQuery,
+				Params: map[string]any{
+					"conversation_id": mh.conversationId,
+					"insight_id":      actionItem.ID,
+					"type":            strings.ToLower(actionItem.Type),
+					"content":         content,
+					"sequence_number": cnt,
+					"assignee_id":     actionItem.Assignee.ID, // TODO: Look into it ID or Name
+					"user_real_id":    actionItem.From.ID,
+					"user_id":         actionItem.From.ID, // TODO: Look into it
+					"user_name":       actionItem.From.Name,
+					"raw":             raw.Inline(),
+					"raw_event_id":    raw.Id(),
+				},
+			})
+
 			cnt++
 		}
 	}
@@ -815,13 +872,13 @@ func (mh *MessageHandler) ActionItemResult(air *sdkinterfaces.ActionItemResult)
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncActionItem, data)
+	err = mh.commit(statements, shared.RabbitAsyncActionItem, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("ActionItemResult LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("ActionItemResult.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("ActionItemResult.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("ActionItemResult Succeeded\n")
 	klog.V(6).Infof("ActionItemResult LEAVE\n")
@@ -832,6 +889,13 @@ func (mh *MessageHandler) ActionItemResult(air *sdkinterfaces.ActionItemResult)
 func (mh *MessageHandler) TopicResult(tr *sdkinterfaces.TopicResult) error {
 	klog.V(6).Infof("TopicResult ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(tr, shared.RabbitAsyncTopic)
+	if err != nil {
+		klog.V(6).Infof("TopicResult LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(tr)
 	if err != nil {
 		klog.V(1).Infof("TopicResult json.Marshal failed. Err: %v\n", err)
@@ -847,7 +911,7 @@ func (mh *MessageHandler) TopicResult(tr *sdkinterfaces.TopicResult) error {
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TopicResult:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncTopic).V(2).Info("TopicResult", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// any insights?
@@ -865,87 +929,96 @@ func (mh *MessageHandler) TopicResult(tr *sdkinterfaces.TopicResult) error {
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy, nothing is added for existing conversations
+	raw, err := mh.rawEvent(shared.RabbitAsyncTopic, data)
+	if err != nil {
+		klog.V(6).Infof("TopicResult LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil && !exists {
+		statements = append(statements, *statement)
+	}
+
 	// only add to DB if new
 	if !exists {
-		// write the object to the database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		for _, topic := range tr.Topics {
-			_, err := (*mh.neo4jMgr).ExecuteWrite(ctx,
-				func(tx neo4j.ManagedTransaction) (any, error) {
-					createTopicsQuery := utils.ReplaceIndexes(`
-						MATCH (c:Conversation { #conversation_index#: $conversation_id })
-						MERGE (t:Topic { #topic_index#: $topic_id })
-							ON CREATE SET
-								t.createdAt = datetime(),
-								t.lastAccessed = datetime()
-							ON MATCH SET
-								t.lastAccessed = datetime()
-						SET t = { #topic_index#: $topic_id, phrases: $phrases, score: $score, type: $type, messageIndex: $symbl_message_index, rootWords: $root_words, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						MERGE (c)-[x:TOPICS { #conversation_index#: $conversation_id }]-(t)
-							ON CREATE SET
-								x.createdAt = datetime(),
-								x.lastAccessed = datetime()
-							ON MATCH SET
-								x.lastAccessed = datetime()
-						SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-						`)
-					result, err := tx.Run(ctx, createTopicsQuery, map[string]any{
-						"conversation_id":     mh.conversationId,
-						"topic_id":            "TODO", // TODO
-						"phrases":             strings.ToLower(topic.Text),
-						"score":               topic.Score,
-						"type":                topic.Type,
-						"symbl_message_index": "TODO", // TODO: topic.MessageIndex,
-						"root_words":          "TODO", // TODO: convertRootWordToString(topic.RootWords),
-						"raw":                 string(data),
-					})
-					if err != nil {
-						klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-						return nil, err
-					}
-					return result.Collect(ctx)
-				})
-			if err != nil {
-				klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-				klog.V(6).Infof("TopicResult LEAVE\n")
-				return err
-			}
+			// async topics dont have an id, so derive one from the normalized phrase
+			topicId := fmt.Sprintf("%s/%s", mh.conversationId, utils.NormalizeId(topic.Text))
+
+			createTopicsQuery := utils.ReplaceIndexes(`
+				MATCH (c:Conversation { #conversation_index#: $conversation_id })
+				MERGE (t:Topic { #topic_index#: $topic_id })
+					ON CREATE SET
+						t.createdAt = datetime(),
+						t.lastAccessed = datetime()
+					ON MATCH SET
+						t.lastAccessed = datetime()
+				SET t += { #topic_index#: $topic_id, phrases: $phrases, score: $score, type: $type, messageIndex: $symbl_message_index, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (c)-[x:TOPICS { #conversation_index#: $conversation_id }]-(t)
+					ON CREATE SET
+						x.createdAt = datetime(),
+						x.lastAccessed = datetime()
+					ON MATCH SET
+						x.lastAccessed = datetime()
+				SET x += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+				MERGE (p:TopicPhrase { #phrase_index#: $phrase_id })
+					ON CREATE SET
+						p.createdAt = datetime(),
+						p.lastAccessed = datetime()
+					ON MATCH SET
+						p.lastAccessed = datetime()
+				SET p += { #phrase_index#: $phrase_id, phrase: $phrases }
+				MERGE (t)-[y:TOPIC_PHRASE { #conversation_index#: $conversation_id }]-(p)
+					ON CREATE SET
+						y.createdAt = datetime(),
+						y.lastAccessed = datetime()
+					ON MATCH SET
+						y.lastAccessed = datetime()
+				SET y += { #conversation_index#: $conversation_id }
+				`)
+			statements = append(statements, outbox.Statement{
+				Query: createTopicsQuery,
+				Params: map[string]any{
+					"conversation_id":     mh.conversationId,
+					"topic_id":            topicId,
+					"phrase_id":           utils.NormalizeId(topic.Text),
+					"phrases":             strings.ToLower(topic.Text),
+					"score":               topic.Score,
+					"type":                topic.Type,
+					"symbl_message_index": "TODO", // TODO: topic.MessageIndex,
+					"raw":                 raw.Inline(),
+					"raw_event_id":        raw.Id(),
+				},
+			})
 
 			// associate topic to message
 			for _, msgId := range topic.MessageIds {
-				_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-					func(tx neo4j.ManagedTransaction) (any, error) {
-						createTopicsQuery := utils.ReplaceIndexes(`
-							MATCH (t:Topic { topicId: $topic_id })
-							MATCH (m:Message { #message_index#: $message_id })
-							MERGE (t)-[x:TOPIC_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
-								ON CREATE SET
-									x.createdAt = datetime(),
-									x.lastAccessed = datetime()
-								ON MATCH SET
-									x.lastAccessed = datetime()
-							SET x = { #conversation_index#: $conversation_id, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-							`)
-						result, err := tx.Run(ctx, createTopicsQuery, map[string]any{
-							"conversation_id": mh.conversationId,
-							"topic_id":        "TODO", // TODO
-							"message_id":      msgId,
-							"value":           strings.ToLower(topic.Text),
-							"raw":             string(data),
-						})
-						if err != nil {
-							klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-							return nil, err
-						}
-						return result.Collect(ctx)
-					})
-				if err != nil {
-					klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-					klog.V(6).Infof("TopicResult LEAVE\n")
-					return err
-				}
+				createTopicsQuery := utils.ReplaceIndexes(`
+					MATCH (t:Topic { topicId: $topic_id })
+					MATCH (m:Message { #message_index#: $message_id })
+					MERGE (t)-[x:TOPIC_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
+						ON CREATE SET
+							x.createdAt = datetime(),
+							x.lastAccessed = datetime()
+						ON MATCH SET
+							x.lastAccessed = datetime()
+					SET x += { #conversation_index#: $conversation_id, value: $value, raw: $raw, rawEventId: $raw_event_id }
+					`)
+				statements = append(statements, outbox.Statement{
+					Query: createTopicsQuery,
+					Params: map[string]any{
+						"conversation_id": mh.conversationId,
+						"topic_id":        topicId,
+						"message_id":      msgId,
+						"value":           strings.ToLower(topic.Text),
+						"raw":             raw.Inline(),
+						"raw_event_id":    raw.Id(),
+					},
+				})
 			}
 		}
 	}
@@ -964,13 +1037,13 @@ func (mh *MessageHandler) TopicResult(tr *sdkinterfaces.TopicResult) error {
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncTopic, data)
+	err = mh.commit(statements, shared.RabbitAsyncTopic, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("TopicResult LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("TopicResult.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("TopicResult.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("TopicResult Succeeded\n")
 	klog.V(6).Infof("TopicResult LEAVE\n")
@@ -980,6 +1053,13 @@ func (mh *MessageHandler) TopicResult(tr *sdkinterfaces.TopicResult) error {
 func (mh *MessageHandler) TrackerResult(tr *sdkinterfaces.TrackerResult) error {
 	klog.V(6).Infof("TrackerResult ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(tr, shared.RabbitAsyncTracker)
+	if err != nil {
+		klog.V(6).Infof("TrackerResult LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(tr)
 	if err != nil {
 		klog.V(1).Infof("TrackerResult json.Marshal failed. Err: %v\n", err)
@@ -995,7 +1075,7 @@ func (mh *MessageHandler) TrackerResult(tr *sdkinterfaces.TrackerResult) error {
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TrackerResult:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncTracker).V(2).Info("TrackerResult", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// any insights?
@@ -1013,122 +1093,105 @@ func (mh *MessageHandler) TrackerResult(tr *sdkinterfaces.TrackerResult) error {
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy, nothing is added for existing conversations
+	raw, err := mh.rawEvent(shared.RabbitAsyncTracker, data)
+	if err != nil {
+		klog.V(6).Infof("TrackerResult LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil && !exists {
+		statements = append(statements, *statement)
+	}
+
 	// only add to DB if new
 	if !exists {
-		// write the object to the database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		createTrackersQuery := utils.ReplaceIndexes(`
+			MATCH (c:Conversation { #conversation_index#: $conversation_id })
+			MERGE (t:Tracker { #tracker_index#: $tracker_id })
+				ON CREATE SET
+					t.createdAt = datetime(),
+					t.lastAccessed = datetime()
+				ON MATCH SET
+					t.lastAccessed = datetime()
+			SET t += { #tracker_index#: $tracker_id, name: $tracker_name }
+			MERGE (c)-[x:TRACKER { #conversation_index#: $conversation_id }]-(t)
+				ON CREATE SET
+					x.createdAt = datetime(),
+					x.lastAccessed = datetime()
+				ON MATCH SET
+					x.lastAccessed = datetime()
+			SET x += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+			`)
+		statements = append(statements, outbox.Statement{
+			Query: createTrackersQuery,
+			Params: map[string]any{
+				"conversation_id": mh.conversationId,
+				"tracker_id":      tr.ID,
+				"tracker_name":    strings.ToLower(tr.Name),
+				"raw":             raw.Inline(),
+				"raw_event_id":    raw.Id(),
+			},
+		})
 
-		_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-			func(tx neo4j.ManagedTransaction) (any, error) {
-				createTrackersQuery := utils.ReplaceIndexes(`
-					MATCH (c:Conversation { #conversation_index#: $conversation_id })
-					MERGE (t:Tracker { #tracker_index#: $tracker_id })
-						ON CREATE SET
-							t.createdAt = datetime(),
-							t.lastAccessed = datetime()
-						ON MATCH SET
-							t.lastAccessed = datetime()
-					SET t = { #tracker_index#: $tracker_id, name: $tracker_name, createdAt: datetime(), lastAccessed: datetime() }
-					MERGE (c)-[x:TRACKER { #conversation_index#: $conversation_id }]-(t)
+		// associate tracker to messages and insights
+		for _, match := range tr.Matches {
+
+			// messages
+			for _, msgRef := range match.MessageRefs {
+				createTopicsQuery := utils.ReplaceIndexes(`
+					MATCH (t:Tracker { #tracker_index#: $tracker_id })
+					MATCH (m:Message { #message_index#: $message_id })
+					MERGE (t)-[x:TRACKER_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
 						ON CREATE SET
 							x.createdAt = datetime(),
 							x.lastAccessed = datetime()
 						ON MATCH SET
 							x.lastAccessed = datetime()
-					SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
+					SET x += { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, raw: $raw, rawEventId: $raw_event_id }
 					`)
-				result, err := tx.Run(ctx, createTrackersQuery, map[string]any{
-					"conversation_id": mh.conversationId,
-					"tracker_id":      tr.ID,
-					"tracker_name":    strings.ToLower(tr.Name),
-					"raw":             string(data),
+				statements = append(statements, outbox.Statement{
+					Query: createTopicsQuery,
+					Params: map[string]any{
+						"conversation_id": mh.conversationId,
+						"tracker_id":      tr.ID,
+						"message_id":      msgRef.ID,
+						"tracker_name":    strings.ToLower(tr.Name),
+						"value":           strings.ToLower(match.Value),
+						"raw":             raw.Inline(),
+						"raw_event_id":    raw.Id(),
+					},
 				})
-				if err != nil {
-					klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-					return nil, err
-				}
-				return result.Collect(ctx)
-			})
-		if err != nil {
-			klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-			klog.V(6).Infof("TrackerResult LEAVE\n")
-			return err
-		}
-
-		// associate tracker to messages and insights
-		for _, match := range tr.Matches {
-
-			// messages
-			for _, msgRef := range match.MessageRefs {
-				_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-					func(tx neo4j.ManagedTransaction) (any, error) {
-						createTopicsQuery := utils.ReplaceIndexes(`
-							MATCH (t:Tracker { #tracker_index#: $tracker_id })
-							MATCH (m:Message { #message_index#: $message_id })
-							MERGE (t)-[x:TRACKER_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
-								ON CREATE SET
-									x.createdAt = datetime(),
-									x.lastAccessed = datetime()
-								ON MATCH SET
-									x.lastAccessed = datetime()
-							SET x = { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-							`)
-						result, err := tx.Run(ctx, createTopicsQuery, map[string]any{
-							"conversation_id": mh.conversationId,
-							"tracker_id":      tr.ID,
-							"message_id":      msgRef.ID,
-							"tracker_name":    strings.ToLower(tr.Name),
-							"value":           strings.ToLower(match.Value),
-							"raw":             string(data),
-						})
-						if err != nil {
-							klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-							return nil, err
-						}
-						return result.Collect(ctx)
-					})
-				if err != nil {
-					klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-					klog.V(6).Infof("TrackerResult LEAVE\n")
-					return err
-				}
 			}
 
 			// insights
 			for _, inRef := range match.InsightRefs {
-				_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-					func(tx neo4j.ManagedTransaction) (any, error) {
-						createTrackerMatchQuery := utils.ReplaceIndexes(`
-							MATCH (t:Tracker { #tracker_index#: $tracker_id })
-							MATCH (i:Insight { #insight_index#: $insight_id })
-							MERGE (t)-[x:TRACKER_INSIGHT_REF { #conversation_index#: $conversation_id }]-(i)
-								ON CREATE SET
-									x.createdAt = datetime(),
-									x.lastAccessed = datetime()
-								ON MATCH SET
-									x.lastAccessed = datetime()
-							SET x = { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-							`)
-						result, err := tx.Run(ctx, createTrackerMatchQuery, map[string]any{
-							"conversation_id": mh.conversationId,
-							"tracker_id":      tr.ID,
-							"insight_id":      inRef.ID,
-							"tracker_name":    strings.ToLower(tr.Name),
-							"value":           strings.ToLower(match.Value),
-							"raw":             string(data),
-						})
-						if err != nil {
-							klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-							return nil, err
-						}
-						return result.Collect(ctx)
-					})
-				if err != nil {
-					klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-					klog.V(6).Infof("TrackerResult LEAVE\n")
-					return err
-				}
+				createTrackerMatchQuery := utils.ReplaceIndexes(`
+					MATCH (t:Tracker { #tracker_index#: $tracker_id })
+					MATCH (i:Insight { #insight_index#: $insight_id })
+					MERGE (t)-[x:TRACKER_INSIGHT_REF { #conversation_index#: $conversation_id }]-(i)
+						ON CREATE SET
+							x.createdAt = datetime(),
+							x.lastAccessed = datetime()
+						ON MATCH SET
+							x.lastAccessed = datetime()
+					SET x += { #conversation_index#: $conversation_id, name: $tracker_name, value: $value, raw: $raw, rawEventId: $raw_event_id }
+					`)
+				statements = append(statements, outbox.Statement{
+					Query: createTrackerMatchQuery,
+					Params: map[string]any{
+						"conversation_id": mh.conversationId,
+						"tracker_id":      tr.ID,
+						"insight_id":      inRef.ID,
+						"tracker_name":    strings.ToLower(tr.Name),
+						"value":           strings.ToLower(match.Value),
+						"raw":             raw.Inline(),
+						"raw_event_id":    raw.Id(),
+					},
+				})
 			}
 		}
 	}
@@ -1147,13 +1210,13 @@ func (mh *MessageHandler) TrackerResult(tr *sdkinterfaces.TrackerResult) error {
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncTracker, data)
+	err = mh.commit(statements, shared.RabbitAsyncTracker, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("TrackerResult LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("TrackerResult.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("TrackerResult.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("TrackerResult Succeeded\n")
 	klog.V(6).Infof("TrackerResult LEAVE\n")
@@ -1164,6 +1227,13 @@ func (mh *MessageHandler) TrackerResult(tr *sdkinterfaces.TrackerResult) error {
 func (mh *MessageHandler) EntityResult(er *sdkinterfaces.EntityResult) error {
 	klog.V(6).Infof("EntityResult ENTER\n")
 
+	// mask PII before it is saved or published
+	redactions, err := mh.redact(er, shared.RabbitAsyncEntity)
+	if err != nil {
+		klog.V(6).Infof("EntityResult LEAVE\n")
+		return err
+	}
+
 	data, err := json.Marshal(er)
 	if err != nil {
 		klog.V(1).Infof("EntityResult json.Marshal failed. Err: %v\n", err)
@@ -1179,7 +1249,7 @@ func (mh *MessageHandler) EntityResult(er *sdkinterfaces.EntityResult) error {
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("EntityResult:\n%v\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncEntity).V(2).Info("EntityResult", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// any insights?
@@ -1197,98 +1267,86 @@ func (mh *MessageHandler) EntityResult(er *sdkinterfaces.EntityResult) error {
 		return err
 	}
 
+	// queue up the database writes
+	statements := make([]outbox.Statement, 0)
+	statements = append(statements, redactions...)
+
+	// keep the raw payload according to the policy, nothing is added for existing conversations
+	raw, err := mh.rawEvent(shared.RabbitAsyncEntity, data)
+	if err != nil {
+		klog.V(6).Infof("EntityResult LEAVE\n")
+		return err
+	}
+	if statement := raw.Statement(); statement != nil && !exists {
+		statements = append(statements, *statement)
+	}
+
 	// only add to DB if new
 	if !exists {
-		// write the object to the database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
 		for _, entity := range er.Entities {
 
 			// associate tracker to messages and insights
 			for _, match := range entity.Matches {
 
 				// entity id
-				entityCategory := strings.ToLower(strings.ReplaceAll(entity.Category, " ", "_"))
-				entityType := strings.ToLower(strings.ReplaceAll(entity.Type, " ", "_"))
-				entitySubType := strings.ToLower(strings.ReplaceAll(entity.SubType, " ", "_"))
-				entityValue := strings.ToLower(strings.ReplaceAll(match.DetectedValue, " ", "_"))
-				entityId := fmt.Sprintf("%s/%s/%s/%s", entityCategory, entityType, entitySubType, entityValue)
+				entityId := utils.EntityId(entity.Category, entity.Type, entity.SubType, match.DetectedValue)
 
 				// entity
-				_, err := (*mh.neo4jMgr).ExecuteWrite(ctx,
-					func(tx neo4j.ManagedTransaction) (any, error) {
-						createEntitiesQuery := utils.ReplaceIndexes(`
-							MATCH (c:Conversation { #conversation_index#: $conversation_id })
-							MERGE (e:Entity { #entity_index#: $entity_id })
-								ON CREATE SET
-									e.createdAt = datetime(),
-									e.lastAccessed = datetime()
-								ON MATCH SET
-									e.lastAccessed = datetime()
-							SET e = { #entity_index#: $entity_id, type: $type, subType: $sub_type, category: $category, value: $value, createdAt: datetime(), lastAccessed: datetime() }
-							MERGE (c)-[x:ENTITY { #conversation_index#: $conversation_id }]-(e)
-								ON CREATE SET
-									x.createdAt = datetime(),
-									x.lastAccessed = datetime()
-								ON MATCH SET
-									x.lastAccessed = datetime()
-							SET x = { #conversation_index#: $conversation_id, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-							`)
-						result, err := tx.Run(ctx, createEntitiesQuery, map[string]any{
+				createEntitiesQuery := utils.ReplaceIndexes(`
+					MATCH (c:Conversation { #conversation_index#: $conversation_id })
+					MERGE (e:Entity { #entity_index#: $entity_id })
+						ON CREATE SET
+							e.createdAt = datetime(),
+							e.lastAccessed = datetime()
+						ON MATCH SET
+							e.lastAccessed = datetime()
+					SET e += { #entity_index#: $entity_id, type: $type, subType: $sub_type, category: $category, value: $value }
+					MERGE (c)-[x:ENTITY { #conversation_index#: $conversation_id }]-(e)
+						ON CREATE SET
+							x.createdAt = datetime(),
+							x.lastAccessed = datetime()
+						ON MATCH SET
+							x.lastAccessed = datetime()
+					SET x += { #conversation_index#: $conversation_id, raw: $raw, rawEventId: $raw_event_id }
+					`)
+				statements = append(statements, outbox.Statement{
+					Query: createEntitiesQuery,
+					Params: map[string]any{
+						"conversation_id": mh.conversationId,
+						"entity_id":       entityId,
+						"type":            strings.ToLower(entity.Type),
+						"sub_type":        strings.ToLower(entity.SubType),
+						"category":        strings.ToLower(entity.Category),
+						"value":           strings.ToLower(match.DetectedValue),
+						"raw":             raw.Inline(),
+						"raw_event_id":    raw.Id(),
+					},
+				})
+
+				// message
+				for _, msgRef := range match.MessageRefs {
+					createEntitiesQuery := utils.ReplaceIndexes(`
+						MATCH (e:Entity { #entity_index#: $entity_id })
+						MATCH (m:Message { #message_index#: $message_id })
+						MERGE (e)-[x:ENTITY_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
+							ON CREATE SET
+								x.createdAt = datetime(),
+								x.lastAccessed = datetime()
+							ON MATCH SET
+								x.lastAccessed = datetime()
+						SET x += { #conversation_index#: $conversation_id, value: $value, raw: $raw, rawEventId: $raw_event_id }
+						`)
+					statements = append(statements, outbox.Statement{
+						Query: createEntitiesQuery,
+						Params: map[string]any{
 							"conversation_id": mh.conversationId,
 							"entity_id":       entityId,
-							"type":            strings.ToLower(entity.Type),
-							"sub_type":        strings.ToLower(entity.SubType),
-							"category":        strings.ToLower(entity.Category),
+							"message_id":      msgRef.ID,
 							"value":           strings.ToLower(match.DetectedValue),
-							"raw":             string(data),
-						})
-						if err != nil {
-							klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-							return nil, err
-						}
-						return result.Collect(ctx)
+							"raw":             raw.Inline(),
+							"raw_event_id":    raw.Id(),
+						},
 					})
-				if err != nil {
-					klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-					klog.V(6).Infof("EntityResult LEAVE\n")
-					return err
-				}
-
-				// message
-				for _, msgRef := range match.MessageRefs {
-					_, err = (*mh.neo4jMgr).ExecuteWrite(ctx,
-						func(tx neo4j.ManagedTransaction) (any, error) {
-							createEntitiesQuery := utils.ReplaceIndexes(`
-								MATCH (e:Entity { #entity_index#: $entity_id })
-								MATCH (m:Message { #message_index#: $message_id })
-								MERGE (e)-[x:ENTITY_MESSAGE_REF { #conversation_index#: $conversation_id }]-(m)
-									ON CREATE SET
-										x.createdAt = datetime(),
-										x.lastAccessed = datetime()
-									ON MATCH SET
-										x.lastAccessed = datetime()
-								SET x = { #conversation_index#: $conversation_id, value: $value, createdAt: datetime(), lastAccessed: datetime(), raw: $raw }
-								`)
-							result, err := tx.Run(ctx, createEntitiesQuery, map[string]any{
-								"conversation_id": mh.conversationId,
-								"entity_id":       entityId,
-								"message_id":      msgRef.ID,
-								"value":           strings.ToLower(match.DetectedValue),
-								"raw":             string(data),
-							})
-							if err != nil {
-								klog.V(1).Infof("neo4j.Run failed create conversation object. Err: %v\n", err)
-								return nil, err
-							}
-							return result.Collect(ctx)
-						})
-					if err != nil {
-						klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
-						klog.V(6).Infof("EntityResult LEAVE\n")
-						return err
-					}
 				}
 			}
 		}
@@ -1308,13 +1366,13 @@ func (mh *MessageHandler) EntityResult(er *sdkinterfaces.EntityResult) error {
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncEntity, data)
+	err = mh.commit(statements, shared.RabbitAsyncEntity, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("EntityResult LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("EntityResult.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("EntityResult.Outbox:\n%s\n", string(data))
 
 	klog.V(4).Infof("EntityResult Succeeded\n")
 	klog.V(6).Infof("EntityResult LEAVE\n")
@@ -1346,7 +1404,7 @@ func (mh *MessageHandler) TeardownConversation(tm *sdkinterfaces.TeardownMessage
 		return err
 	}
 	klog.V(6).Infof("\n\n-------------------------------\n")
-	klog.V(2).Infof("TeardownConversation:\n%v\n\n", string(prettyJson))
+	logging.Conversation(mh.conversationId, shared.RabbitAsyncConversationTeardown).V(2).Info("TeardownConversation", "message", string(prettyJson))
 	klog.V(6).Infof("-------------------------------\n\n")
 
 	// insights already created
@@ -1372,17 +1430,19 @@ func (mh *MessageHandler) TeardownConversation(tm *sdkinterfaces.TeardownMessage
 		return err
 	}
 
-	err = (*mh.rabbitMgr).PublishMessageByName(shared.RabbitAsyncConversationTeardown, data)
+	err = mh.commit(nil, shared.RabbitAsyncConversationTeardown, data)
 	if err != nil {
-		klog.V(1).Infof("PublishMessageByName failed. Err: %v\n", err)
+		klog.V(1).Infof("commit failed. Err: %v\n", err)
 		klog.V(6).Infof("TeardownConversation LEAVE\n")
 		return err
 	}
-	klog.V(3).Infof("TeardownConversation.PublishWithContext:\n%s\n", string(data))
+	klog.V(3).Infof("TeardownConversation.Outbox:\n%s\n", string(data))
 
 	// mark as teardown message sent
 	mh.terminationSent = true
 
+	metrics.ConversationsTornDown.WithLabelValues(shared.SourceRestDataminer).Inc()
+
 	klog.V(4).Infof("TeardownConversation Succeeded\n")
 	klog.V(6).Infof("TeardownConversation LEAVE\n")
 
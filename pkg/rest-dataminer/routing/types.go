@@ -4,8 +4,12 @@
 package routing
 
 import (
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
 )
 
 /*
@@ -15,12 +19,18 @@ import (
 type MessageHandlerOptions struct {
 	//housekeeping
 	ConversationId string
+	Tenant         string // shared.DefaultTenant when empty
+
+	// features
+	RawPolicy rawevent.Policy
+	Redaction *redaction.Options // nil disables redaction
 
 	// neo4j
-	Neo4jMgr *neo4j.SessionWithContext
+	Neo4jMgr  *neo4j.SessionWithContext
+	Encryptor *encryption.Encryptor // nil stores the content in plaintext
 
-	// neo4j session
-	RabbitMgr *rabbitinterfaces.Manager
+	// publishes the events recorded by the handler
+	Outbox *outbox.Relay
 }
 
 // MessageHandler takes the Symbl objects and performs an action with them
@@ -34,9 +44,13 @@ type MessageHandler struct {
 	// features
 	options MessageHandlerOptions
 
+	// masks PII before it is saved or published
+	redactor *redaction.Redactor
+
 	// neo4j
-	neo4jMgr *neo4j.SessionWithContext
+	neo4jMgr  *neo4j.SessionWithContext
+	encryptor *encryption.Encryptor
 
-	// rabbitmq
-	rabbitMgr *rabbitinterfaces.Manager
+	// outbox
+	outbox *outbox.Relay
 }
@@ -5,64 +5,141 @@ package dataminer
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 
-	rabbit "github.com/dvonthenen/rabbitmq-manager/pkg"
-	rabbitinterfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 	async "github.com/dvonthenen/symbl-go-sdk/pkg/api/async/v1"
 	interfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/async/v1/interfaces"
 	symbl "github.com/dvonthenen/symbl-go-sdk/pkg/client"
-	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	klog "k8s.io/klog/v2"
 
+	auth "github.com/dvonthenen/enterprise-conversation-application/pkg/auth"
+	messagebus "github.com/dvonthenen/enterprise-conversation-application/pkg/bus"
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	health "github.com/dvonthenen/enterprise-conversation-application/pkg/health"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	migrations "github.com/dvonthenen/enterprise-conversation-application/pkg/migrations"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
 	routing "github.com/dvonthenen/enterprise-conversation-application/pkg/rest-dataminer/routing"
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+	supervisor "github.com/dvonthenen/enterprise-conversation-application/pkg/supervisor"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
+	trends "github.com/dvonthenen/enterprise-conversation-application/pkg/trends"
 )
 
 func New(options ServerOptions) (*Server, error) {
-	var connectionStr string
-	if v := os.Getenv("NEO4J_CONNECTION"); v != "" {
-		klog.V(4).Info("NEO4J_CONNECTION found")
-		connectionStr = v
-	} else {
-		klog.Errorf("NEO4J_CONNECTION not found\n")
-		return nil, ErrInvalidInput
+	// neo4j
+	if options.Neo4j == nil {
+		neo4jConfig, err := dbconfig.FromEnv()
+		if err != nil {
+			klog.Errorf("dbconfig.FromEnv failed. Err: %v\n", err)
+			return nil, err
+		}
+		options.Neo4j = neo4jConfig
+	} else if err := options.Neo4j.Validate(); err != nil {
+		klog.Errorf("Neo4j config is invalid. Err: %v\n", err)
+		return nil, err
 	}
-	var username string
-	if v := os.Getenv("NEO4J_USERNAME"); v != "" {
-		klog.V(4).Info("NEO4J_USERNAME found")
-		username = v
-	} else {
-		klog.Errorf("NEO4J_USERNAME not found\n")
-		return nil, ErrInvalidInput
+
+	// options
+	if !rawevent.Supported(options.RawPolicy) {
+		klog.Errorf("RawPolicy %s is not supported\n", options.RawPolicy)
+		return nil, rawevent.ErrUnsupportedPolicy
 	}
-	var password string
-	if v := os.Getenv("NEO4J_PASSWORD"); v != "" {
-		klog.V(4).Info("NEO4J_PASSWORD found")
-		password = v
-	} else {
-		klog.Errorf("NEO4J_PASSWORD not found\n")
-		return nil, ErrInvalidInput
+	if options.Redaction != nil {
+		_, err := redaction.New(*options.Redaction)
+		if err != nil {
+			klog.Errorf("Redaction options are invalid. Err: %v\n", err)
+			return nil, err
+		}
 	}
-
-	// DB Creds
-	creds := Credentials{
-		ConnectionStr: connectionStr,
-		Username:      username,
-		Password:      password,
+	var encryptors *encryption.Encryptors
+	if options.Encryption != nil {
+		var err error
+		encryptors, err = encryption.NewEncryptors(*options.Encryption)
+		if err != nil {
+			klog.Errorf("Encryption options are invalid. Err: %v\n", err)
+			return nil, err
+		}
+	}
+	var guard *auth.Guard
+	if options.Auth != nil {
+		var err error
+		guard, err = auth.New(*options.Auth)
+		if err != nil {
+			klog.Errorf("Auth options are invalid. Err: %v\n", err)
+			return nil, err
+		}
 	}
 
 	if options.AuthMethod == AuthTypeDefault {
 		options.AuthMethod = AuthTypeReuseToken
 	}
 
+	// tls
+	tlsOptions := tlsconfig.Options{
+		CrtFile: options.CrtFile,
+		KeyFile: options.KeyFile,
+	}
+	if options.TLS != nil {
+		tlsOptions = *options.TLS
+	}
+	reloader, err := tlsconfig.New(tlsOptions)
+	if err != nil {
+		klog.Errorf("TLS options are invalid. Err: %v\n", err)
+		return nil, err
+	}
+
 	// server
 	server := &Server{
-		options: options,
-		creds:   creds,
+		options:    options,
+		tls:        reloader,
+		encryptors: encryptors,
+		guard:      guard,
+		health:     health.New(health.CheckerOptions{}),
+	}
+
+	// reconnect the database and bus when they drop, the Neo4j driver reopens its own connections
+	neo4jSupervisor, err := supervisor.New(supervisor.SupervisorOptions{
+		Component:  shared.SourceRestDataminer,
+		Connection: supervisor.ConnectionNeo4j,
+		Check: func(ctx context.Context) error {
+			return health.Neo4j(ctx, server.driver)
+		},
+	})
+	if err != nil {
+		klog.Errorf("supervisor.New failed. Err: %v\n", err)
+		return nil, err
+	}
+	busSupervisor, err := supervisor.New(supervisor.SupervisorOptions{
+		Component:  shared.SourceRestDataminer,
+		Connection: supervisor.ConnectionBus,
+		Check: func(ctx context.Context) error {
+			return health.Bus(server.bus)
+		},
+		Reconnect: func() error {
+			if server.bus == nil {
+				return health.ErrNotConnected
+			}
+			return (*server.bus).Reconnect()
+		},
+	})
+	if err != nil {
+		klog.Errorf("supervisor.New failed. Err: %v\n", err)
+		return nil, err
 	}
+	server.supervisors = []*supervisor.Supervisor{neo4jSupervisor, busSupervisor}
+
+	server.health.Add(health.CheckNeo4j, neo4jSupervisor.Healthy)
+	server.health.Add(health.CheckBus, busSupervisor.Healthy)
 	return server, nil
 }
 
@@ -71,26 +148,34 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 	klog.V(3).Infof("URL: %s\n", r.URL.String())
 	klog.V(3).Infof("conversationId: %s\n", conversationId)
 
-	// init rabbit manager
-	rabbitMgr, err := rabbit.New(rabbitinterfaces.ManagerOptions{
-		RabbitURI: s.options.RabbitURI,
-	})
+	// the conversation is saved for the tenant of the client, without a guard only the default tenant
+	identity, err := s.guard.Check(r, auth.ActionOpen, conversationId, nil)
 	if err != nil {
-		str := fmt.Sprintf("rabbit.New failed. Err: %v\n", err)
-		klog.V(1).Infof(str)
-		http.Error(w, str, http.StatusBadRequest)
+		klog.V(1).Infof("Client for conversationId (%s) rejected. Err: %v\n", conversationId, err)
+		auth.Reject(w, err)
+		return
+	}
+	tenant, err := auth.Tenant(r, identity)
+	if err != nil {
+		klog.V(1).Infof("Tenant for conversationId (%s) rejected. Err: %v\n", conversationId, err)
+		auth.Reject(w, err)
 		return
 	}
 
 	// init the db
 	ctx := context.Background()
-	session := (*s.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := (*s.driver).NewSession(ctx, s.options.Neo4j.SessionConfig(tenant))
+	defer session.Close(ctx)
 
 	// init message handler
 	handler, err := routing.NewHandler(routing.MessageHandlerOptions{
 		ConversationId: conversationId,
+		Tenant:         tenant,
+		RawPolicy:      s.options.RawPolicy,
+		Redaction:      s.options.Redaction,
 		Neo4jMgr:       &session,
-		RabbitMgr:      rabbitMgr,
+		Encryptor:      s.encryptors.Tenant(tenant),
+		Outbox:         s.outbox,
 	})
 	if err != nil {
 		str := fmt.Sprintf("NewHandler failed. Err: %v\n", err)
@@ -100,6 +185,11 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	exists, err := handler.DoesConversationExist()
+	if errors.Is(err, routing.ErrTenantMismatch) {
+		klog.V(1).Infof("Conversation %s belongs to another tenant than %s\n", conversationId, tenant)
+		auth.Reject(w, auth.ErrForbidden)
+		return
+	}
 	if err != nil {
 		str := fmt.Sprintf("handler.Init failed. Err: %v\n", err)
 		klog.V(1).Infof(str)
@@ -165,6 +255,7 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, str, http.StatusBadRequest)
 		return
 	}
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceRestDataminer, "topics").Inc()
 
 	err = handler.TopicResult(topicResult)
 	if err != nil {
@@ -182,6 +273,7 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, str, http.StatusBadRequest)
 		return
 	}
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceRestDataminer, "questions").Inc()
 
 	err = handler.QuestionResult(questionResult)
 	if err != nil {
@@ -199,6 +291,7 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, str, http.StatusBadRequest)
 		return
 	}
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceRestDataminer, "follow-ups").Inc()
 
 	err = handler.FollowUpResult(followupResult)
 	if err != nil {
@@ -216,6 +309,7 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, str, http.StatusBadRequest)
 		return
 	}
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceRestDataminer, "entities").Inc()
 
 	err = handler.EntityResult(entityResult)
 	if err != nil {
@@ -233,6 +327,7 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, str, http.StatusBadRequest)
 		return
 	}
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceRestDataminer, "action-items").Inc()
 
 	err = handler.ActionItemResult(actionitemResult)
 	if err != nil {
@@ -250,6 +345,7 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, str, http.StatusBadRequest)
 		return
 	}
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceRestDataminer, "messages").Inc()
 
 	err = handler.MessageResult(messageResult)
 	if err != nil {
@@ -267,6 +363,7 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, str, http.StatusBadRequest)
 		return
 	}
+	metrics.SymblMessagesReceived.WithLabelValues(shared.SourceRestDataminer, "trackers").Inc()
 
 	err = handler.TrackerResult(trackerResult)
 	if err != nil {
@@ -290,6 +387,96 @@ func (s *Server) processConversation(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (s *Server) processTrends(w http.ResponseWriter, r *http.Request) {
+	lastToken := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	klog.V(3).Infof("URL: %s\n", r.URL.String())
+	klog.V(3).Infof("Last Token: %s\n", lastToken)
+
+	limit := trends.DefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			str := fmt.Sprintf("Invalid limit: %s\n", v)
+			klog.V(1).Infof(str)
+			http.Error(w, str, http.StatusBadRequest)
+			return
+		}
+		limit = i
+	}
+
+	// only the conversations of the tenant of the client are counted
+	identity, err := s.guard.Check(r, auth.ActionRead, "", nil)
+	if err != nil {
+		klog.V(1).Infof("Client for trends rejected. Err: %v\n", err)
+		auth.Reject(w, err)
+		return
+	}
+	tenant, err := auth.Tenant(r, identity)
+	if err != nil {
+		klog.V(1).Infof("Tenant for trends rejected. Err: %v\n", err)
+		auth.Reject(w, err)
+		return
+	}
+
+	var result any
+
+	var kind trends.Kind
+	switch lastToken {
+	case "similar":
+		result, err = s.trends.SimilarTopics(tenant, r.URL.Query().Get("phrase"), limit)
+		s.writeTrends(w, result, err)
+		return
+	case "topics":
+		kind = trends.KindTopic
+	case "entities":
+		kind = trends.KindEntity
+	case "trackers":
+		kind = trends.KindTracker
+	default:
+		str := fmt.Sprintf("Unknown trend kind: %s\n", lastToken)
+		klog.V(1).Infof(str)
+		http.Error(w, str, http.StatusNotFound)
+		return
+	}
+
+	window := trends.Window(r.URL.Query().Get("window"))
+	if len(window) == 0 {
+		window = trends.WindowWeek
+	}
+
+	direction := trends.Direction(r.URL.Query().Get("direction"))
+	switch direction {
+	case "":
+		result, err = s.trends.TopMentions(tenant, kind, window, limit)
+	case trends.DirectionUp, trends.DirectionDown:
+		result, err = s.trends.Compare(tenant, kind, window, direction, limit)
+	default:
+		err = fmt.Errorf("invalid direction: %s", direction)
+	}
+	s.writeTrends(w, result, err)
+}
+
+func (s *Server) writeTrends(w http.ResponseWriter, result any, err error) {
+	if err != nil {
+		str := fmt.Sprintf("Trends query failed. Err: %v\n", err)
+		klog.V(1).Infof(str)
+		http.Error(w, str, http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		str := fmt.Sprintf("json.Marshal failed. Err: %v\n", err)
+		klog.V(1).Infof(str)
+		http.Error(w, str, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 func (s *Server) Start() error {
 	klog.V(6).Infof("Server.Start ENTER\n")
 
@@ -304,8 +491,130 @@ func (s *Server) Start() error {
 		}
 	}
 
+	// upgrade existing data, in the database of every tenant
+	for _, database := range s.options.Neo4j.Databases() {
+		migrator, err := migrations.New(migrations.MigratorOptions{
+			Driver:       s.driver,
+			DatabaseName: database,
+			Tenant:       s.options.Neo4j.Tenant(database),
+		})
+		if err != nil {
+			klog.V(1).Infof("migrations.New failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+
+		err = migrator.Run()
+		if err != nil {
+			klog.V(1).Infof("migrator.Run(%s) failed. Err: %v\n", database, err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+	}
+
+	// message bus, picked by the RabbitURI scheme
+	if s.options.Bus == nil {
+		bus, err := messagebus.New(messagebus.BusOptions{
+			URI: s.options.RabbitURI,
+		})
+		if err != nil {
+			klog.V(1).Infof("messagebus.New failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.options.Bus = bus
+	}
+
+	err := (*s.options.Bus).Init()
+	if err != nil {
+		klog.V(1).Infof("bus.Init failed. Err: %v\n", err)
+		klog.V(6).Infof("Server.Start LEAVE\n")
+		return err
+	}
+	s.bus = s.options.Bus
+
+	// outbox relay
+	if s.outbox == nil {
+		relay, err := outbox.NewRelay(outbox.RelayOptions{
+			Driver:      s.driver,
+			Neo4j:       s.options.Neo4j,
+			Bus:         s.bus,
+			ContentType: s.options.ContentType,
+			Encryptors:  s.encryptors,
+		})
+		if err != nil {
+			klog.V(1).Infof("outbox.NewRelay failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+
+		err = relay.Start()
+		if err != nil {
+			klog.V(1).Infof("relay.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.outbox = relay
+	}
+
+	// tracing
+	if s.tracer == nil && s.options.Tracing != nil {
+		tracer, err := tracing.NewProvider(*s.options.Tracing)
+		if err != nil {
+			klog.V(1).Infof("tracing.NewProvider failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		err = tracer.Start()
+		if err != nil {
+			klog.V(1).Infof("tracer.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.tracer = tracer
+	}
+
+	// metrics endpoint
+	if s.metrics == nil && s.options.Metrics != nil {
+		metricsServer := metrics.NewServer(*s.options.Metrics)
+		err := metricsServer.Start()
+		if err != nil {
+			klog.V(1).Infof("metrics.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.metrics = metricsServer
+	}
+
+	// trends
+	if s.trends == nil {
+		trendsMgr, err := trends.New(trends.TrendsOptions{
+			Driver: s.driver,
+			Neo4j:  s.options.Neo4j,
+		})
+		if err != nil {
+			klog.V(1).Infof("trends.New failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+
+		err = trendsMgr.Start()
+		if err != nil {
+			klog.V(1).Infof("trends.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+		s.trends = trendsMgr
+	}
+
 	// redirect
 	mux := http.NewServeMux()
+	mux.HandleFunc(DefaultTrendsPath, s.processTrends)
+	if s.guard != nil {
+		// the outbox holds the events of every tenant, admins only see their own
+		mux.Handle(outbox.DefaultOutboxPath, s.guard.AdminHandler(s.outbox))
+	}
+	s.health.Register(mux)
 	mux.HandleFunc("/", s.processConversation)
 
 	s.server = &http.Server{
@@ -316,12 +625,23 @@ func (s *Server) Start() error {
 	// start the main entry endpoint to direct traffic
 	go func() {
 		// this is a blocking call
-		err := s.server.ListenAndServeTLS(s.options.CrtFile, s.options.KeyFile)
+		err := s.tls.ListenAndServeTLS(s.server)
 		if err != nil {
 			klog.V(6).Infof("ListenAndServeTLS server stopped. Err: %v\n", err)
 		}
 	}()
 
+	// reconnect with backoff from now on
+	for _, sup := range s.supervisors {
+		err := sup.Start()
+		if err != nil {
+			klog.V(1).Infof("supervisor.Start failed. Err: %v\n", err)
+			klog.V(6).Infof("Server.Start LEAVE\n")
+			return err
+		}
+	}
+	s.health.Started()
+
 	klog.V(4).Infof("Server.Start Succeeded\n")
 	klog.V(6).Infof("Server.Start LEAVE\n")
 
@@ -338,15 +658,12 @@ func (s *Server) RebuildDatabase() error {
 		s.driver = nil
 	}
 
-	// init neo4j
-	auth := neo4j.BasicAuth(s.creds.Username, s.creds.Password, "")
-
 	// You typically have one driver instance for the entire application. The
 	// driver maintains a pool of database connections to be used by the sessions.
 	// The driver is thread safe.
-	driver, err := neo4j.NewDriverWithContext(s.creds.ConnectionStr, auth)
+	driver, err := s.options.Neo4j.NewDriver()
 	if err != nil {
-		klog.V(1).Infof("NewDriverWithContext failed. Err: %v\n", err)
+		klog.V(1).Infof("NewDriver failed. Err: %v\n", err)
 		klog.V(6).Infof("Server.RebuildDatabase LEAVE\n")
 		return err
 	}
@@ -363,6 +680,43 @@ func (s *Server) RebuildDatabase() error {
 func (s *Server) Stop() error {
 	klog.V(6).Infof("Server.Stop ENTER\n")
 
+	// readiness turns false while everything below shuts down
+	s.health.Drain()
+
+	// no reconnects while shutting down
+	for _, sup := range s.supervisors {
+		err := sup.Stop()
+		if err != nil {
+			klog.V(1).Infof("supervisor.Stop failed. Err: %v\n", err)
+		}
+	}
+
+	// stop rollups
+	if s.trends != nil {
+		err := s.trends.Stop()
+		if err != nil {
+			klog.V(1).Infof("trends.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.trends = nil
+
+	// flush and stop the outbox relay
+	if s.outbox != nil {
+		err := s.outbox.Stop()
+		if err != nil {
+			klog.V(1).Infof("outbox.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.outbox = nil
+
+	if s.bus != nil {
+		err := (*s.bus).Teardown()
+		if err != nil {
+			klog.V(1).Infof("bus.Teardown() failed. Err: %v\n", err)
+		}
+	}
+	s.bus = nil
+
 	// clean up neo4j driver
 	if s.driver != nil {
 		ctx := context.Background()
@@ -379,6 +733,24 @@ func (s *Server) Stop() error {
 	}
 	s.server = nil
 
+	// stop the metrics endpoint
+	if s.metrics != nil {
+		err := s.metrics.Stop()
+		if err != nil {
+			klog.V(1).Infof("metrics.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.metrics = nil
+
+	// flush the spans
+	if s.tracer != nil {
+		err := s.tracer.Stop()
+		if err != nil {
+			klog.V(1).Infof("tracer.Stop() failed. Err: %v\n", err)
+		}
+	}
+	s.tracer = nil
+
 	klog.V(4).Infof("Server.Stop Succeeded\n")
 	klog.V(6).Infof("Server.Stop LEAVE\n")
 
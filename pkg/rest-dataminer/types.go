@@ -8,33 +8,71 @@ import (
 	"sync"
 
 	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
-)
 
-// Credentials is the input needed to login to neo4j
-type Credentials struct {
-	ConnectionStr string
-	Username      string
-	Password      string
-}
+	auth "github.com/dvonthenen/enterprise-conversation-application/pkg/auth"
+	businterfaces "github.com/dvonthenen/enterprise-conversation-application/pkg/bus/interfaces"
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+	encryption "github.com/dvonthenen/enterprise-conversation-application/pkg/encryption"
+	health "github.com/dvonthenen/enterprise-conversation-application/pkg/health"
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	outbox "github.com/dvonthenen/enterprise-conversation-application/pkg/outbox"
+	rawevent "github.com/dvonthenen/enterprise-conversation-application/pkg/rawevent"
+	redaction "github.com/dvonthenen/enterprise-conversation-application/pkg/redaction"
+	supervisor "github.com/dvonthenen/enterprise-conversation-application/pkg/supervisor"
+	tlsconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/tlsconfig"
+	tracing "github.com/dvonthenen/enterprise-conversation-application/pkg/tracing"
+	trends "github.com/dvonthenen/enterprise-conversation-application/pkg/trends"
+)
 
 // ServerOptions for the main HTTP endpoint
 type ServerOptions struct {
 	AuthMethod       AuthType
 	CrtFile          string
 	KeyFile          string
+	TLS              *tlsconfig.Options // nil serves CrtFile and KeyFile with the defaults
+	Neo4j            *dbconfig.Config   // defaults to dbconfig.FromEnv()
 	RabbitURI        string
+	Bus              *businterfaces.Bus  // defaults to the bus for the RabbitURI scheme
+	ContentType      string              // codec.ContentTypeJSON (default) or codec.ContentTypeProtobuf
+	RawPolicy        rawevent.Policy     // rawevent.DefaultPolicy when empty
+	Redaction        *redaction.Options  // nil saves and publishes the payloads as received
+	Encryption       *encryption.Options // nil stores the content in plaintext
+	Auth             *auth.Options       // nil turns off the outbox endpoint and only allows the default tenant
 	DisableDuplicate bool
+	Metrics          *metrics.ServerOptions   // nil disables the metrics endpoint
+	Tracing          *tracing.ProviderOptions // nil disables tracing
 }
 
 type Server struct {
 	// server versions
 	options ServerOptions
-	creds   Credentials
 
 	// bookkeeping
 	server *http.Server
+	tls    *tlsconfig.Reloader
 	mu     sync.Mutex
 
 	// neo4j
-	driver *neo4j.DriverWithContext
+	driver     *neo4j.DriverWithContext
+	encryptors *encryption.Encryptors
+
+	// authn/authz
+	guard *auth.Guard
+
+	// outbox
+	bus    *businterfaces.Bus
+	outbox *outbox.Relay
+
+	// analytics
+	trends *trends.Trends
+
+	// metrics and tracing
+	metrics *metrics.Server
+	tracer  *tracing.Provider
+
+	// health
+	health *health.Checker
+
+	// reconnects
+	supervisors []*supervisor.Supervisor
 }
@@ -24,6 +24,19 @@ const (
 	RabbitAsyncEntity               string = "async-entity-created"
 	RabbitAsyncConversationTeardown string = "async-conversation-teardown"
 
+	// plugin events that failed all retries
+	RabbitDeadLetter string = "dead-letter"
+
+	// event envelope, bump the version when a payload changes in a way old consumers can't read
+	EnvelopeVersion      string = "1"
+	EnvelopeKey          string = "envelope"
+	SourceProxyDataminer string = "symbl-proxy-dataminer"
+	SourceRestDataminer  string = "symbl-rest-dataminer"
+
+	// tenants, conversations created without one belong to the default tenant
+	DefaultTenant string = "default"
+	HeaderTenant  string = "X-ERI-TENANT"
+
 	// user-defined messages
 	MessageTypeUserDefined string = "user_defined"
 
@@ -34,6 +47,20 @@ const (
 	DatabaseIndexTopic        string = "topicId"
 	DatabaseIndexTracker      string = "trackerId"
 	DatabaseIndexInsight      string = "insightId"
-	DatabaseIndexEntity       string = "entityId" // = entity.Type + "_" + entity.SubType + "_" + entity.Category
-	DatabaseIndexEntityMatch  string = "matchId"  // = conversationId + "_" + entityId
+	DatabaseIndexEntity       string = "entityId"   // = entity.Type + "_" + entity.SubType + "_" + entity.Category
+	DatabaseIndexEntityMatch  string = "matchId"    // = conversationId + "_" + entityId
+	DatabaseIndexTopicPhrase  string = "phraseId"   // = utils.NormalizeId(topic phrase)
+	DatabaseIndexTrendRollup  string = "rollupId"   // = kind + "/" + tenant + "/" + key + "/" + day
+	DatabaseIndexRootWord     string = "rootWordId" // = utils.NormalizeId(root word)
+	DatabaseIndexOutboxEvent  string = "eventId"    // = uuid
+	DatabaseIndexRawEvent     string = "rawEventId" // = uuid, or sha256 of the payload when compacted
+)
+
+var (
+	// fields of the Symbl payloads holding transcripts or PII, pkg/redaction applies its policies
+	// to them before they are saved and pkg/logging hides them in the log lines
+	ContentKeys = []string{"content", "text", "transcript", "originalContent", "words", "word", "phrases"}
+	UserKeys    = []string{"userId", "email"}
+	NameKeys    = []string{"name"} // pkg/redaction only looks at the speakers and assignees
+	EntityKeys  = []string{"detectedValue", "value"}
 )
@@ -0,0 +1,86 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package interfaces
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	asyncinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/async/v1/interfaces"
+)
+
+//go:generate go run ../../cmd/schema-generator -out ../../schemas/v1
+//go:generate go run ../../cmd/schema-generator -out ../../schemas/v1 -format proto
+
+// NewEnvelope describes a new event of the given type produced by this host
+func NewEnvelope(id, eventType, conversationId, source string) *Envelope {
+	host, _ := os.Hostname()
+
+	return &Envelope{
+		ID:             id,
+		Type:           eventType,
+		Version:        EnvelopeVersion,
+		ConversationID: conversationId,
+		ProducedAt:     time.Now().UTC(),
+		Source:         source,
+		Host:           host,
+	}
+}
+
+/*
+	WrapEnvelope adds the envelope to a JSON marshalled payload. The payload fields stay where
+	they are so plugins built before the envelope existed can still read the message.
+*/
+func WrapEnvelope(payload []byte, envelope *Envelope) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	err := json.Unmarshal(payload, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	byEnvelope, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+	fields[EnvelopeKey] = byEnvelope
+
+	return json.Marshal(fields)
+}
+
+// ReadEnvelope returns the envelope for a message or nil if it was sent without one
+func ReadEnvelope(byData []byte) (*Envelope, error) {
+	var message struct {
+		Envelope *Envelope `json:"envelope,omitempty"`
+	}
+	err := json.Unmarshal(byData, &message)
+	if err != nil {
+		return nil, err
+	}
+	return message.Envelope, nil
+}
+
+/*
+	UnmarshalJSON accepts the corrected actionItemResult field as well as the misspelled
+	acitonItemResult which version 1 of the envelope still sends
+*/
+func (r *ActionItemResult) UnmarshalJSON(byData []byte) error {
+	type actionItemResult ActionItemResult
+	message := struct {
+		*actionItemResult
+		Corrected *asyncinterfaces.ActionItemResult `json:"actionItemResult,omitempty"`
+	}{
+		actionItemResult: (*actionItemResult)(r),
+	}
+
+	err := json.Unmarshal(byData, &message)
+	if err != nil {
+		return err
+	}
+	if r.ActionItemResult == nil {
+		r.ActionItemResult = message.Corrected
+	}
+
+	return nil
+}
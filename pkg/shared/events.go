@@ -0,0 +1,49 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package interfaces
+
+import (
+	"reflect"
+	"sort"
+)
+
+// the payload carried on each insight channel
+var eventTypes = map[string]reflect.Type{
+	RabbitRealTimeConversationInit:     reflect.TypeOf(InitializationResponse{}),
+	RabbitRealTimeMessage:              reflect.TypeOf(MessageResponse{}),
+	RabbitRealTimeTopic:                reflect.TypeOf(TopicResponse{}),
+	RabbitRealTimeTracker:              reflect.TypeOf(TrackerResponse{}),
+	RabbitRealTimeEntity:               reflect.TypeOf(EntityResponse{}),
+	RabbitRealTimeInsight:              reflect.TypeOf(InsightResponse{}),
+	RabbitRealTimeConversationTeardown: reflect.TypeOf(TeardownResponse{}),
+
+	RabbitAsyncConversationInit:     reflect.TypeOf(InitializationResult{}),
+	RabbitAsyncMessage:              reflect.TypeOf(MessageResult{}),
+	RabbitAsyncQuestion:             reflect.TypeOf(QuestionResult{}),
+	RabbitAsyncFollowUp:             reflect.TypeOf(FollowUpResult{}),
+	RabbitAsyncActionItem:           reflect.TypeOf(ActionItemResult{}),
+	RabbitAsyncTopic:                reflect.TypeOf(TopicResult{}),
+	RabbitAsyncTracker:              reflect.TypeOf(TrackerResult{}),
+	RabbitAsyncEntity:               reflect.TypeOf(EntityResult{}),
+	RabbitAsyncConversationTeardown: reflect.TypeOf(TeardownResult{}),
+}
+
+// EventTypes lists the channels with a known payload
+func EventTypes() []string {
+	names := make([]string, 0, len(eventTypes))
+	for name := range eventTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEvent returns a pointer to an empty payload for the channel or nil if it isn't known
+func NewEvent(eventType string) interface{} {
+	t, ok := eventTypes[eventType]
+	if !ok {
+		return nil
+	}
+	return reflect.New(t).Interface()
+}
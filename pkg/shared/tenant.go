@@ -0,0 +1,24 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package interfaces
+
+import (
+	"regexp"
+)
+
+// tenant ids end up in Cypher parameters, rollup ids and log lines, keep them plain
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ValidTenant is true for ids of letters, digits, '.', '_' and '-' up to 64 characters long
+func ValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
+
+// GetTenant is the tenant the event belongs to, events sent without one belong to the DefaultTenant
+func (e *Envelope) GetTenant() string {
+	if e == nil || len(e.Tenant) == 0 {
+		return DefaultTenant
+	}
+	return e.Tenant
+}
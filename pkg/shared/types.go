@@ -4,48 +4,75 @@
 package interfaces
 
 import (
+	"time"
+
 	asyncinterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/async/v1/interfaces"
 	streaminginterfaces "github.com/dvonthenen/symbl-go-sdk/pkg/api/streaming/v1/interfaces"
 )
 
+/*
+	Envelope describes the event carrying the payload. It is added next to the payload fields
+	so consumers that don't know about it keep working.
+*/
+type Envelope struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	Version        string    `json:"version"`
+	ConversationID string    `json:"conversationId,omitempty"`
+	ProducedAt     time.Time `json:"producedAt"`
+	Source         string    `json:"source"`
+	Host           string    `json:"host,omitempty"`
+	TraceParent    string    `json:"traceparent,omitempty"` // W3C trace context, see pkg/tracing
+	TraceState     string    `json:"tracestate,omitempty"`
+	Tenant         string    `json:"tenant,omitempty"` // DefaultTenant when empty
+}
+
 /*
 	Conversation Insight Responses (RealTime) with Metadata
 */
 type InitializationResponse struct {
+	Envelope              *Envelope                                  `json:"envelope,omitempty"`
 	InitializationMessage *streaminginterfaces.InitializationMessage `json:"initializationMessage,omitempty"`
 }
 
 type RecognitionResponse struct {
+	Envelope          *Envelope                              `json:"envelope,omitempty"`
 	ConversationID    string                                 `json:"conversationId,omitempty"`
 	RecognitionResult *streaminginterfaces.RecognitionResult `json:"recognitionResult,omitempty"`
 }
 
 type MessageResponse struct {
+	Envelope        *Envelope                            `json:"envelope,omitempty"`
 	ConversationID  string                               `json:"conversationId,omitempty"`
 	MessageResponse *streaminginterfaces.MessageResponse `json:"messageResponse,omitempty"`
 }
 
 type InsightResponse struct {
+	Envelope        *Envelope                            `json:"envelope,omitempty"`
 	ConversationID  string                               `json:"conversationId,omitempty"`
 	InsightResponse *streaminginterfaces.InsightResponse `json:"insightResponse,omitempty"`
 }
 
 type TopicResponse struct {
+	Envelope       *Envelope                          `json:"envelope,omitempty"`
 	ConversationID string                             `json:"conversationId,omitempty"`
 	TopicResponse  *streaminginterfaces.TopicResponse `json:"topicResponse,omitempty"`
 }
 
 type TrackerResponse struct {
+	Envelope        *Envelope                            `json:"envelope,omitempty"`
 	ConversationID  string                               `json:"conversationId,omitempty"`
 	TrackerResponse *streaminginterfaces.TrackerResponse `json:"trackerResponse,omitempty"`
 }
 
 type EntityResponse struct {
+	Envelope       *Envelope                           `json:"envelope,omitempty"`
 	ConversationID string                              `json:"conversationId,omitempty"`
 	EntityResponse *streaminginterfaces.EntityResponse `json:"entityResponse,omitempty"`
 }
 
 type TeardownResponse struct {
+	Envelope        *Envelope                            `json:"envelope,omitempty"`
 	TeardownMessage *streaminginterfaces.TeardownMessage `json:"teardownMessage,omitempty"`
 }
 
@@ -53,53 +80,62 @@ type TeardownResponse struct {
 	Conversation Asynchronous Results (Asynchronous) with Metadata
 */
 type InitializationResult struct {
+	Envelope              *Envelope                              `json:"envelope,omitempty"`
 	Duplicate             bool                                   `json:"duplicate,omitempty"`
 	InitializationMessage *asyncinterfaces.InitializationMessage `json:"initializationMessage,omitempty"`
 }
 
 type MessageResult struct {
+	Envelope       *Envelope                      `json:"envelope,omitempty"`
 	Duplicate      bool                           `json:"duplicate,omitempty"`
 	ConversationID string                         `json:"conversationId,omitempty"`
 	MessageResult  *asyncinterfaces.MessageResult `json:"messageResult,omitempty"`
 }
 
 type QuestionResult struct {
+	Envelope       *Envelope                       `json:"envelope,omitempty"`
 	Duplicate      bool                            `json:"duplicate,omitempty"`
 	ConversationID string                          `json:"conversationId,omitempty"`
 	QuestionResult *asyncinterfaces.QuestionResult `json:"questionResult,omitempty"`
 }
 
 type FollowUpResult struct {
+	Envelope       *Envelope                       `json:"envelope,omitempty"`
 	Duplicate      bool                            `json:"duplicate,omitempty"`
 	ConversationID string                          `json:"conversationId,omitempty"`
 	FollowUpResult *asyncinterfaces.FollowUpResult `json:"followUpResult,omitempty"`
 }
 
 type ActionItemResult struct {
+	Envelope         *Envelope                         `json:"envelope,omitempty"`
 	Duplicate        bool                              `json:"duplicate,omitempty"`
 	ConversationID   string                            `json:"conversationId,omitempty"`
 	ActionItemResult *asyncinterfaces.ActionItemResult `json:"acitonItemResult,omitempty"`
 }
 
 type TopicResult struct {
+	Envelope       *Envelope                    `json:"envelope,omitempty"`
 	Duplicate      bool                         `json:"duplicate,omitempty"`
 	ConversationID string                       `json:"conversationId,omitempty"`
 	TopicResult    *asyncinterfaces.TopicResult `json:"topicResult,omitempty"`
 }
 
 type TrackerResult struct {
+	Envelope       *Envelope                      `json:"envelope,omitempty"`
 	Duplicate      bool                           `json:"duplicate,omitempty"`
 	ConversationID string                         `json:"conversationId,omitempty"`
 	TrackerResult  *asyncinterfaces.TrackerResult `json:"trackerResult,omitempty"`
 }
 
 type EntityResult struct {
+	Envelope       *Envelope                     `json:"envelope,omitempty"`
 	Duplicate      bool                          `json:"duplicate,omitempty"`
 	ConversationID string                        `json:"conversationId,omitempty"`
 	EntityResult   *asyncinterfaces.EntityResult `json:"entityResult,omitempty"`
 }
 
 type TeardownResult struct {
+	Envelope        *Envelope                        `json:"envelope,omitempty"`
 	Duplicate       bool                             `json:"duplicate,omitempty"`
 	TeardownMessage *asyncinterfaces.TeardownMessage `json:"teardownResult,omitempty"`
 }
@@ -0,0 +1,37 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package supervisor
+
+import (
+	"errors"
+	"time"
+)
+
+// State of the supervised connection
+type State string
+
+const (
+	StateConnected    State = "connected"
+	StateReconnecting State = "reconnecting"
+)
+
+const (
+	// names of the supervised connections, used in logs and metrics
+	ConnectionNeo4j string = "neo4j"
+	ConnectionBus   string = "bus"
+
+	// defaults
+	DefaultInterval       time.Duration = 10 * time.Second
+	DefaultInitialBackoff time.Duration = time.Second
+	DefaultMaxBackoff     time.Duration = time.Minute
+	DefaultCheckTimeout   time.Duration = 5 * time.Second
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrReconnecting the connection dropped and hasn't been restored yet
+	ErrReconnecting = errors.New("reconnecting")
+)
@@ -0,0 +1,209 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	klog "k8s.io/klog/v2"
+
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+)
+
+/*
+	New creates a supervisor for a connection. Once started it runs Check every Interval. When
+	Check fails the connection is marked as reconnecting and Reconnect is called, followed by
+	Check, until both pass. The attempts back off exponentially from InitialBackoff up to
+	MaxBackoff, with jitter so replicas don't all reconnect at the same moment after a broker
+	restart.
+
+	The state is reported by Healthy, which is meant to be added to a health.Checker, and by
+	the eca_connection_up and eca_reconnects_total metrics.
+*/
+func New(options SupervisorOptions) (*Supervisor, error) {
+	if len(options.Connection) == 0 || options.Check == nil {
+		klog.V(1).Infof("Connection or Check is empty\n")
+		return nil, ErrInvalidInput
+	}
+	if options.Interval == 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.InitialBackoff == 0 {
+		options.InitialBackoff = DefaultInitialBackoff
+	}
+	if options.MaxBackoff == 0 {
+		options.MaxBackoff = DefaultMaxBackoff
+	}
+
+	s := &Supervisor{
+		options: options,
+		state:   StateConnected,
+	}
+	return s, nil
+}
+
+func (s *Supervisor) Start() error {
+	klog.V(6).Infof("Supervisor.Start ENTER\n")
+
+	if s.stopPoll != nil {
+		klog.V(4).Infof("Supervisor for %s is already running\n", s.options.Connection)
+		klog.V(6).Infof("Supervisor.Start LEAVE\n")
+		return nil
+	}
+
+	s.stopPoll = make(chan struct{})
+	s.done = make(chan struct{})
+	metrics.SetConnectionUp(s.options.Component, s.options.Connection, true)
+
+	go s.run(s.stopPoll, s.done)
+
+	klog.V(4).Infof("Supervising %s every %v\n", s.options.Connection, s.options.Interval)
+	klog.V(6).Infof("Supervisor.Start LEAVE\n")
+
+	return nil
+}
+
+func (s *Supervisor) run(stopChan, done chan struct{}) {
+	defer close(done)
+
+	backoff := s.options.InitialBackoff
+	timer := time.NewTimer(s.options.Interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-stopChan:
+			return
+		}
+
+		if s.State() == StateConnected {
+			err := s.check()
+			if err == nil {
+				timer.Reset(s.options.Interval)
+				continue
+			}
+			s.lost(err)
+			backoff = s.options.InitialBackoff
+		}
+
+		// the first attempt is made right away
+		err := s.reconnect()
+		if err == nil {
+			timer.Reset(s.options.Interval)
+			continue
+		}
+
+		timer.Reset(jitter(backoff))
+		backoff *= 2
+		if backoff > s.options.MaxBackoff {
+			backoff = s.options.MaxBackoff
+		}
+	}
+}
+
+func (s *Supervisor) check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCheckTimeout)
+	defer cancel()
+
+	return s.options.Check(ctx)
+}
+
+func (s *Supervisor) lost(err error) {
+	klog.V(1).Infof("Connection %s lost. Err: %v\n", s.options.Connection, err)
+
+	s.mu.Lock()
+	s.state = StateReconnecting
+	s.lastErr = err
+	s.attempts = 0
+	s.mu.Unlock()
+
+	metrics.SetConnectionUp(s.options.Component, s.options.Connection, false)
+}
+
+func (s *Supervisor) reconnect() error {
+	s.mu.Lock()
+	s.attempts++
+	attempts := s.attempts
+	s.mu.Unlock()
+
+	var err error
+	if s.options.Reconnect != nil {
+		err = s.options.Reconnect()
+	}
+	if err == nil {
+		err = s.check()
+	}
+	metrics.ObserveReconnect(s.options.Component, s.options.Connection, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		klog.V(1).Infof("Reconnect %s attempt %d failed. Err: %v\n", s.options.Connection, attempts, err)
+		s.lastErr = err
+		return err
+	}
+
+	klog.V(3).Infof("Connection %s restored after %d attempts\n", s.options.Connection, attempts)
+	s.state = StateConnected
+	s.lastErr = nil
+	s.attempts = 0
+
+	return nil
+}
+
+// jitter waits between half and all of the backoff
+func jitter(backoff time.Duration) time.Duration {
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// State is StateReconnecting from the failed check until the connection is restored
+func (s *Supervisor) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// Healthy fails while reconnecting and otherwise runs the check, it is a health.CheckFunc
+func (s *Supervisor) Healthy(ctx context.Context) error {
+	s.mu.Lock()
+	state := s.state
+	lastErr := s.lastErr
+	attempts := s.attempts
+	s.mu.Unlock()
+
+	if state == StateReconnecting {
+		return fmt.Errorf("%w after %d attempts: %v", ErrReconnecting, attempts, lastErr)
+	}
+	return s.options.Check(ctx)
+}
+
+func (s *Supervisor) Stop() error {
+	klog.V(6).Infof("Supervisor.Stop ENTER\n")
+
+	if s.stopPoll == nil {
+		klog.V(6).Infof("Supervisor.Stop LEAVE\n")
+		return nil
+	}
+
+	// waits for a reconnect in progress
+	close(s.stopPoll)
+	<-s.done
+	s.stopPoll = nil
+	s.done = nil
+
+	klog.V(4).Infof("Supervisor.Stop Succeeded\n")
+	klog.V(6).Infof("Supervisor.Stop LEAVE\n")
+
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package supervisor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SupervisorOptions for New
+type SupervisorOptions struct {
+	Component  string                          // metrics label, ie shared.SourceProxyDataminer
+	Connection string                          // ConnectionNeo4j, ConnectionBus, ...
+	Check      func(ctx context.Context) error // nil while the connection works
+	Reconnect  func() error                    // rebuilds the connection, nil waits for Check to pass again
+
+	Interval       time.Duration // DefaultInterval between checks while connected
+	InitialBackoff time.Duration // DefaultInitialBackoff
+	MaxBackoff     time.Duration // DefaultMaxBackoff
+}
+
+// Supervisor watches a connection and reconnects it with exponential backoff
+type Supervisor struct {
+	options SupervisorOptions
+
+	// state
+	state    State
+	lastErr  error
+	attempts int
+	mu       sync.Mutex
+
+	// housekeeping
+	stopPoll chan struct{}
+	done     chan struct{}
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"errors"
+	"time"
+)
+
+const (
+	// ClientAuthNone doesn't ask for client certificates
+	ClientAuthNone ClientAuth = "none"
+
+	// ClientAuthOptional verifies a client certificate when the client sends one
+	ClientAuthOptional ClientAuth = "optional"
+
+	// ClientAuthRequire rejects clients without a certificate signed by the ClientCAFile
+	ClientAuthRequire ClientAuth = "require"
+
+	// DefaultClientAuth is used when none is given
+	DefaultClientAuth ClientAuth = ClientAuthNone
+)
+
+const (
+	// DefaultMinVersion is the oldest TLS version accepted when none is given
+	DefaultMinVersion uint16 = tls.VersionTLS12
+
+	// DefaultReloadInterval is how often the files are checked for changes during handshakes
+	DefaultReloadInterval time.Duration = 30 * time.Second
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnsupportedClientAuth the client auth mode is not known
+	ErrUnsupportedClientAuth = errors.New("client auth mode is not supported")
+
+	// ErrUnsupportedVersion the TLS version is not known or too old
+	ErrUnsupportedVersion = errors.New("tls version is not supported")
+
+	// ErrUnsupportedCipherSuite the cipher suite is not known or is insecure
+	ErrUnsupportedCipherSuite = errors.New("cipher suite is not supported")
+
+	// ErrInvalidClientCA the client CA file has no PEM certificates in it
+	ErrInvalidClientCA = errors.New("no certificates found in client CA file")
+)
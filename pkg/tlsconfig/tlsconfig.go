@@ -0,0 +1,246 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	New reads the certificate, key and client CAs once so a bad file stops the server from
+	starting. The files are checked again during handshakes, at most once per
+	ReloadInterval, and read again when they changed. New connections get the new files,
+	connections already open keep going with the ones they started with, so rotating a
+	certificate doesn't drop live calls.
+
+	A file that fails to load while reloading is logged and the previous one stays in use.
+*/
+func New(options Options) (*Reloader, error) {
+	if len(options.CrtFile) == 0 || len(options.KeyFile) == 0 {
+		klog.V(1).Infof("CrtFile or KeyFile is empty\n")
+		return nil, ErrInvalidInput
+	}
+	if len(options.ClientAuth) == 0 {
+		options.ClientAuth = DefaultClientAuth
+	}
+	if !SupportedClientAuth(options.ClientAuth) {
+		klog.V(1).Infof("ClientAuth %s is not supported\n", options.ClientAuth)
+		return nil, ErrUnsupportedClientAuth
+	}
+	if options.ClientAuth != ClientAuthNone && len(options.ClientCAFile) == 0 {
+		klog.V(1).Infof("ClientAuth %s needs a ClientCAFile\n", options.ClientAuth)
+		return nil, ErrInvalidInput
+	}
+	if options.MinVersion == 0 {
+		options.MinVersion = DefaultMinVersion
+	}
+	if options.MinVersion < tls.VersionTLS12 {
+		klog.V(1).Infof("MinVersion %x is too old\n", options.MinVersion)
+		return nil, ErrUnsupportedVersion
+	}
+	if options.ReloadInterval <= 0 {
+		options.ReloadInterval = DefaultReloadInterval
+	}
+
+	r := &Reloader{
+		options: options,
+	}
+
+	err := r.Reload()
+	if err != nil {
+		klog.V(1).Infof("Reload failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// TLSConfig for an http.Server or listener, each handshake gets the files currently on disk
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.options.MinVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config(), nil
+		},
+	}
+}
+
+// ListenAndServeTLS serves the server with the TLSConfig, it blocks like http.Server.ListenAndServeTLS
+func (r *Reloader) ListenAndServeTLS(server *http.Server) error {
+	server.TLSConfig = r.TLSConfig()
+	return server.ListenAndServeTLS("", "")
+}
+
+// Reload reads the files now, ie on SIGHUP, instead of waiting for the next check
+func (r *Reloader) Reload() error {
+	klog.V(6).Infof("Reloader.Reload ENTER\n")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.load()
+	if err != nil {
+		klog.V(1).Infof("load failed. Err: %v\n", err)
+		klog.V(6).Infof("Reloader.Reload LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Reloader.Reload Succeeded\n")
+	klog.V(6).Infof("Reloader.Reload LEAVE\n")
+
+	return nil
+}
+
+// SupportedClientAuth is true when the mode is known
+func SupportedClientAuth(clientAuth ClientAuth) bool {
+	switch clientAuth {
+	case ClientAuthNone, ClientAuthOptional, ClientAuthRequire:
+		return true
+	}
+	return false
+}
+
+// ParseVersion converts 1.2 or 1.3 to the TLS version, zero for an empty string
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, ErrUnsupportedVersion
+}
+
+// ParseCipherSuites converts names like TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, insecure suites are refused
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+
+	var ids []uint16
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		id, ok := suites[name]
+		if !ok {
+			klog.V(1).Infof("Cipher suite %s is not supported\n", name)
+			return nil, ErrUnsupportedCipherSuite
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// config returns the config for a new connection, reading the files again when they changed
+func (r *Reloader) config() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < r.options.ReloadInterval {
+		return r.current
+	}
+	r.checked = time.Now()
+
+	if !r.changed() {
+		return r.current
+	}
+
+	err := r.load()
+	if err != nil {
+		klog.V(1).Infof("Reloading %s failed, keeping the previous certificate. Err: %v\n", r.options.CrtFile, err)
+		return r.current
+	}
+	klog.V(3).Infof("Reloaded certificate %s\n", r.options.CrtFile)
+
+	return r.current
+}
+
+// changed is true when any of the files was modified since the last load, the caller holds the lock
+func (r *Reloader) changed() bool {
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			// a file being replaced can be missing for a moment
+			klog.V(3).Infof("os.Stat(%s) failed. Err: %v\n", path, err)
+			return false
+		}
+		if !info.ModTime().Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the files into a new config, the caller holds the lock
+func (r *Reloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.options.CrtFile, r.options.KeyFile)
+	if err != nil {
+		klog.V(1).Infof("tls.LoadX509KeyPair failed. Err: %v\n", err)
+		return err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   r.options.MinVersion,
+		CipherSuites: r.options.CipherSuites,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if len(r.options.ClientCAFile) > 0 {
+		data, err := os.ReadFile(r.options.ClientCAFile)
+		if err != nil {
+			klog.V(1).Infof("os.ReadFile(%s) failed. Err: %v\n", r.options.ClientCAFile, err)
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return ErrInvalidClientCA
+		}
+		config.ClientCAs = pool
+	}
+
+	switch r.options.ClientAuth {
+	case ClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		config.ClientAuth = tls.NoClientCert
+	}
+
+	r.current = config
+	r.modTimes = modTimes
+
+	return nil
+}
+
+// files the config is read from
+func (r *Reloader) files() []string {
+	files := []string{r.options.CrtFile, r.options.KeyFile}
+	if len(r.options.ClientCAFile) > 0 {
+		files = append(files, r.options.ClientCAFile)
+	}
+	return files
+}
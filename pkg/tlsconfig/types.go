@@ -0,0 +1,35 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+// ClientAuth is how client certificates are checked
+type ClientAuth string
+
+// Options for New
+type Options struct {
+	CrtFile        string
+	KeyFile        string
+	ClientCAFile   string        // PEM CA certificates the client certificates are verified against
+	ClientAuth     ClientAuth    // DefaultClientAuth when empty, anything else needs a ClientCAFile
+	MinVersion     uint16        // DefaultMinVersion when zero
+	CipherSuites   []uint16      // the Go defaults when empty, only applies below TLS 1.3
+	ReloadInterval time.Duration // DefaultReloadInterval when zero
+}
+
+// Reloader serves the certificate and client CAs on disk, picking up new files without a restart
+type Reloader struct {
+	options Options
+
+	// config for new connections and the files it was read from
+	current  *tls.Config
+	modTimes map[string]time.Time
+	checked  time.Time
+	mu       sync.Mutex
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tracing
+
+import (
+	"errors"
+)
+
+// Exporter picks where the spans are sent
+type Exporter string
+
+const (
+	// ExporterNone doesn't record any spans
+	ExporterNone Exporter = "none"
+
+	// ExporterStdout writes the spans as JSON, it needs no collector so it works offline
+	ExporterStdout Exporter = "stdout"
+
+	// ExporterOTLP sends the spans to an OpenTelemetry collector over gRPC
+	ExporterOTLP Exporter = "otlp"
+)
+
+const (
+	// InstrumentationName names the tracer used by every component
+	InstrumentationName string = "github.com/dvonthenen/enterprise-conversation-application"
+
+	// DefaultOTLPEndpoint is the collector when Endpoint and OTEL_EXPORTER_OTLP_ENDPOINT are empty
+	DefaultOTLPEndpoint string = "localhost:4317"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrUnsupportedExporter the exporter isn't one of the Exporter values
+	ErrUnsupportedExporter = errors.New("tracing exporter must be none, stdout or otlp")
+)
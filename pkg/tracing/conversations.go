@@ -0,0 +1,86 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tracing
+
+import (
+	"context"
+
+	uuid "github.com/google/uuid"
+	trace "go.opentelemetry.io/otel/trace"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	The plugin callbacks don't take a context. While a callback runs, its span is kept under
+	the conversationId so PublishMessage, which is given the conversationId as the channel name,
+	can continue the trace for the notification sent back to the client.
+*/
+func NewConversations() *Conversations {
+	return &Conversations{
+		contexts: make(map[string]context.Context),
+	}
+}
+
+// StartCallback continues the trace in the envelope with a span for the callback of eventType
+func (c *Conversations) StartCallback(envelope *shared.Envelope, eventType string) trace.Span {
+	ctx := Extract(context.Background(), envelope)
+	ctx, span := Tracer().Start(ctx, eventType, trace.WithSpanKind(trace.SpanKindConsumer))
+
+	if c != nil && envelope != nil && len(envelope.ConversationID) > 0 {
+		c.mu.Lock()
+		c.contexts[envelope.ConversationID] = ctx
+		c.mu.Unlock()
+	}
+	return span
+}
+
+// EndCallback records the result of the callback and forgets the conversation
+func (c *Conversations) EndCallback(envelope *shared.Envelope, span trace.Span, err error) {
+	Fail(span, err)
+	span.End()
+
+	if c != nil && envelope != nil {
+		c.mu.Lock()
+		delete(c.contexts, envelope.ConversationID)
+		c.mu.Unlock()
+	}
+}
+
+/*
+	StartNotification continues the trace of the callback running for the conversation with a
+	span for a notification to the client. When tracing is enabled and the notification is a
+	JSON object, an envelope with the trace is added so the Proxy/Dataminer can finish it.
+*/
+func (c *Conversations) StartNotification(conversationId, source string, data []byte) ([]byte, trace.Span) {
+	ctx, span := Tracer().Start(c.Context(conversationId), shared.RabbitRealTimeClientNotifications, trace.WithSpanKind(trace.SpanKindProducer))
+	if !span.SpanContext().IsValid() {
+		return data, span
+	}
+
+	envelope := shared.NewEnvelope(uuid.New().String(), shared.RabbitRealTimeClientNotifications, conversationId, source)
+	Inject(ctx, envelope)
+
+	wrapped, err := shared.WrapEnvelope(data, envelope)
+	if err != nil {
+		// not a JSON object, send it as is
+		return data, span
+	}
+	return wrapped, span
+}
+
+// Context is the span of the callback running for the conversation or context.Background()
+func (c *Conversations) Context(conversationId string) context.Context {
+	if c == nil {
+		return context.Background()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ctx, ok := c.contexts[conversationId]; ok {
+		return ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tracing
+
+import (
+	"context"
+
+	propagation "go.opentelemetry.io/otel/propagation"
+
+	shared "github.com/dvonthenen/enterprise-conversation-application/pkg/shared"
+)
+
+/*
+	The RabbitMQ publisher doesn't expose AMQP headers, so the W3C trace context is carried in
+	the envelope every event already has. It works the same for each bus and content type.
+*/
+var propagator = propagation.TraceContext{}
+
+// envelopeCarrier maps the traceparent and tracestate headers onto the envelope
+type envelopeCarrier struct {
+	envelope *shared.Envelope
+}
+
+func (c envelopeCarrier) Get(key string) string {
+	switch key {
+	case "traceparent":
+		return c.envelope.TraceParent
+	case "tracestate":
+		return c.envelope.TraceState
+	}
+	return ""
+}
+
+func (c envelopeCarrier) Set(key, value string) {
+	switch key {
+	case "traceparent":
+		c.envelope.TraceParent = value
+	case "tracestate":
+		c.envelope.TraceState = value
+	}
+}
+
+func (c envelopeCarrier) Keys() []string {
+	return []string{"traceparent", "tracestate"}
+}
+
+// Inject saves the span in ctx to the envelope, nothing is saved when tracing is disabled
+func Inject(ctx context.Context, envelope *shared.Envelope) {
+	if envelope == nil {
+		return
+	}
+	propagator.Inject(ctx, envelopeCarrier{envelope: envelope})
+}
+
+// Extract continues the trace saved in the envelope, ctx is returned as is without one
+func Extract(ctx context.Context, envelope *shared.Envelope) context.Context {
+	if envelope == nil || len(envelope.TraceParent) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, envelopeCarrier{envelope: envelope})
+}
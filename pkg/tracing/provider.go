@@ -0,0 +1,152 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tracing
+
+import (
+	"context"
+	"os"
+	"time"
+
+	otel "go.opentelemetry.io/otel"
+	codes "go.opentelemetry.io/otel/codes"
+	otlptracegrpc "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	stdouttrace "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	resource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	trace "go.opentelemetry.io/otel/trace"
+	klog "k8s.io/klog/v2"
+)
+
+// Supported is true for the known exporters, an empty exporter is ExporterNone
+func Supported(exporter Exporter) bool {
+	switch exporter {
+	case "", ExporterNone, ExporterStdout, ExporterOTLP:
+		return true
+	}
+	return false
+}
+
+// Tracer creates the spans for every component, it does nothing until a Provider is started
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Fail marks the span as failed with err, a nil err leaves the span as it is
+func Fail(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func NewProvider(options ProviderOptions) (*Provider, error) {
+	if len(options.ServiceName) == 0 {
+		klog.V(1).Infof("ServiceName is empty\n")
+		return nil, ErrInvalidInput
+	}
+	if !Supported(options.Exporter) {
+		klog.V(1).Infof("Exporter %s is not supported\n", options.Exporter)
+		return nil, ErrUnsupportedExporter
+	}
+	if len(options.Exporter) == 0 {
+		options.Exporter = ExporterNone
+	}
+	if len(options.Endpoint) == 0 {
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+			klog.V(4).Info("OTEL_EXPORTER_OTLP_ENDPOINT found")
+			options.Endpoint = v
+		} else {
+			options.Endpoint = DefaultOTLPEndpoint
+		}
+	}
+	if options.Output == nil {
+		options.Output = os.Stdout
+	}
+
+	p := &Provider{
+		options: options,
+	}
+	return p, nil
+}
+
+/*
+	Start installs the provider for the whole process. The OTLP exporter connects in the
+	background, so a missing collector drops spans instead of failing the command.
+*/
+func (p *Provider) Start() error {
+	klog.V(6).Infof("tracing.Provider.Start ENTER\n")
+
+	if p.options.Exporter == ExporterNone {
+		klog.V(4).Infof("Tracing is disabled\n")
+		klog.V(6).Infof("tracing.Provider.Start LEAVE\n")
+		return nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch p.options.Exporter {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(p.options.Output))
+	case ExporterOTLP:
+		grpcOptions := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(p.options.Endpoint),
+		}
+		if p.options.Insecure {
+			grpcOptions = append(grpcOptions, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(context.Background(), grpcOptions...)
+	}
+	if err != nil {
+		klog.V(1).Infof("New %s exporter failed. Err: %v\n", p.options.Exporter, err)
+		klog.V(6).Infof("tracing.Provider.Start LEAVE\n")
+		return err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(p.options.ServiceName),
+	))
+	if err != nil {
+		klog.V(1).Infof("resource.Merge failed. Err: %v\n", err)
+		klog.V(6).Infof("tracing.Provider.Start LEAVE\n")
+		return err
+	}
+
+	p.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(p.provider)
+
+	klog.V(4).Infof("Sending spans for %s to %s\n", p.options.ServiceName, p.options.Exporter)
+	klog.V(6).Infof("tracing.Provider.Start LEAVE\n")
+
+	return nil
+}
+
+// Stop flushes the spans that haven't been exported yet
+func (p *Provider) Stop() error {
+	klog.V(6).Infof("tracing.Provider.Stop ENTER\n")
+
+	if p.provider == nil {
+		klog.V(6).Infof("tracing.Provider.Stop LEAVE\n")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := p.provider.Shutdown(ctx)
+	if err != nil {
+		klog.V(1).Infof("provider.Shutdown failed. Err: %v\n", err)
+	}
+	p.provider = nil
+
+	klog.V(4).Infof("tracing.Provider.Stop Succeeded\n")
+	klog.V(6).Infof("tracing.Provider.Stop LEAVE\n")
+
+	return err
+}
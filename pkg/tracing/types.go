@@ -0,0 +1,33 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package tracing
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ProviderOptions for the tracer provider
+type ProviderOptions struct {
+	ServiceName string
+	Exporter    Exporter  // ExporterNone when empty
+	Endpoint    string    // host:port of the OTLP collector, defaults to OTEL_EXPORTER_OTLP_ENDPOINT or DefaultOTLPEndpoint
+	Insecure    bool      // talk to the OTLP collector without TLS
+	Output      io.Writer // where ExporterStdout writes, defaults to os.Stdout
+}
+
+// Provider records the spans of this process and sends them to the exporter
+type Provider struct {
+	options  ProviderOptions
+	provider *sdktrace.TracerProvider
+}
+
+// Conversations remembers the span of the plugin callback running for each conversation
+type Conversations struct {
+	contexts map[string]context.Context
+	mu       sync.Mutex
+}
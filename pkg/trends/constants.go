@@ -0,0 +1,56 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package trends
+
+import (
+	"errors"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindTopic   Kind = "topic"
+	KindEntity  Kind = "entity"
+	KindTracker Kind = "tracker"
+)
+
+type Window string
+
+const (
+	WindowDay   Window = "day"
+	WindowWeek  Window = "week"
+	WindowMonth Window = "month"
+)
+
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+const (
+	// rollup defaults
+	DefaultRollupInterval time.Duration = 15 * time.Minute
+	DefaultBackfillDays   int           = 62
+	DefaultLookbackDays   int           = 2
+
+	// query defaults
+	DefaultLimit int = 10
+
+	// date format used for rollup buckets
+	dayFormat string = "2006-01-02"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrInvalidKind trend kind is not supported
+	ErrInvalidKind = errors.New("trend kind is not supported")
+
+	// ErrInvalidWindow trend window is not supported
+	ErrInvalidWindow = errors.New("trend window is not supported")
+)
@@ -0,0 +1,452 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package trends
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	klog "k8s.io/klog/v2"
+
+	metrics "github.com/dvonthenen/enterprise-conversation-application/pkg/metrics"
+	utils "github.com/dvonthenen/enterprise-conversation-application/pkg/utils"
+)
+
+type kindQuery struct {
+	match string
+	key   string
+	label string
+}
+
+var (
+	// how each kind hangs off of a conversation. x is the conversation relationship and n is
+	// the node shared across conversations
+	kindQueries = map[Kind]kindQuery{
+		KindTopic: {
+			match: "(c:Conversation)-[x:TOPICS]-(:Topic)-[:TOPIC_PHRASE]-(n:TopicPhrase)",
+			key:   "n.#phrase_index#",
+			label: "n.phrase",
+		},
+		KindEntity: {
+			match: "(c:Conversation)-[x:ENTITY]-(n:Entity)",
+			key:   "n.#entity_index#",
+			label: "n.value",
+		},
+		KindTracker: {
+			match: "(c:Conversation)-[x:TRACKER]-(n:Tracker)",
+			key:   "n.#tracker_index#",
+			label: "n.name",
+		},
+	}
+
+	windowDays = map[Window]int{
+		WindowDay:   1,
+		WindowWeek:  7,
+		WindowMonth: 30,
+	}
+)
+
+func New(options TrendsOptions) (*Trends, error) {
+	if options.Driver == nil {
+		klog.V(1).Infof("Driver is nil\n")
+		return nil, ErrInvalidInput
+	}
+	if options.RollupInterval == 0 {
+		options.RollupInterval = DefaultRollupInterval
+	}
+	if options.BackfillDays == 0 {
+		options.BackfillDays = DefaultBackfillDays
+	}
+	if options.LookbackDays == 0 {
+		options.LookbackDays = DefaultLookbackDays
+	}
+
+	t := &Trends{
+		options:  options,
+		driver:   options.Driver,
+		ticker:   time.NewTicker(options.RollupInterval),
+		stopPoll: make(chan struct{}),
+	}
+	return t, nil
+}
+
+func (t *Trends) Start() error {
+	klog.V(6).Infof("Trends.Start ENTER\n")
+
+	// scheduled rollup
+	rollup := func(stopChan chan struct{}) {
+		err := t.Rollup()
+		if err != nil {
+			klog.V(1).Infof("Rollup failed. Err: %v\n", err)
+		}
+
+		for {
+			select {
+			case <-t.ticker.C:
+				err := t.Rollup()
+				if err != nil {
+					klog.V(1).Infof("Rollup failed. Err: %v\n", err)
+				}
+			case <-stopChan:
+				return
+			}
+		}
+	}
+	go rollup(t.stopPoll)
+
+	klog.V(4).Infof("Trends.Start Succeeded\n")
+	klog.V(6).Infof("Trends.Start LEAVE\n")
+
+	return nil
+}
+
+/*
+	Rollup counts the conversations each topic phrase, entity and tracker showed up in per day
+	and tenant and saves the result as TrendRollup nodes, in the database of each tenant. The
+	first run backfills BackfillDays, subsequent runs only recompute the last LookbackDays to
+	pick up late arriving conversations.
+*/
+func (t *Trends) Rollup() error {
+	klog.V(6).Infof("Trends.Rollup ENTER\n")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, end := rollupDays(time.Now(), t.lastRollup, t.options.BackfillDays, t.options.LookbackDays)
+
+	for _, database := range t.options.Neo4j.Databases() {
+		for kind := range kindQueries {
+			err := t.rollupKind(database, kind, start, end)
+			if err != nil {
+				klog.V(1).Infof("rollupKind(%s, %s) failed. Err: %v\n", database, kind, err)
+				klog.V(6).Infof("Trends.Rollup LEAVE\n")
+				return err
+			}
+		}
+	}
+
+	t.lastRollup = time.Now()
+
+	klog.V(4).Infof("Trends.Rollup Succeeded\n")
+	klog.V(6).Infof("Trends.Rollup LEAVE\n")
+
+	return nil
+}
+
+func (t *Trends) rollupKind(database string, kind Kind, start, end time.Time) error {
+	kq := kindQueries[kind]
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	session := (*t.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: database})
+	defer session.Close(ctx)
+
+	startWrite := time.Now()
+	_, err := session.ExecuteWrite(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			params := map[string]any{
+				"kind":  string(kind),
+				"start": start.Format(time.RFC3339),
+				"end":   end.Format(time.RFC3339),
+			}
+
+			// clear out the buckets being recomputed, for every tenant
+			deleteRollupQuery := `
+				MATCH (r:TrendRollup { kind: $kind })
+				WHERE r.day >= date(datetime($start)) AND r.day < date(datetime($end))
+				DETACH DELETE r
+				`
+			_, err := tx.Run(ctx, deleteRollupQuery, params)
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed delete rollup objects. Err: %v\n", err)
+				return nil, err
+			}
+
+			createRollupQuery := utils.ReplaceIndexes(fmt.Sprintf(`
+				MATCH %s
+				WHERE x.createdAt >= datetime($start) AND x.createdAt < datetime($end)
+				WITH c.tenantId AS tenant, %s AS key, %s AS label, date(x.createdAt) AS day, count(DISTINCT c) AS mentions
+				MERGE (r:TrendRollup { #rollup_index#: $kind + "/" + tenant + "/" + key + "/" + toString(day) })
+					ON CREATE SET
+						r.createdAt = datetime(),
+						r.lastAccessed = datetime()
+					ON MATCH SET
+						r.lastAccessed = datetime()
+				SET r += { #rollup_index#: $kind + "/" + tenant + "/" + key + "/" + toString(day), kind: $kind, tenantId: tenant, key: key, label: label, day: day, mentions: mentions }
+				`, kq.match, kq.key, kq.label))
+			result, err := tx.Run(ctx, createRollupQuery, params)
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed create rollup objects. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Collect(ctx)
+		})
+	metrics.ObserveNeo4jWrite("trends-"+string(kind), startWrite, err)
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteWrite failed. Err: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// TopMentions returns the topics, entities or trackers found in the most conversations of the tenant within the window
+func (t *Trends) TopMentions(tenant string, kind Kind, window Window, limit int) ([]Mention, error) {
+	klog.V(6).Infof("Trends.TopMentions ENTER\n")
+
+	if _, ok := kindQueries[kind]; !ok {
+		klog.V(1).Infof("Invalid kind: %s\n", kind)
+		klog.V(6).Infof("Trends.TopMentions LEAVE\n")
+		return nil, ErrInvalidKind
+	}
+	days, ok := windowDays[window]
+	if !ok {
+		klog.V(1).Infof("Invalid window: %s\n", window)
+		klog.V(6).Infof("Trends.TopMentions LEAVE\n")
+		return nil, ErrInvalidWindow
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	_, start, end := compareDays(time.Now(), days)
+
+	records, err := t.read(t.options.Neo4j.Database(tenant), `
+		MATCH (r:TrendRollup { kind: $kind, tenantId: $tenant_id })
+		WHERE r.day >= date($start) AND r.day < date($end)
+		WITH r.key AS key, head(collect(r.label)) AS label, sum(r.mentions) AS mentions
+		RETURN key, label, mentions
+		ORDER BY mentions DESC, key ASC
+		LIMIT $limit
+		`, map[string]any{
+		"kind":      string(kind),
+		"tenant_id": tenant,
+		"start":     start.Format(dayFormat),
+		"end":       end.Format(dayFormat),
+		"limit":     limit,
+	})
+	if err != nil {
+		klog.V(1).Infof("read failed. Err: %v\n", err)
+		klog.V(6).Infof("Trends.TopMentions LEAVE\n")
+		return nil, err
+	}
+
+	mentions := make([]Mention, 0)
+	for _, record := range records {
+		mentions = append(mentions, Mention{
+			Kind:     kind,
+			Key:      recordString(record, "key"),
+			Label:    recordString(record, "label"),
+			Mentions: recordInt(record, "mentions"),
+		})
+	}
+
+	klog.V(4).Infof("Trends.TopMentions Succeeded\n")
+	klog.V(6).Infof("Trends.TopMentions LEAVE\n")
+
+	return mentions, nil
+}
+
+// Compare returns what is trending up (or down) for the tenant in the window compared to the window before it
+func (t *Trends) Compare(tenant string, kind Kind, window Window, direction Direction, limit int) ([]Trend, error) {
+	klog.V(6).Infof("Trends.Compare ENTER\n")
+
+	if _, ok := kindQueries[kind]; !ok {
+		klog.V(1).Infof("Invalid kind: %s\n", kind)
+		klog.V(6).Infof("Trends.Compare LEAVE\n")
+		return nil, ErrInvalidKind
+	}
+	days, ok := windowDays[window]
+	if !ok {
+		klog.V(1).Infof("Invalid window: %s\n", window)
+		klog.V(6).Infof("Trends.Compare LEAVE\n")
+		return nil, ErrInvalidWindow
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	order := "DESC"
+	if direction == DirectionDown {
+		order = "ASC"
+	}
+
+	previousStart, currentStart, end := compareDays(time.Now(), days)
+
+	records, err := t.read(t.options.Neo4j.Database(tenant), fmt.Sprintf(`
+		MATCH (r:TrendRollup { kind: $kind, tenantId: $tenant_id })
+		WHERE r.day >= date($previous_start) AND r.day < date($end)
+		WITH r.key AS key, head(collect(r.label)) AS label,
+			sum(CASE WHEN r.day >= date($current_start) THEN r.mentions ELSE 0 END) AS current,
+			sum(CASE WHEN r.day < date($current_start) THEN r.mentions ELSE 0 END) AS previous
+		RETURN key, label, current, previous, current - previous AS delta
+		ORDER BY delta %s, key ASC
+		LIMIT $limit
+		`, order), map[string]any{
+		"kind":           string(kind),
+		"tenant_id":      tenant,
+		"previous_start": previousStart.Format(dayFormat),
+		"current_start":  currentStart.Format(dayFormat),
+		"end":            end.Format(dayFormat),
+		"limit":          limit,
+	})
+	if err != nil {
+		klog.V(1).Infof("read failed. Err: %v\n", err)
+		klog.V(6).Infof("Trends.Compare LEAVE\n")
+		return nil, err
+	}
+
+	trends := make([]Trend, 0)
+	for _, record := range records {
+		trends = append(trends, Trend{
+			Kind:     kind,
+			Key:      recordString(record, "key"),
+			Label:    recordString(record, "label"),
+			Current:  recordInt(record, "current"),
+			Previous: recordInt(record, "previous"),
+			Delta:    recordInt(record, "delta"),
+		})
+	}
+
+	klog.V(4).Infof("Trends.Compare Succeeded\n")
+	klog.V(6).Infof("Trends.Compare LEAVE\n")
+
+	return trends, nil
+}
+
+// SimilarTopics returns the topic phrases of the tenant sharing the most root words with the given phrase
+func (t *Trends) SimilarTopics(tenant, phrase string, limit int) ([]Similar, error) {
+	klog.V(6).Infof("Trends.SimilarTopics ENTER\n")
+
+	if len(phrase) == 0 {
+		klog.V(1).Infof("phrase is empty\n")
+		klog.V(6).Infof("Trends.SimilarTopics LEAVE\n")
+		return nil, ErrInvalidInput
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	records, err := t.read(t.options.Neo4j.Database(tenant), utils.ReplaceIndexes(`
+		MATCH (:Conversation { tenantId: $tenant_id })-[:TOPICS]-(t:Topic)-[:TOPIC_PHRASE]-(p:TopicPhrase { #phrase_index#: $phrase_id })
+		MATCH (t)-[:TOPIC_ROOT_WORD]-(w:RootWord)
+		WITH p, collect(DISTINCT w) AS words
+		UNWIND words AS w
+		MATCH (:Conversation { tenantId: $tenant_id })-[:TOPICS]-(o:Topic)-[:TOPIC_ROOT_WORD]-(w)
+		MATCH (o)-[:TOPIC_PHRASE]-(n:TopicPhrase)
+		WHERE n <> p
+		WITH n, size(words) AS total, count(DISTINCT w) AS shared
+		RETURN n.#phrase_index# AS key, n.phrase AS label, shared, toFloat(shared) / total AS overlap
+		ORDER BY overlap DESC, shared DESC, key ASC
+		LIMIT $limit
+		`), map[string]any{
+		"tenant_id": tenant,
+		"phrase_id": utils.NormalizeId(phrase),
+		"limit":     limit,
+	})
+	if err != nil {
+		klog.V(1).Infof("read failed. Err: %v\n", err)
+		klog.V(6).Infof("Trends.SimilarTopics LEAVE\n")
+		return nil, err
+	}
+
+	similar := make([]Similar, 0)
+	for _, record := range records {
+		overlap, _ := record.Get("overlap")
+		value, _ := overlap.(float64)
+
+		similar = append(similar, Similar{
+			Key:     recordString(record, "key"),
+			Label:   recordString(record, "label"),
+			Shared:  recordInt(record, "shared"),
+			Overlap: value,
+		})
+	}
+
+	klog.V(4).Infof("Trends.SimilarTopics Succeeded\n")
+	klog.V(6).Infof("Trends.SimilarTopics LEAVE\n")
+
+	return similar, nil
+}
+
+func (t *Trends) read(database, query string, params map[string]any) ([]*neo4j.Record, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session := (*t.driver).NewSession(ctx, neo4j.SessionConfig{DatabaseName: database})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx,
+		func(tx neo4j.ManagedTransaction) (any, error) {
+			result, err := tx.Run(ctx, query, params)
+			if err != nil {
+				klog.V(1).Infof("neo4j.Run failed read rollup objects. Err: %v\n", err)
+				return nil, err
+			}
+			return result.Collect(ctx)
+		})
+	if err != nil {
+		klog.V(1).Infof("neo4j.ExecuteRead failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	return result.([]*neo4j.Record), nil
+}
+
+func (t *Trends) Stop() error {
+	klog.V(6).Infof("Trends.Stop ENTER\n")
+
+	// stop thread
+	t.ticker.Stop()
+	close(t.stopPoll)
+
+	klog.V(4).Infof("Trends.Stop Succeeded\n")
+	klog.V(6).Infof("Trends.Stop LEAVE\n")
+
+	return nil
+}
+
+// rollupDays is the range of days the rollup recomputes, up to and including today
+func rollupDays(now, lastRollup time.Time, backfillDays, lookbackDays int) (time.Time, time.Time) {
+	days := lookbackDays
+	if lastRollup.IsZero() {
+		days = backfillDays
+	}
+
+	end := startOfDay(now).AddDate(0, 0, 1)
+	return end.AddDate(0, 0, -days), end
+}
+
+// compareDays splits the last 2*days days, including today, into the previous and the current window
+func compareDays(now time.Time, days int) (time.Time, time.Time, time.Time) {
+	end := startOfDay(now).AddDate(0, 0, 1)
+	currentStart := end.AddDate(0, 0, -days)
+	return currentStart.AddDate(0, 0, -days), currentStart, end
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func recordString(record *neo4j.Record, key string) string {
+	value, ok := record.Get(key)
+	if !ok {
+		return ""
+	}
+	str, _ := value.(string)
+	return str
+}
+
+func recordInt(record *neo4j.Record, key string) int64 {
+	value, ok := record.Get(key)
+	if !ok {
+		return 0
+	}
+	num, _ := value.(int64)
+	return num
+}
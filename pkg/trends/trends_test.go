@@ -0,0 +1,103 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package trends
+
+import (
+	"testing"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func day(value string) time.Time {
+	t, _ := time.Parse(dayFormat, value)
+	return t
+}
+
+func TestRollupDays(t *testing.T) {
+	now := time.Date(2023, time.March, 10, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		lastRollup time.Time
+		start      string
+		end        string
+	}{
+		{name: "first run backfills", now: now, start: "2023-01-08", end: "2023-03-11"},
+		{name: "later runs look back", now: now, lastRollup: now.Add(-15 * time.Minute), start: "2023-03-09", end: "2023-03-11"},
+		{name: "just after midnight", now: time.Date(2023, time.March, 11, 0, 0, 1, 0, time.UTC), lastRollup: now, start: "2023-03-10", end: "2023-03-12"},
+		{name: "days are UTC", now: time.Date(2023, time.March, 10, 20, 0, 0, 0, time.FixedZone("EST", -5*3600)), lastRollup: now, start: "2023-03-10", end: "2023-03-12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := rollupDays(tt.now, tt.lastRollup, DefaultBackfillDays, DefaultLookbackDays)
+			if !start.Equal(day(tt.start)) || !end.Equal(day(tt.end)) {
+				t.Errorf("rollupDays got %s - %s, want %s - %s", start.Format(dayFormat), end.Format(dayFormat), tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestCompareDays(t *testing.T) {
+	now := time.Date(2023, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		window        Window
+		previousStart string
+		currentStart  string
+		end           string
+	}{
+		{window: WindowDay, previousStart: "2023-03-09", currentStart: "2023-03-10", end: "2023-03-11"},
+		{window: WindowWeek, previousStart: "2023-02-25", currentStart: "2023-03-04", end: "2023-03-11"},
+		{window: WindowMonth, previousStart: "2023-01-10", currentStart: "2023-02-09", end: "2023-03-11"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.window), func(t *testing.T) {
+			previousStart, currentStart, end := compareDays(now, windowDays[tt.window])
+			if !previousStart.Equal(day(tt.previousStart)) || !currentStart.Equal(day(tt.currentStart)) || !end.Equal(day(tt.end)) {
+				t.Errorf("compareDays got %s, %s - %s, want %s, %s - %s",
+					previousStart.Format(dayFormat), currentStart.Format(dayFormat), end.Format(dayFormat),
+					tt.previousStart, tt.currentStart, tt.end)
+			}
+		})
+	}
+}
+
+func TestInvalidQueries(t *testing.T) {
+	// never used, the queries are rejected before reaching Neo4j
+	var driver neo4j.DriverWithContext
+	trends, err := New(TrendsOptions{
+		Driver: &driver,
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+	defer trends.ticker.Stop()
+
+	tests := []struct {
+		name   string
+		kind   Kind
+		window Window
+		err    error
+	}{
+		{name: "kind", kind: "speaker", window: WindowWeek, err: ErrInvalidKind},
+		{name: "window", kind: KindTopic, window: "year", err: ErrInvalidWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := trends.TopMentions("acme", tt.kind, tt.window, 0)
+			if err != tt.err {
+				t.Errorf("TopMentions got %v, want %v", err, tt.err)
+			}
+			_, err = trends.Compare("acme", tt.kind, tt.window, DirectionUp, 0)
+			if err != tt.err {
+				t.Errorf("Compare got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package trends
+
+import (
+	"sync"
+	"time"
+
+	neo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	dbconfig "github.com/dvonthenen/enterprise-conversation-application/pkg/dbconfig"
+)
+
+// TrendsOptions to init the trends subsystem
+type TrendsOptions struct {
+	// neo4j
+	Driver *neo4j.DriverWithContext
+	Neo4j  *dbconfig.Config // the databases of the tenants, nil only uses dbconfig.DefaultDatabaseName
+
+	// rollup
+	RollupInterval time.Duration
+	BackfillDays   int
+	LookbackDays   int
+}
+
+// Mention is the number of conversations a topic, entity or tracker showed up in
+type Mention struct {
+	Kind     Kind   `json:"kind"`
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Mentions int64  `json:"mentions"`
+}
+
+// Trend compares mentions in the current window against the window before it
+type Trend struct {
+	Kind     Kind   `json:"kind"`
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Current  int64  `json:"current"`
+	Previous int64  `json:"previous"`
+	Delta    int64  `json:"delta"`
+}
+
+// Similar is a topic phrase sharing root words with another topic phrase
+type Similar struct {
+	Key     string  `json:"key"`
+	Label   string  `json:"label"`
+	Shared  int64   `json:"shared"`
+	Overlap float64 `json:"overlap"`
+}
+
+// Trends aggregates topics, entities and trackers across conversations
+type Trends struct {
+	options TrendsOptions
+
+	// neo4j
+	driver *neo4j.DriverWithContext
+
+	// rollup
+	ticker     *time.Ticker
+	stopPoll   chan struct{}
+	lastRollup time.Time
+	mu         sync.Mutex
+}
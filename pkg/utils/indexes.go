@@ -19,6 +19,11 @@ var (
 		"#insight_index#":      shared.DatabaseIndexInsight,
 		"#entity_index#":       shared.DatabaseIndexEntity,
 		"#match_index#":        shared.DatabaseIndexEntityMatch,
+		"#phrase_index#":       shared.DatabaseIndexTopicPhrase,
+		"#rollup_index#":       shared.DatabaseIndexTrendRollup,
+		"#root_word_index#":    shared.DatabaseIndexRootWord,
+		"#event_index#":        shared.DatabaseIndexOutboxEvent,
+		"#raw_event_index#":    shared.DatabaseIndexRawEvent,
 	}
 )
 
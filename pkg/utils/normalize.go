@@ -0,0 +1,84 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	// words that carry no meaning when comparing topic phrases across conversations
+	phraseStopWords = map[string]bool{
+		"a":    true,
+		"an":   true,
+		"and":  true,
+		"for":  true,
+		"in":   true,
+		"of":   true,
+		"on":   true,
+		"or":   true,
+		"our":  true,
+		"the":  true,
+		"this": true,
+		"that": true,
+		"to":   true,
+		"with": true,
+		"your": true,
+	}
+)
+
+// NormalizePhrase reduces a topic phrase to a canonical form so the same idea expressed slightly
+// differently in two conversations maps to the same key (ie "The Pricing Models!" => "pricing model")
+func NormalizePhrase(phrase string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, phrase)
+
+	words := make([]string, 0)
+	for _, word := range strings.Fields(cleaned) {
+		if phraseStopWords[word] {
+			continue
+		}
+		words = append(words, singularize(word))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// NormalizeId is NormalizePhrase falling back to the lowercased phrase when nothing is left (ie all stop words)
+func NormalizeId(phrase string) string {
+	normalized := NormalizePhrase(phrase)
+	if len(normalized) == 0 {
+		return strings.ToLower(phrase)
+	}
+	return normalized
+}
+
+// EntityId is the key of an Entity node, the same entity found in two conversations gets the same id
+// (ie "Location", "City", "", "New York" => "location/city//new_york")
+func EntityId(category, entityType, subType, value string) string {
+	normalize := func(part string) string {
+		return strings.ToLower(strings.ReplaceAll(part, " ", "_"))
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", normalize(category), normalize(entityType), normalize(subType), normalize(value))
+}
+
+func singularize(word string) string {
+	switch {
+	case len(word) <= 3:
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Enterprise Conversation Application contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache License 2.0
+
+package utils
+
+import (
+	"testing"
+)
+
+func TestNormalizePhrase(t *testing.T) {
+	tests := []struct {
+		name   string
+		phrase string
+		want   string
+	}{
+		{name: "case and punctuation", phrase: "The Pricing Models!", want: "pricing model"},
+		{name: "same idea", phrase: "pricing model", want: "pricing model"},
+		{name: "stop words", phrase: "a review of the pricing for our customers", want: "review pricing customer"},
+		{name: "plural ies", phrase: "Companies", want: "company"},
+		{name: "words ending in ss us is", phrase: "business status analysis", want: "business status analysis"},
+		{name: "short words", phrase: "bus gas", want: "bus gas"},
+		{name: "digits", phrase: "Q3 2023 targets", want: "q3 2023 target"},
+		{name: "separators", phrase: "go-to-market   strategy/plan", want: "go market strategy plan"},
+		{name: "unicode", phrase: "Überweisungen Prüfen", want: "überweisungen prüfen"},
+		{name: "only stop words", phrase: "The And", want: ""},
+		{name: "empty", phrase: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizePhrase(tt.phrase)
+			if got != tt.want {
+				t.Errorf("NormalizePhrase(%q) got %q, want %q", tt.phrase, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeId(t *testing.T) {
+	tests := []struct {
+		name   string
+		phrase string
+		want   string
+	}{
+		{name: "normalized", phrase: "The Pricing Models", want: "pricing model"},
+		{name: "only stop words", phrase: "The And", want: "the and"},
+		{name: "root word", phrase: "Pricing", want: "pricing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeId(tt.phrase)
+			if got != tt.want {
+				t.Errorf("NormalizeId(%q) got %q, want %q", tt.phrase, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntityId(t *testing.T) {
+	tests := []struct {
+		name                          string
+		category, entityType, subType string
+		value                         string
+		want                          string
+	}{
+		{name: "spaces and case", category: "Custom", entityType: "Vendor Name", subType: "SaaS", value: "Acme Corp", want: "custom/vendor_name/saas/acme_corp"},
+		{name: "same entity", category: "custom", entityType: "vendor_name", subType: "saas", value: "acme corp", want: "custom/vendor_name/saas/acme_corp"},
+		{name: "no sub type", category: "Location", entityType: "City", value: "New York", want: "location/city//new_york"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EntityId(tt.category, tt.entityType, tt.subType, tt.value)
+			if got != tt.want {
+				t.Errorf("EntityId got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}